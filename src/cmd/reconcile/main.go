@@ -0,0 +1,80 @@
+// Package main is a command line entry point reconciling a payment provider's settlement file against our
+// transactions, meant to be run on a schedule, e.g. from cron once the provider's daily file is downloaded:
+//
+//	go run ./cmd/reconcile -provider paystack -file settlement-2024-05-01.csv
+//
+// It exits with a non-zero status when the run fails, and with status 2 when it found discrepancies
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	"codematic/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+	codematicStorage "codematic/storage"
+)
+
+func main() {
+	os.Exit(reconcile())
+}
+
+// reconcile runs the reconciliation and returns the exit status, so the deferred cleanups run before exiting
+func reconcile() int {
+	provider := flag.String("provider", "", "payment provider of the settlement file, paystack or flutterwave")
+	fileName := flag.String("file", "", "settlement file to reconcile, .csv or .json")
+	flag.Parse()
+
+	if *provider == "" || *fileName == "" {
+		flag.Usage()
+		return 1
+	}
+
+	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+	applicationLogger := logger.With().Str(helper.LogStrKeyModule, "reconcile").Logger()
+
+	env, err := environment.New()
+	if err != nil {
+		applicationLogger.Error().Err(err).Msg("unable to load the environment")
+		return 1
+	}
+
+	storage := codematicStorage.New(logger, env)
+	defer storage.Close()
+
+	if err := storage.AutoMigrate(); err != nil {
+		applicationLogger.Error().Err(err).Msg("unable to migrate the database")
+		return 1
+	}
+
+	file, err := os.Open(*fileName)
+	if err != nil {
+		applicationLogger.Error().Err(err).Msg("unable to open the settlement file")
+		return 1
+	}
+	defer file.Close()
+
+	application := controller.New(logger, storage, middleware.NewMiddleware(logger, *env, storage))
+
+	run, err := (*application).ReconcileSettlementFile(context.Background(), model.PaymentProvider(*provider), *fileName, file)
+	if err != nil {
+		applicationLogger.Error().Err(err).Msgf("reconciliation of %s failed", *fileName)
+		return 1
+	}
+
+	fmt.Printf("run %s: %d records, %d matched, %d missing on our side, %d missing on %s, %d amount mismatches, %d status mismatches\n",
+		run.ID, run.Records, run.Matched, run.MissingOnOurSide, run.MissingOnProvider, run.Provider, run.AmountMismatches, run.StatusMismatches)
+
+	if run.MissingOnOurSide+run.MissingOnProvider+run.AmountMismatches+run.StatusMismatches > 0 {
+		return 2
+	}
+
+	return 0
+}
@@ -0,0 +1,117 @@
+// Package main is a command line entry point verifying the balance history of the wallets against their ledger
+// accounts and their settled transactions, for every tenant or a single one:
+//
+//	go run ./cmd/verifyledger
+//	go run ./cmd/verifyledger -tenant 5f0c6a3e-... -repair
+//
+// With -repair, wallets whose latest balance entry disagrees with their ledger account get an adjustment entry. It
+// prints a report per tenant and exits with a non-zero status when the verification fails, and with status 2 when
+// breaks are left unrepaired
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	"codematic/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+	codematicStorage "codematic/storage"
+)
+
+func main() {
+	os.Exit(verify())
+}
+
+// verify runs the verification and returns the exit status, so the deferred cleanups run before exiting
+func verify() int {
+	tenant := flag.String("tenant", "", "ID of the tenant to verify, every tenant when empty")
+	repair := flag.Bool("repair", false, "write adjustment entries for wallets whose balance history disagrees with their ledger account")
+	flag.Parse()
+
+	var tenantID uuid.UUID
+	if *tenant != "" {
+		var err error
+		if tenantID, err = uuid.Parse(*tenant); err != nil {
+			flag.Usage()
+			return 1
+		}
+	}
+
+	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+	applicationLogger := logger.With().Str(helper.LogStrKeyModule, "verifyledger").Logger()
+
+	env, err := environment.New()
+	if err != nil {
+		applicationLogger.Error().Err(err).Msg("unable to load the environment")
+		return 1
+	}
+
+	storage := codematicStorage.New(logger, env)
+	defer storage.Close()
+
+	if err := storage.AutoMigrate(); err != nil {
+		applicationLogger.Error().Err(err).Msg("unable to migrate the database")
+		return 1
+	}
+
+	application := controller.New(logger, storage, middleware.NewMiddleware(logger, *env, storage))
+
+	var verifications []model.LedgerVerification
+	if *tenant != "" {
+		verification, err := (*application).VerifyTenantLedger(context.Background(), tenantID, *repair)
+		if err != nil {
+			applicationLogger.Error().Err(err).Msgf("verification of tenant %s failed", tenantID)
+			return 1
+		}
+
+		verifications = append(verifications, verification)
+	} else if verifications, err = (*application).VerifyLedger(context.Background(), *repair); err != nil {
+		applicationLogger.Error().Err(err).Msg("verification failed")
+		return 1
+	}
+
+	unrepaired := 0
+	for _, verification := range verifications {
+		fmt.Printf("tenant %s: verification %s, %d wallets, %d entries, %d breaks, %d repaired\n", verification.TenantID,
+			verification.ID, verification.Wallets, verification.Entries, verification.Breaks, verification.Repaired)
+
+		for _, item := range verification.Items {
+			fmt.Printf("  %s wallet %s user %s: expected %s, got %s%s\n", item.Kind, item.WalletID, item.UserID,
+				item.Expected, item.Actual, breakReference(item))
+		}
+
+		unrepaired += verification.Breaks - verification.Repaired
+	}
+
+	if unrepaired > 0 {
+		return 2
+	}
+
+	return 0
+}
+
+// breakReference names the balance entry and transaction a break is about, if any
+func breakReference(item model.LedgerBreak) string {
+	reference := ""
+	if item.BalanceID != nil {
+		reference += fmt.Sprintf(", balance %s", item.BalanceID)
+	}
+
+	if item.TransactionID != nil {
+		reference += fmt.Sprintf(", transaction %s", item.TransactionID)
+	}
+
+	if item.Repaired {
+		reference += ", repaired"
+	}
+
+	return reference
+}
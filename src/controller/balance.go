@@ -19,14 +19,15 @@ func (c *Controller) CreateBalance(ctx context.Context, balance model.Balance) (
 	return newBalance, nil
 }
 
-func (c *Controller) GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+// GetLastBalanceByUserID returns the latest balance of the user's wallet in the currency
+func (c *Controller) GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID, currency string) (model.Balance, error) {
 	user, err := c.GetUserByID(ctx, userID)
 	if err != nil {
 		c.logger.Error().Msgf("GetTransactionByTypeOfFlow ::: error retrieving user => %v", err)
 		return model.Balance{}, err
 	}
 
-	balance, err := c.balanceStorage.GetLastBalanceByUserID(ctx, user.ID)
+	balance, err := c.balanceStorage.GetLastBalanceByUserID(ctx, user.ID, currency)
 	if err != nil {
 		c.logger.Err(err).Msgf("GetBalanceByID failed: unable to fetch record %s", err)
 		return balance, err
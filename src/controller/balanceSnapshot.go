@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/storage"
+)
+
+// GetBalanceAsOf returns the balance of the user's wallet in the currency at asOf, every movement made before it
+func (c *Controller) GetBalanceAsOf(ctx context.Context, userID uuid.UUID, currency string, asOf time.Time) (model.BalanceAsOf, error) {
+	wallet, err := c.walletStorage.GetWalletByUserID(ctx, userID, currency)
+	if err != nil {
+		return model.BalanceAsOf{}, ErrNoWalletForCurrency
+	}
+
+	return c.balanceAsOf(ctx, wallet, asOf)
+}
+
+// GetTenantUserBalanceAsOf returns the balance of the wallet in the currency of one of the tenant's users at asOf
+func (c *Controller) GetTenantUserBalanceAsOf(ctx context.Context, tenantID, userID uuid.UUID, currency string, asOf time.Time) (model.BalanceAsOf, error) {
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil || user.TenantID != tenantID {
+		return model.BalanceAsOf{}, ErrRecordNotFound
+	}
+
+	return c.GetBalanceAsOf(ctx, userID, currency, asOf)
+}
+
+// GetTenantBalanceTotals returns the closing balances of the tenant's wallets at the end of the day, one total per
+// currency. Only the date of day is used, in the tenant's timezone. Wallets the snapshot job has not covered yet for
+// that day are snapshotted first
+func (c *Controller) GetTenantBalanceTotals(ctx context.Context, tenantID uuid.UUID, day time.Time) ([]model.BalanceTotal, error) {
+	tenant, err := c.tenantStorage.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	snapshotDay, closedAt := dayClose(day, tenant.Location())
+	if closedAt.After(time.Now()) {
+		return nil, ErrDayNotClosed
+	}
+
+	if _, err := c.snapshotTenantBalances(ctx, tenant, snapshotDay, closedAt); err != nil {
+		return nil, err
+	}
+
+	return c.balanceSnapshotStorage.GetBalanceTotals(ctx, tenantID, snapshotDay)
+}
+
+// SnapshotBalances saves the closing balance of every wallet for the last day that has ended in its tenant's
+// timezone. Wallets already snapshotted for that day are skipped, so it is safe to run as often as needed. It returns
+// how many snapshots were saved, a tenant that fails is logged and retried on the next run
+func (c *Controller) SnapshotBalances(ctx context.Context) (int, error) {
+	tenants, err := c.tenantStorage.GetTenants(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	saved := 0
+	for _, tenant := range tenants {
+		location := tenant.Location()
+		snapshotDay, closedAt := dayClose(now.In(location).AddDate(0, 0, -1), location)
+
+		count, err := c.snapshotTenantBalances(ctx, tenant, snapshotDay, closedAt)
+		saved += count
+		if err != nil {
+			c.logger.Err(err).Msgf("SnapshotBalances ::: tenant %s day %s ===> %v", tenant.ID, snapshotDay, err)
+		}
+	}
+
+	return saved, nil
+}
+
+// snapshotTenantBalances saves the closing balance of the tenant's wallets that have no snapshot for the day yet
+func (c *Controller) snapshotTenantBalances(ctx context.Context, tenant model.Tenant, day string, closedAt time.Time) (int, error) {
+	wallets, err := c.walletStorage.GetWalletsByTenantID(ctx, tenant.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	done, err := c.balanceSnapshotStorage.GetSnapshottedWalletIDs(ctx, tenant.ID, day)
+	if err != nil {
+		return 0, err
+	}
+
+	snapshotted := make(map[uuid.UUID]bool, len(done))
+	for _, walletID := range done {
+		snapshotted[walletID] = true
+	}
+
+	saved := 0
+	for _, wallet := range wallets {
+		// wallets opened after the day have no balance to close it with
+		if snapshotted[wallet.ID] || wallet.LedgerAccountID == nil || !wallet.CreatedAt.Before(closedAt) {
+			continue
+		}
+
+		balance, err := c.ledgerStorage.GetPostingsTotal(ctx, *wallet.LedgerAccountID, time.Time{}, closedAt)
+		if err != nil {
+			return saved, err
+		}
+
+		err = c.balanceSnapshotStorage.SaveBalanceSnapshot(ctx, model.BalanceSnapshot{
+			ID:              uuid.New(),
+			WalletID:        wallet.ID,
+			Day:             day,
+			UserID:          wallet.UserID,
+			TenantID:        tenant.ID,
+			LedgerAccountID: *wallet.LedgerAccountID,
+			Balance:         balance,
+			ClosedAt:        closedAt,
+		})
+		if err != nil {
+			return saved, err
+		}
+
+		saved++
+	}
+
+	return saved, nil
+}
+
+// balanceAsOf adds the postings made to the wallet's ledger account since its latest snapshot closed before asOf to
+// the balance of that snapshot, or sums all of them when the wallet has no such snapshot
+func (c *Controller) balanceAsOf(ctx context.Context, wallet model.Wallet, asOf time.Time) (model.BalanceAsOf, error) {
+	result := model.BalanceAsOf{
+		WalletID: wallet.ID,
+		UserID:   wallet.UserID,
+		AsOf:     asOf,
+		Balance:  model.ZeroMoney(wallet.Currency),
+	}
+
+	if wallet.LedgerAccountID == nil {
+		return result, nil
+	}
+
+	var from time.Time
+	snapshot, err := c.balanceSnapshotStorage.GetLatestBalanceSnapshot(ctx, wallet.ID, asOf)
+	switch err {
+	case nil:
+		from = snapshot.ClosedAt
+		result.Balance = snapshot.Balance
+		result.SnapshotDay = snapshot.Day
+	case storage.ErrRecordNotFound:
+	default:
+		return model.BalanceAsOf{}, err
+	}
+
+	later, err := c.ledgerStorage.GetPostingsTotal(ctx, *wallet.LedgerAccountID, from, asOf)
+	if err != nil {
+		return model.BalanceAsOf{}, err
+	}
+
+	if result.Balance, err = result.Balance.Add(later); err != nil {
+		return model.BalanceAsOf{}, err
+	}
+
+	return result, nil
+}
+
+// dayClose returns the date of day as a snapshot day and the midnight it closes at, both in location
+func dayClose(day time.Time, location *time.Location) (string, time.Time) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, location)
+	return start.Format(model.SnapshotDayLayout), start.AddDate(0, 0, 1)
+}
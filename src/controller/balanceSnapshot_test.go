@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DayClose(t *testing.T) {
+	lagos, err := time.LoadLocation("Africa/Lagos")
+	require.NoError(t, err)
+
+	// 23:30 UTC on March 31 is already April 1 in Lagos, the day is taken from the date as given
+	day, closedAt := dayClose(time.Date(2024, time.March, 31, 23, 30, 0, 0, time.UTC), lagos)
+	require.Equal(t, "2024-03-31", day)
+	require.True(t, closedAt.Equal(time.Date(2024, time.March, 31, 23, 0, 0, 0, time.UTC)))
+
+	day, closedAt = dayClose(time.Date(2024, time.March, 31, 23, 30, 0, 0, time.UTC).In(lagos), lagos)
+	require.Equal(t, "2024-04-01", day)
+	require.True(t, closedAt.Equal(time.Date(2024, time.April, 1, 23, 0, 0, 0, time.UTC)))
+
+	// the last day of the year closes on the first midnight of the next one
+	day, closedAt = dayClose(time.Date(2024, time.December, 31, 12, 0, 0, 0, time.UTC), time.UTC)
+	require.Equal(t, "2024-12-31", day)
+	require.True(t, closedAt.Equal(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)))
+}
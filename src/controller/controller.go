@@ -3,6 +3,8 @@ package controller
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -34,24 +36,109 @@ type Operations interface {
 	GetAuditLogByID(ctx context.Context, id uuid.UUID) (model.AuditLog, error)
 
 	CreateBalance(ctx context.Context, balance model.Balance) (model.Balance, error)
-	GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID) (model.Balance, error)
+	GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID, currency string) (model.Balance, error)
 
-	GetWalletByUserID(ctx context.Context, userID uuid.UUID) (model.Wallet, error)
+	GetWalletByUserID(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error)
+	GetWalletsByUserID(ctx context.Context, userID uuid.UUID) ([]model.Wallet, error)
+	OpenWallet(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error)
+	GetWalletStatement(ctx context.Context, userID uuid.UUID, currency string, from, to time.Time) (model.Statement, error)
+	GetBalanceAsOf(ctx context.Context, userID uuid.UUID, currency string, asOf time.Time) (model.BalanceAsOf, error)
+
+	SetFXRate(ctx context.Context, tenantID *uuid.UUID, rate model.FXRate) (model.FXRate, error)
+	GetFXRates(ctx context.Context, tenantID uuid.UUID) ([]model.FXRate, error)
+	LoadFXRatesFromFile(ctx context.Context, path string) (int, error)
+	QuoteFX(ctx context.Context, userID uuid.UUID, amount model.Money, to string) (model.FXQuote, error)
+	ConvertFX(ctx context.Context, userID uuid.UUID, amount model.Money, to string) (model.FXConversion, error)
 
 	CreateTransaction(ctx context.Context, transaction model.Transaction) (model.Transaction, error)
 	GetTransactionsByUserID(ctx context.Context, userID uuid.UUID, transactionFlow *model.TransactionFlow, page pagination.Page) ([]model.Transaction, pagination.PageInfo, error)
 	GetTransactionByID(ctx context.Context, transactionID uuid.UUID) (model.Transaction, error)
 	UpdateTransactionByID(ctx context.Context, transaction model.Transaction) error
 
-	ProcessPaymentWebhook(ctx context.Context, payload model.PaymentWebhook) error
+	ProcessPaymentWebhook(ctx context.Context, event model.PaymentEvent) error
+	ReceiveWebhookEvent(ctx context.Context, event model.WebhookEvent) (model.WebhookEvent, error)
+	RejectWebhookEvent(ctx context.Context, event model.WebhookEvent, reason string) (model.WebhookEvent, error)
+	ReplayWebhookEvent(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error)
+	GetWebhookEvent(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error)
+	GetWebhookEvents(ctx context.Context, provider *model.PaymentProvider, status *model.WebhookEventStatus, page pagination.Page) ([]model.WebhookEvent, pagination.PageInfo, error)
 
 	CreateTenant(ctx context.Context, tenant model.Tenant) (model.Tenant, error)
 	GetAllUsersByTenantID(ctx context.Context, tenantId uuid.UUID, page pagination.Page) ([]*model.User, pagination.PageInfo, error)
 	AuthenticateTenant(ctx context.Context, email, password string) (model.Tenant, error)
+	SetAllowCrossTenantTransfers(ctx context.Context, tenantID uuid.UUID, allow bool) (model.Tenant, error)
+	SetTenantTimezone(ctx context.Context, tenantID uuid.UUID, timezone string) (model.Tenant, error)
+	GetTenantUserBalanceAsOf(ctx context.Context, tenantID, userID uuid.UUID, currency string, asOf time.Time) (model.BalanceAsOf, error)
+	GetTenantBalanceTotals(ctx context.Context, tenantID uuid.UUID, day time.Time) ([]model.BalanceTotal, error)
+	RefundTransaction(ctx context.Context, tenantID, transactionID uuid.UUID, amount *model.Money, reason, reference string) (model.Refund, error)
+	GetRefundsByTransactionID(ctx context.Context, tenantID, transactionID uuid.UUID) ([]model.Refund, error)
+	RetryStuckRefunds(ctx context.Context) (int, error)
+
+	OpenDispute(ctx context.Context, actor model.Actor, actorID, transactionID uuid.UUID, amount *model.Money, reason string) (model.Dispute, error)
+	AddDisputeEvidence(ctx context.Context, actor model.Actor, actorID, disputeID uuid.UUID, note string) (model.Dispute, error)
+	DecideDispute(ctx context.Context, tenantID, disputeID uuid.UUID, status model.DisputeStatus, note string) (model.Dispute, error)
+	GetDispute(ctx context.Context, actor model.Actor, actorID, disputeID uuid.UUID) (model.Dispute, error)
+	GetDisputes(ctx context.Context, actor model.Actor, actorID uuid.UUID, status *model.DisputeStatus, page pagination.Page) ([]model.Dispute, pagination.PageInfo, error)
 
 	VirtualAccount(ctx context.Context, userID uuid.UUID, fullName, bankName string) (model.VirtualAccount, error)
-	Deposit(ctx context.Context, userID uuid.UUID, amount float64) error
-	Transfer(ctx context.Context, userID uuid.UUID, bankNumber, accountNumber string, amount float64) error
+	Deposit(ctx context.Context, userID uuid.UUID, amount model.Money, splitID *uuid.UUID) error
+	Transfer(ctx context.Context, userID uuid.UUID, bankNumber, accountNumber string, amount model.Money) error
+	InternalTransfer(ctx context.Context, senderID uuid.UUID, recipientEmail string, amount model.Money, narration string) (model.Transaction, error)
+	ExpireHolds(ctx context.Context) (int, error)
+	SnapshotBalances(ctx context.Context) (int, error)
+
+	ReconcileSettlementFile(ctx context.Context, provider model.PaymentProvider, fileName string, r io.Reader) (model.ReconciliationRun, error)
+	GetReconciliationRun(ctx context.Context, runID uuid.UUID) (model.ReconciliationRun, error)
+	GetReconciliationRuns(ctx context.Context, provider *model.PaymentProvider, page pagination.Page) ([]model.ReconciliationRun, pagination.PageInfo, error)
+	GetReconciliationItems(ctx context.Context, runID uuid.UUID, kind *model.ReconciliationKind, page pagination.Page) ([]model.ReconciliationItem, pagination.PageInfo, error)
+
+	VerifyLedger(ctx context.Context, repair bool) ([]model.LedgerVerification, error)
+	VerifyTenantLedger(ctx context.Context, tenantID uuid.UUID, repair bool) (model.LedgerVerification, error)
+	GetLedgerVerifications(ctx context.Context, tenantID *uuid.UUID, page pagination.Page) ([]model.LedgerVerification, pagination.PageInfo, error)
+
+	SetFeeRule(ctx context.Context, tenantID uuid.UUID, rule model.FeeRule) (model.FeeRule, error)
+	RetireFeeRule(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, currency string) error
+	GetFeeRules(ctx context.Context, tenantID uuid.UUID, withRetired bool) ([]model.FeeRule, error)
+	QuoteFee(ctx context.Context, userID uuid.UUID, action model.FeeAction, amount model.Money) (model.FeeQuote, error)
+	QuoteTenantFee(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, amount model.Money) (model.FeeQuote, error)
+
+	GetRevenueWallets(ctx context.Context, tenantID uuid.UUID) ([]model.RevenueWallet, error)
+	GetRevenueHistory(ctx context.Context, tenantID uuid.UUID, currency string, page pagination.Page) ([]model.RevenueEntry, pagination.PageInfo, error)
+	GetRevenueDays(ctx context.Context, tenantID uuid.UUID, currency string, from, to time.Time) ([]model.RevenueDay, error)
+	WithdrawRevenue(ctx context.Context, tenantID uuid.UUID, bankNumber, accountNumber string, amount model.Money) (model.RevenueWithdrawal, error)
+	GetRevenueWithdrawals(ctx context.Context, tenantID uuid.UUID, page pagination.Page) ([]model.RevenueWithdrawal, pagination.PageInfo, error)
+	SetWalletStatus(ctx context.Context, tenantID, userID uuid.UUID, currency string, status model.WalletStatus, reason string) (model.Wallet, error)
+	CreatePayoutBatch(ctx context.Context, userID uuid.UUID, fileName, currency string, r io.Reader) (model.PayoutBatch, error)
+	ProcessPayoutBatches(ctx context.Context) (int, error)
+	GetPayoutBatch(ctx context.Context, userID, batchID uuid.UUID) (model.PayoutBatch, error)
+	GetPayoutBatches(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.PayoutBatch, pagination.PageInfo, error)
+	GetPayoutItems(ctx context.Context, userID, batchID uuid.UUID, status *model.PayoutItemStatus, page pagination.Page) ([]model.PayoutItem, pagination.PageInfo, error)
+	GetPayoutResult(ctx context.Context, userID, batchID uuid.UUID) (model.PayoutBatch, []model.PayoutItem, error)
+	CreateInvoice(ctx context.Context, userID uuid.UUID, invoice model.Invoice) (model.Invoice, error)
+	GetInvoice(ctx context.Context, userID, invoiceID uuid.UUID) (model.Invoice, error)
+	GetInvoices(ctx context.Context, userID uuid.UUID, status *model.InvoiceStatus, page pagination.Page) ([]model.Invoice, pagination.PageInfo, error)
+	GetInvoiceByReference(ctx context.Context, reference string) (model.Invoice, error)
+	PayInvoice(ctx context.Context, reference, payerName, payerEmail string, amount *model.Money) (model.InvoicePayment, error)
+	ExpireInvoices(ctx context.Context) (int64, error)
+	GetNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, page pagination.Page) ([]model.Notification, pagination.PageInfo, error)
+	MarkNotificationRead(ctx context.Context, userID, notificationID uuid.UUID) error
+	CreateEscrow(ctx context.Context, buyerID uuid.UUID, sellerEmail string, amount model.Money, description string, autoReleaseAt *time.Time) (model.Escrow, error)
+	ReleaseEscrow(ctx context.Context, actor model.Actor, actorID, escrowID uuid.UUID) (model.Escrow, error)
+	RefundEscrow(ctx context.Context, actor model.Actor, actorID, escrowID uuid.UUID) (model.Escrow, error)
+	ReleaseDueEscrows(ctx context.Context) (int, error)
+	GetEscrow(ctx context.Context, actor model.Actor, actorID, escrowID uuid.UUID) (model.Escrow, error)
+	GetEscrows(ctx context.Context, actor model.Actor, actorID uuid.UUID, status *model.EscrowStatus, page pagination.Page) ([]model.Escrow, pagination.PageInfo, error)
+
+	CreateSplit(ctx context.Context, actor model.Actor, actorID uuid.UUID, ownerEmail string, split model.Split) (model.Split, error)
+	GetSplit(ctx context.Context, actor model.Actor, actorID, splitID uuid.UUID) (model.Split, error)
+	GetSplits(ctx context.Context, actor model.Actor, actorID uuid.UUID, page pagination.Page) ([]model.Split, pagination.PageInfo, error)
+
+	BeginIdempotentRequest(ctx context.Context, request model.IdempotencyKey) (model.IdempotencyKey, error)
+	CompleteIdempotentRequest(ctx context.Context, key model.IdempotencyKey) error
+	AbandonIdempotentRequest(ctx context.Context, key model.IdempotencyKey) error
+	PurgeIdempotencyKeys(ctx context.Context) (int64, error)
+	SetTransactionLimit(ctx context.Context, tenantID uuid.UUID, limit model.TransactionLimit) (model.TransactionLimit, error)
+	GetTransactionLimits(ctx context.Context, tenantID uuid.UUID) ([]model.TransactionLimit, error)
+	DeleteTransactionLimit(ctx context.Context, tenantID uuid.UUID, action model.LimitAction, currency string) error
 }
 
 // Controller object to hold necessary reference to other dependencies
@@ -61,12 +148,31 @@ type Controller struct {
 	middleware *middleware.Middleware
 
 	// storage layers
-	userStorage        storage.UserDatabase
-	auditLogStorage    storage.AuditLogDatabase
-	balanceStorage     storage.BalanceDatabase
-	walletStorage      storage.WalletDatabase
-	transactionStorage storage.TransactionDatabase
-	tenantStorage      storage.TenantDatabase
+	repos                     storage.Repositories
+	userStorage               storage.UserDatabase
+	auditLogStorage           storage.AuditLogDatabase
+	balanceStorage            storage.BalanceDatabase
+	walletStorage             storage.WalletDatabase
+	transactionStorage        storage.TransactionDatabase
+	tenantStorage             storage.TenantDatabase
+	ledgerStorage             storage.LedgerDatabase
+	holdStorage               storage.HoldDatabase
+	fxStorage                 storage.FXDatabase
+	refundStorage             storage.RefundDatabase
+	disputeStorage            storage.DisputeDatabase
+	reconciliationStorage     storage.ReconciliationDatabase
+	balanceSnapshotStorage    storage.BalanceSnapshotDatabase
+	ledgerVerificationStorage storage.LedgerVerificationDatabase
+	feeStorage                storage.FeeDatabase
+	revenueStorage            storage.RevenueDatabase
+	limitStorage              storage.LimitDatabase
+	payoutStorage             storage.PayoutDatabase
+	invoiceStorage            storage.InvoiceDatabase
+	notificationStorage       storage.NotificationDatabase
+	escrowStorage             storage.EscrowDatabase
+	splitStorage              storage.SplitDatabase
+	idempotencyStorage        storage.IdempotencyDatabase
+	webhookEventStorage       storage.WebhookEventDatabase
 
 	redis redis.KvStore
 	// third party services
@@ -77,38 +183,65 @@ type Controller struct {
 func New(z zerolog.Logger, s *storage.Storage, m *middleware.Middleware) *Operations {
 	l := z.With().Str(helper.LogStrKeyModule, packageName).Logger()
 
-	// init all storage layer under here
-	user := storage.NewUser(s)
-
-	auditLog := storage.NewAuditLog(s)
-	balance := storage.NewBalance(s)
-	wallet := storage.NewWallet(s)
-	transaction := storage.NewTransaction(s)
-	tenant := storage.NewTenant(s)
-
 	newRedis := redis.NewRedis(s.Env, z, s.Env.Get("REDIS_SERVER_ADDRESS"))
 
 	payment := payment.New(z, s.Env, s)
 	ctrl := &Controller{
-		logger:      l,
-		env:         s.Env,
-		middleware:  m,
-		userStorage: *user,
-
-		auditLogStorage:    *auditLog,
-		balanceStorage:     *balance,
-		walletStorage:      *wallet,
-		transactionStorage: *transaction,
-		tenantStorage:      *tenant,
+		logger:     l,
+		env:        s.Env,
+		middleware: m,
 
 		redis:          *newRedis,
 		paymentService: *payment,
 	}
 
+	// init all storage layer under here
+	ctrl.bind(storage.NewRepositories(s))
+
 	op := Operations(ctrl)
 	return &op
 }
 
+// bind points every storage layer of the controller at the repositories
+func (c *Controller) bind(repos storage.Repositories) {
+	c.repos = repos
+	c.userStorage = repos.User
+	c.auditLogStorage = repos.AuditLog
+	c.balanceStorage = repos.Balance
+	c.walletStorage = repos.Wallet
+	c.transactionStorage = repos.Transaction
+	c.tenantStorage = repos.Tenant
+	c.ledgerStorage = repos.Ledger
+	c.holdStorage = repos.Hold
+	c.fxStorage = repos.FX
+	c.refundStorage = repos.Refund
+	c.disputeStorage = repos.Dispute
+	c.reconciliationStorage = repos.Reconciliation
+	c.balanceSnapshotStorage = repos.BalanceSnapshot
+	c.ledgerVerificationStorage = repos.LedgerVerification
+	c.feeStorage = repos.Fee
+	c.revenueStorage = repos.Revenue
+	c.limitStorage = repos.Limit
+	c.payoutStorage = repos.Payout
+	c.invoiceStorage = repos.Invoice
+	c.notificationStorage = repos.Notification
+	c.escrowStorage = repos.Escrow
+	c.splitStorage = repos.Split
+	c.idempotencyStorage = repos.Idempotency
+	c.webhookEventStorage = repos.WebhookEvent
+}
+
+// withTx runs fn as a unit of work. The controller handed to fn has every storage layer bound to one
+// database transaction, so all the writes fn makes through it are committed or rolled back together
+func (c *Controller) withTx(ctx context.Context, fn func(tc *Controller) error) error {
+	return c.repos.WithTx(ctx, func(repos storage.Repositories) error {
+		tc := *c
+		tc.bind(repos)
+
+		return fn(&tc)
+	})
+}
+
 // Middleware returns the middleware object exposed by this app
 func (c *Controller) Middleware() *middleware.Middleware {
 	return c.middleware
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"codematic/model"
+	"codematic/pkg/environment"
+	"codematic/storage"
+	"codematic/thirdparty/payment"
+)
+
+// testController returns a controller on the postgres database of PG_TEST_DSN, with redis in memory and the
+// simulated payment providers. The test is skipped without a database
+func testController(t *testing.T) (*Controller, *gorm.DB) {
+	t.Helper()
+
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	s := storage.NewFromDB(db)
+	s.Logger = zerolog.Nop()
+	require.NoError(t, s.AutoMigrate())
+
+	c := &Controller{
+		logger:         zerolog.Nop(),
+		env:            &environment.Env{},
+		redis:          &memoryKvStore{values: map[string]string{}},
+		paymentService: *payment.New(zerolog.Nop(), &environment.Env{}, s),
+	}
+	c.bind(storage.NewRepositories(s))
+
+	return c, db
+}
+
+// testUser creates a tenant and one of its users with a wallet in the default currency
+func testUser(t *testing.T, c *Controller, db *gorm.DB) (model.Tenant, model.User) {
+	t.Helper()
+
+	tenant := model.Tenant{ID: uuid.New(), BusinessName: "Test Ltd", Email: uuid.NewString() + "@tenant.test", Password: "secret"}
+	require.NoError(t, db.Create(&tenant).Error)
+
+	return tenant, testTenantUser(t, c, db, tenant)
+}
+
+// testTenantUser creates a user of the tenant with a wallet in the default currency
+func testTenantUser(t *testing.T, c *Controller, db *gorm.DB, tenant model.Tenant) model.User {
+	t.Helper()
+
+	user := model.User{ID: uuid.New(), TenantID: tenant.ID, FirstName: "Ada", LastName: "Obi", Email: uuid.NewString() + "@user.test", Password: "secret"}
+	require.NoError(t, db.Create(&user).Error)
+
+	_, err := c.openWallet(context.Background(), user, model.DefaultCurrency)
+	require.NoError(t, err)
+
+	return user
+}
+
+// testDeposit credits the user's wallet with a deposit the provider collected, as its webhook would, priced with the
+// tenant's fee rule as Deposit prices it
+func testDeposit(t *testing.T, c *Controller, user model.User, amount model.Money) model.Transaction {
+	t.Helper()
+	ctx := context.Background()
+
+	quote, err := c.quoteFee(ctx, user.TenantID, model.FeeActionDeposit, amount)
+	require.NoError(t, err)
+
+	deposit := model.Transaction{
+		ID:              uuid.New(),
+		UserID:          user.ID,
+		Amount:          amount,
+		Charges:         model.ZeroMoney(amount.Currency),
+		Currency:        amount.Currency,
+		Provider:        model.PaymentProviderPaystack,
+		TransactionType: model.TransactionTypeCredit,
+		Status:          model.TransactionStatusPending,
+		TransactionFlow: model.TransactionFlowRevenue,
+	}
+	deposit.SetFee(quote)
+
+	deposit, err = c.CreateTransaction(ctx, deposit)
+	require.NoError(t, err)
+
+	require.NoError(t, c.ProcessPaymentWebhook(ctx, model.PaymentEvent{
+		Provider:  model.PaymentProviderPaystack,
+		Kind:      model.PaymentEventCharge,
+		Status:    model.TransactionStatusSuccessful,
+		Reference: "crt_" + deposit.ID.String(),
+		Amount:    amount,
+		Fees:      model.ZeroMoney(amount.Currency),
+	}))
+
+	deposit, err = c.GetTransactionByID(ctx, deposit.ID)
+	require.NoError(t, err)
+	return deposit
+}
@@ -0,0 +1,336 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/storage"
+)
+
+// OpenDispute opens a dispute against a successful deposit, on behalf of the user who made it or of their tenant.
+// A nil amount disputes whatever is left of what the deposit credited to the wallet after its refunds and other
+// disputes. The dispute creates the pending debit it posts if it is lost, and freezes the disputed amount on the wallet
+// with a hold for it. When part of the amount was spent already, only what is available is frozen
+func (c *Controller) OpenDispute(ctx context.Context, actor model.Actor, actorID, transactionID uuid.UUID, amount *model.Money, reason string) (model.Dispute, error) {
+	var dispute model.Dispute
+
+	err := c.withTx(ctx, func(tc *Controller) error {
+		// lock the deposit so disputes and refunds of it are made one after the other
+		original, err := tc.transactionStorage.GetTransactionByIDForUpdate(ctx, transactionID)
+		if err != nil {
+			return ErrRecordNotFound
+		}
+
+		user, err := tc.GetUserByID(ctx, original.UserID)
+		if err != nil {
+			return err
+		}
+
+		if !canAccessDispute(actor, actorID, user.TenantID, user.ID) {
+			return ErrRecordNotFound
+		}
+
+		if !original.IsProviderCollection() {
+			return ErrTransactionNotDisputable
+		}
+
+		open, err := tc.disputeStorage.GetDisputesByTransactionID(ctx, original.ID, model.DisputeStatusOpened, model.DisputeStatusEvidenceSubmitted)
+		if err != nil {
+			return err
+		}
+
+		if len(open) > 0 {
+			return ErrDisputeAlreadyOpen
+		}
+
+		remaining, err := tc.reversibleAmount(ctx, original)
+		if err != nil {
+			return err
+		}
+
+		disputed := remaining
+		if amount != nil {
+			disputed = *amount
+		}
+
+		if err := checkReversalAmount(disputed, remaining, ErrDisputeExceedsAmount); err != nil {
+			return err
+		}
+
+		// the wallet's status is not checked on purpose: a chargeback is imposed by the payer's bank, not asked for by the
+		// user, and a frozen wallet must not keep funds the provider takes back
+		wallet, err := tc.walletForDebit(ctx, user.ID, disputed.Currency)
+		if err != nil {
+			return err
+		}
+
+		debit := model.Transaction{
+			ID:                   uuid.New(),
+			UserID:               user.ID,
+			Amount:               disputed,
+			Charges:              model.ZeroMoney(disputed.Currency),
+			Currency:             disputed.Currency,
+			TransactionType:      model.DebitTransaction,
+			Status:               model.TransactionStatusPending,
+			Provider:             original.Provider,
+			TransactionFlow:      model.TransactionFlowDispute,
+			RelatedTransactionID: &original.ID,
+		}
+
+		if _, err := tc.CreateTransaction(ctx, debit); err != nil {
+			tc.logger.Err(err).Msgf("OpenDispute ::: CreateTransaction ===> %v", err)
+			return err
+		}
+
+		frozen, err := tc.freezeDisputedAmount(ctx, wallet, debit)
+		if err != nil {
+			tc.logger.Err(err).Msgf("OpenDispute ::: freezeDisputedAmount ===> %v", err)
+			return err
+		}
+
+		dispute, err = tc.disputeStorage.CreateDispute(ctx, model.Dispute{
+			ID:                 uuid.New(),
+			TenantID:           user.TenantID,
+			UserID:             user.ID,
+			TransactionID:      original.ID,
+			DebitTransactionID: debit.ID,
+			OpenedBy:           actor,
+			Amount:             disputed,
+			Reason:             reason,
+			Status:             model.DisputeStatusOpened,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tc.auditDispute(ctx, dispute, actor, model.ActionInDispute,
+			fmt.Sprintf("dispute of %s opened by %s, %s frozen: %s", disputed, actor, frozen, reason))
+	})
+	if err != nil {
+		return model.Dispute{}, err
+	}
+
+	return dispute, nil
+}
+
+// AddDisputeEvidence attaches an evidence note to an open dispute and moves it to evidence_submitted
+func (c *Controller) AddDisputeEvidence(ctx context.Context, actor model.Actor, actorID, disputeID uuid.UUID, note string) (model.Dispute, error) {
+	err := c.withTx(ctx, func(tc *Controller) error {
+		dispute, err := tc.disputeStorage.GetDisputeByIDForUpdate(ctx, disputeID)
+		if err != nil || !canAccessDispute(actor, actorID, dispute.TenantID, dispute.UserID) {
+			return ErrRecordNotFound
+		}
+
+		if !dispute.Status.CanMoveTo(model.DisputeStatusEvidenceSubmitted) {
+			return ErrDisputeClosed
+		}
+
+		if err := tc.addDisputeEvidence(ctx, dispute, actor, note); err != nil {
+			return err
+		}
+
+		if err := tc.disputeStorage.UpdateDisputeStatus(ctx, dispute.ID, model.DisputeStatusEvidenceSubmitted, nil); err != nil {
+			return err
+		}
+
+		return tc.auditDispute(ctx, dispute, actor, model.ActionInDispute, fmt.Sprintf("evidence submitted by %s", actor))
+	})
+	if err != nil {
+		return model.Dispute{}, err
+	}
+
+	return c.disputeStorage.GetDisputeByID(ctx, disputeID)
+}
+
+// DecideDispute closes an open dispute of one of the tenant's users. A lost dispute captures the frozen amount and
+// posts the disputed amount from the wallet back to the provider, a won or resolved dispute releases the frozen
+// amount and cancels the pending debit. The note, when given, is attached to the dispute as the tenant's evidence
+func (c *Controller) DecideDispute(ctx context.Context, tenantID, disputeID uuid.UUID, status model.DisputeStatus, note string) (model.Dispute, error) {
+	if status != model.DisputeStatusWon && status != model.DisputeStatusLost && status != model.DisputeStatusResolved {
+		return model.Dispute{}, ErrInvalidDisputeStatus
+	}
+
+	err := c.withTx(ctx, func(tc *Controller) error {
+		dispute, err := tc.disputeStorage.GetDisputeByIDForUpdate(ctx, disputeID)
+		if err != nil || dispute.TenantID != tenantID {
+			return ErrRecordNotFound
+		}
+
+		if !dispute.Status.CanMoveTo(status) {
+			return ErrDisputeClosed
+		}
+
+		if note != "" {
+			if err := tc.addDisputeEvidence(ctx, dispute, model.ActorTenant, note); err != nil {
+				return err
+			}
+		}
+
+		debit, err := tc.transactionStorage.GetTransactionByIDForUpdate(ctx, dispute.DebitTransactionID)
+		if err != nil {
+			return err
+		}
+
+		if status == model.DisputeStatusLost {
+			err = tc.postLostDispute(ctx, dispute, debit)
+		} else {
+			err = tc.cancelDisputeDebit(ctx, debit)
+		}
+		if err != nil {
+			return err
+		}
+
+		closedAt := time.Now()
+		if err := tc.disputeStorage.UpdateDisputeStatus(ctx, dispute.ID, status, &closedAt); err != nil {
+			return err
+		}
+
+		return tc.auditDispute(ctx, dispute, model.ActorTenant, model.ActionResolved, fmt.Sprintf("dispute of %s %s", dispute.Amount, status))
+	})
+	if err != nil {
+		return model.Dispute{}, err
+	}
+
+	return c.disputeStorage.GetDisputeByID(ctx, disputeID)
+}
+
+// GetDispute returns a dispute with its evidence, to the user it was opened for or to their tenant
+func (c *Controller) GetDispute(ctx context.Context, actor model.Actor, actorID, disputeID uuid.UUID) (model.Dispute, error) {
+	dispute, err := c.disputeStorage.GetDisputeByID(ctx, disputeID)
+	if err != nil || !canAccessDispute(actor, actorID, dispute.TenantID, dispute.UserID) {
+		return model.Dispute{}, ErrRecordNotFound
+	}
+
+	return dispute, nil
+}
+
+// GetDisputes returns the disputes of the user, or of every user of the tenant, optionally in one status
+func (c *Controller) GetDisputes(ctx context.Context, actor model.Actor, actorID uuid.UUID, status *model.DisputeStatus, page pagination.Page) ([]model.Dispute, pagination.PageInfo, error) {
+	filter := storage.DisputeFilter{Status: status}
+	if actor == model.ActorTenant {
+		filter.TenantID = &actorID
+	} else {
+		filter.UserID = &actorID
+	}
+
+	return c.disputeStorage.GetDisputes(ctx, filter, page)
+}
+
+// canAccessDispute reports whether the actor may see and act on a dispute of the user of the tenant
+func canAccessDispute(actor model.Actor, actorID, tenantID, userID uuid.UUID) bool {
+	switch actor {
+	case model.ActorTenant:
+		return actorID == tenantID
+	case model.ActorUser:
+		return actorID == userID
+	default:
+		return false
+	}
+}
+
+// freezeDisputedAmount holds the disputed amount of the pending dispute debit on the wallet, or what is available of
+// it. The hold lasts until the dispute is decided, at most model.DisputeHoldTTL
+func (c *Controller) freezeDisputedAmount(ctx context.Context, wallet model.Wallet, debit model.Transaction) (model.Money, error) {
+	frozen := debit.Amount
+
+	left, err := wallet.AvailableBalance.Sub(debit.Amount)
+	if err != nil {
+		return model.Money{}, err
+	}
+
+	if left.IsNegative() {
+		frozen = wallet.AvailableBalance
+	}
+
+	if !frozen.IsPositive() {
+		return model.ZeroMoney(debit.Amount.Currency), nil
+	}
+
+	_, err = c.holdStorage.CreateHold(ctx, model.Hold{
+		ID:            uuid.New(),
+		WalletID:      wallet.ID,
+		UserID:        wallet.UserID,
+		TransactionID: debit.ID,
+		Amount:        frozen,
+		Status:        model.HoldStatusActive,
+		ExpiresAt:     time.Now().Add(model.DisputeHoldTTL),
+	})
+
+	return frozen, err
+}
+
+// postLostDispute captures the frozen amount of a lost dispute and moves the disputed amount from the wallet back to
+// the provider's clearing account. The wallet can go below zero when part of the amount had been spent already
+func (c *Controller) postLostDispute(ctx context.Context, dispute model.Dispute, debit model.Transaction) error {
+	user, err := c.GetUserByID(ctx, dispute.UserID)
+	if err != nil {
+		return err
+	}
+
+	// a lost dispute is debited whatever the wallet's status, like the dispute was opened, see OpenDispute
+	wallet, err := c.walletForDebit(ctx, user.ID, dispute.Amount.Currency)
+	if err != nil {
+		return err
+	}
+
+	if wallet, err = c.ensureWalletLedgerAccount(ctx, user, wallet); err != nil {
+		return err
+	}
+
+	if err := c.settleHold(ctx, debit.ID, model.HoldStatusCaptured); err != nil {
+		return err
+	}
+
+	if _, err := c.postProviderTransaction(ctx, user.TenantID, debit, model.LedgerAccount{ID: *wallet.LedgerAccountID}, dispute.Amount); err != nil {
+		c.logger.Err(err).Msgf("postLostDispute ::: error posting dispute debit to the ledger ===> %v", err)
+		return err
+	}
+
+	if err := c.recordWalletMovement(ctx, wallet, debit); err != nil {
+		return err
+	}
+
+	debit.Status = model.TransactionStatusSuccessful
+	return c.UpdateTransactionByID(ctx, debit)
+}
+
+// cancelDisputeDebit releases the frozen amount of a dispute that was not lost and cancels its pending debit
+func (c *Controller) cancelDisputeDebit(ctx context.Context, debit model.Transaction) error {
+	if err := c.settleHold(ctx, debit.ID, model.HoldStatusReleased); err != nil {
+		return err
+	}
+
+	debit.Status = model.TransactionStatusCanceled
+	return c.UpdateTransactionByID(ctx, debit)
+}
+
+func (c *Controller) addDisputeEvidence(ctx context.Context, dispute model.Dispute, actor model.Actor, note string) error {
+	_, err := c.disputeStorage.CreateDisputeEvidence(ctx, model.DisputeEvidence{
+		ID:          uuid.New(),
+		DisputeID:   dispute.ID,
+		SubmittedBy: actor,
+		Note:        note,
+	})
+
+	return err
+}
+
+func (c *Controller) auditDispute(ctx context.Context, dispute model.Dispute, actor model.Actor, action model.AuditLogAction, message string) error {
+	auditLog := model.AuditLog{
+		ID:            uuid.New(),
+		TenantID:      &dispute.TenantID,
+		UserID:        &dispute.UserID,
+		TransactionID: &dispute.TransactionID,
+		Actor:         actor,
+		ActionDone:    action,
+		Messages:      message,
+	}
+
+	_, err := c.CreateAuditLog(ctx, auditLog)
+	return err
+}
@@ -2,6 +2,9 @@ package controller
 
 import (
 	"errors"
+	"fmt"
+
+	"codematic/model"
 )
 
 var (
@@ -19,4 +22,121 @@ var (
 	ErrTransactionID = errors.New("error getting transaction with ID")
 	// MismatchedTransactionType when the transaction type from the webhook and that in the transaction model mismatch
 	MismatchedTransactionType = errors.New("mismatch transaction type")
+	// ErrNoWalletForCurrency when the user has no wallet in the currency of the transaction
+	ErrNoWalletForCurrency = errors.New("no wallet in this currency")
+	// ErrNoFXRate when the tenant has no rate to convert between both currencies
+	ErrNoFXRate = errors.New("no fx rate for this currency pair")
+	// ErrSelfTransfer when a user makes an internal transfer to themselves
+	ErrSelfTransfer = errors.New("cannot transfer to yourself")
+	// ErrCrossTenantTransfer when an internal transfer crosses tenants that do not both allow it
+	ErrCrossTenantTransfer = errors.New("transfers to users of another tenant are not allowed")
+	// ErrTransactionNotRefundable when a refund is asked for a transaction that is not a successful provider credit
+	ErrTransactionNotRefundable = errors.New("only successful deposits can be refunded")
+	// ErrRefundExceedsAmount when a refund is larger than what is left to refund of the transaction
+	ErrRefundExceedsAmount = errors.New("refund exceeds the amount left to refund")
+	// ErrRefundReferenceReused when a refund reference was already used for another refund
+	ErrRefundReferenceReused = errors.New("refund reference already used for another refund")
+	// ErrProviderCallFailed when a request failed after it was sent to the payment provider, which may still have
+	// carried it out
+	ErrProviderCallFailed = errors.New("payment provider call failed")
+	// ErrTransactionNotDisputable when a dispute is opened against a transaction that is not a successful provider credit
+	ErrTransactionNotDisputable = errors.New("only successful deposits can be disputed")
+	// ErrDisputeAlreadyOpen when a transaction already has a dispute waiting for a decision
+	ErrDisputeAlreadyOpen = errors.New("transaction already has an open dispute")
+	// ErrDisputeExceedsAmount when a dispute is larger than what is left of the transaction
+	ErrDisputeExceedsAmount = errors.New("dispute exceeds the amount left of the transaction")
+	// ErrDisputeClosed when a dispute that was already decided is changed
+	ErrDisputeClosed = errors.New("dispute is already closed")
+	// ErrInvalidDisputeStatus when a dispute is decided with a status other than won, lost or resolved
+	ErrInvalidDisputeStatus = errors.New("dispute can only be decided as won, lost or resolved")
+	// ErrInvalidSettlementFile when a provider's settlement file cannot be read, the reason is wrapped in it
+	ErrInvalidSettlementFile = errors.New("invalid settlement file")
+	// ErrInvalidStatementPeriod when a statement ends before it starts or spans more than maxStatementDays
+	ErrInvalidStatementPeriod = errors.New("statement period must start before it ends and span at most 366 days")
+	// ErrInvalidTimezone when a tenant sets a timezone that is not an IANA timezone, e.g. Africa/Lagos
+	ErrInvalidTimezone = errors.New("invalid timezone")
+	// ErrDayNotClosed when end-of-day balances are asked for a day that has not ended yet in the tenant's timezone
+	ErrDayNotClosed = errors.New("day has not ended yet")
+	// ErrFeeExceedsAmount when the fee of a deposit would take all of it
+	ErrFeeExceedsAmount = errors.New("fee exceeds the amount")
+	// ErrFeeRuleConflict when another version of a fee rule was set at the same time
+	ErrFeeRuleConflict = errors.New("fee rule was changed at the same time, try again")
+	// ErrInvalidRevenuePeriod when a revenue breakdown ends before it starts or spans more than maxStatementDays
+	ErrInvalidRevenuePeriod = errors.New("revenue period must start before it ends and span at most 366 days")
+	// ErrInsufficientFunds when a debit is larger than the wallet's available balance, see InsufficientFundsError
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrWalletDebitBlocked when funds would leave a wallet that is frozen or post-no-debit
+	ErrWalletDebitBlocked = errors.New("wallet does not allow debits")
+	// ErrWalletCreditBlocked when funds would enter a wallet that is frozen or post-no-credit
+	ErrWalletCreditBlocked = errors.New("wallet does not allow credits")
+	// ErrInvalidPayoutBatch when a payout batch is refused up front, the reason is wrapped in it
+	ErrInvalidPayoutBatch = errors.New("invalid payout batch")
+	// ErrPayoutBatchNotFinished when the result of a payout batch is asked before every item was paid
+	ErrPayoutBatchNotFinished = errors.New("payout batch has not finished yet")
+	// ErrInvoiceNotPayable when a payment is started on an invoice that is paid or expired
+	ErrInvoiceNotPayable = errors.New("invoice cannot be paid")
+	// ErrInvalidInvoicePayment when the amount of an invoice payment is more than is left to pay, or less on an
+	// invoice that must be paid in full
+	ErrInvalidInvoicePayment = errors.New("invalid invoice payment")
+	// ErrSelfEscrow when a buyer puts funds in escrow for themselves
+	ErrSelfEscrow = errors.New("cannot put funds in escrow for yourself")
+	// ErrInvalidEscrowDeadline when the auto release time of an escrow is in the past or more than a year away
+	ErrInvalidEscrowDeadline = errors.New("auto release must be in the future and at most a year away")
+	// ErrEscrowClosed when an escrow that was already released or refunded is closed again
+	ErrEscrowClosed = errors.New("escrow is already closed")
+	// ErrEscrowCloseNotAllowed when a party to an escrow that can only see it closes it, i.e. the seller
+	ErrEscrowCloseNotAllowed = errors.New("escrow cannot be closed by you")
+	// ErrSplitNotUsable when a payment is attached to a split that is not its receiver's, or is in another currency
+	ErrSplitNotUsable = errors.New("split cannot divide this payment")
+	// ErrInvalidWebhookReference when a webhook's reference is not a dbt_ or crt_ prefix and a transaction ID
+	ErrInvalidWebhookReference = errors.New("the reference format is dbt_... or crt_...")
+	// ErrInvalidWebhookStatus when a webhook's status is none the transactions can be in
+	ErrInvalidWebhookStatus = errors.New("status can either be success, failed, pending")
+	// ErrWebhookAmountMismatch when a provider reports a transfer of another amount than the one held for it
+	ErrWebhookAmountMismatch = errors.New("webhook amount does not match the transaction")
+	// ErrWebhookProviderMismatch when a provider reports on a transaction that was made with another provider
+	ErrWebhookProviderMismatch = errors.New("webhook provider does not match the transaction")
+	// ErrReversalExceedsAmount when a provider reverses more than the transfer it reverses
+	ErrReversalExceedsAmount = errors.New("reversal exceeds the amount of the transfer")
+	// ErrWebhookEventProcessed when an event of the webhook inbox that was already applied is replayed
+	ErrWebhookEventProcessed = errors.New("webhook event was already processed")
+	// ErrWebhookEventRejected when a delivery that was rejected for its signature or source is replayed
+	ErrWebhookEventRejected = errors.New("webhook event was rejected and cannot be processed")
+	// ErrWebhookRejectionsLimited when a source sent more rejected deliveries in a minute than are recorded
+	ErrWebhookRejectionsLimited = errors.New("too many rejected webhooks from this source, the rejection was not recorded")
+	// ErrLimitExceeded when a transaction breaks a limit of the user's tenant, see LimitExceededError
+	ErrLimitExceeded = errors.New("transaction limit exceeded")
 )
+
+// InsufficientFundsError is returned when a debit is larger than the wallet's available balance.
+// errors.Is(err, ErrInsufficientFunds) reports true for it
+type InsufficientFundsError struct {
+	Available model.Money
+	Requested model.Money
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("%s: available balance is %s, requested %s", ErrInsufficientFunds, e.Available, e.Requested)
+}
+
+// Is makes InsufficientFundsError match ErrInsufficientFunds
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+// LimitExceededError is returned when a transaction breaks a limit the user's tenant set for its action.
+// errors.Is(err, ErrLimitExceeded) reports true for it
+type LimitExceededError struct {
+	Action   model.LimitAction
+	Limit    model.LimitKind
+	Currency string
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s: %s limit of %s in %s", ErrLimitExceeded, e.Limit, e.Action, e.Currency)
+}
+
+// Is makes LimitExceededError match ErrLimitExceeded
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
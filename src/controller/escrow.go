@@ -0,0 +1,373 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/storage"
+)
+
+// dueEscrowsBatch is how many due escrows ReleaseDueEscrows releases per run
+const dueEscrowsBatch = 100
+
+// CreateEscrow sets funds of the buyer aside for the seller with the email: the amount leaves the buyer's wallet for
+// the buyer's escrow account in one journal entry, with a successful debit transaction. The funds stay there until
+// the buyer or their tenant releases them to the seller or refunds them, or until autoReleaseAt, when set, passes
+func (c *Controller) CreateEscrow(ctx context.Context, buyerID uuid.UUID, sellerEmail string, amount model.Money, description string, autoReleaseAt *time.Time) (model.Escrow, error) {
+	buyer, err := c.GetUserByID(ctx, buyerID)
+	if err != nil {
+		c.logger.Err(err).Msgf("CreateEscrow ::: error getting buyer by ID %v", err)
+		return model.Escrow{}, err
+	}
+
+	seller, err := c.userStorage.GetUserByEmail(ctx, strings.ToLower(sellerEmail))
+	if err != nil {
+		c.logger.Err(err).Msgf("CreateEscrow ::: error getting seller by email %v", err)
+		return model.Escrow{}, ErrUserDoesNotExist
+	}
+
+	if buyer.ID == seller.ID {
+		return model.Escrow{}, ErrSelfEscrow
+	}
+
+	if err := c.checkTransferTenants(ctx, buyer, seller); err != nil {
+		return model.Escrow{}, err
+	}
+
+	now := time.Now()
+	if autoReleaseAt != nil && (!autoReleaseAt.After(now) || autoReleaseAt.After(now.Add(model.MaxEscrowAutoRelease))) {
+		return model.Escrow{}, ErrInvalidEscrowDeadline
+	}
+
+	escrow := model.Escrow{
+		ID:            uuid.New(),
+		TenantID:      buyer.TenantID,
+		BuyerID:       buyer.ID,
+		SellerID:      seller.ID,
+		Amount:        amount,
+		Description:   description,
+		Status:        model.EscrowStatusHeld,
+		AutoReleaseAt: autoReleaseAt,
+	}
+
+	debit := model.Transaction{
+		ID:              uuid.New(),
+		UserID:          buyer.ID,
+		Amount:          amount,
+		Charges:         model.ZeroMoney(amount.Currency),
+		Fee:             model.ZeroMoney(amount.Currency),
+		FeeVAT:          model.ZeroMoney(amount.Currency),
+		Currency:        amount.Currency,
+		TransactionType: model.DebitTransaction,
+		Status:          model.TransactionStatusSuccessful,
+		TransactionFlow: model.TransactionFlowEscrow,
+	}
+	escrow.FundingTransactionID = debit.ID
+
+	if err := debit.SetMetaData(model.MetaData{"escrow_id": escrow.ID, "seller_id": seller.ID}); err != nil {
+		return model.Escrow{}, err
+	}
+
+	err = c.withTx(ctx, func(tc *Controller) error {
+		wallet, err := tc.walletForDebit(ctx, buyer.ID, amount.Currency)
+		if err != nil {
+			return err
+		}
+
+		if err := checkWalletDebit(wallet); err != nil {
+			return err
+		}
+
+		if amount.Minor > wallet.AvailableBalance.Minor {
+			return &InsufficientFundsError{Available: wallet.AvailableBalance, Requested: amount}
+		}
+
+		if wallet, err = tc.ensureWalletLedgerAccount(ctx, buyer, wallet); err != nil {
+			return err
+		}
+
+		account, err := tc.escrowLedgerAccount(ctx, buyer, amount.Currency)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tc.CreateTransaction(ctx, debit); err != nil {
+			tc.logger.Err(err).Msgf("CreateEscrow ::: CreateTransaction ===> %v", err)
+			return err
+		}
+
+		entry := model.JournalEntry{
+			ID:            uuid.New(),
+			TransactionID: &debit.ID,
+			Description:   string(model.TransactionFlowEscrow),
+			Postings: []model.Posting{
+				{ID: uuid.New(), AccountID: *wallet.LedgerAccountID, Amount: amount.Neg()},
+				{ID: uuid.New(), AccountID: account.ID, Amount: amount},
+			},
+		}
+
+		if _, err := tc.ledgerStorage.PostJournalEntry(ctx, entry); err != nil {
+			tc.logger.Err(err).Msgf("CreateEscrow ::: unable to post escrow to the ledger ===> %v", err)
+			return err
+		}
+
+		if err := tc.recordWalletMovement(ctx, wallet, debit); err != nil {
+			return err
+		}
+
+		if escrow, err = tc.escrowStorage.CreateEscrow(ctx, escrow); err != nil {
+			return err
+		}
+
+		return tc.auditEscrow(ctx, escrow, buyer.ID, debit.ID, model.ActorUser, model.ActionCreated,
+			fmt.Sprintf("%s put in escrow for %s", amount, seller.Email))
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("CreateEscrow ::: unable to create escrow %v", err)
+		return model.Escrow{}, err
+	}
+
+	return escrow, nil
+}
+
+// ReleaseEscrow pays a held escrow to its seller, on behalf of its buyer or their tenant
+func (c *Controller) ReleaseEscrow(ctx context.Context, actor model.Actor, actorID, escrowID uuid.UUID) (model.Escrow, error) {
+	return c.closeEscrow(ctx, actor, actorID, escrowID, model.EscrowStatusReleased)
+}
+
+// RefundEscrow gives a held escrow back to its buyer, on behalf of the buyer or their tenant
+func (c *Controller) RefundEscrow(ctx context.Context, actor model.Actor, actorID, escrowID uuid.UUID) (model.Escrow, error) {
+	return c.closeEscrow(ctx, actor, actorID, escrowID, model.EscrowStatusRefunded)
+}
+
+// ReleaseDueEscrows releases the held escrows whose auto release time passed and returns how many it released. An
+// escrow that cannot be released, e.g. the seller's wallet does not allow credits, is tried again on the next run
+func (c *Controller) ReleaseDueEscrows(ctx context.Context) (int, error) {
+	escrows, err := c.escrowStorage.GetDueEscrows(ctx, time.Now(), dueEscrowsBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, escrow := range escrows {
+		if _, err := c.closeEscrow(ctx, model.ActorSystem, uuid.Nil, escrow.ID, model.EscrowStatusReleased); err != nil {
+			c.logger.Err(err).Msgf("ReleaseDueEscrows ::: escrow %s ===> %v", escrow.ID, err)
+			continue
+		}
+
+		released++
+	}
+
+	return released, nil
+}
+
+// GetEscrow returns an escrow the user is the buyer or the seller of, or of a user of the tenant
+func (c *Controller) GetEscrow(ctx context.Context, actor model.Actor, actorID, escrowID uuid.UUID) (model.Escrow, error) {
+	escrow, err := c.escrowStorage.GetEscrowByID(ctx, escrowID)
+	if err != nil || !canSeeEscrow(actor, actorID, escrow) {
+		return model.Escrow{}, ErrRecordNotFound
+	}
+
+	return escrow, nil
+}
+
+// GetEscrows returns the escrows the user is the buyer or the seller of, or those of every user of the tenant,
+// optionally of one status, newest first
+func (c *Controller) GetEscrows(ctx context.Context, actor model.Actor, actorID uuid.UUID, status *model.EscrowStatus, page pagination.Page) ([]model.Escrow, pagination.PageInfo, error) {
+	filter := storage.EscrowFilter{Status: status}
+	if actor == model.ActorTenant {
+		filter.TenantID = &actorID
+	} else {
+		filter.UserID = &actorID
+	}
+
+	return c.escrowStorage.GetEscrows(ctx, filter, page)
+}
+
+// closeEscrow moves the funds of a held escrow out of the buyer's escrow account, to the seller's wallet when it is
+// released or back to the buyer's when it is refunded, with a successful credit transaction on the wallet credited
+func (c *Controller) closeEscrow(ctx context.Context, actor model.Actor, actorID, escrowID uuid.UUID, status model.EscrowStatus) (model.Escrow, error) {
+	var escrow model.Escrow
+
+	err := c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		// lock the escrow, a release and a refund of the same escrow at the same time cannot both move its funds
+		escrow, err = tc.escrowStorage.GetEscrowByIDForUpdate(ctx, escrowID)
+		if err != nil || (actor != model.ActorSystem && !canSeeEscrow(actor, actorID, escrow)) {
+			return ErrRecordNotFound
+		}
+
+		if !canCloseEscrow(actor, actorID, escrow, status) {
+			return ErrEscrowCloseNotAllowed
+		}
+
+		if escrow.Status != model.EscrowStatusHeld {
+			return fmt.Errorf("%w: escrow is %s", ErrEscrowClosed, escrow.Status)
+		}
+
+		buyer, err := tc.GetUserByID(ctx, escrow.BuyerID)
+		if err != nil {
+			return err
+		}
+
+		recipient := buyer
+		if status == model.EscrowStatusReleased {
+			if recipient, err = tc.GetUserByID(ctx, escrow.SellerID); err != nil {
+				return err
+			}
+		}
+
+		wallet, err := tc.openWallet(ctx, recipient, escrow.Amount.Currency)
+		if err != nil {
+			return err
+		}
+
+		locked, err := tc.lockWallets(ctx, wallet.ID)
+		if err != nil {
+			return err
+		}
+		wallet = locked[wallet.ID]
+
+		if err := checkWalletCredit(wallet); err != nil {
+			return err
+		}
+
+		if wallet, err = tc.ensureWalletLedgerAccount(ctx, recipient, wallet); err != nil {
+			return err
+		}
+
+		account, err := tc.escrowLedgerAccount(ctx, buyer, escrow.Amount.Currency)
+		if err != nil {
+			return err
+		}
+
+		credit := model.Transaction{
+			ID:                   uuid.New(),
+			UserID:               recipient.ID,
+			Amount:               escrow.Amount,
+			Charges:              model.ZeroMoney(escrow.Amount.Currency),
+			Fee:                  model.ZeroMoney(escrow.Amount.Currency),
+			FeeVAT:               model.ZeroMoney(escrow.Amount.Currency),
+			Currency:             escrow.Amount.Currency,
+			TransactionType:      model.CreditTransaction,
+			Status:               model.TransactionStatusSuccessful,
+			TransactionFlow:      model.TransactionFlowEscrow,
+			RelatedTransactionID: &escrow.FundingTransactionID,
+		}
+
+		if err := credit.SetMetaData(model.MetaData{"escrow_id": escrow.ID, "escrow_status": status}); err != nil {
+			return err
+		}
+
+		if _, err := tc.CreateTransaction(ctx, credit); err != nil {
+			tc.logger.Err(err).Msgf("closeEscrow ::: CreateTransaction ===> %v", err)
+			return err
+		}
+
+		entry := model.JournalEntry{
+			ID:            uuid.New(),
+			TransactionID: &credit.ID,
+			Description:   string(model.TransactionFlowEscrow),
+			Postings: []model.Posting{
+				{ID: uuid.New(), AccountID: account.ID, Amount: escrow.Amount.Neg()},
+				{ID: uuid.New(), AccountID: *wallet.LedgerAccountID, Amount: escrow.Amount},
+			},
+		}
+
+		if _, err := tc.ledgerStorage.PostJournalEntry(ctx, entry); err != nil {
+			tc.logger.Err(err).Msgf("closeEscrow ::: unable to post escrow to the ledger ===> %v", err)
+			return err
+		}
+
+		if err := tc.recordWalletMovement(ctx, wallet, credit); err != nil {
+			return err
+		}
+
+		closedAt := time.Now()
+		escrow.Status = status
+		escrow.SettlementTransactionID = &credit.ID
+		escrow.ClosedBy = &actor
+		escrow.ClosedAt = &closedAt
+
+		if err := tc.escrowStorage.UpdateEscrow(ctx, escrow); err != nil {
+			return err
+		}
+
+		return tc.auditEscrow(ctx, escrow, recipient.ID, credit.ID, actor, model.ActionResolved,
+			fmt.Sprintf("escrow of %s %s by %s", escrow.Amount, status, actor))
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("closeEscrow ::: unable to close escrow %s %v", escrowID, err)
+		return model.Escrow{}, err
+	}
+
+	return escrow, nil
+}
+
+// escrowLedgerAccount returns the account holding the buyer's escrowed funds in the currency
+func (c *Controller) escrowLedgerAccount(ctx context.Context, buyer model.User, currency string) (model.LedgerAccount, error) {
+	account := model.LedgerAccount{
+		ID:       uuid.New(),
+		Code:     model.EscrowAccountCode(buyer.ID, currency),
+		Type:     model.LedgerAccountTypeEscrow,
+		TenantID: &buyer.TenantID,
+		UserID:   &buyer.ID,
+		Currency: currency,
+	}
+
+	return c.ledgerStorage.GetOrCreateLedgerAccount(ctx, account)
+}
+
+// auditEscrow writes an audit log of a movement of the escrow on the transaction of the user
+func (c *Controller) auditEscrow(ctx context.Context, escrow model.Escrow, userID, transactionID uuid.UUID, actor model.Actor, action model.AuditLogAction, message string) error {
+	auditLog := model.AuditLog{
+		ID:            uuid.New(),
+		TenantID:      &escrow.TenantID,
+		UserID:        &userID,
+		TransactionID: &transactionID,
+		Actor:         actor,
+		ActionDone:    action,
+		Messages:      message,
+	}
+
+	if _, err := c.CreateAuditLog(ctx, auditLog); err != nil {
+		c.logger.Err(err).Msgf("auditEscrow ::: error creating audit log %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// canSeeEscrow reports whether the actor may see the escrow: its buyer, its seller or the tenant of its buyer
+func canSeeEscrow(actor model.Actor, actorID uuid.UUID, escrow model.Escrow) bool {
+	switch actor {
+	case model.ActorTenant:
+		return actorID == escrow.TenantID
+	case model.ActorUser:
+		return actorID == escrow.BuyerID || actorID == escrow.SellerID
+	default:
+		return false
+	}
+}
+
+// canCloseEscrow reports whether the actor may close the escrow with the status. The buyer and the tenant of the buyer
+// can release it to the seller or refund it to the buyer, the seller can only see it. The system only releases it,
+// once its auto release time passed
+func canCloseEscrow(actor model.Actor, actorID uuid.UUID, escrow model.Escrow, status model.EscrowStatus) bool {
+	switch actor {
+	case model.ActorTenant:
+		return actorID == escrow.TenantID
+	case model.ActorUser:
+		return actorID == escrow.BuyerID
+	case model.ActorSystem:
+		return status == model.EscrowStatusReleased && escrow.IsDue(time.Now())
+	default:
+		return false
+	}
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+func Test_CanCloseEscrow(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	escrow := model.Escrow{ID: uuid.New(), TenantID: uuid.New(), BuyerID: uuid.New(), SellerID: uuid.New(), Status: model.EscrowStatusHeld}
+	released, refunded := model.EscrowStatusReleased, model.EscrowStatusRefunded
+
+	// the buyer closes it either way, the seller cannot close it at all
+	require.True(t, canCloseEscrow(model.ActorUser, escrow.BuyerID, escrow, released))
+	require.True(t, canCloseEscrow(model.ActorUser, escrow.BuyerID, escrow, refunded))
+	require.False(t, canCloseEscrow(model.ActorUser, escrow.SellerID, escrow, refunded))
+	require.False(t, canCloseEscrow(model.ActorUser, escrow.SellerID, escrow, released), "the seller cannot pay themselves")
+	require.False(t, canCloseEscrow(model.ActorUser, uuid.New(), escrow, released))
+
+	// the tenant of the buyer settles it either way
+	require.True(t, canCloseEscrow(model.ActorTenant, escrow.TenantID, escrow, released))
+	require.True(t, canCloseEscrow(model.ActorTenant, escrow.TenantID, escrow, refunded))
+	require.False(t, canCloseEscrow(model.ActorTenant, uuid.New(), escrow, refunded))
+
+	// the system only releases, once the escrow is due
+	require.False(t, canCloseEscrow(model.ActorSystem, uuid.Nil, escrow, released))
+	escrow.AutoReleaseAt = &past
+	require.True(t, canCloseEscrow(model.ActorSystem, uuid.Nil, escrow, released))
+	require.False(t, canCloseEscrow(model.ActorSystem, uuid.Nil, escrow, refunded))
+}
+
+func Test_CloseEscrow(t *testing.T) {
+	c, db := testController(t)
+	ctx := context.Background()
+
+	tenant, buyer := testUser(t, c, db)
+	seller := testTenantUser(t, c, db, tenant)
+	testDeposit(t, c, buyer, model.NewMoney(500000, model.DefaultCurrency))
+	amount := model.NewMoney(200000, model.DefaultCurrency)
+
+	escrow, err := c.CreateEscrow(ctx, buyer.ID, seller.Email, amount, "laptop", nil)
+	require.NoError(t, err)
+
+	// the seller cannot close the escrow, and an outsider cannot see it
+	_, err = c.ReleaseEscrow(ctx, model.ActorUser, seller.ID, escrow.ID)
+	require.ErrorIs(t, err, ErrEscrowCloseNotAllowed)
+	_, err = c.RefundEscrow(ctx, model.ActorUser, seller.ID, escrow.ID)
+	require.ErrorIs(t, err, ErrEscrowCloseNotAllowed)
+	_, err = c.ReleaseEscrow(ctx, model.ActorUser, uuid.New(), escrow.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// a seller's wallet that does not allow credits keeps the funds in escrow
+	_, err = c.SetWalletStatus(ctx, tenant.ID, seller.ID, model.DefaultCurrency, model.WalletStatusFrozen, "compliance review")
+	require.NoError(t, err)
+	_, err = c.ReleaseEscrow(ctx, model.ActorUser, buyer.ID, escrow.ID)
+	require.ErrorIs(t, err, ErrWalletCreditBlocked)
+
+	escrow, err = c.GetEscrow(ctx, model.ActorUser, buyer.ID, escrow.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.EscrowStatusHeld, escrow.Status)
+
+	_, err = c.SetWalletStatus(ctx, tenant.ID, seller.ID, model.DefaultCurrency, model.WalletStatusActive, "review cleared")
+	require.NoError(t, err)
+
+	// released once, the escrow can neither be released nor refunded again
+	escrow, err = c.ReleaseEscrow(ctx, model.ActorUser, buyer.ID, escrow.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.EscrowStatusReleased, escrow.Status)
+
+	_, err = c.ReleaseEscrow(ctx, model.ActorUser, buyer.ID, escrow.ID)
+	require.ErrorIs(t, err, ErrEscrowClosed)
+	_, err = c.RefundEscrow(ctx, model.ActorTenant, tenant.ID, escrow.ID)
+	require.ErrorIs(t, err, ErrEscrowClosed)
+
+	// every posting of the escrow balances, the escrow account is empty and the seller got the amount once
+	var entries []model.JournalEntry
+	require.NoError(t, db.Preload("Postings").
+		Where("transaction_id IN ?", []uuid.UUID{escrow.FundingTransactionID, *escrow.SettlementTransactionID}).
+		Find(&entries).Error)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		total := model.ZeroMoney(model.DefaultCurrency)
+		for _, posting := range entry.Postings {
+			total, err = total.Add(posting.Amount)
+			require.NoError(t, err)
+		}
+		require.True(t, total.IsZero(), "journal entry %s does not balance", entry.ID)
+	}
+
+	account, err := c.ledgerStorage.GetLedgerAccountByCode(ctx, model.EscrowAccountCode(buyer.ID, model.DefaultCurrency))
+	require.NoError(t, err)
+	balance, err := c.ledgerStorage.GetLedgerAccountBalance(ctx, account.ID)
+	require.NoError(t, err)
+	require.True(t, balance.IsZero())
+
+	account, err = c.ledgerStorage.GetLedgerAccountByCode(ctx, model.UserWalletAccountCode(seller.ID, model.DefaultCurrency))
+	require.NoError(t, err)
+	balance, err = c.ledgerStorage.GetLedgerAccountBalance(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, amount, balance)
+}
+
+func Test_RefundEscrow(t *testing.T) {
+	c, db := testController(t)
+	ctx := context.Background()
+
+	tenant, buyer := testUser(t, c, db)
+	seller := testTenantUser(t, c, db, tenant)
+	testDeposit(t, c, buyer, model.NewMoney(500000, model.DefaultCurrency))
+	amount := model.NewMoney(200000, model.DefaultCurrency)
+
+	escrow, err := c.CreateEscrow(ctx, buyer.ID, seller.Email, amount, "laptop", nil)
+	require.NoError(t, err)
+
+	// the buyer takes the funds back, after which they cannot release them
+	escrow, err = c.RefundEscrow(ctx, model.ActorUser, buyer.ID, escrow.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.EscrowStatusRefunded, escrow.Status)
+
+	_, err = c.ReleaseEscrow(ctx, model.ActorUser, buyer.ID, escrow.ID)
+	require.ErrorIs(t, err, ErrEscrowClosed)
+
+	account, err := c.ledgerStorage.GetLedgerAccountByCode(ctx, model.EscrowAccountCode(buyer.ID, model.DefaultCurrency))
+	require.NoError(t, err)
+	balance, err := c.ledgerStorage.GetLedgerAccountBalance(ctx, account.ID)
+	require.NoError(t, err)
+	require.True(t, balance.IsZero())
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/storage"
+)
+
+// SetFeeRule sets how the tenant prices an action in a currency. The rule is saved as a new version and the version
+// it replaces is retired, transactions already priced keep pointing at the version they were priced with
+func (c *Controller) SetFeeRule(ctx context.Context, tenantID uuid.UUID, rule model.FeeRule) (model.FeeRule, error) {
+	if err := rule.Validate(); err != nil {
+		return model.FeeRule{}, err
+	}
+
+	rule.ID = uuid.New()
+	rule.TenantID = tenantID
+	rule.RetiredAt = nil
+
+	var newRule model.FeeRule
+	err := c.withTx(ctx, func(tc *Controller) error {
+		latest, err := tc.feeStorage.GetLatestFeeRuleVersion(ctx, tenantID, rule.Action, rule.Currency)
+		if err != nil {
+			return err
+		}
+		rule.Version = latest + 1
+
+		if _, err := tc.feeStorage.RetireFeeRule(ctx, tenantID, rule.Action, rule.Currency, time.Now()); err != nil {
+			return err
+		}
+
+		newRule, err = tc.feeStorage.CreateFeeRule(ctx, rule)
+		if err == storage.ErrDuplicateRecord {
+			// another version was set at the same time, the caller can retry on top of it
+			return ErrFeeRuleConflict
+		}
+
+		if err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &tenantID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionUpdated,
+			Messages:   fmt.Sprintf("set %s fee rule for %s to version %d", rule.Action, rule.Currency, rule.Version),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("SetFeeRule ::: unable to save fee rule %v", err)
+		return model.FeeRule{}, err
+	}
+
+	return newRule, nil
+}
+
+// RetireFeeRule retires the tenant's rule for an action in a currency, the action is free from then on
+func (c *Controller) RetireFeeRule(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, currency string) error {
+	if !action.IsValid() {
+		return model.ErrInvalidFeeAction
+	}
+	currency = strings.ToUpper(currency)
+
+	return c.withTx(ctx, func(tc *Controller) error {
+		retired, err := tc.feeStorage.RetireFeeRule(ctx, tenantID, action, currency, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if !retired {
+			return ErrRecordNotFound
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &tenantID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionUpdated,
+			Messages:   fmt.Sprintf("retired %s fee rule for %s", action, currency),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+}
+
+// GetFeeRules returns the tenant's fee rules in use, or every version of them withRetired
+func (c *Controller) GetFeeRules(ctx context.Context, tenantID uuid.UUID, withRetired bool) ([]model.FeeRule, error) {
+	return c.feeStorage.GetFeeRules(ctx, tenantID, withRetired)
+}
+
+// QuoteFee tells the user what the action on the amount costs at their tenant's fee rules
+func (c *Controller) QuoteFee(ctx context.Context, userID uuid.UUID, action model.FeeAction, amount model.Money) (model.FeeQuote, error) {
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil {
+		c.logger.Err(err).Msgf("QuoteFee ::: error getting user by ID %v", err)
+		return model.FeeQuote{}, err
+	}
+
+	return c.quoteFee(ctx, user.TenantID, action, amount)
+}
+
+// QuoteTenantFee tells the tenant what the action on the amount costs at its fee rules
+func (c *Controller) QuoteTenantFee(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, amount model.Money) (model.FeeQuote, error) {
+	return c.quoteFee(ctx, tenantID, action, amount)
+}
+
+// quoteFee prices the action on the amount with the tenant's rule in use. The action is free when there is none, the
+// tenants that were charged the legacy fee were given rules for it when fee rules were introduced
+func (c *Controller) quoteFee(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, amount model.Money) (model.FeeQuote, error) {
+	if !action.IsValid() {
+		return model.FeeQuote{}, model.ErrInvalidFeeAction
+	}
+
+	rule, err := c.feeStorage.GetActiveFeeRule(ctx, tenantID, action, amount.Currency)
+	if err == storage.ErrRecordNotFound {
+		return model.FreeQuote(action, amount), nil
+	}
+
+	if err != nil {
+		return model.FeeQuote{}, err
+	}
+
+	return rule.Quote(amount)
+}
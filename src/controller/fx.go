@@ -0,0 +1,325 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/storage"
+)
+
+// fxRateFileEntry is one rate in an FX rates file. A rate without a tenant_id applies to every tenant
+type fxRateFileEntry struct {
+	TenantID          *uuid.UUID  `json:"tenant_id"`
+	BaseCurrency      string      `json:"base_currency"`
+	QuoteCurrency     string      `json:"quote_currency"`
+	Rate              json.Number `json:"rate"`
+	SpreadBasisPoints int64       `json:"spread_basis_points"`
+}
+
+// SetFXRate sets the rate a tenant converts a currency pair with, a nil tenant sets the default rate
+func (c *Controller) SetFXRate(ctx context.Context, tenantID *uuid.UUID, rate model.FXRate) (model.FXRate, error) {
+	rate.TenantID = tenantID
+	if err := rate.Validate(); err != nil {
+		return model.FXRate{}, err
+	}
+
+	newRate, err := c.fxStorage.UpsertFXRate(ctx, rate)
+	if err != nil {
+		c.logger.Err(err).Msgf("SetFXRate ::: unable to save fx rate %v", err)
+		return model.FXRate{}, err
+	}
+
+	return newRate, nil
+}
+
+// GetFXRates returns the rates a tenant converts with, its own rates first and then the default rates
+func (c *Controller) GetFXRates(ctx context.Context, tenantID uuid.UUID) ([]model.FXRate, error) {
+	return c.fxStorage.GetFXRatesByTenantID(ctx, tenantID)
+}
+
+// LoadFXRatesFromFile sets every rate listed in a JSON file and returns how many rates were set
+func (c *Controller) LoadFXRatesFromFile(ctx context.Context, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var entries []fxRateFileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return 0, fmt.Errorf("invalid fx rates file %s: %w", path, err)
+	}
+
+	err = c.withTx(ctx, func(tc *Controller) error {
+		for i, entry := range entries {
+			rate := model.FXRate{
+				BaseCurrency:      entry.BaseCurrency,
+				QuoteCurrency:     entry.QuoteCurrency,
+				Rate:              entry.Rate.String(),
+				SpreadBasisPoints: entry.SpreadBasisPoints,
+			}
+
+			if _, err := tc.SetFXRate(ctx, entry.TenantID, rate); err != nil {
+				return fmt.Errorf("fx rate %d (%s/%s): %w", i, entry.BaseCurrency, entry.QuoteCurrency, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(entries), nil
+}
+
+// QuoteFX tells the user what converting the amount into the currency would give at their tenant's rate
+func (c *Controller) QuoteFX(ctx context.Context, userID uuid.UUID, amount model.Money, to string) (model.FXQuote, error) {
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil {
+		c.logger.Err(err).Msgf("QuoteFX ::: error getting user by ID %v", err)
+		return model.FXQuote{}, err
+	}
+
+	return c.quoteFX(ctx, user.TenantID, amount, to)
+}
+
+func (c *Controller) quoteFX(ctx context.Context, tenantID uuid.UUID, amount model.Money, to string) (model.FXQuote, error) {
+	to = strings.ToUpper(to)
+	if !model.IsSupportedCurrency(to) {
+		return model.FXQuote{}, model.ErrUnsupportedCurrency
+	}
+
+	if strings.EqualFold(amount.Currency, to) {
+		return model.FXQuote{}, model.ErrCurrencyMismatch
+	}
+
+	rate, err := c.fxStorage.GetFXRate(ctx, tenantID, amount.Currency, to)
+	if err == storage.ErrRecordNotFound {
+		return model.FXQuote{}, ErrNoFXRate
+	}
+
+	if err != nil {
+		return model.FXQuote{}, err
+	}
+
+	return rate.Quote(amount)
+}
+
+// ConvertFX moves the amount from the user's wallet in its currency into the user's wallet in the target currency
+// at the tenant's rate. The source wallet is debited the amount and the tenant's conversion fee, the target wallet is
+// credited the converted amount less the spread, and the fee and spread are posted to the tenant's fee accounts as
+// revenue
+func (c *Controller) ConvertFX(ctx context.Context, userID uuid.UUID, amount model.Money, to string) (model.FXConversion, error) {
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil {
+		c.logger.Err(err).Msgf("ConvertFX ::: error getting user by ID %v", err)
+		return model.FXConversion{}, err
+	}
+
+	quote, err := c.quoteFX(ctx, user.TenantID, amount, to)
+	if err != nil {
+		c.logger.Err(err).Msgf("ConvertFX ::: unable to quote %v", err)
+		return model.FXConversion{}, err
+	}
+
+	fee, err := c.quoteFee(ctx, user.TenantID, model.FeeActionFX, amount)
+	if err != nil {
+		c.logger.Err(err).Msgf("ConvertFX ::: unable to quote fee %v", err)
+		return model.FXConversion{}, err
+	}
+
+	// the usage is reserved against the limit before the action is made, and given back unless it is made
+	reservation, err := c.reserveLimit(ctx, user, model.LimitActionFX, amount)
+	if err != nil {
+		return model.FXConversion{}, err
+	}
+	defer func() { c.releaseLimit(ctx, reservation) }()
+
+	var conversion model.FXConversion
+	err = c.withTx(ctx, func(tc *Controller) error {
+		source, err := tc.walletStorage.GetWalletByUserID(ctx, user.ID, amount.Currency)
+		if err != nil {
+			return ErrNoWalletForCurrency
+		}
+
+		target, err := tc.openWallet(ctx, user, quote.To.Currency)
+		if err != nil {
+			return err
+		}
+
+		locked, err := tc.lockWallets(ctx, source.ID, target.ID)
+		if err != nil {
+			return err
+		}
+		source, target = locked[source.ID], locked[target.ID]
+
+		if err := checkWalletDebit(source); err != nil {
+			return err
+		}
+
+		if err := checkWalletCredit(target); err != nil {
+			return err
+		}
+
+		debit := model.Transaction{
+			ID:              uuid.New(),
+			UserID:          user.ID,
+			Amount:          amount,
+			Charges:         model.ZeroMoney(amount.Currency),
+			Currency:        amount.Currency,
+			TransactionType: model.DebitTransaction,
+			Status:          model.TransactionStatusSuccessful,
+			TransactionFlow: model.TransactionFlowConversion,
+		}
+		debit.SetFee(fee)
+
+		movement, err := debit.WalletMovement()
+		if err != nil {
+			return err
+		}
+
+		remaining, err := source.AvailableBalance.Add(movement)
+		if err != nil {
+			return err
+		}
+
+		if remaining.IsNegative() {
+			return &InsufficientFundsError{Available: source.AvailableBalance, Requested: movement.Neg()}
+		}
+
+		credit := model.Transaction{
+			ID:              uuid.New(),
+			UserID:          user.ID,
+			Amount:          quote.To,
+			Charges:         quote.Fee,
+			Currency:        quote.To.Currency,
+			TransactionType: model.CreditTransaction,
+			Status:          model.TransactionStatusSuccessful,
+			TransactionFlow: model.TransactionFlowConversion,
+		}
+
+		for _, tx := range []model.Transaction{debit, credit} {
+			if _, err := tc.CreateTransaction(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		if err := tc.postFXConversion(ctx, user.TenantID, source, target, debit, credit, quote); err != nil {
+			tc.logger.Err(err).Msgf("ConvertFX ::: unable to post conversion to the ledger %v", err)
+			return err
+		}
+
+		if err := tc.recordWalletMovement(ctx, source, debit); err != nil {
+			return err
+		}
+
+		if err := tc.recordWalletMovement(ctx, target, credit); err != nil {
+			return err
+		}
+
+		conversion, err = tc.fxStorage.CreateFXConversion(ctx, model.FXConversion{
+			ID:                  uuid.New(),
+			TenantID:            user.TenantID,
+			UserID:              user.ID,
+			DebitTransactionID:  debit.ID,
+			CreditTransactionID: credit.ID,
+			From:                quote.From,
+			To:                  quote.To,
+			Fee:                 quote.Fee,
+			Rate:                quote.Rate,
+			SpreadBasisPoints:   quote.SpreadBasisPoints,
+		})
+		if err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:            uuid.New(),
+			TenantID:      &user.TenantID,
+			UserID:        &user.ID,
+			TransactionID: &debit.ID,
+			Actor:         model.ActorUser,
+			ActionDone:    model.ActionSuccess,
+			Messages:      fmt.Sprintf("converted %s to %s", quote.From, quote.To),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+	if err != nil {
+		return model.FXConversion{}, err
+	}
+
+	// the action was made, its usage is kept
+	reservation = nil
+	return conversion, nil
+}
+
+// postFXConversion posts a conversion to the ledger. Postings of a journal entry share one currency, so the
+// conversion is two entries that meet in the tenant's FX position accounts: the source currency leaves the user's
+// wallet for the source position and, for the conversion fee, the tenant's fee accounts, and the target currency
+// leaves the target position for the user's wallet and, for the spread, the tenant's fee account
+func (c *Controller) postFXConversion(ctx context.Context, tenantID uuid.UUID, source, target model.Wallet, debit, credit model.Transaction, quote model.FXQuote) error {
+	sourcePosition, err := c.fxPositionLedgerAccount(ctx, tenantID, quote.From.Currency)
+	if err != nil {
+		return err
+	}
+
+	targetPosition, err := c.fxPositionLedgerAccount(ctx, tenantID, quote.To.Currency)
+	if err != nil {
+		return err
+	}
+
+	movement, err := debit.WalletMovement()
+	if err != nil {
+		return err
+	}
+
+	fees, err := c.feePostings(ctx, tenantID, debit)
+	if err != nil {
+		return err
+	}
+
+	out := model.JournalEntry{
+		ID:            uuid.New(),
+		TransactionID: &debit.ID,
+		Description:   string(model.TransactionFlowConversion),
+		Postings: append([]model.Posting{
+			{ID: uuid.New(), AccountID: *source.LedgerAccountID, Amount: movement},
+			{ID: uuid.New(), AccountID: sourcePosition.ID, Amount: quote.From},
+		}, fees...),
+	}
+
+	in := model.JournalEntry{
+		ID:            uuid.New(),
+		TransactionID: &credit.ID,
+		Description:   string(model.TransactionFlowConversion),
+		Postings: []model.Posting{
+			{ID: uuid.New(), AccountID: targetPosition.ID, Amount: quote.Gross.Neg()},
+			{ID: uuid.New(), AccountID: *target.LedgerAccountID, Amount: quote.To},
+		},
+	}
+
+	if !quote.Fee.IsZero() {
+		fees, err := c.CreateTenantFeeLedgerAccount(ctx, tenantID, quote.Fee.Currency)
+		if err != nil {
+			return err
+		}
+
+		in.Postings = append(in.Postings, model.Posting{ID: uuid.New(), AccountID: fees.ID, Amount: quote.Fee})
+	}
+
+	for _, entry := range []model.JournalEntry{out, in} {
+		if _, err := c.ledgerStorage.PostJournalEntry(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
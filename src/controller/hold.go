@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/storage"
+)
+
+// expiredHoldsBatchSize is how many expired holds ExpireHolds settles per database transaction
+const expiredHoldsBatchSize = 100
+
+// holdTTL is how long a hold reserves funds before it expires, in minutes from HOLD_TTL_MINUTES
+func (c *Controller) holdTTL() time.Duration {
+	ttl, err := strconv.Atoi(c.env.Get("HOLD_TTL_MINUTES"))
+	if err != nil || ttl <= 0 {
+		return model.DefaultHoldTTL
+	}
+
+	return time.Minute * time.Duration(ttl)
+}
+
+// placeHold reserves what a pending debit will take off the wallet, its amount and fee. The wallet must have been read
+// with its row locked in the current database transaction, so that two debits cannot both spend the same available
+// balance
+func (c *Controller) placeHold(ctx context.Context, wallet model.Wallet, tx model.Transaction) (model.Hold, error) {
+	debit, err := tx.WalletMovement()
+	if err != nil {
+		return model.Hold{}, err
+	}
+	debit = debit.Abs()
+
+	remaining, err := wallet.AvailableBalance.Sub(debit)
+	if err != nil {
+		return model.Hold{}, err
+	}
+
+	if remaining.IsNegative() {
+		return model.Hold{}, &InsufficientFundsError{Available: wallet.AvailableBalance, Requested: debit}
+	}
+
+	hold := model.Hold{
+		ID:            uuid.New(),
+		WalletID:      wallet.ID,
+		UserID:        wallet.UserID,
+		TransactionID: tx.ID,
+		Amount:        debit,
+		Status:        model.HoldStatusActive,
+		ExpiresAt:     time.Now().Add(c.holdTTL()),
+	}
+
+	return c.holdStorage.CreateHold(ctx, hold)
+}
+
+// settleHold moves the active hold of a transaction to captured or released. Transactions without an active
+// hold, e.g. credits or holds that already expired, are left alone
+func (c *Controller) settleHold(ctx context.Context, transactionID uuid.UUID, status model.HoldStatus) error {
+	hold, err := c.holdStorage.GetActiveHoldByTransactionID(ctx, transactionID)
+	if err == storage.ErrRecordNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.holdStorage.UpdateHoldStatus(ctx, hold.ID, status)
+}
+
+// ExpireHolds expires every active hold past its expiry so its funds are available again, and returns how many
+// holds were expired. It is run on a schedule. A transfer the provider has not answered for by the time its hold
+// expires is failed with it, a success webhook arriving after that still debits the wallet as the funds did leave.
+// The hold of a refund or a dispute still pending is kept until it is settled, its funds may still leave the wallet
+// and cannot be spent again meanwhile
+func (c *Controller) ExpireHolds(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		holds, err := c.holdStorage.GetExpiredHolds(ctx, time.Now(), expiredHoldsBatchSize)
+		if err != nil {
+			c.logger.Err(err).Msgf("ExpireHolds ::: error getting expired holds %v", err)
+			return expired, err
+		}
+
+		if len(holds) == 0 {
+			return expired, nil
+		}
+
+		err = c.withTx(ctx, func(tc *Controller) error {
+			for _, hold := range holds {
+				if err := tc.holdStorage.UpdateHoldStatus(ctx, hold.ID, model.HoldStatusExpired); err != nil {
+					return err
+				}
+
+				user, err := tc.GetUserByID(ctx, hold.UserID)
+				if err != nil {
+					return err
+				}
+
+				auditLog := model.AuditLog{
+					ID:            uuid.New(),
+					TenantID:      &user.TenantID,
+					UserID:        &hold.UserID,
+					TransactionID: &hold.TransactionID,
+					Actor:         model.ActorSystem,
+					ActionDone:    model.ActionExpired,
+					Messages:      "hold expired, funds released to available balance",
+				}
+
+				failed, err := tc.failUnansweredTransfer(ctx, hold.TransactionID)
+				if err != nil {
+					return err
+				}
+
+				if failed {
+					auditLog.ActionDone = model.ActionFailed
+					auditLog.Messages = "transfer failed, the provider did not answer before its hold expired, funds released to available balance"
+				}
+
+				if _, err := tc.CreateAuditLog(ctx, auditLog); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			c.logger.Err(err).Msgf("ExpireHolds ::: error expiring holds %v", err)
+			return expired, err
+		}
+
+		expired += len(holds)
+	}
+}
+
+// failUnansweredTransfer fails the transaction of an expired hold when it is a transfer still pending with its
+// provider, and reports whether it did
+func (c *Controller) failUnansweredTransfer(ctx context.Context, transactionID uuid.UUID) (bool, error) {
+	tx, err := c.transactionStorage.GetTransactionByIDForUpdate(ctx, transactionID)
+	if err != nil {
+		return false, err
+	}
+
+	if tx.TransactionFlow != model.TransactionFlowWithdrawal || tx.Status != model.TransactionStatusPending {
+		return false, nil
+	}
+
+	tx.Status = model.TransactionStatusFailed
+	return true, c.UpdateTransactionByID(ctx, tx)
+}
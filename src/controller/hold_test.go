@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+func Test_PlaceHold_InsufficientFunds(t *testing.T) {
+	c := &Controller{}
+	wallet := model.Wallet{
+		ID:               uuid.New(),
+		UserID:           uuid.New(),
+		AvailableBalance: model.NewMoney(100000, "NGN"),
+	}
+	tx := model.Transaction{ID: uuid.New(), Amount: model.NewMoney(100001, "NGN")}
+
+	_, err := c.placeHold(context.Background(), wallet, tx)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	var fundsErr *InsufficientFundsError
+	require.True(t, errors.As(err, &fundsErr))
+	require.Equal(t, wallet.AvailableBalance, fundsErr.Available)
+	require.Equal(t, tx.Amount, fundsErr.Requested)
+
+	// a debit in another currency can never be held against the wallet
+	tx.Amount = model.NewMoney(100, "USD")
+	_, err = c.placeHold(context.Background(), wallet, tx)
+	require.ErrorIs(t, err, model.ErrCurrencyMismatch)
+}
+
+func Test_ExpireHolds_PendingWithProvider(t *testing.T) {
+	c, db := testController(t)
+	ctx := context.Background()
+
+	tenant, user := testUser(t, c, db)
+	testDeposit(t, c, user, model.NewMoney(500000, model.DefaultCurrency))
+	amount := model.NewMoney(100000, model.DefaultCurrency)
+
+	require.NoError(t, c.Transfer(ctx, user.ID, "044", "0123456789", amount))
+	var transfer model.Transaction
+	require.NoError(t, db.Where("user_id = ? AND transaction_flow = ?", user.ID, model.TransactionFlowWithdrawal).First(&transfer).Error)
+
+	deposit := testDeposit(t, c, user, model.NewMoney(200000, model.DefaultCurrency))
+	refund, _, err := c.createRefund(ctx, tenant.ID, deposit.ID, &amount, "customer asked", uuid.NewString())
+	require.NoError(t, err)
+
+	require.NoError(t, db.Model(&model.Hold{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	// a transfer the provider did not answer for fails with its hold, a refund still being sent keeps its hold
+	_, err = c.ExpireHolds(ctx)
+	require.NoError(t, err)
+
+	var got model.Hold
+	require.NoError(t, db.First(&got, "transaction_id = ?", transfer.ID).Error)
+	require.Equal(t, model.HoldStatusExpired, got.Status)
+	require.NoError(t, db.First(&got, "transaction_id = ?", refund.RefundTransactionID).Error)
+	require.Equal(t, model.HoldStatusActive, got.Status)
+
+	transfer, err = c.GetTransactionByID(ctx, transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.TransactionStatusFailed, transfer.Status)
+
+	wallet, err := c.GetWalletByUserID(ctx, user.ID, model.DefaultCurrency)
+	require.NoError(t, err)
+	held, err := c.holdStorage.GetActiveHoldsTotal(ctx, wallet.ID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, amount, held)
+
+	// the provider paid the transfer out after all, its late webhook still takes it off the wallet
+	require.NoError(t, c.ProcessPaymentWebhook(ctx, model.PaymentEvent{
+		Provider:  model.PaymentProviderFlutterwave,
+		Kind:      model.PaymentEventTransfer,
+		Status:    model.TransactionStatusSuccessful,
+		Reference: "dbt_" + transfer.ID.String(),
+		Amount:    amount,
+		Fees:      model.ZeroMoney(amount.Currency),
+	}))
+
+	transfer, err = c.GetTransactionByID(ctx, transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.TransactionStatusSuccessful, transfer.Status)
+
+	account, err := c.ledgerStorage.GetLedgerAccountByCode(ctx, model.UserWalletAccountCode(user.ID, model.DefaultCurrency))
+	require.NoError(t, err)
+	balance, err := c.ledgerStorage.GetLedgerAccountBalance(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.NewMoney(600000, model.DefaultCurrency), balance)
+}
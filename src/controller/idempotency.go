@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+)
+
+// BeginIdempotentRequest claims the idempotency key of a request for its first attempt, or returns the completed key
+// whose response a retry is to get replayed. Completed keys are read from redis first and from the database when
+// redis does not have them. It returns model.ErrIdempotencyKeyReused when the key was used with another request and
+// model.ErrIdempotentRequestInFlight while the first attempt is still being handled
+func (c *Controller) BeginIdempotentRequest(ctx context.Context, request model.IdempotencyKey) (model.IdempotencyKey, error) {
+	cacheKey := idempotencyCacheKey(request)
+	if cached, err := c.redis.GetStringValue(ctx, cacheKey); err == nil && cached != "" {
+		var key model.IdempotencyKey
+		if err := json.Unmarshal([]byte(cached), &key); err == nil {
+			return key, checkIdempotencyFingerprint(key, request)
+		}
+	}
+
+	now := time.Now()
+	request.ID = uuid.New()
+	request.Status = model.IdempotencyStatusInFlight
+	request.ExpiresAt = now.Add(model.IdempotencyKeyTTL)
+	request.UpdatedAt = now
+
+	created, err := c.idempotencyStorage.CreateIdempotencyKey(ctx, request)
+	if err != nil {
+		return model.IdempotencyKey{}, err
+	}
+
+	if created {
+		return request, nil
+	}
+
+	key, err := c.idempotencyStorage.GetIdempotencyKey(ctx, request.TenantID, request.UserID, request.Key)
+	if err != nil {
+		// the key was purged or abandoned since it could not be created, the client can send the request again
+		return model.IdempotencyKey{}, model.ErrIdempotentRequestInFlight
+	}
+
+	// a key whose response expired, or whose first attempt died before answering, goes to the next request with it
+	if key.IsStale(now) {
+		claimed, err := c.idempotencyStorage.ClaimIdempotencyKey(ctx, key, request)
+		if err != nil {
+			return model.IdempotencyKey{}, err
+		}
+
+		if !claimed {
+			return model.IdempotencyKey{}, model.ErrIdempotentRequestInFlight
+		}
+
+		request.ID = key.ID
+		return request, nil
+	}
+
+	if err := checkIdempotencyFingerprint(key, request); err != nil {
+		return model.IdempotencyKey{}, err
+	}
+
+	if key.Status == model.IdempotencyStatusInFlight {
+		return model.IdempotencyKey{}, model.ErrIdempotentRequestInFlight
+	}
+
+	c.cacheIdempotencyKey(ctx, key)
+	return key, nil
+}
+
+// CompleteIdempotentRequest stores the response of the first attempt of a request, the retries get it replayed until
+// the key expires
+func (c *Controller) CompleteIdempotentRequest(ctx context.Context, key model.IdempotencyKey) error {
+	key.Status = model.IdempotencyStatusCompleted
+	key.UpdatedAt = time.Now()
+
+	if err := c.idempotencyStorage.CompleteIdempotencyKey(ctx, key); err != nil {
+		c.logger.Err(err).Msgf("CompleteIdempotentRequest ::: unable to store response %v", err)
+		return err
+	}
+
+	c.cacheIdempotencyKey(ctx, key)
+	return nil
+}
+
+// AbandonIdempotentRequest lets go of the key of a first attempt that failed on the server's side, the next request
+// with the key is handled as a first attempt again
+func (c *Controller) AbandonIdempotentRequest(ctx context.Context, key model.IdempotencyKey) error {
+	return c.idempotencyStorage.DeleteIdempotencyKey(ctx, key.ID)
+}
+
+// PurgeIdempotencyKeys removes the idempotency keys that expired and returns how many
+func (c *Controller) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	return c.idempotencyStorage.DeleteExpiredIdempotencyKeys(ctx, time.Now())
+}
+
+// cacheIdempotencyKey keeps a completed key in redis until it expires. A key redis does not keep is read from the
+// database instead
+func (c *Controller) cacheIdempotencyKey(ctx context.Context, key model.IdempotencyKey) {
+	ttl := time.Until(key.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+
+	value, err := json.Marshal(key)
+	if err != nil {
+		return
+	}
+
+	if err := c.redis.SetValue(ctx, idempotencyCacheKey(key), string(value), ttl); err != nil {
+		c.logger.Err(err).Msgf("cacheIdempotencyKey ::: unable to cache key %v", err)
+	}
+}
+
+// checkIdempotencyFingerprint refuses a key sent again with another request than it was first used with
+func checkIdempotencyFingerprint(key, request model.IdempotencyKey) error {
+	if key.Fingerprint != request.Fingerprint {
+		return model.ErrIdempotencyKeyReused
+	}
+
+	return nil
+}
+
+// idempotencyCacheKey is the redis key of an idempotency key, scoped like the key by tenant and user
+func idempotencyCacheKey(key model.IdempotencyKey) string {
+	return fmt.Sprintf("idempotency:%s:%s:%s", key.TenantID, key.UserID, key.Key)
+}
@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+	"codematic/storage"
+)
+
+// invoiceReferenceLength is the length of the random part of an invoice's reference
+const invoiceReferenceLength = 16
+
+// CreateInvoice creates an invoice of the user from its line items and returns it with the reference the user shares
+// with the payer
+func (c *Controller) CreateInvoice(ctx context.Context, userID uuid.UUID, invoice model.Invoice) (model.Invoice, error) {
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil {
+		c.logger.Err(err).Msgf("CreateInvoice ::: error getting user by ID %v", err)
+		return model.Invoice{}, err
+	}
+
+	if err := invoice.Prepare(time.Now()); err != nil {
+		return model.Invoice{}, err
+	}
+
+	if err := c.checkSplit(ctx, user, invoice.SplitID, invoice.Currency); err != nil {
+		return model.Invoice{}, err
+	}
+
+	key, err := helper.GenerateKey(invoiceReferenceLength)
+	if err != nil {
+		return model.Invoice{}, err
+	}
+
+	invoice.ID = uuid.New()
+	invoice.TenantID = user.TenantID
+	invoice.UserID = user.ID
+	invoice.Reference = "inv_" + key
+	invoice.Status = model.InvoiceStatusOpen
+	invoice.Payments = nil
+	for i := range invoice.LineItems {
+		invoice.LineItems[i].ID = uuid.New()
+		invoice.LineItems[i].InvoiceID = invoice.ID
+	}
+
+	var newInvoice model.Invoice
+	err = c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		if newInvoice, err = tc.invoiceStorage.CreateInvoice(ctx, invoice); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &user.TenantID,
+			UserID:     &user.ID,
+			Actor:      model.ActorUser,
+			ActionDone: model.ActionCreated,
+			Messages:   fmt.Sprintf("invoice %s of %s created", invoice.Reference, invoice.Amount),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("CreateInvoice ::: unable to save invoice %v", err)
+		return model.Invoice{}, err
+	}
+
+	return newInvoice, nil
+}
+
+// GetInvoice returns one of the user's invoices with its payments
+func (c *Controller) GetInvoice(ctx context.Context, userID, invoiceID uuid.UUID) (model.Invoice, error) {
+	invoice, err := c.invoiceStorage.GetInvoiceByID(ctx, invoiceID)
+	if err != nil || invoice.UserID != userID {
+		return model.Invoice{}, ErrRecordNotFound
+	}
+
+	return invoice, nil
+}
+
+// GetInvoices returns the user's invoices, optionally of one status, newest first
+func (c *Controller) GetInvoices(ctx context.Context, userID uuid.UUID, status *model.InvoiceStatus, page pagination.Page) ([]model.Invoice, pagination.PageInfo, error) {
+	return c.invoiceStorage.GetInvoices(ctx, userID, status, page)
+}
+
+// GetInvoiceByReference returns the invoice shared under the reference, as the payer sees it
+func (c *Controller) GetInvoiceByReference(ctx context.Context, reference string) (model.Invoice, error) {
+	invoice, err := c.invoiceStorage.GetInvoiceByReference(ctx, reference)
+	if err != nil {
+		return model.Invoice{}, ErrRecordNotFound
+	}
+
+	return invoice, nil
+}
+
+// PayInvoice starts a payment of the invoice shared under the reference: a deposit through the provider into the
+// wallet of the invoice's creator, priced and limited as the creator's own deposits. The amount is what is left to
+// pay when it is nil, and can only be less when the invoice allows partial payments. The invoice counts the payment
+// once the provider's webhook settles its transaction
+func (c *Controller) PayInvoice(ctx context.Context, reference, payerName, payerEmail string, amount *model.Money) (model.InvoicePayment, error) {
+	invoice, err := c.GetInvoiceByReference(ctx, reference)
+	if err != nil {
+		return model.InvoicePayment{}, err
+	}
+
+	if !invoice.IsPayable(time.Now()) {
+		return model.InvoicePayment{}, fmt.Errorf("%w: invoice is %s", ErrInvoiceNotPayable, invoice.Status)
+	}
+
+	outstanding := invoice.Outstanding()
+	if amount == nil {
+		amount = &outstanding
+	}
+
+	switch {
+	case !strings.EqualFold(amount.Currency, invoice.Currency):
+		return model.InvoicePayment{}, model.ErrCurrencyMismatch
+	case amount.Minor > outstanding.Minor:
+		return model.InvoicePayment{}, fmt.Errorf("%w: only %s is left to pay", ErrInvalidInvoicePayment, outstanding)
+	case amount.Minor < outstanding.Minor && !invoice.AllowPartial:
+		return model.InvoicePayment{}, fmt.Errorf("%w: the invoice must be paid in full", ErrInvalidInvoicePayment)
+	}
+
+	user, err := c.GetUserByID(ctx, invoice.UserID)
+	if err != nil {
+		c.logger.Err(err).Msgf("PayInvoice ::: error getting user by ID %v", err)
+		return model.InvoicePayment{}, err
+	}
+
+	payment := model.InvoicePayment{
+		ID:         uuid.New(),
+		InvoiceID:  invoice.ID,
+		PayerName:  payerName,
+		PayerEmail: strings.ToLower(payerEmail),
+		Amount:     *amount,
+		Status:     model.InvoicePaymentPending,
+	}
+
+	_, err = c.deposit(ctx, user, *amount, invoice.SplitID, func(tc *Controller, transaction model.Transaction) error {
+		payment.TransactionID = transaction.ID
+
+		var err error
+		payment, err = tc.invoiceStorage.CreateInvoicePayment(ctx, payment)
+		return err
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("PayInvoice ::: deposit ===> %v", err)
+		return model.InvoicePayment{}, err
+	}
+
+	return payment, nil
+}
+
+// ExpireInvoices moves the invoices whose expiry passed before they were paid in full to expired and returns how
+// many. Payments started before the expiry are still counted when their webhook arrives
+func (c *Controller) ExpireInvoices(ctx context.Context) (int64, error) {
+	return c.invoiceStorage.ExpireInvoices(ctx, time.Now())
+}
+
+// settleInvoicePayment applies the outcome of a deposit's transaction to the invoice payment it was made for, if any.
+// A successful payment is counted towards the invoice and its creator is notified. It must be called within withTx
+func (c *Controller) settleInvoicePayment(ctx context.Context, user model.User, tx model.Transaction) error {
+	if tx.TransactionType != model.CreditTransaction {
+		return nil
+	}
+
+	payment, err := c.invoiceStorage.GetInvoicePaymentByTransactionID(ctx, tx.ID)
+	if err == storage.ErrRecordNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if payment.Status != model.InvoicePaymentPending {
+		return nil
+	}
+
+	switch tx.Status {
+	case model.TransactionStatusSuccessful:
+		payment.Status = model.InvoicePaymentSuccessful
+		// the provider's amount is what the payer actually paid
+		payment.Amount = tx.Amount
+	case model.TransactionStatusFailed:
+		payment.Status = model.InvoicePaymentFailed
+	default:
+		return nil
+	}
+
+	if err := c.invoiceStorage.UpdateInvoicePayment(ctx, payment); err != nil {
+		return err
+	}
+
+	if payment.Status != model.InvoicePaymentSuccessful {
+		return nil
+	}
+
+	// lock the invoice, payments of the same invoice settled at the same time are counted one after the other
+	invoice, err := c.invoiceStorage.GetInvoiceByIDForUpdate(ctx, payment.InvoiceID)
+	if err != nil {
+		return err
+	}
+
+	invoice.RecordPayment(payment.Amount, time.Now())
+	if err := c.invoiceStorage.UpdateInvoice(ctx, invoice); err != nil {
+		return err
+	}
+
+	payer := payment.PayerName
+	if payer == "" {
+		payer = "a payer"
+	}
+
+	kind := model.NotificationInvoicePartiallyPaid
+	message := fmt.Sprintf("%s paid %s of invoice %s, %s is left to pay", payer, payment.Amount, invoice.Title, invoice.Outstanding())
+	if invoice.Status == model.InvoiceStatusPaid {
+		kind = model.NotificationInvoicePaid
+		message = fmt.Sprintf("%s paid %s of invoice %s, it is paid in full", payer, payment.Amount, invoice.Title)
+	}
+
+	auditLog := model.AuditLog{
+		ID:            uuid.New(),
+		TenantID:      &user.TenantID,
+		UserID:        &user.ID,
+		TransactionID: &tx.ID,
+		Actor:         model.ActorUser,
+		ActionDone:    model.ActionSuccess,
+		Messages:      fmt.Sprintf("%s paid towards invoice %s, now %s", payment.Amount, invoice.Reference, invoice.Status),
+	}
+
+	if _, err := c.CreateAuditLog(ctx, auditLog); err != nil {
+		return err
+	}
+
+	return c.notify(ctx, user, kind, invoice.Reference, message)
+}
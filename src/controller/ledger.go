@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+)
+
+// CreateUserWalletLedgerAccount opens the ledger account that backs a user's wallet in the currency
+func (c *Controller) CreateUserWalletLedgerAccount(ctx context.Context, user model.User, currency string) (model.LedgerAccount, error) {
+	account := model.LedgerAccount{
+		ID:       uuid.New(),
+		Code:     model.UserWalletAccountCode(user.ID, currency),
+		Type:     model.LedgerAccountTypeUserWallet,
+		TenantID: &user.TenantID,
+		UserID:   &user.ID,
+		Currency: currency,
+	}
+
+	return c.ledgerStorage.GetOrCreateLedgerAccount(ctx, account)
+}
+
+// CreateTenantFeeLedgerAccount opens the ledger account that collects a tenant's fees in the currency
+func (c *Controller) CreateTenantFeeLedgerAccount(ctx context.Context, tenantID uuid.UUID, currency string) (model.LedgerAccount, error) {
+	account := model.LedgerAccount{
+		ID:       uuid.New(),
+		Code:     model.TenantFeeAccountCode(tenantID, currency),
+		Type:     model.LedgerAccountTypeTenantFee,
+		TenantID: &tenantID,
+		Currency: currency,
+	}
+
+	return c.ledgerStorage.GetOrCreateLedgerAccount(ctx, account)
+}
+
+// providerClearingLedgerAccount returns the clearing account of the provider that processed the transaction
+func (c *Controller) providerClearingLedgerAccount(ctx context.Context, provider model.PaymentProvider, currency string) (model.LedgerAccount, error) {
+	if provider == "" {
+		provider = model.PaymentProviderFlutterwave
+	}
+
+	account := model.LedgerAccount{
+		ID:       uuid.New(),
+		Code:     model.ProviderClearingAccountCode(provider, currency),
+		Type:     model.LedgerAccountTypeProviderClearing,
+		Currency: currency,
+	}
+
+	return c.ledgerStorage.GetOrCreateLedgerAccount(ctx, account)
+}
+
+// fxPositionLedgerAccount returns the account a tenant's conversions go through in the currency
+func (c *Controller) fxPositionLedgerAccount(ctx context.Context, tenantID uuid.UUID, currency string) (model.LedgerAccount, error) {
+	account := model.LedgerAccount{
+		ID:       uuid.New(),
+		Code:     model.FXPositionAccountCode(tenantID, currency),
+		Type:     model.LedgerAccountTypeFXPosition,
+		TenantID: &tenantID,
+		Currency: currency,
+	}
+
+	return c.ledgerStorage.GetOrCreateLedgerAccount(ctx, account)
+}
+
+// tenantVATLedgerAccount returns the ledger account that collects the VAT on a tenant's fees in the currency
+func (c *Controller) tenantVATLedgerAccount(ctx context.Context, tenantID uuid.UUID, currency string) (model.LedgerAccount, error) {
+	account := model.LedgerAccount{
+		ID:       uuid.New(),
+		Code:     model.TenantVATAccountCode(tenantID, currency),
+		Type:     model.LedgerAccountTypeTenantVAT,
+		TenantID: &tenantID,
+		Currency: currency,
+	}
+
+	return c.ledgerStorage.GetOrCreateLedgerAccount(ctx, account)
+}
+
+// feePostings returns the postings crediting the tenant's fee and VAT accounts with the fee of the transaction,
+// none when it has no fee. The wallet side of the fee is left to the caller's wallet posting
+func (c *Controller) feePostings(ctx context.Context, tenantID uuid.UUID, tx model.Transaction) ([]model.Posting, error) {
+	if !tx.Fee.IsPositive() {
+		return nil, nil
+	}
+
+	fee, err := tx.Fee.Sub(tx.FeeVAT)
+	if err != nil {
+		return nil, err
+	}
+
+	feeAccount, err := c.CreateTenantFeeLedgerAccount(ctx, tenantID, tx.Fee.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	postings := []model.Posting{{ID: uuid.New(), AccountID: feeAccount.ID, Amount: fee}}
+
+	if tx.FeeVAT.IsPositive() {
+		vatAccount, err := c.tenantVATLedgerAccount(ctx, tenantID, tx.Fee.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		postings = append(postings, model.Posting{ID: uuid.New(), AccountID: vatAccount.ID, Amount: tx.FeeVAT})
+	}
+
+	return postings, nil
+}
+
+// postProviderTransaction posts a transaction settled by a payment provider to the ledger.
+// A credit moves funds from the provider's clearing account into the user's wallet, a debit moves them back out.
+// The fee of the transaction goes from the wallet to the tenant's fee and VAT accounts within the same entry, so the
+// wallet gets a single posting of what the transaction moved it by
+func (c *Controller) postProviderTransaction(ctx context.Context, tenantID uuid.UUID, tx model.Transaction, walletAccount model.LedgerAccount, amount model.Money) (model.JournalEntry, error) {
+	clearing, err := c.providerClearingLedgerAccount(ctx, tx.Provider, amount.Currency)
+	if err != nil {
+		c.logger.Err(err).Msgf("postProviderTransaction ::: unable to get provider clearing account %v", err)
+		return model.JournalEntry{}, err
+	}
+
+	tx.Amount = amount
+	movement, err := tx.WalletMovement()
+	if err != nil {
+		return model.JournalEntry{}, err
+	}
+
+	if tx.TransactionType == model.TransactionTypeDebit {
+		amount = amount.Neg()
+	}
+
+	fees, err := c.feePostings(ctx, tenantID, tx)
+	if err != nil {
+		c.logger.Err(err).Msgf("postProviderTransaction ::: unable to get fee accounts %v", err)
+		return model.JournalEntry{}, err
+	}
+
+	entry := model.JournalEntry{
+		ID:            uuid.New(),
+		TransactionID: &tx.ID,
+		Description:   string(tx.TransactionFlow),
+		Postings: append([]model.Posting{
+			{ID: uuid.New(), AccountID: walletAccount.ID, Amount: movement},
+			{ID: uuid.New(), AccountID: clearing.ID, Amount: amount.Neg()},
+		}, fees...),
+	}
+
+	return c.ledgerStorage.PostJournalEntry(ctx, entry)
+}
+
+// GetLedgerAccountBalance returns the balance of a ledger account
+func (c *Controller) GetLedgerAccountBalance(ctx context.Context, accountID uuid.UUID) (model.Money, error) {
+	return c.ledgerStorage.GetLedgerAccountBalance(ctx, accountID)
+}
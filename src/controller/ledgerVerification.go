@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+)
+
+// verificationGrace keeps the movements still being written out of a verification: wallets are verified as they
+// were this long ago, so the posting, balance entry and transaction of a movement are either all in or all out
+const verificationGrace = time.Minute
+
+// VerifyLedger verifies the balance history of the wallets of every tenant, see VerifyTenantLedger. A tenant whose
+// verification fails is logged and skipped
+func (c *Controller) VerifyLedger(ctx context.Context, repair bool) ([]model.LedgerVerification, error) {
+	tenants, err := c.tenantStorage.GetTenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	verifications := make([]model.LedgerVerification, 0, len(tenants))
+	for _, tenant := range tenants {
+		verification, err := c.VerifyTenantLedger(ctx, tenant.ID, repair)
+		if err != nil {
+			c.logger.Err(err).Msgf("VerifyLedger ::: tenant %s ===> %v", tenant.ID, err)
+			continue
+		}
+
+		verifications = append(verifications, verification)
+	}
+
+	return verifications, nil
+}
+
+// VerifyTenantLedger walks the balance entries of every wallet of the tenant, oldest first, and checks that each
+// starts from the balance the previous one left, that it moved the wallet by its transaction's amount and fee, that
+// every settled transaction of the wallet has one, and that the latest leaves the balance of the wallet's ledger
+// account. The breaks found are saved with the verification.
+//
+// The ledger is the source of truth, so with repair a wallet whose latest entry disagrees with its ledger account
+// gets an adjustment entry bringing its balance history back in line. Earlier breaks are only reported, the balance
+// history is never rewritten
+func (c *Controller) VerifyTenantLedger(ctx context.Context, tenantID uuid.UUID, repair bool) (model.LedgerVerification, error) {
+	verification := model.LedgerVerification{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		Repair:        repair,
+		VerifiedUntil: time.Now().Add(-verificationGrace),
+	}
+
+	wallets, err := c.walletStorage.GetWalletsByTenantID(ctx, tenantID)
+	if err != nil {
+		return model.LedgerVerification{}, err
+	}
+
+	for _, wallet := range wallets {
+		entries, breaks, err := c.verifyWallet(ctx, wallet, verification.VerifiedUntil)
+		if err != nil {
+			return model.LedgerVerification{}, err
+		}
+
+		verification.Wallets++
+		verification.Entries += entries
+
+		for i := range breaks {
+			breaks[i].ID = uuid.New()
+			breaks[i].VerificationID = verification.ID
+			breaks[i].TenantID = tenantID
+
+			if repair && breaks[i].Kind == model.LedgerBreakWallet {
+				if breaks[i].Repaired, err = c.repairWalletBalance(ctx, tenantID, wallet.ID, verification.ID); err != nil {
+					return model.LedgerVerification{}, err
+				}
+			}
+
+			if breaks[i].Repaired {
+				verification.Repaired++
+			}
+		}
+
+		verification.Breaks += len(breaks)
+		verification.Items = append(verification.Items, breaks...)
+	}
+
+	finishedAt := time.Now()
+	verification.FinishedAt = &finishedAt
+
+	if verification.Breaks > verification.Repaired {
+		c.logger.Warn().Msgf("VerifyTenantLedger ::: tenant %s has %d unrepaired ledger breaks, see verification %s",
+			tenantID, verification.Breaks-verification.Repaired, verification.ID)
+	}
+
+	return c.ledgerVerificationStorage.CreateLedgerVerification(ctx, verification)
+}
+
+// GetLedgerVerifications returns the ledger verifications, optionally of one tenant, newest first
+func (c *Controller) GetLedgerVerifications(ctx context.Context, tenantID *uuid.UUID, page pagination.Page) ([]model.LedgerVerification, pagination.PageInfo, error) {
+	return c.ledgerVerificationStorage.GetLedgerVerifications(ctx, tenantID, page)
+}
+
+// verifyWallet checks the balance entries of the wallet written before until against its ledger account and its
+// settled transactions. It returns how many entries it checked and the breaks it found
+func (c *Controller) verifyWallet(ctx context.Context, wallet model.Wallet, until time.Time) (int, []model.LedgerBreak, error) {
+	balances, err := c.balanceStorage.GetBalancesByUserID(ctx, wallet.UserID, wallet.Currency, time.Time{}, until)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	transactions, err := c.transactionStorage.GetSettledTransactionsByUserID(ctx, wallet.UserID, wallet.Currency, until)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	settled := make(map[uuid.UUID]bool, len(transactions))
+	for _, transaction := range transactions {
+		settled[transaction.ID] = true
+	}
+
+	// the transactions of the entries that are not settled ones of the wallet, to tell why they do not belong
+	var ids []uuid.UUID
+	for _, balance := range balances {
+		if !settled[balance.TransactionID] {
+			ids = append(ids, balance.TransactionID)
+		}
+	}
+
+	others, err := c.transactionStorage.GetTransactionsByIDs(ctx, ids)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ledgerBalance := model.ZeroMoney(wallet.Currency)
+	if wallet.LedgerAccountID != nil {
+		if ledgerBalance, err = c.ledgerStorage.GetPostingsTotal(ctx, *wallet.LedgerAccountID, time.Time{}, until); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return len(balances), checkBalanceHistory(wallet, ledgerBalance, balances, append(transactions, others...)), nil
+}
+
+// repairWalletBalance writes an adjustment entry taking the wallet's latest balance entry to the balance of its
+// ledger account. It reports false when they agree by then, e.g. the wallet moved since it was verified
+func (c *Controller) repairWalletBalance(ctx context.Context, tenantID, walletID, verificationID uuid.UUID) (bool, error) {
+	repaired := false
+
+	err := c.withTx(ctx, func(tc *Controller) error {
+		locked, err := tc.lockWallets(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		wallet := locked[walletID]
+
+		latest, err := tc.balanceStorage.GetBalanceAt(ctx, wallet.UserID, wallet.Currency, time.Now())
+		if err != nil {
+			return err
+		}
+
+		difference, err := wallet.BookBalance.Sub(latest.BalanceAfter)
+		if err != nil || difference.IsZero() {
+			return err
+		}
+
+		adjustment := model.Transaction{
+			ID:              uuid.New(),
+			UserID:          wallet.UserID,
+			Amount:          difference.Abs(),
+			Charges:         model.ZeroMoney(wallet.Currency),
+			Currency:        wallet.Currency,
+			TransactionType: model.CreditTransaction,
+			Status:          model.TransactionStatusSuccessful,
+			TransactionFlow: model.TransactionFlowAdjustment,
+		}
+
+		if difference.IsNegative() {
+			adjustment.TransactionType = model.DebitTransaction
+		}
+
+		if err := adjustment.SetMetaData(model.MetaData{"verification_id": verificationID}); err != nil {
+			return err
+		}
+
+		if _, err := tc.CreateTransaction(ctx, adjustment); err != nil {
+			return err
+		}
+
+		balance, err := tc.CreateBalance(ctx, model.Balance{
+			ID:              uuid.New(),
+			UserID:          wallet.UserID,
+			TransactionType: adjustment.TransactionType,
+			TransactionID:   adjustment.ID,
+			BalanceBefore:   latest.BalanceAfter,
+			BalanceAfter:    wallet.BookBalance,
+		})
+		if err != nil {
+			return err
+		}
+
+		txType := adjustment.TransactionType
+		wallet.BalanceBefore = balance.BalanceBefore
+		wallet.BalanceAfter = balance.BalanceAfter
+		wallet.TransactionID = &adjustment.ID
+		wallet.TransactionType = &txType
+		wallet.BalanceID = &balance.ID
+
+		if err := tc.UpdateWalletByID(ctx, wallet); err != nil {
+			return err
+		}
+
+		_, err = tc.CreateAuditLog(ctx, model.AuditLog{
+			ID:            uuid.New(),
+			TenantID:      &tenantID,
+			UserID:        &wallet.UserID,
+			TransactionID: &adjustment.ID,
+			Actor:         model.ActorSystem,
+			ActionDone:    model.ActionUpdated,
+			Messages:      "adjusted the wallet balance history to its ledger balance by " + difference.String(),
+		})
+		if err != nil {
+			return err
+		}
+
+		repaired = true
+		return nil
+	})
+
+	return repaired, err
+}
+
+// checkBalanceHistory checks the balance entries of a wallet, oldest first, against the balance of its ledger
+// account and its transactions: the settled ones of the wallet and those the entries point at
+func checkBalanceHistory(wallet model.Wallet, ledgerBalance model.Money, balances []model.Balance, transactions []model.Transaction) []model.LedgerBreak {
+	byID := make(map[uuid.UUID]model.Transaction, len(transactions))
+	for _, transaction := range transactions {
+		byID[transaction.ID] = transaction
+	}
+
+	var breaks []model.LedgerBreak
+	seen := make(map[uuid.UUID]bool, len(balances))
+	previous := model.ZeroMoney(wallet.Currency)
+
+	for _, balance := range balances {
+		balanceID := balance.ID
+		entry := model.LedgerBreak{
+			UserID:    wallet.UserID,
+			WalletID:  wallet.ID,
+			BalanceID: &balanceID,
+		}
+
+		if balance.BalanceBefore != previous {
+			chain := entry
+			chain.Kind = model.LedgerBreakChain
+			chain.Expected = previous
+			chain.Actual = balance.BalanceBefore
+			breaks = append(breaks, chain)
+		}
+		previous = balance.BalanceAfter
+
+		movement, err := balance.BalanceAfter.Sub(balance.BalanceBefore)
+		if err != nil {
+			movement = balance.BalanceAfter
+		}
+		entry.Actual = movement
+
+		transaction, ok := byID[balance.TransactionID]
+		if !ok || !settlesWallet(wallet, transaction) {
+			entry.Kind = model.LedgerBreakOrphanEntry
+			breaks = append(breaks, entry)
+			continue
+		}
+
+		transactionID := transaction.ID
+		entry.TransactionID = &transactionID
+		entry.Expected, _ = transaction.WalletMovement()
+
+		if seen[transaction.ID] {
+			entry.Kind = model.LedgerBreakDuplicateEntry
+			breaks = append(breaks, entry)
+			continue
+		}
+		seen[transaction.ID] = true
+
+		if err != nil || movement != entry.Expected {
+			entry.Kind = model.LedgerBreakAmount
+			breaks = append(breaks, entry)
+		}
+	}
+
+	for _, transaction := range transactions {
+		if seen[transaction.ID] || !settlesWallet(wallet, transaction) {
+			continue
+		}
+		seen[transaction.ID] = true
+
+		transactionID := transaction.ID
+		expected, _ := transaction.WalletMovement()
+		breaks = append(breaks, model.LedgerBreak{
+			UserID:        wallet.UserID,
+			WalletID:      wallet.ID,
+			Kind:          model.LedgerBreakMissingEntry,
+			TransactionID: &transactionID,
+			Expected:      expected,
+			Actual:        model.ZeroMoney(wallet.Currency),
+		})
+	}
+
+	if previous != ledgerBalance {
+		breaks = append(breaks, model.LedgerBreak{
+			UserID:   wallet.UserID,
+			WalletID: wallet.ID,
+			Kind:     model.LedgerBreakWallet,
+			Expected: ledgerBalance,
+			Actual:   previous,
+		})
+	}
+
+	return breaks
+}
+
+// settlesWallet reports whether the transaction is a settled one of the wallet, one that moved its balance
+func settlesWallet(wallet model.Wallet, transaction model.Transaction) bool {
+	settled := transaction.Status == model.TransactionStatusSuccessful || transaction.Status == model.TransactionStatusRefunded
+	return settled && transaction.UserID == wallet.UserID && transaction.Amount.Currency == wallet.Currency
+}
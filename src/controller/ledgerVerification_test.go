@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+func Test_CheckBalanceHistory(t *testing.T) {
+	wallet := model.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: "NGN"}
+	ngn := func(minor int64) model.Money { return model.NewMoney(minor, "NGN") }
+
+	transaction := func(amount int64, transactionType model.TransactionType, status model.TransactionStatus) model.Transaction {
+		return model.Transaction{
+			ID:              uuid.New(),
+			UserID:          wallet.UserID,
+			Amount:          ngn(amount),
+			TransactionType: transactionType,
+			Status:          status,
+		}
+	}
+	entry := func(transaction model.Transaction, before, after int64) model.Balance {
+		return model.Balance{ID: uuid.New(), UserID: wallet.UserID, TransactionID: transaction.ID, BalanceBefore: ngn(before), BalanceAfter: ngn(after)}
+	}
+
+	deposit := transaction(500000, model.CreditTransaction, model.TransactionStatusSuccessful)
+	refunded := transaction(100000, model.CreditTransaction, model.TransactionStatusRefunded)
+	withdrawal := transaction(200000, model.DebitTransaction, model.TransactionStatusSuccessful)
+
+	t.Run("consistent history", func(t *testing.T) {
+		balances := []model.Balance{entry(deposit, 0, 500000), entry(refunded, 500000, 600000), entry(withdrawal, 600000, 400000)}
+		require.Empty(t, checkBalanceHistory(wallet, ngn(400000), balances, []model.Transaction{deposit, refunded, withdrawal}))
+	})
+
+	t.Run("breaks", func(t *testing.T) {
+		canceled := transaction(50000, model.DebitTransaction, model.TransactionStatusCanceled)
+		otherUsers := transaction(70000, model.CreditTransaction, model.TransactionStatusSuccessful)
+		otherUsers.UserID = uuid.New()
+		unrecorded := transaction(30000, model.CreditTransaction, model.TransactionStatusSuccessful)
+
+		// the withdrawal entry starts from the balance before the previous entry, as the old webhook debit did
+		balances := []model.Balance{
+			entry(deposit, 0, 500000),
+			entry(withdrawal, 0, 300000),
+			entry(withdrawal, 300000, 100000),
+			entry(canceled, 100000, 50000),
+			entry(otherUsers, 50000, 120000),
+		}
+		transactions := []model.Transaction{deposit, withdrawal, unrecorded, canceled, otherUsers}
+
+		breaks := checkBalanceHistory(wallet, ngn(150000), balances, transactions)
+
+		kinds := make([]model.LedgerBreakKind, 0, len(breaks))
+		for _, item := range breaks {
+			require.Equal(t, wallet.ID, item.WalletID)
+			kinds = append(kinds, item.Kind)
+		}
+		require.Equal(t, []model.LedgerBreakKind{
+			model.LedgerBreakChain,
+			model.LedgerBreakAmount,
+			model.LedgerBreakDuplicateEntry,
+			model.LedgerBreakOrphanEntry,
+			model.LedgerBreakOrphanEntry,
+			model.LedgerBreakMissingEntry,
+			model.LedgerBreakWallet,
+		}, kinds)
+
+		require.Equal(t, ngn(500000), breaks[0].Expected)
+		require.Equal(t, ngn(0), breaks[0].Actual)
+		require.Equal(t, ngn(-200000), breaks[1].Expected)
+		require.Equal(t, ngn(300000), breaks[1].Actual)
+		require.Equal(t, unrecorded.ID, *breaks[5].TransactionID)
+		require.Equal(t, ngn(30000), breaks[5].Expected)
+		require.Equal(t, ngn(150000), breaks[6].Expected)
+		require.Equal(t, ngn(120000), breaks[6].Actual)
+	})
+
+	t.Run("no entries", func(t *testing.T) {
+		require.Empty(t, checkBalanceHistory(wallet, model.ZeroMoney("NGN"), nil, nil))
+
+		breaks := checkBalanceHistory(wallet, ngn(500000), nil, []model.Transaction{deposit})
+		require.Len(t, breaks, 2)
+		require.Equal(t, model.LedgerBreakMissingEntry, breaks[0].Kind)
+		require.Equal(t, model.LedgerBreakWallet, breaks[1].Kind)
+	})
+}
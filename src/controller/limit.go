@@ -0,0 +1,271 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/storage"
+)
+
+// SetTransactionLimit sets how much each of the tenant's users can move with an action in a currency
+func (c *Controller) SetTransactionLimit(ctx context.Context, tenantID uuid.UUID, limit model.TransactionLimit) (model.TransactionLimit, error) {
+	if err := limit.Validate(); err != nil {
+		return model.TransactionLimit{}, err
+	}
+
+	limit.ID = uuid.New()
+	limit.TenantID = tenantID
+
+	var newLimit model.TransactionLimit
+	err := c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		if newLimit, err = tc.limitStorage.UpsertTransactionLimit(ctx, limit); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &tenantID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionUpdated,
+			Messages:   fmt.Sprintf("set %s limit for %s", limit.Action, limit.Currency),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("SetTransactionLimit ::: unable to save transaction limit %v", err)
+		return model.TransactionLimit{}, err
+	}
+
+	return newLimit, nil
+}
+
+// GetTransactionLimits returns the tenant's transaction limits
+func (c *Controller) GetTransactionLimits(ctx context.Context, tenantID uuid.UUID) ([]model.TransactionLimit, error) {
+	return c.limitStorage.GetTransactionLimits(ctx, tenantID)
+}
+
+// DeleteTransactionLimit removes the tenant's limit for an action in a currency, its users are not limited from then on
+func (c *Controller) DeleteTransactionLimit(ctx context.Context, tenantID uuid.UUID, action model.LimitAction, currency string) error {
+	if !action.IsValid() {
+		return model.ErrInvalidLimitAction
+	}
+	currency = strings.ToUpper(currency)
+
+	return c.withTx(ctx, func(tc *Controller) error {
+		deleted, err := tc.limitStorage.DeleteTransactionLimit(ctx, tenantID, action, currency)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrRecordNotFound
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &tenantID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionUpdated,
+			Messages:   fmt.Sprintf("removed %s limit for %s", action, currency),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+}
+
+// limitReservation is the usage an action reserved against the limit of the user's tenant before it was made. A nil
+// reservation reserved nothing
+type limitReservation struct {
+	keys   []string
+	ttls   []time.Duration
+	amount model.Money
+}
+
+// reserveLimit refuses the action on the amount with a LimitExceededError when it breaks the limit of the user's
+// tenant for it, and audits the refusal. The action is added to the user's usage of the day and month in redis before
+// the limit is checked, so concurrent actions cannot all pass on the same remaining allowance. The reservation must
+// be released when the action is not made. Counters redis does not have are seeded from the user's transactions, and
+// when redis cannot count at all the usage is counted from the transactions without a reservation
+func (c *Controller) reserveLimit(ctx context.Context, user model.User, action model.LimitAction, amount model.Money) (*limitReservation, error) {
+	limit, err := c.limitStorage.GetTransactionLimit(ctx, user.TenantID, action, amount.Currency)
+	if err == storage.ErrRecordNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	tenant, err := c.tenantStorage.GetTenantByID(ctx, user.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := &limitReservation{amount: amount}
+	var usages [2]model.LimitUsage
+	for i, period := range limitPeriods(time.Now(), tenant.Location()) {
+		if usages[i], err = c.reserveLimitUsage(ctx, reservation, user.ID, action, amount, period); err != nil {
+			c.logger.Err(err).Msgf("reserveLimit ::: unable to reserve usage, counting it from transactions %v", err)
+			c.releaseLimit(ctx, reservation)
+			return nil, c.checkLimitFromTransactions(ctx, user, tenant, limit, action, amount)
+		}
+	}
+
+	kind, exceeded := limit.Check(amount, usages[0], usages[1])
+	if !exceeded {
+		return reservation, nil
+	}
+
+	c.releaseLimit(ctx, reservation)
+	return nil, c.limitExceeded(ctx, user, action, kind, amount)
+}
+
+// releaseLimit gives back the usage of an action that was not made
+func (c *Controller) releaseLimit(ctx context.Context, reservation *limitReservation) {
+	if reservation == nil {
+		return
+	}
+
+	for i, key := range reservation.keys {
+		if _, err := c.redis.IncrementBy(ctx, key+":count", -1, reservation.ttls[i]); err != nil {
+			c.logger.Err(err).Msgf("releaseLimit ::: error releasing usage count %v", err)
+		}
+
+		if _, err := c.redis.IncrementBy(ctx, key+":amount", -reservation.amount.Minor, reservation.ttls[i]); err != nil {
+			c.logger.Err(err).Msgf("releaseLimit ::: error releasing usage amount %v", err)
+		}
+	}
+	reservation.keys, reservation.ttls = nil, nil
+}
+
+// reserveLimitUsage adds the action on the amount to the user's usage counters of the period and returns the usage
+// before it. Counters redis does not have are seeded from the user's transactions first, only once however many
+// actions seed them at the same time
+func (c *Controller) reserveLimitUsage(ctx context.Context, reservation *limitReservation, userID uuid.UUID, action model.LimitAction, amount model.Money, period limitPeriod) (model.LimitUsage, error) {
+	key := limitUsageKey(userID, action, amount.Currency, period)
+	ttl := time.Until(period.end) + limitUsageGrace
+
+	if err := c.seedLimitUsage(ctx, key, userID, action, amount.Currency, period, ttl); err != nil {
+		return model.LimitUsage{}, err
+	}
+
+	count, err := c.redis.IncrementBy(ctx, key+":count", 1, ttl)
+	if err != nil {
+		return model.LimitUsage{}, err
+	}
+
+	total, err := c.redis.IncrementBy(ctx, key+":amount", amount.Minor, ttl)
+	if err != nil {
+		_, _ = c.redis.IncrementBy(ctx, key+":count", -1, ttl)
+		return model.LimitUsage{}, err
+	}
+
+	reservation.keys = append(reservation.keys, key)
+	reservation.ttls = append(reservation.ttls, ttl)
+
+	return model.LimitUsage{Count: count - 1, Amount: model.NewMoney(total-amount.Minor, amount.Currency)}, nil
+}
+
+// seedLimitUsage sets the usage counters of the period redis does not have from the user's transactions. A counter
+// another action seeded or counted on meanwhile is left alone
+func (c *Controller) seedLimitUsage(ctx context.Context, key string, userID uuid.UUID, action model.LimitAction, currency string, period limitPeriod, ttl time.Duration) error {
+	count, countErr := c.redis.GetStringValue(ctx, key+":count")
+	amount, amountErr := c.redis.GetStringValue(ctx, key+":amount")
+	if countErr == nil && amountErr == nil && count != "" && amount != "" {
+		return nil
+	}
+
+	usage, err := c.limitUsage(ctx, userID, action, currency, period)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.redis.SetValueIfAbsent(ctx, key+":count", usage.Count, ttl); err != nil {
+		return err
+	}
+
+	_, err = c.redis.SetValueIfAbsent(ctx, key+":amount", usage.Amount.Minor, ttl)
+	return err
+}
+
+// checkLimitFromTransactions refuses the action on the amount when it breaks the limit, with the user's usage counted
+// from their transactions. It reserves nothing, it is used when redis cannot count
+func (c *Controller) checkLimitFromTransactions(ctx context.Context, user model.User, tenant model.Tenant, limit model.TransactionLimit, action model.LimitAction, amount model.Money) error {
+	var usages [2]model.LimitUsage
+	for i, period := range limitPeriods(time.Now(), tenant.Location()) {
+		var err error
+		if usages[i], err = c.limitUsage(ctx, user.ID, action, amount.Currency, period); err != nil {
+			return err
+		}
+	}
+
+	kind, exceeded := limit.Check(amount, usages[0], usages[1])
+	if !exceeded {
+		return nil
+	}
+
+	return c.limitExceeded(ctx, user, action, kind, amount)
+}
+
+// limitExceeded audits the refusal of the action on the amount for breaking the kind of limit and returns its
+// LimitExceededError
+func (c *Controller) limitExceeded(ctx context.Context, user model.User, action model.LimitAction, kind model.LimitKind, amount model.Money) error {
+	limitErr := &LimitExceededError{Action: action, Limit: kind, Currency: amount.Currency}
+
+	auditLog := model.AuditLog{
+		ID:         uuid.New(),
+		TenantID:   &user.TenantID,
+		UserID:     &user.ID,
+		Actor:      model.ActorUser,
+		ActionDone: model.ActionLimitExceeded,
+		Messages:   fmt.Sprintf("%s of %s refused: %s", action, amount, limitErr),
+	}
+
+	if _, err := c.CreateAuditLog(ctx, auditLog); err != nil {
+		c.logger.Err(err).Msgf("limitExceeded ::: error creating audit log %v", err)
+	}
+
+	return limitErr
+}
+
+// limitUsage returns how many transactions of the action the user made in the period and the amount they moved,
+// counted from the user's transactions
+func (c *Controller) limitUsage(ctx context.Context, userID uuid.UUID, action model.LimitAction, currency string, period limitPeriod) (model.LimitUsage, error) {
+	flow, txType := action.Flow()
+	return c.transactionStorage.GetTransactionUsage(ctx, userID, flow, txType, currency, period.start)
+}
+
+// limitUsageGrace keeps the usage counters of a period around a while after it ends
+const limitUsageGrace = 24 * time.Hour
+
+// limitPeriod is a day or a month of the tenant's timezone, name identifies it in the usage keys
+type limitPeriod struct {
+	name       string
+	start, end time.Time
+}
+
+// limitPeriods returns the day and the month now falls in, in location
+func limitPeriods(now time.Time, location *time.Location) [2]limitPeriod {
+	now = now.In(location)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+
+	return [2]limitPeriod{
+		{name: day.Format("2006-01-02"), start: day, end: day.AddDate(0, 0, 1)},
+		{name: month.Format("2006-01"), start: month, end: month.AddDate(0, 1, 0)},
+	}
+}
+
+// limitUsageKey is the redis key prefix of the user's usage counters of the action in the currency for the period
+func limitUsageKey(userID uuid.UUID, action model.LimitAction, currency string, period limitPeriod) string {
+	return fmt.Sprintf("limit:%s:%s:%s:%s", userID, action, strings.ToUpper(currency), period.name)
+}
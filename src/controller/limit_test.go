@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+	"codematic/storage"
+)
+
+// limitStubs stand in for the storage layers a limit check reads
+type (
+	stubLimitStorage struct {
+		storage.LimitDatabase
+		limit model.TransactionLimit
+	}
+
+	stubTenantStorage struct {
+		storage.TenantDatabase
+		tenant model.Tenant
+	}
+
+	stubTransactionStorage struct {
+		storage.TransactionDatabase
+		usage model.LimitUsage
+	}
+
+	stubAuditLogStorage struct {
+		storage.AuditLogDatabase
+	}
+)
+
+func (s stubLimitStorage) GetTransactionLimit(_ context.Context, _ uuid.UUID, _ model.LimitAction, _ string) (model.TransactionLimit, error) {
+	return s.limit, nil
+}
+
+func (s stubTenantStorage) GetTenantByID(_ context.Context, _ uuid.UUID) (model.Tenant, error) {
+	return s.tenant, nil
+}
+
+func (s stubTransactionStorage) GetTransactionUsage(_ context.Context, _ uuid.UUID, _ model.TransactionFlow, _ model.TransactionType, _ string, _ time.Time) (model.LimitUsage, error) {
+	return s.usage, nil
+}
+
+func (s stubAuditLogStorage) CreateAuditLog(_ context.Context, auditLog model.AuditLog) (model.AuditLog, error) {
+	return auditLog, nil
+}
+
+func Test_ReserveLimit_Concurrent(t *testing.T) {
+	tenant := model.Tenant{ID: uuid.New()}
+	c := &Controller{
+		logger:             zerolog.Nop(),
+		redis:              &memoryKvStore{values: map[string]string{}},
+		limitStorage:       stubLimitStorage{limit: model.TransactionLimit{TenantID: tenant.ID, DailyCount: 5, DailyAmount: model.NewMoney(1000000, model.DefaultCurrency)}},
+		tenantStorage:      stubTenantStorage{tenant: tenant},
+		transactionStorage: stubTransactionStorage{usage: model.LimitUsage{Count: 1, Amount: model.NewMoney(100000, model.DefaultCurrency)}},
+		auditLogStorage:    stubAuditLogStorage{},
+	}
+	ctx := context.Background()
+	user := model.User{ID: uuid.New(), TenantID: tenant.ID}
+	amount := model.NewMoney(100000, model.DefaultCurrency)
+
+	// parallel transfers all start from the same usage, only as many as the daily count leaves room for get through
+	const requests = 20
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		reservations []*limitReservation
+		refusals     []error
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := c.reserveLimit(ctx, user, model.LimitActionTransfer, amount)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				refusals = append(refusals, err)
+				return
+			}
+			reservations = append(reservations, reservation)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, reservations, 4)
+	require.Len(t, refusals, requests-4)
+	for _, err := range refusals {
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	}
+
+	// a transfer that was not made gives its usage back, the next one takes its place
+	c.releaseLimit(ctx, reservations[0])
+	reservation, err := c.reserveLimit(ctx, user, model.LimitActionTransfer, amount)
+	require.NoError(t, err)
+	require.NotNil(t, reservation)
+
+	_, err = c.reserveLimit(ctx, user, model.LimitActionTransfer, amount)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+
+	// the daily amount is reserved the same way
+	_, err = c.reserveLimit(ctx, model.User{ID: uuid.New(), TenantID: tenant.ID}, model.LimitActionTransfer, model.NewMoney(900001, model.DefaultCurrency))
+	require.ErrorIs(t, err, ErrLimitExceeded)
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+)
+
+// GetNotifications returns the user's notifications, optionally the unread ones only, newest first
+func (c *Controller) GetNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, page pagination.Page) ([]model.Notification, pagination.PageInfo, error) {
+	return c.notificationStorage.GetNotifications(ctx, userID, unreadOnly, page)
+}
+
+// MarkNotificationRead marks one of the user's notifications read
+func (c *Controller) MarkNotificationRead(ctx context.Context, userID, notificationID uuid.UUID) error {
+	marked, err := c.notificationStorage.MarkNotificationRead(ctx, userID, notificationID)
+	if err != nil {
+		return err
+	}
+
+	if !marked {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// notify tells the user about something that happened, reference points at what it is about
+func (c *Controller) notify(ctx context.Context, user model.User, kind model.NotificationKind, reference, message string) error {
+	notification := model.Notification{
+		ID:        uuid.New(),
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		Kind:      kind,
+		Reference: reference,
+		Message:   message,
+	}
+
+	if _, err := c.notificationStorage.CreateNotification(ctx, notification); err != nil {
+		c.logger.Err(err).Msgf("notify ::: error creating notification %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -3,6 +3,8 @@ package controller
 import (
 	"codematic/model"
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -45,14 +47,52 @@ func (c *Controller) VirtualAccount(ctx context.Context, userID uuid.UUID, fullN
 
 // Deposit is a method used to add funds to once wallet. We would be assumming that we already have the users card details.
 // And as such, all we need to for the user to pass in the amount they would want to depoist into their wallet, and it gets processed
-// and their wallet gets deposited if no errors occures
-func (c *Controller) Deposit(ctx context.Context, userID uuid.UUID, amount float64) error {
+// and their wallet gets deposited if no errors occures. The deposit is divided by the user's split splitID, when set,
+// once it succeeds
+func (c *Controller) Deposit(ctx context.Context, userID uuid.UUID, amount model.Money, splitID *uuid.UUID) error {
 	user, err := c.GetUserByID(ctx, userID)
 	if err != nil {
 		c.logger.Err(err).Msgf("error getting user by ID ::: %v", err)
 		return err
 	}
 
+	_, err = c.deposit(ctx, user, amount, splitID, nil)
+	return err
+}
+
+// deposit does the work of Deposit and returns the deposit's transaction. saved, when set, is called within the
+// database transaction that saves the deposit, e.g. to link it to what it pays
+func (c *Controller) deposit(ctx context.Context, user model.User, amount model.Money, splitID *uuid.UUID, saved func(tc *Controller, transaction model.Transaction) error) (model.Transaction, error) {
+	if err := c.checkSplit(ctx, user, splitID, amount.Currency); err != nil {
+		return model.Transaction{}, err
+	}
+
+	quote, err := c.quoteFee(ctx, user.TenantID, model.FeeActionDeposit, amount)
+	if err != nil {
+		c.logger.Err(err).Msgf("Deposit ::: quoteFee ===> %v", err)
+		return model.Transaction{}, err
+	}
+
+	// the fee is taken off the deposit, a deposit it would take all of is refused before the provider is called
+	if quote.Total.Minor >= amount.Minor {
+		return model.Transaction{}, ErrFeeExceedsAmount
+	}
+
+	// a deposit into a wallet that does not allow credits is refused before the provider is called, a wallet not
+	// opened yet is opened active
+	if wallet, err := c.walletStorage.GetWalletByUserID(ctx, user.ID, amount.Currency); err == nil {
+		if err := checkWalletCredit(wallet); err != nil {
+			return model.Transaction{}, err
+		}
+	}
+
+	// the usage is reserved against the limit before the action is made, and given back unless it is made
+	reservation, err := c.reserveLimit(ctx, user, model.LimitActionDeposit, amount)
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	defer func() { c.releaseLimit(ctx, reservation) }()
+
 	payload := model.InitiateTransaction{
 		Amount: amount,
 	}
@@ -60,7 +100,7 @@ func (c *Controller) Deposit(ctx context.Context, userID uuid.UUID, amount float
 	_, err = c.paymentService.InitiateTransaction(model.PaymentProviderFlutterwave, model.PaymentActionDeposit, payload)
 	if err != nil {
 		c.logger.Err(err).Msgf("Deposit ::: InitiateTransaction ===> %v", err)
-		return err
+		return model.Transaction{}, err
 	}
 
 	// create a transaction history
@@ -68,42 +108,146 @@ func (c *Controller) Deposit(ctx context.Context, userID uuid.UUID, amount float
 		ID:              uuid.New(),
 		UserID:          user.ID,
 		Amount:          amount,
-		Charges:         (amount * 10) / 100, // assumming processing charges is 10%
+		Charges:         model.ZeroMoney(amount.Currency),
+		Currency:        amount.Currency,
 		TransactionType: model.CreditTransaction,
+		Status:          model.TransactionStatusPending,
+		Provider:        model.PaymentProviderFlutterwave,
 		TransactionFlow: model.TransactionFlowRevenue,
+		SplitID:         splitID,
 	}
+	transaction.SetFee(quote)
 
-	if _, err := c.CreateTransaction(ctx, transaction); err != nil {
-		c.logger.Err(err).Msgf("Deposit ::: CreateTransaction ::: error creating transaction history ===> %v", err)
-		return err
-	}
+	// the transaction history and its audit log are written as one unit of work
+	err = c.withTx(ctx, func(tc *Controller) error {
+		// deposits land in the wallet of their currency, open it if the user does not hold that currency yet
+		if _, err := tc.openWallet(ctx, user, amount.Currency); err != nil {
+			tc.logger.Err(err).Msgf("Deposit ::: openWallet ===> %v", err)
+			return err
+		}
 
-	// create a audit log
-	auditLog := model.AuditLog{
-		ID:            uuid.New(),
-		TenantID:      &user.TenantID,
-		TransactionID: &transaction.ID,
-		UserID:        &user.ID,
-		Actor:         model.ActorUser,
-		ActionDone:    model.ActionCreated,
-		Messages:      "deposit created",
-	}
+		if _, err := tc.CreateTransaction(ctx, transaction); err != nil {
+			tc.logger.Err(err).Msgf("Deposit ::: CreateTransaction ::: error creating transaction history ===> %v", err)
+			return err
+		}
 
-	if _, err = c.CreateAuditLog(ctx, auditLog); err != nil {
-		c.logger.Err(err).Msgf("error creating audit log")
-		return err
+		// create a audit log
+		auditLog := model.AuditLog{
+			ID:            uuid.New(),
+			TenantID:      &user.TenantID,
+			TransactionID: &transaction.ID,
+			UserID:        &user.ID,
+			Actor:         model.ActorUser,
+			ActionDone:    model.ActionCreated,
+			Messages:      "deposit created",
+		}
+
+		if _, err := tc.CreateAuditLog(ctx, auditLog); err != nil {
+			tc.logger.Err(err).Msgf("error creating audit log")
+			return err
+		}
+
+		if saved != nil {
+			return saved(tc, transaction)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return model.Transaction{}, err
 	}
 
-	return nil
+	// the action was made, its usage is kept
+	reservation = nil
+	return transaction, nil
 }
 
-func (c *Controller) Transfer(ctx context.Context, userID uuid.UUID, bankNumber, accountNumber string, amount float64) error {
+// Transfer makes a withdrawal from the user's wallet to a bank account. The amount and its fee are held on the wallet
+// before the provider is called, the hold is captured or released when the provider's webhook arrives
+func (c *Controller) Transfer(ctx context.Context, userID uuid.UUID, bankNumber, accountNumber string, amount model.Money) error {
 	user, err := c.GetUserByID(ctx, userID)
 	if err != nil {
 		c.logger.Err(err).Msgf("error getting user by ID ::: %v", err)
 		return err
 	}
 
+	_, err = c.transfer(ctx, user, bankNumber, accountNumber, amount)
+	return err
+}
+
+// transfer does the work of Transfer and returns the withdrawal's transaction
+func (c *Controller) transfer(ctx context.Context, user model.User, bankNumber, accountNumber string, amount model.Money) (model.Transaction, error) {
+	quote, err := c.quoteFee(ctx, user.TenantID, model.FeeActionTransfer, amount)
+	if err != nil {
+		c.logger.Err(err).Msgf("Transfer ::: quoteFee ===> %v", err)
+		return model.Transaction{}, err
+	}
+
+	// the usage is reserved against the limit before the action is made, and given back unless it is made
+	reservation, err := c.reserveLimit(ctx, user, model.LimitActionTransfer, amount)
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	defer func() { c.releaseLimit(ctx, reservation) }()
+
+	// create a transaction history
+	transaction := model.Transaction{
+		ID:              uuid.New(),
+		UserID:          user.ID,
+		Amount:          amount,
+		Charges:         model.ZeroMoney(amount.Currency),
+		Currency:        amount.Currency,
+		TransactionType: model.DebitTransaction,
+		Status:          model.TransactionStatusPending,
+		Provider:        model.PaymentProviderFlutterwave,
+		TransactionFlow: model.TransactionFlowWithdrawal,
+	}
+	transaction.SetFee(quote)
+
+	// the transaction history, the hold on the wallet and the audit log are written as one unit of work
+	err = c.withTx(ctx, func(tc *Controller) error {
+		// lock the wallet of the transfer's currency so concurrent transfers see each other's holds
+		wallet, err := tc.walletForDebit(ctx, user.ID, amount.Currency)
+		if err != nil {
+			tc.logger.Err(err).Msgf("Transfer ::: error getting wallet by userID ===> %v", err)
+			return err
+		}
+
+		if err := checkWalletDebit(wallet); err != nil {
+			return err
+		}
+
+		if _, err := tc.CreateTransaction(ctx, transaction); err != nil {
+			tc.logger.Err(err).Msgf("Transfer ::: CreateTransaction ::: error creating transaction history ===> %v", err)
+			return err
+		}
+
+		if _, err := tc.placeHold(ctx, wallet, transaction); err != nil {
+			tc.logger.Err(err).Msgf("Transfer ::: placeHold ===> %v", err)
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:            uuid.New(),
+			TenantID:      &user.TenantID,
+			TransactionID: &transaction.ID,
+			UserID:        &user.ID,
+			Actor:         model.ActorUser,
+			ActionDone:    model.ActionCreated,
+			Messages:      "withdrawal created",
+		}
+
+		if _, err := tc.CreateAuditLog(ctx, auditLog); err != nil {
+			tc.logger.Err(err).Msgf("error creating audit log")
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return model.Transaction{}, err
+	}
+
 	payload := model.InitiateTransaction{
 		BankNumber:    bankNumber,
 		AccountNumber: accountNumber,
@@ -113,38 +257,263 @@ func (c *Controller) Transfer(ctx context.Context, userID uuid.UUID, bankNumber,
 	_, err = c.paymentService.InitiateTransaction(model.PaymentProviderFlutterwave, model.PaymentActionTransfer, payload)
 	if err != nil {
 		c.logger.Err(err).Msgf("Transfer ::: InitiateTransaction ===> %v", err)
+
+		// the provider never took the withdrawal, give the held funds back
+		if releaseErr := c.failTransfer(ctx, user, transaction); releaseErr != nil {
+			c.logger.Err(releaseErr).Msgf("Transfer ::: failTransfer ===> %v", releaseErr)
+		}
+
+		return model.Transaction{}, fmt.Errorf("%w: %v", ErrProviderCallFailed, err)
+	}
+
+	// the action was made, its usage is kept
+	reservation = nil
+	return transaction, nil
+}
+
+// failTransfer marks a transfer the provider rejected as failed and releases its hold
+func (c *Controller) failTransfer(ctx context.Context, user model.User, transaction model.Transaction) error {
+	return c.withTx(ctx, func(tc *Controller) error {
+		if err := tc.settleHold(ctx, transaction.ID, model.HoldStatusReleased); err != nil {
+			return err
+		}
+
+		transaction.Status = model.TransactionStatusFailed
+		if err := tc.UpdateTransactionByID(ctx, transaction); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:            uuid.New(),
+			TenantID:      &user.TenantID,
+			TransactionID: &transaction.ID,
+			UserID:        &user.ID,
+			Actor:         model.ActorUser,
+			ActionDone:    model.ActionFailed,
+			Messages:      "withdrawal rejected by provider, hold released",
+		}
+
+		_, err := tc.CreateAuditLog(ctx, auditLog)
 		return err
+	})
+}
+
+// InternalTransfer instantly moves funds from the sender's wallet to the wallet of the recipient with the email, in
+// the amount's currency. No payment provider is involved: both sides get a successful transaction, linked to each
+// other, a balance entry and an audit log, and the movement is one journal entry between the two wallet accounts.
+// The sender pays the fee of their tenant on top of the amount, it is posted to the tenant's fee account in the same
+// entry.
+// The recipient's wallet is opened when they do not hold the currency yet
+func (c *Controller) InternalTransfer(ctx context.Context, senderID uuid.UUID, recipientEmail string, amount model.Money, narration string) (model.Transaction, error) {
+	sender, err := c.GetUserByID(ctx, senderID)
+	if err != nil {
+		c.logger.Err(err).Msgf("InternalTransfer ::: error getting sender by ID %v", err)
+		return model.Transaction{}, err
 	}
 
-	// create a transaction history
-	transaction := model.Transaction{
+	recipient, err := c.userStorage.GetUserByEmail(ctx, strings.ToLower(recipientEmail))
+	if err != nil {
+		c.logger.Err(err).Msgf("InternalTransfer ::: error getting recipient by email %v", err)
+		return model.Transaction{}, ErrUserDoesNotExist
+	}
+
+	if sender.ID == recipient.ID {
+		return model.Transaction{}, ErrSelfTransfer
+	}
+
+	if err := c.checkTransferTenants(ctx, sender, recipient); err != nil {
+		return model.Transaction{}, err
+	}
+
+	// the transfer is priced by the sender's tenant, the sender pays the fee on top of the amount
+	quote, err := c.quoteFee(ctx, sender.TenantID, model.FeeActionInternalTransfer, amount)
+	if err != nil {
+		c.logger.Err(err).Msgf("InternalTransfer ::: quoteFee ===> %v", err)
+		return model.Transaction{}, err
+	}
+
+	// the usage is reserved against the limit before the action is made, and given back unless it is made
+	reservation, err := c.reserveLimit(ctx, sender, model.LimitActionInternalTransfer, amount)
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	defer func() { c.releaseLimit(ctx, reservation) }()
+
+	debit := model.Transaction{
 		ID:              uuid.New(),
-		UserID:          user.ID,
+		UserID:          sender.ID,
 		Amount:          amount,
-		Charges:         (amount * 10) / 100, // assumming processing charges is 10%
+		Charges:         model.ZeroMoney(amount.Currency),
+		Currency:        amount.Currency,
 		TransactionType: model.DebitTransaction,
-		TransactionFlow: model.TransactionFlowWithdrawal,
+		Status:          model.TransactionStatusSuccessful,
+		TransactionFlow: model.TransactionFlowInternalTransfer,
 	}
+	debit.SetFee(quote)
 
-	if _, err := c.CreateTransaction(ctx, transaction); err != nil {
-		c.logger.Err(err).Msgf("Deposit ::: CreateTransaction ::: error creating transaction history ===> %v", err)
-		return err
+	credit := model.Transaction{
+		ID:              uuid.New(),
+		UserID:          recipient.ID,
+		Amount:          amount,
+		Charges:         model.ZeroMoney(amount.Currency),
+		Currency:        amount.Currency,
+		TransactionType: model.CreditTransaction,
+		Status:          model.TransactionStatusSuccessful,
+		TransactionFlow: model.TransactionFlowInternalTransfer,
 	}
 
-	auditLog := model.AuditLog{
-		ID:            uuid.New(),
-		TenantID:      &user.TenantID,
-		TransactionID: &transaction.ID,
-		UserID:        &user.ID,
-		Actor:         model.ActorUser,
-		ActionDone:    model.ActionCreated,
-		Messages:      "withdrawal created",
+	debit.RelatedTransactionID = &credit.ID
+	credit.RelatedTransactionID = &debit.ID
+
+	if err := debit.SetMetaData(model.MetaData{"narration": narration, "recipient_id": recipient.ID}); err != nil {
+		return model.Transaction{}, err
 	}
 
-	if _, err = c.CreateAuditLog(ctx, auditLog); err != nil {
-		c.logger.Err(err).Msgf("error creating audit log")
+	if err := credit.SetMetaData(model.MetaData{"narration": narration, "sender_id": sender.ID}); err != nil {
+		return model.Transaction{}, err
+	}
+
+	err = c.withTx(ctx, func(tc *Controller) error {
+		source, err := tc.walletStorage.GetWalletByUserID(ctx, sender.ID, amount.Currency)
+		if err != nil {
+			return ErrNoWalletForCurrency
+		}
+
+		target, err := tc.openWallet(ctx, recipient, amount.Currency)
+		if err != nil {
+			return err
+		}
+
+		// both wallets are locked in ID order, so opposite transfers between the same users cannot deadlock
+		locked, err := tc.lockWallets(ctx, source.ID, target.ID)
+		if err != nil {
+			return err
+		}
+		source, target = locked[source.ID], locked[target.ID]
+
+		if err := checkWalletDebit(source); err != nil {
+			return err
+		}
+
+		if err := checkWalletCredit(target); err != nil {
+			return err
+		}
+
+		movement, err := debit.WalletMovement()
+		if err != nil {
+			return err
+		}
+
+		remaining, err := source.AvailableBalance.Add(movement)
+		if err != nil {
+			return err
+		}
+
+		if remaining.IsNegative() {
+			return &InsufficientFundsError{Available: source.AvailableBalance, Requested: movement.Neg()}
+		}
+
+		if source, err = tc.ensureWalletLedgerAccount(ctx, sender, source); err != nil {
+			return err
+		}
+
+		if target, err = tc.ensureWalletLedgerAccount(ctx, recipient, target); err != nil {
+			return err
+		}
+
+		for _, tx := range []model.Transaction{debit, credit} {
+			if _, err := tc.CreateTransaction(ctx, tx); err != nil {
+				tc.logger.Err(err).Msgf("InternalTransfer ::: CreateTransaction ===> %v", err)
+				return err
+			}
+		}
+
+		fees, err := tc.feePostings(ctx, sender.TenantID, debit)
+		if err != nil {
+			return err
+		}
+
+		entry := model.JournalEntry{
+			ID:            uuid.New(),
+			TransactionID: &debit.ID,
+			Description:   string(model.TransactionFlowInternalTransfer),
+			Postings: append([]model.Posting{
+				{ID: uuid.New(), AccountID: *source.LedgerAccountID, Amount: movement},
+				{ID: uuid.New(), AccountID: *target.LedgerAccountID, Amount: amount},
+			}, fees...),
+		}
+
+		if _, err := tc.ledgerStorage.PostJournalEntry(ctx, entry); err != nil {
+			tc.logger.Err(err).Msgf("InternalTransfer ::: unable to post transfer to the ledger ===> %v", err)
+			return err
+		}
+
+		if err := tc.recordWalletMovement(ctx, source, debit); err != nil {
+			return err
+		}
+
+		if err := tc.recordWalletMovement(ctx, target, credit); err != nil {
+			return err
+		}
+
+		auditLogs := []model.AuditLog{
+			{
+				ID:            uuid.New(),
+				TenantID:      &sender.TenantID,
+				TransactionID: &debit.ID,
+				UserID:        &sender.ID,
+				Actor:         model.ActorUser,
+				ActionDone:    model.ActionSuccess,
+				Messages:      fmt.Sprintf("sent %s to %s", amount, recipient.Email),
+			},
+			{
+				ID:            uuid.New(),
+				TenantID:      &recipient.TenantID,
+				TransactionID: &credit.ID,
+				UserID:        &recipient.ID,
+				Actor:         model.ActorUser,
+				ActionDone:    model.ActionSuccess,
+				Messages:      fmt.Sprintf("received %s from %s", amount, sender.Email),
+			},
+		}
+
+		for _, auditLog := range auditLogs {
+			if _, err := tc.CreateAuditLog(ctx, auditLog); err != nil {
+				tc.logger.Err(err).Msgf("error creating audit log")
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return model.Transaction{}, err
+	}
+
+	// the action was made, its usage is kept
+	reservation = nil
+	return debit, nil
+}
+
+// checkTransferTenants blocks an internal transfer between users of two tenants unless both tenants allow it
+func (c *Controller) checkTransferTenants(ctx context.Context, sender, recipient model.User) error {
+	if sender.TenantID == recipient.TenantID {
+		return nil
+	}
+
+	senderTenant, err := c.tenantStorage.GetTenantByID(ctx, sender.TenantID)
+	if err != nil {
 		return err
 	}
 
+	recipientTenant, err := c.tenantStorage.GetTenantByID(ctx, recipient.TenantID)
+	if err != nil {
+		return err
+	}
+
+	if !senderTenant.AllowsTransfersWith(recipientTenant) {
+		return ErrCrossTenantTransfer
+	}
+
 	return nil
 }
@@ -0,0 +1,519 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/storage"
+)
+
+const (
+	// maxPayoutItems is the most items a payout batch can have
+	maxPayoutItems = 1000
+	// payoutItemTimeout is how long an item can be processing before it is taken as interrupted, e.g. by a restart
+	payoutItemTimeout = 10 * time.Minute
+	// payoutItemInterrupted is the error of an item that was interrupted while it was being paid
+	payoutItemInterrupted = "interrupted before its outcome was saved, check the user's transactions before paying it again"
+)
+
+// payoutFile is a payout batch uploaded as json, its items have the columns of a csv batch as keys
+type payoutFile struct {
+	Items []map[string]any `json:"items"`
+}
+
+// CreatePayoutBatch validates a file of payouts the user makes from their wallet in the currency and queues it. Each
+// row pays either a user, by email, or a bank account, with an amount in major units and an optional narration. The
+// whole batch is refused when a row is invalid, two rows pay the same amount to the same recipient, the amounts and
+// their fees exceed the wallet's available balance, or the rows would break the limits of the user's tenant. The items
+// are paid in the background, one after the other, through the transfer flows
+func (c *Controller) CreatePayoutBatch(ctx context.Context, userID uuid.UUID, fileName, currency string, r io.Reader) (model.PayoutBatch, error) {
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil {
+		c.logger.Err(err).Msgf("CreatePayoutBatch ::: error getting user by ID %v", err)
+		return model.PayoutBatch{}, err
+	}
+
+	currency = strings.ToUpper(currency)
+	if !model.IsSupportedCurrency(currency) {
+		return model.PayoutBatch{}, model.ErrUnsupportedCurrency
+	}
+
+	items, err := readPayoutFile(fileName, r, currency)
+	if err != nil {
+		return model.PayoutBatch{}, fmt.Errorf("%w: %v", ErrInvalidPayoutBatch, err)
+	}
+
+	batch := model.PayoutBatch{
+		ID:          uuid.New(),
+		TenantID:    user.TenantID,
+		UserID:      user.ID,
+		FileName:    fileName,
+		Currency:    currency,
+		Status:      model.PayoutBatchQueued,
+		Items:       len(items),
+		TotalAmount: model.ZeroMoney(currency),
+		TotalFees:   model.ZeroMoney(currency),
+		PaidAmount:  model.ZeroMoney(currency),
+	}
+
+	for i := range items {
+		quote, err := c.quoteFee(ctx, user.TenantID, items[i].FeeAction(), items[i].Amount)
+		if err != nil {
+			return model.PayoutBatch{}, err
+		}
+
+		items[i].ID = uuid.New()
+		items[i].BatchID = batch.ID
+		items[i].Fee = quote.Total
+		items[i].Status = model.PayoutItemPending
+
+		batch.TotalAmount.Minor += items[i].Amount.Minor
+		batch.TotalFees.Minor += quote.Total.Minor
+	}
+
+	wallet, err := c.walletStorage.GetWalletByUserID(ctx, user.ID, currency)
+	if err == storage.ErrRecordNotFound {
+		return model.PayoutBatch{}, ErrNoWalletForCurrency
+	}
+
+	if err != nil {
+		return model.PayoutBatch{}, err
+	}
+
+	if err := checkWalletDebit(wallet); err != nil {
+		return model.PayoutBatch{}, err
+	}
+
+	total, err := batch.TotalAmount.Add(batch.TotalFees)
+	if err != nil {
+		return model.PayoutBatch{}, err
+	}
+
+	if total.Minor > wallet.AvailableBalance.Minor {
+		return model.PayoutBatch{}, &InsufficientFundsError{Available: wallet.AvailableBalance, Requested: total}
+	}
+
+	if err := c.checkPayoutLimits(ctx, user, items); err != nil {
+		return model.PayoutBatch{}, err
+	}
+
+	err = c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		if batch, err = tc.payoutStorage.CreatePayoutBatch(ctx, batch); err != nil {
+			return err
+		}
+
+		if err := tc.payoutStorage.CreatePayoutItems(ctx, items); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &user.TenantID,
+			UserID:     &user.ID,
+			Actor:      model.ActorUser,
+			ActionDone: model.ActionCreated,
+			Messages:   fmt.Sprintf("payout batch %s of %d items for %s created", fileName, batch.Items, batch.TotalAmount),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("CreatePayoutBatch ::: unable to save payout batch %v", err)
+		return model.PayoutBatch{}, err
+	}
+
+	// start paying right away, the scheduled ProcessPayoutBatches picks the batch up should this stop half way
+	go func() {
+		if err := c.processPayoutBatch(context.Background(), batch); err != nil {
+			c.logger.Err(err).Msgf("CreatePayoutBatch ::: processPayoutBatch ===> %v", err)
+		}
+	}()
+
+	return batch, nil
+}
+
+// ProcessPayoutBatches pays the pending items of every queued or processing batch and returns how many batches it
+// completed. Items another worker is paying are left to it
+func (c *Controller) ProcessPayoutBatches(ctx context.Context) (int, error) {
+	batches, err := c.payoutStorage.GetUnfinishedPayoutBatches(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, batch := range batches {
+		if err := c.processPayoutBatch(ctx, batch); err != nil {
+			c.logger.Err(err).Msgf("ProcessPayoutBatches ::: batch %s ===> %v", batch.ID, err)
+			continue
+		}
+
+		if batch, err = c.payoutStorage.GetPayoutBatchByID(ctx, batch.ID); err == nil && batch.Status == model.PayoutBatchCompleted {
+			completed++
+		}
+	}
+
+	return completed, nil
+}
+
+// GetPayoutBatch returns one of the user's payout batches
+func (c *Controller) GetPayoutBatch(ctx context.Context, userID, batchID uuid.UUID) (model.PayoutBatch, error) {
+	batch, err := c.payoutStorage.GetPayoutBatchByID(ctx, batchID)
+	if err != nil || batch.UserID != userID {
+		return model.PayoutBatch{}, ErrRecordNotFound
+	}
+
+	return batch, nil
+}
+
+// GetPayoutBatches returns the user's payout batches, newest first
+func (c *Controller) GetPayoutBatches(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.PayoutBatch, pagination.PageInfo, error) {
+	return c.payoutStorage.GetPayoutBatches(ctx, userID, page)
+}
+
+// GetPayoutItems returns the items of one of the user's payout batches, optionally of one status, in the order of
+// the file
+func (c *Controller) GetPayoutItems(ctx context.Context, userID, batchID uuid.UUID, status *model.PayoutItemStatus, page pagination.Page) ([]model.PayoutItem, pagination.PageInfo, error) {
+	if _, err := c.GetPayoutBatch(ctx, userID, batchID); err != nil {
+		return nil, pagination.PageInfo{}, err
+	}
+
+	return c.payoutStorage.GetPayoutItems(ctx, batchID, status, page)
+}
+
+// GetPayoutResult returns one of the user's payout batches with every item and its outcome, once the batch completed
+func (c *Controller) GetPayoutResult(ctx context.Context, userID, batchID uuid.UUID) (model.PayoutBatch, []model.PayoutItem, error) {
+	batch, err := c.GetPayoutBatch(ctx, userID, batchID)
+	if err != nil {
+		return model.PayoutBatch{}, nil, err
+	}
+
+	if batch.Status != model.PayoutBatchCompleted {
+		return model.PayoutBatch{}, nil, ErrPayoutBatchNotFinished
+	}
+
+	items, err := c.payoutStorage.GetAllPayoutItems(ctx, batchID)
+	if err != nil {
+		return model.PayoutBatch{}, nil, err
+	}
+
+	return batch, items, nil
+}
+
+// processPayoutBatch pays the pending items of the batch one after the other and completes the batch once none is
+// left. An item is claimed before it is paid, so two workers never pay the same item
+func (c *Controller) processPayoutBatch(ctx context.Context, batch model.PayoutBatch) error {
+	if batch.Status == model.PayoutBatchQueued {
+		batch.Status = model.PayoutBatchProcessing
+		if err := c.payoutStorage.UpdatePayoutBatch(ctx, batch); err != nil {
+			return err
+		}
+	}
+
+	if err := c.payoutStorage.FailStalePayoutItems(ctx, batch.ID, time.Now().Add(-payoutItemTimeout), payoutItemInterrupted); err != nil {
+		return err
+	}
+
+	user, err := c.GetUserByID(ctx, batch.UserID)
+	if err != nil {
+		return err
+	}
+
+	items, err := c.payoutStorage.GetAllPayoutItems(ctx, batch.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if item.Status != model.PayoutItemPending {
+			continue
+		}
+
+		claimed, err := c.payoutStorage.ClaimPayoutItem(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		item = c.payPayoutItem(ctx, user, item)
+		if err := c.payoutStorage.UpdatePayoutItem(ctx, item); err != nil {
+			return err
+		}
+	}
+
+	return c.completePayoutBatch(ctx, user, batch)
+}
+
+// payPayoutItem makes the transfer of an item and returns the item with its outcome
+func (c *Controller) payPayoutItem(ctx context.Context, user model.User, item model.PayoutItem) model.PayoutItem {
+	var (
+		tx  model.Transaction
+		err error
+	)
+
+	if item.IsInternal() {
+		tx, err = c.InternalTransfer(ctx, user.ID, item.RecipientEmail, item.Amount, item.Narration)
+	} else {
+		tx, err = c.transfer(ctx, user, item.BankNumber, item.AccountNumber, item.Amount)
+	}
+
+	if err != nil {
+		c.logger.Err(err).Msgf("payPayoutItem ::: row %d of batch %s ===> %v", item.Row, item.BatchID, err)
+		item.Status = model.PayoutItemFailed
+		item.Error = err.Error()
+		return item
+	}
+
+	item.Status = model.PayoutItemSucceeded
+	item.TransactionID = &tx.ID
+	return item
+}
+
+// completePayoutBatch counts the outcomes of the batch's items and completes it when none is left to pay
+func (c *Controller) completePayoutBatch(ctx context.Context, user model.User, batch model.PayoutBatch) error {
+	items, err := c.payoutStorage.GetAllPayoutItems(ctx, batch.ID)
+	if err != nil {
+		return err
+	}
+
+	batch.Succeeded, batch.Failed, batch.PaidAmount = 0, 0, model.ZeroMoney(batch.Currency)
+	for _, item := range items {
+		if item.Status == model.PayoutItemPending || item.Status == model.PayoutItemProcessing {
+			return nil
+		}
+
+		batch.Count(item)
+	}
+
+	finishedAt := time.Now()
+	batch.Status = model.PayoutBatchCompleted
+	batch.FinishedAt = &finishedAt
+
+	return c.withTx(ctx, func(tc *Controller) error {
+		if err := tc.payoutStorage.UpdatePayoutBatch(ctx, batch); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &user.TenantID,
+			UserID:     &user.ID,
+			Actor:      model.ActorUser,
+			ActionDone: model.ActionSuccess,
+			Messages:   fmt.Sprintf("payout batch %s completed, %d succeeded and %d failed", batch.FileName, batch.Succeeded, batch.Failed),
+		}
+
+		_, err := tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+}
+
+// checkPayoutLimits refuses a batch whose items would break the limits of the user's tenant, counting the items one
+// after the other on top of what the user already used today and this month
+func (c *Controller) checkPayoutLimits(ctx context.Context, user model.User, items []model.PayoutItem) error {
+	tenant, err := c.tenantStorage.GetTenantByID(ctx, user.TenantID)
+	if err != nil {
+		return err
+	}
+
+	type limitState struct {
+		limit  *model.TransactionLimit
+		usages [2]model.LimitUsage
+	}
+
+	periods := limitPeriods(time.Now(), tenant.Location())
+	states := make(map[model.LimitAction]*limitState)
+
+	for _, item := range items {
+		action := item.LimitAction()
+
+		state, ok := states[action]
+		if !ok {
+			state = &limitState{}
+			states[action] = state
+
+			limit, err := c.limitStorage.GetTransactionLimit(ctx, user.TenantID, action, item.Amount.Currency)
+			if err != nil && err != storage.ErrRecordNotFound {
+				return err
+			}
+
+			if err == nil {
+				state.limit = &limit
+				for i, period := range periods {
+					if state.usages[i], err = c.limitUsage(ctx, user.ID, action, item.Amount.Currency, period); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if state.limit == nil {
+			continue
+		}
+
+		if kind, exceeded := state.limit.Check(item.Amount, state.usages[0], state.usages[1]); exceeded {
+			return fmt.Errorf("row %d: %w", item.Row, c.limitExceeded(ctx, user, action, kind, item.Amount))
+		}
+
+		for i := range state.usages {
+			state.usages[i].Count++
+			state.usages[i].Amount.Minor += item.Amount.Minor
+		}
+	}
+
+	return nil
+}
+
+// readPayoutFile reads the items of a payout batch in the currency from a csv file with a header row or a json file
+// listing them under items. The columns are email, bank_number, account_number, amount and narration
+func readPayoutFile(fileName string, r io.Reader, currency string) ([]model.PayoutItem, error) {
+	var (
+		rows []map[string]string
+		err  error
+	)
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		rows, err = readPayoutCSV(r)
+	case ".json":
+		rows, err = readPayoutJSON(r)
+	default:
+		return nil, fmt.Errorf("payout file must be .csv or .json")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("payout file has no items")
+	}
+
+	if len(rows) > maxPayoutItems {
+		return nil, fmt.Errorf("payout file has %d items, at most %d are allowed", len(rows), maxPayoutItems)
+	}
+
+	items := make([]model.PayoutItem, 0, len(rows))
+	seen := make(map[string]int, len(rows))
+
+	for i, row := range rows {
+		item, err := payoutItem(row, currency)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		item.Row = i + 1
+
+		key := fmt.Sprintf("%s:%d", item.Recipient(), item.Amount.Minor)
+		if first, ok := seen[key]; ok {
+			return nil, fmt.Errorf("rows %d and %d pay the same amount to the same recipient", first, item.Row)
+		}
+		seen[key] = item.Row
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// payoutItem converts one row of a payout file into an item
+func payoutItem(row map[string]string, currency string) (model.PayoutItem, error) {
+	item := model.PayoutItem{
+		RecipientEmail: strings.ToLower(strings.TrimSpace(row["email"])),
+		BankNumber:     strings.TrimSpace(row["bank_number"]),
+		AccountNumber:  strings.TrimSpace(row["account_number"]),
+		Narration:      strings.TrimSpace(row["narration"]),
+	}
+
+	switch {
+	case item.RecipientEmail != "" && (item.BankNumber != "" || item.AccountNumber != ""):
+		return model.PayoutItem{}, fmt.Errorf("pay either an email or a bank account, not both")
+	case item.RecipientEmail == "" && (item.BankNumber == "" || item.AccountNumber == ""):
+		return model.PayoutItem{}, fmt.Errorf("email or bank_number and account_number are required")
+	case len(item.Narration) > 140:
+		return model.PayoutItem{}, fmt.Errorf("narration is longer than 140 characters")
+	}
+
+	amount, err := model.ParseMoney(strings.TrimSpace(row["amount"]), currency)
+	if err != nil {
+		return model.PayoutItem{}, fmt.Errorf("invalid amount %q: %w", row["amount"], err)
+	}
+
+	if !amount.IsPositive() {
+		return model.PayoutItem{}, fmt.Errorf("amount must be positive")
+	}
+
+	item.Amount = amount
+	return item, nil
+}
+
+func readPayoutCSV(r io.Reader) ([]map[string]string, error) {
+	lines, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	header := lines[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	rows := make([]map[string]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(line) {
+				row[column] = line[i]
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func readPayoutJSON(r io.Reader) ([]map[string]string, error) {
+	var file payoutFile
+
+	decoder := json.NewDecoder(r)
+	// keep amounts exact, a float64 would round large amounts
+	decoder.UseNumber()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]string, 0, len(file.Items))
+	for _, data := range file.Items {
+		row := make(map[string]string, len(data))
+		for key, value := range data {
+			if value != nil {
+				row[strings.ToLower(key)] = fmt.Sprint(value)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
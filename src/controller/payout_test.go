@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+func Test_ReadPayoutFile(t *testing.T) {
+	payoutCSV := "Email,Bank_Number,Account_Number,Amount,Narration\n" +
+		"Ada@Example.com,,,5000.25,rent\n" +
+		",058,0123456789,1200,\n"
+	payoutJSON := `{"items":[{"email":"ada@example.com","amount":5000.25,"narration":"rent"},` +
+		`{"bank_number":"058","account_number":"0123456789","amount":1200}]}`
+
+	for name, file := range map[string]struct{ name, content string }{
+		"csv":  {"payouts.csv", payoutCSV},
+		"json": {"payouts.JSON", payoutJSON},
+	} {
+		t.Run(name, func(t *testing.T) {
+			items, err := readPayoutFile(file.name, strings.NewReader(file.content), "NGN")
+			require.NoError(t, err)
+			require.Len(t, items, 2)
+
+			require.Equal(t, 1, items[0].Row)
+			require.True(t, items[0].IsInternal())
+			require.Equal(t, "ada@example.com", items[0].RecipientEmail)
+			require.Equal(t, model.NewMoney(500025, "NGN"), items[0].Amount)
+			require.Equal(t, "rent", items[0].Narration)
+
+			require.Equal(t, 2, items[1].Row)
+			require.False(t, items[1].IsInternal())
+			require.Equal(t, "058/0123456789", items[1].Recipient())
+			require.Equal(t, model.NewMoney(120000, "NGN"), items[1].Amount)
+		})
+	}
+}
+
+func Test_ReadPayoutFileRejects(t *testing.T) {
+	header := "email,bank_number,account_number,amount,narration\n"
+
+	for name, test := range map[string]struct{ name, content, err string }{
+		"unknown extension": {"payouts.txt", header, "must be .csv or .json"},
+		"no items":          {"payouts.csv", header, "no items"},
+		"no recipient":      {"payouts.csv", header + ",058,,100,\n", "row 1: email or bank_number and account_number are required"},
+		"two recipients":    {"payouts.csv", header + "ada@example.com,058,0123456789,100,\n", "row 1: pay either"},
+		"invalid amount":    {"payouts.csv", header + "ada@example.com,,,ten,\n", "row 1: invalid amount"},
+		"negative amount":   {"payouts.csv", header + "ada@example.com,,,-10,\n", "row 1: amount must be positive"},
+		"long narration":    {"payouts.csv", header + "ada@example.com,,,10," + strings.Repeat("a", 141) + "\n", "row 1: narration"},
+		"duplicate":         {"payouts.csv", header + "ada@example.com,,,10,\nbob@example.com,,,10,\nADA@example.com,,,10.00,\n", "rows 1 and 3"},
+		"invalid json":      {"payouts.json", `{"items":`, "unexpected EOF"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := readPayoutFile(test.name, strings.NewReader(test.content), "NGN")
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+
+	rows := strings.Repeat("ada@example.com,,,10,\n", maxPayoutItems+1)
+	_, err := readPayoutFile("payouts.csv", strings.NewReader(header+rows), "NGN")
+	require.ErrorContains(t, err, "at most")
+}
@@ -4,19 +4,65 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 
 	"codematic/model"
 )
 
-func (c *Controller) ProcessPaymentWebhook(ctx context.Context, payload model.PaymentWebhook) error {
-	txID := strings.Split(payload.Data.Reference, "_")
-	tx, err := c.GetTransactionByID(ctx, uuid.MustParse(txID[1]))
+// ProcessPaymentWebhook applies a payment provider's webhook, normalised by its adapter, to the transaction it
+// references. All the writes happen in one database transaction with the transaction and wallet rows locked,
+// so concurrent webhooks for the same wallet are applied one after the other. Deliveries from the
+// providers go through the webhook inbox, see ReceiveWebhookEvent
+func (c *Controller) ProcessPaymentWebhook(ctx context.Context, event model.PaymentEvent) error {
+	prefix, transactionID, err := parsePaymentEvent(event)
 	if err != nil {
-		c.logger.Err(err).Msgf("GetTransactionByID ===> error getting transaction by ID %v", err)
+		c.logger.Err(err).Msgf("ProcessPaymentWebhook ===> invalid webhook %v", event.Reference)
+		return err
+	}
+
+	return c.withTx(ctx, func(tc *Controller) error {
+		return tc.applyPaymentEvent(ctx, prefix, transactionID, event)
+	})
+}
+
+// parsePaymentEvent checks an event can be applied and returns the prefix and the transaction ID of its reference.
+// A charge credits a wallet and must reference a crt_ transaction, a transfer debits one and must reference a dbt_
+// transaction
+func parsePaymentEvent(event model.PaymentEvent) (string, uuid.UUID, error) {
+	prefix, id, found := strings.Cut(event.Reference, "_")
+	if !found || (prefix != "dbt" && prefix != "crt") {
+		return "", uuid.Nil, ErrInvalidWebhookReference
+	}
+
+	if event.Kind == model.PaymentEventCharge && prefix != "crt" || event.Kind == model.PaymentEventTransfer && prefix != "dbt" {
+		return "", uuid.Nil, MismatchedTransactionType
+	}
+
+	transactionID, err := uuid.Parse(id)
+	if err != nil {
+		return "", uuid.Nil, ErrTransactionID
+	}
+
+	switch event.Status {
+	case model.TransactionStatusSuccessful, model.TransactionStatusFailed, model.TransactionStatusPending:
+	case model.TransactionStatusRefunded:
+		if prefix != "dbt" {
+			return "", uuid.Nil, ErrInvalidWebhookStatus
+		}
+	default:
+		return "", uuid.Nil, ErrInvalidWebhookStatus
+	}
+
+	return prefix, transactionID, nil
+}
+
+// applyPaymentEvent does the database work of ProcessPaymentWebhook, it must be called within withTx
+func (c *Controller) applyPaymentEvent(ctx context.Context, prefix string, transactionID uuid.UUID, event model.PaymentEvent) error {
+	// lock the transaction row first, a concurrent delivery of the same webhook waits here until we commit
+	tx, err := c.transactionStorage.GetTransactionByIDForUpdate(ctx, transactionID)
+	if err != nil {
+		c.logger.Err(err).Msgf("GetTransactionByIDForUpdate ===> error getting transaction by ID %v", err)
 		return ErrTransactionID
 	}
 
@@ -24,96 +70,97 @@ func (c *Controller) ProcessPaymentWebhook(ctx context.Context, payload model.Pa
 	// make credits to the wallets just to avoid getting wallets credited just because the webhook reference has the prefix
 	// crt meanwhile the transaction type is debit
 
-	if txID[0] == "crt" && tx.TransactionType == model.DebitTransaction || txID[0] == "dbt" && tx.TransactionType == model.CreditTransaction {
+	if prefix == "crt" && tx.TransactionType == model.DebitTransaction || prefix == "dbt" && tx.TransactionType == model.CreditTransaction {
 		return MismatchedTransactionType
 	}
 
+	// only the provider the transaction was made with can settle or reverse it, a reference is not enough: another
+	// provider's signed webhook could carry it
+	if tx.Provider != event.Provider {
+		return fmt.Errorf("%w: webhook is from %s, transaction was made with %s", ErrWebhookProviderMismatch, event.Provider, tx.Provider)
+	}
+
+	// a reversal comes after the transfer settled, it is applied whatever state the transfer is in
+	if event.Status == model.TransactionStatusRefunded {
+		return c.reverseTransfer(ctx, tx, event)
+	}
+
+	if tx.Status == model.TransactionStatusSuccessful || tx.Status == model.TransactionStatusRefunded {
+		// the transaction was settled by an earlier delivery of this webhook
+		return nil
+	}
+
 	user, err := c.GetUserByID(ctx, tx.UserID)
 	if err != nil {
 		c.logger.Err(err).Msgf("GetUserByID ===> error getting user by ID %v", err)
 		return err
 	}
 
-	// TODO save the ID into redis to avoid updating the transaction matching ID several times
-	setStatus, err := c.GetStringValue(ctx, fmt.Sprintf("%s_%s", txID[0], tx.ID.String()))
-	if err != nil {
-		if err == redis.Nil {
-			setStatus = ""
-		} else {
-			return err
-		}
+	// the adapters read the provider's amounts into minor units. The transaction was routed to the wallet of its
+	// currency, a webhook in any other currency is rejected
+	currency := tx.Amount.Currency
+	if !strings.EqualFold(event.Amount.Currency, currency) {
+		return fmt.Errorf("%w: webhook is in %s, transaction is in %s", model.ErrCurrencyMismatch, event.Amount.Currency, currency)
 	}
 
-	if setStatus == string(model.TransactionStatusSuccessful) {
-		// do not do anything, since the transaction has already been updated. Just return without an error
-		return nil
+	amount, fees := event.Amount, event.Fees
+	if err := c.checkEventAmount(ctx, user.TenantID, &tx, amount); err != nil {
+		c.logger.Err(err).Msgf("ProcessPaymentWebhook ===> amount of transaction %s refused", tx.ID)
+		return err
 	}
 
-	// tx.MetaData = &payload.Data.Metadata
-	tx.SetMetaData(payload.Data.Metadata)
-	tx.Charges = payload.Data.Fees
-	tx.Amount = payload.Data.Amount
-	tx.Currency = payload.Data.Currency
+	tx.SetMetaData(event.Metadata)
+	tx.Charges = fees
+	tx.Amount = amount
+	tx.Currency = amount.Currency
 
 	// check if imcoming transaction status is successful
-	switch payload.Data.Status {
-	case "success":
-		// previous balance
-		previousBal, err := c.GetLastBalanceByUserID(ctx, tx.UserID)
+	switch event.Status {
+	case model.TransactionStatusSuccessful:
+		tx.Status = model.TransactionStatusSuccessful
+
+		// get the wallet of the transaction's currency and hold its row lock until the end of the database transaction
+		wallet, err := c.openWallet(ctx, user, currency)
 		if err != nil {
-			c.logger.Err(err).Msgf("error getting user last balance ===> %v", err)
+			c.logger.Err(err).Msgf("error getting wallet by userID ===> %v", err)
 			return err
 		}
 
-		// create new balance
-		balance := model.Balance{
-			ID:              uuid.New(),
-			UserID:          tx.UserID,
-			TransactionType: model.TransactionTypeCredit,
-			TransactionID:   tx.ID,
-			BalanceBefore:   previousBal.BalanceAfter,
-			BalanceAfter:    previousBal.BalanceAfter + payload.Data.Amount,
+		locked, err := c.lockWallets(ctx, wallet.ID)
+		if err != nil {
+			c.logger.Err(err).Msgf("error locking wallet ===> %v", err)
+			return err
 		}
+		wallet = locked[wallet.ID]
 
-		// if the transaction type is withdrawal
-		if txID[0] == "dbt" {
-			balance.TransactionType = model.DebitTransaction
-			balance.BalanceBefore = previousBal.BalanceBefore - payload.Data.Amount
-			balance.BalanceAfter = previousBal.BalanceAfter - payload.Data.Amount
+		// a credit to a wallet that does not allow credits is refused and the transaction stays pending, the provider's
+		// next delivery applies it once the wallet allows credits again. A debit already left the wallet as a hold when
+		// it was made, it is captured whatever the wallet's status
+		if tx.TransactionType == model.CreditTransaction {
+			if err := checkWalletCredit(wallet); err != nil {
+				c.logger.Err(err).Msgf("ProcessPaymentWebhook ===> credit of transaction %s refused", tx.ID)
+				return err
+			}
 		}
 
-		newBal, err := c.CreateBalance(ctx, balance)
-		if err != nil {
-			c.logger.Err(err).Msgf("error creating user balance ===> %v", err)
+		if wallet, err = c.ensureWalletLedgerAccount(ctx, user, wallet); err != nil {
+			c.logger.Err(err).Msgf("error creating wallet ledger account ===> %v", err)
 			return err
 		}
 
-		// get user wallet balance
-		wallet, err := c.GetWalletByUserID(ctx, tx.UserID)
-		if err != nil {
-			c.logger.Err(err).Msgf("error getting wallet by userID ===> %v", err)
+		// a successful debit takes the held funds off the book balance with the posting below
+		if err := c.settleHold(ctx, tx.ID, model.HoldStatusCaptured); err != nil {
+			c.logger.Err(err).Msgf("error capturing hold ===> %v", err)
 			return err
 		}
 
-		txType := model.CreditTransaction
-
-		// update wallet balance
-		wallet.BalanceBefore = wallet.BalanceAfter
-		wallet.BalanceAfter = wallet.BalanceAfter + payload.Data.Amount
-		wallet.TransactionID = &tx.ID
-		wallet.TransactionType = &txType
-		wallet.BalanceID = &newBal.ID
-
-		// if the transaction type is withdrawal
-		if txID[0] == "dbt" {
-			txType = model.DebitTransaction
-			wallet.TransactionType = &txType
-			wallet.BalanceBefore = previousBal.BalanceBefore - payload.Data.Amount
-			wallet.BalanceAfter = previousBal.BalanceAfter - payload.Data.Amount
+		// post the movement to the ledger, the ledger is the source of truth for the wallet balance
+		if _, err := c.postProviderTransaction(ctx, user.TenantID, tx, model.LedgerAccount{ID: *wallet.LedgerAccountID}, amount); err != nil {
+			c.logger.Err(err).Msgf("error posting transaction to the ledger ===> %v", err)
+			return err
 		}
 
-		if err := c.UpdateWalletByID(ctx, wallet); err != nil {
-			c.logger.Err(err).Msgf("error updating wallet by ID ===> %v", err)
+		if err := c.recordWalletMovement(ctx, wallet, tx); err != nil {
 			return err
 		}
 
@@ -128,7 +175,7 @@ func (c *Controller) ProcessPaymentWebhook(ctx context.Context, payload model.Pa
 			Messages:      "reveived a credit to wallet",
 		}
 
-		if txID[0] == "dbt" {
+		if prefix == "dbt" {
 			auditLog.Messages = "reveived a debit to wallet"
 		}
 
@@ -137,14 +184,20 @@ func (c *Controller) ProcessPaymentWebhook(ctx context.Context, payload model.Pa
 			return err
 		}
 
-		// save that key to redis for 7 days, it is safe to assume that webhooks would not keep on being sent for 7 straight days :D.
-		// And as such, safely delete the key + value after 7 days
-		if err := c.SetValue(ctx, fmt.Sprintf("%s_%s", txID[0], tx.ID.String()), string(model.TransactionStatusSuccessful), 7*24*time.Hour); err != nil {
+		// a credit with a split is divided between the split's recipients as soon as it is in the wallet
+		if err := c.runSplit(ctx, user, wallet, tx); err != nil {
+			c.logger.Err(err).Msgf("error running split ===> %v", err)
 			return err
 		}
-	case "failed":
+	case model.TransactionStatusFailed:
 		tx.Status = model.TransactionStatusFailed
 
+		// a failed debit never left the wallet, its held funds are available again
+		if err := c.settleHold(ctx, tx.ID, model.HoldStatusReleased); err != nil {
+			c.logger.Err(err).Msgf("error releasing hold ===> %v", err)
+			return err
+		}
+
 		// create a audit log
 		auditLog := model.AuditLog{
 			ID:            uuid.New(),
@@ -160,7 +213,7 @@ func (c *Controller) ProcessPaymentWebhook(ctx context.Context, payload model.Pa
 			c.logger.Err(err).Msgf("error creating audit log")
 			return err
 		}
-	case "pending":
+	case model.TransactionStatusPending:
 		tx.Status = model.TransactionStatusPending
 
 		// create a audit log
@@ -185,5 +238,139 @@ func (c *Controller) ProcessPaymentWebhook(ctx context.Context, payload model.Pa
 		return err
 	}
 
+	// a deposit a payer made towards an invoice is counted on the invoice with the credit
+	if err := c.settleInvoicePayment(ctx, user, tx); err != nil {
+		c.logger.Err(err).Msgf("error settling invoice payment ===> %v", err)
+		return err
+	}
+
 	return nil
 }
+
+// checkEventAmount checks the amount a provider reports against the transaction it settles. A debit was held for its
+// amount and fee when it was made, a transfer of any other amount is refused with ErrWebhookAmountMismatch. A payer
+// may pay another amount than the deposit was made for, the deposit is priced again for what was paid then, and
+// refused with ErrFeeExceedsAmount when its fee would take all of it
+func (c *Controller) checkEventAmount(ctx context.Context, tenantID uuid.UUID, tx *model.Transaction, amount model.Money) error {
+	if amount.Minor == tx.Amount.Minor {
+		return nil
+	}
+
+	if tx.TransactionType == model.DebitTransaction {
+		return fmt.Errorf("%w: webhook is for %s, transaction is for %s", ErrWebhookAmountMismatch, amount, tx.Amount)
+	}
+
+	quote, err := c.quoteFee(ctx, tenantID, model.FeeActionDeposit, amount)
+	if err != nil {
+		return err
+	}
+
+	if quote.Total.Minor >= amount.Minor {
+		return ErrFeeExceedsAmount
+	}
+
+	tx.SetFee(quote)
+	return nil
+}
+
+// reverseTransfer applies a transfer the provider reversed. A transfer still pending never left the wallet, its hold
+// is released and it fails. A settled transfer is given back to the wallet by a reversing credit transaction linked
+// to it, and is marked refunded. The fee of the transfer is not given back. It must be called within withTx
+func (c *Controller) reverseTransfer(ctx context.Context, tx model.Transaction, event model.PaymentEvent) error {
+	if tx.Status == model.TransactionStatusRefunded || tx.Status == model.TransactionStatusFailed || tx.Status == model.TransactionStatusCanceled {
+		// reversed by an earlier delivery, or never taken from the wallet
+		return nil
+	}
+
+	if err := checkReversalAmount(event.Amount, tx.Amount, ErrReversalExceedsAmount); err != nil {
+		return err
+	}
+
+	user, err := c.GetUserByID(ctx, tx.UserID)
+	if err != nil {
+		c.logger.Err(err).Msgf("GetUserByID ===> error getting user by ID %v", err)
+		return err
+	}
+
+	auditLog := model.AuditLog{
+		ID:            uuid.New(),
+		TransactionID: &tx.ID,
+		UserID:        &tx.UserID,
+		TenantID:      &user.TenantID,
+		Actor:         model.ActorSystem,
+		ActionDone:    model.ActionReversed,
+		Messages:      fmt.Sprintf("transfer of %s reversed by %s", event.Amount, event.Provider),
+	}
+
+	if tx.Status == model.TransactionStatusPending {
+		if err := c.settleHold(ctx, tx.ID, model.HoldStatusReleased); err != nil {
+			c.logger.Err(err).Msgf("error releasing hold ===> %v", err)
+			return err
+		}
+
+		tx.Status = model.TransactionStatusFailed
+		if err := c.UpdateTransactionByID(ctx, tx); err != nil {
+			c.logger.Err(err).Msgf("error updating transaction by ID ===> %v", err)
+			return err
+		}
+
+		auditLog.Messages = fmt.Sprintf("transfer of %s reversed by %s before it settled", event.Amount, event.Provider)
+		_, err := c.CreateAuditLog(ctx, auditLog)
+		return err
+	}
+
+	wallet, err := c.openWallet(ctx, user, event.Amount.Currency)
+	if err != nil {
+		c.logger.Err(err).Msgf("error getting wallet by userID ===> %v", err)
+		return err
+	}
+
+	locked, err := c.lockWallets(ctx, wallet.ID)
+	if err != nil {
+		c.logger.Err(err).Msgf("error locking wallet ===> %v", err)
+		return err
+	}
+	wallet = locked[wallet.ID]
+
+	if wallet, err = c.ensureWalletLedgerAccount(ctx, user, wallet); err != nil {
+		c.logger.Err(err).Msgf("error creating wallet ledger account ===> %v", err)
+		return err
+	}
+
+	// the funds are the wallet's own coming back, they are credited whatever the wallet's status
+	reversal := model.Transaction{
+		ID:                   uuid.New(),
+		UserID:               user.ID,
+		Amount:               event.Amount,
+		Charges:              model.ZeroMoney(event.Amount.Currency),
+		Currency:             event.Amount.Currency,
+		TransactionType:      model.CreditTransaction,
+		Status:               model.TransactionStatusSuccessful,
+		Provider:             tx.Provider,
+		TransactionFlow:      model.TransactionFlowReversal,
+		RelatedTransactionID: &tx.ID,
+	}
+
+	if _, err := c.CreateTransaction(ctx, reversal); err != nil {
+		c.logger.Err(err).Msgf("error creating reversal transaction ===> %v", err)
+		return err
+	}
+
+	if _, err := c.postProviderTransaction(ctx, user.TenantID, reversal, model.LedgerAccount{ID: *wallet.LedgerAccountID}, reversal.Amount); err != nil {
+		c.logger.Err(err).Msgf("error posting reversal to the ledger ===> %v", err)
+		return err
+	}
+
+	if err := c.recordWalletMovement(ctx, wallet, reversal); err != nil {
+		return err
+	}
+
+	tx.Status = model.TransactionStatusRefunded
+	if err := c.UpdateTransactionByID(ctx, tx); err != nil {
+		c.logger.Err(err).Msgf("error updating transaction by ID ===> %v", err)
+		return err
+	}
+
+	_, err = c.CreateAuditLog(ctx, auditLog)
+	return err
+}
@@ -0,0 +1,456 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"codematic/model"
+	"codematic/storage"
+	"codematic/thirdparty/payment"
+)
+
+// memoryKvStore is an in-memory redis.KvStore for tests
+type memoryKvStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (m *memoryKvStore) GetValue(_ context.Context, key string, result interface{}) error {
+	value, err := m.GetStringValue(context.Background(), key)
+	if err != nil {
+		return err
+	}
+
+	*result.(*string) = value
+	return nil
+}
+
+func (m *memoryKvStore) GetStringValue(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.values[key]
+	if !ok {
+		return "", goredis.Nil
+	}
+
+	return value, nil
+}
+
+func (m *memoryKvStore) SetValue(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (m *memoryKvStore) SetValueIfAbsent(_ context.Context, key string, value interface{}, _ time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.values[key]; ok {
+		return false, nil
+	}
+
+	m.values[key] = fmt.Sprint(value)
+	return true, nil
+}
+
+func (m *memoryKvStore) IncrementBy(_ context.Context, key string, value int64, _ time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total, _ := strconv.ParseInt(m.values[key], 10, 64)
+	total += value
+	m.values[key] = strconv.FormatInt(total, 10)
+	return total, nil
+}
+
+func (m *memoryKvStore) DeleteValue(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryKvStore) Connect() error {
+	return nil
+}
+
+// Test_ProcessPaymentWebhook_Concurrent fires parallel webhooks, each delivered twice, at one wallet and
+// checks that every credit lands exactly once. It needs a real postgres database for the row locks,
+// e.g. PG_TEST_DSN="host=localhost user=postgres password=postgres dbname=codematic_test sslmode=disable"
+func Test_ProcessPaymentWebhook_Concurrent(t *testing.T) {
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	s := storage.NewFromDB(db)
+	s.Logger = zerolog.Nop()
+	require.NoError(t, s.AutoMigrate())
+
+	c := &Controller{
+		logger: zerolog.Nop(),
+		redis:  &memoryKvStore{values: map[string]string{}},
+	}
+	c.bind(storage.NewRepositories(s))
+
+	ctx := context.Background()
+	tenant := model.Tenant{ID: uuid.New(), BusinessName: "Concurrency Ltd", Email: uuid.NewString() + "@tenant.test", Password: "secret"}
+	require.NoError(t, db.Create(&tenant).Error)
+
+	user := model.User{ID: uuid.New(), TenantID: tenant.ID, FirstName: "Ada", LastName: "Obi", Email: uuid.NewString() + "@user.test", Password: "secret"}
+	require.NoError(t, db.Create(&user).Error)
+
+	account, err := c.CreateUserWalletLedgerAccount(ctx, user, model.DefaultCurrency)
+	require.NoError(t, err)
+
+	wallet := model.Wallet{ID: uuid.New(), UserID: user.ID, Currency: model.DefaultCurrency, LedgerAccountID: &account.ID}
+	require.NoError(t, db.Create(&wallet).Error)
+
+	const webhooks = 10
+	amount := model.NewMoney(150000, model.DefaultCurrency)
+
+	payloads := make([]model.PaymentEvent, 0, webhooks)
+	for i := 0; i < webhooks; i++ {
+		tx, err := c.CreateTransaction(ctx, model.Transaction{
+			ID:              uuid.New(),
+			UserID:          user.ID,
+			Amount:          amount,
+			Currency:        amount.Currency,
+			Provider:        model.PaymentProviderFlutterwave,
+			TransactionType: model.TransactionTypeCredit,
+			Status:          model.TransactionStatusPending,
+			TransactionFlow: model.TransactionFlowRevenue,
+		})
+		require.NoError(t, err)
+
+		payloads = append(payloads, model.PaymentEvent{
+			Provider:  model.PaymentProviderFlutterwave,
+			Type:      "charge.completed",
+			Kind:      model.PaymentEventCharge,
+			Status:    model.TransactionStatusSuccessful,
+			Reference: fmt.Sprintf("crt_%s", tx.ID),
+			Amount:    amount,
+			Fees:      model.ZeroMoney(amount.Currency),
+		})
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*webhooks)
+	for _, payload := range payloads {
+		// every webhook is delivered twice, the duplicate must not credit the wallet again
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(payload model.PaymentEvent) {
+				defer wg.Done()
+				errs <- c.ProcessPaymentWebhook(ctx, payload)
+			}(payload)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	balance, err := c.GetLedgerAccountBalance(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.NewMoney(webhooks*amount.Minor, model.DefaultCurrency), balance)
+
+	got, err := c.GetWalletByUserID(ctx, user.ID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, balance, got.BalanceAfter)
+}
+
+// ruleFeeStorage is a storage.FeeDatabase that has one rule in use for every action
+type ruleFeeStorage struct {
+	storage.FeeDatabase
+	rule model.FeeRule
+}
+
+func (s ruleFeeStorage) GetActiveFeeRule(_ context.Context, _ uuid.UUID, _ model.FeeAction, _ string) (model.FeeRule, error) {
+	return s.rule, nil
+}
+
+func Test_CheckEventAmount(t *testing.T) {
+	c := &Controller{
+		logger: zerolog.Nop(),
+		feeStorage: ruleFeeStorage{rule: model.FeeRule{
+			ID:       uuid.New(),
+			Currency: model.DefaultCurrency,
+			Type:     model.FeeTypeFlat,
+			FlatFee:  model.NewMoney(10000, model.DefaultCurrency),
+		}},
+	}
+	ctx := context.Background()
+	amount := model.NewMoney(500000, model.DefaultCurrency)
+	fee := model.NewMoney(10000, model.DefaultCurrency)
+
+	// a transfer is held for its amount, the provider cannot settle another one
+	transfer := model.Transaction{TransactionType: model.DebitTransaction, Amount: amount, Fee: fee}
+	require.NoError(t, c.checkEventAmount(ctx, uuid.New(), &transfer, amount))
+	require.ErrorIs(t, c.checkEventAmount(ctx, uuid.New(), &transfer, model.NewMoney(500001, model.DefaultCurrency)), ErrWebhookAmountMismatch)
+	require.ErrorIs(t, c.checkEventAmount(ctx, uuid.New(), &transfer, model.NewMoney(100, model.DefaultCurrency)), ErrWebhookAmountMismatch)
+
+	// a deposit paid with another amount is priced again, one its fee takes all of is refused
+	deposit := model.Transaction{TransactionType: model.CreditTransaction, Amount: amount, Fee: model.NewMoney(25000, model.DefaultCurrency)}
+	require.NoError(t, c.checkEventAmount(ctx, uuid.New(), &deposit, model.NewMoney(300000, model.DefaultCurrency)))
+	require.Equal(t, fee, deposit.Fee)
+	require.NotNil(t, deposit.FeeRuleID)
+	require.ErrorIs(t, c.checkEventAmount(ctx, uuid.New(), &deposit, fee), ErrFeeExceedsAmount)
+	require.ErrorIs(t, c.checkEventAmount(ctx, uuid.New(), &deposit, model.NewMoney(5000, model.DefaultCurrency)), ErrFeeExceedsAmount)
+}
+
+func Test_LimitWebhookRejections(t *testing.T) {
+	t.Setenv("WEBHOOK_REJECTIONS_PER_MINUTE", "3")
+	c := &Controller{logger: zerolog.Nop(), redis: &memoryKvStore{values: map[string]string{}}}
+	ctx := context.Background()
+
+	// a source's rejections are recorded up to the limit of the minute, another source has its own
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.limitWebhookRejections(ctx, "192.0.2.1"))
+	}
+	require.ErrorIs(t, c.limitWebhookRejections(ctx, "192.0.2.1"), ErrWebhookRejectionsLimited)
+	require.NoError(t, c.limitWebhookRejections(ctx, "192.0.2.2"))
+}
+
+func Test_ReceiveWebhookEvent_ExactlyOnce(t *testing.T) {
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	s := storage.NewFromDB(db)
+	s.Logger = zerolog.Nop()
+	require.NoError(t, s.AutoMigrate())
+
+	c := &Controller{
+		logger: zerolog.Nop(),
+		redis:  &memoryKvStore{values: map[string]string{}},
+	}
+	c.bind(storage.NewRepositories(s))
+
+	ctx := context.Background()
+	tenant := model.Tenant{ID: uuid.New(), BusinessName: "Inbox Ltd", Email: uuid.NewString() + "@tenant.test", Password: "secret"}
+	require.NoError(t, db.Create(&tenant).Error)
+
+	user := model.User{ID: uuid.New(), TenantID: tenant.ID, FirstName: "Ada", LastName: "Obi", Email: uuid.NewString() + "@user.test", Password: "secret"}
+	require.NoError(t, db.Create(&user).Error)
+
+	account, err := c.CreateUserWalletLedgerAccount(ctx, user, model.DefaultCurrency)
+	require.NoError(t, err)
+
+	wallet := model.Wallet{ID: uuid.New(), UserID: user.ID, Currency: model.DefaultCurrency, LedgerAccountID: &account.ID}
+	require.NoError(t, db.Create(&wallet).Error)
+
+	amount := model.NewMoney(150000, model.DefaultCurrency)
+	tx, err := c.CreateTransaction(ctx, model.Transaction{
+		ID:              uuid.New(),
+		UserID:          user.ID,
+		Amount:          amount,
+		Currency:        amount.Currency,
+		Provider:        model.PaymentProviderFlutterwave,
+		TransactionType: model.TransactionTypeCredit,
+		Status:          model.TransactionStatusPending,
+		TransactionFlow: model.TransactionFlowRevenue,
+	})
+	require.NoError(t, err)
+
+	body := fmt.Sprintf(`{"event":"charge.completed","data":{"id":%d,"tx_ref":"crt_%s","amount":%s,"currency":%q,"app_fee":0,"status":"successful"}}`,
+		time.Now().UnixNano(), tx.ID, amount.Decimal(), amount.Currency)
+	event := model.WebhookEvent{Provider: model.PaymentProviderFlutterwave, Body: body}
+
+	// the provider delivers the event several times at once, it is stored and applied once
+	const deliveries = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, deliveries)
+	for i := 0; i < deliveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.ReceiveWebhookEvent(ctx, event)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	stored, err := c.webhookEventStorage.GetWebhookEventByProviderEventID(ctx, event.Provider, payment.WebhookEventID(event.Provider, []byte(body)))
+	require.NoError(t, err)
+	require.Equal(t, model.WebhookEventProcessed, stored.Status)
+	require.Equal(t, 1, stored.Attempts)
+	require.NotNil(t, stored.ProcessedAt)
+
+	_, err = c.ReplayWebhookEvent(ctx, stored.ID)
+	require.ErrorIs(t, err, ErrWebhookEventProcessed)
+
+	balance, err := c.GetLedgerAccountBalance(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, amount, balance)
+}
+
+func Test_ProcessPaymentWebhook_Reversal(t *testing.T) {
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	s := storage.NewFromDB(db)
+	s.Logger = zerolog.Nop()
+	require.NoError(t, s.AutoMigrate())
+
+	c := &Controller{
+		logger: zerolog.Nop(),
+		redis:  &memoryKvStore{values: map[string]string{}},
+	}
+	c.bind(storage.NewRepositories(s))
+
+	ctx := context.Background()
+	tenant := model.Tenant{ID: uuid.New(), BusinessName: "Reversal Ltd", Email: uuid.NewString() + "@tenant.test", Password: "secret"}
+	require.NoError(t, db.Create(&tenant).Error)
+
+	user := model.User{ID: uuid.New(), TenantID: tenant.ID, FirstName: "Ada", LastName: "Obi", Email: uuid.NewString() + "@user.test", Password: "secret"}
+	require.NoError(t, db.Create(&user).Error)
+
+	account, err := c.CreateUserWalletLedgerAccount(ctx, user, model.DefaultCurrency)
+	require.NoError(t, err)
+
+	wallet := model.Wallet{ID: uuid.New(), UserID: user.ID, Currency: model.DefaultCurrency, LedgerAccountID: &account.ID}
+	require.NoError(t, db.Create(&wallet).Error)
+
+	transaction := func(transactionType model.TransactionType, amount model.Money) model.Transaction {
+		tx, err := c.CreateTransaction(ctx, model.Transaction{
+			ID:              uuid.New(),
+			UserID:          user.ID,
+			Amount:          amount,
+			Currency:        amount.Currency,
+			Provider:        model.PaymentProviderPaystack,
+			TransactionType: transactionType,
+			Status:          model.TransactionStatusPending,
+			TransactionFlow: model.TransactionFlowRevenue,
+		})
+		require.NoError(t, err)
+		return tx
+	}
+
+	event := func(kind model.PaymentEventKind, status model.TransactionStatus, reference string, amount model.Money) model.PaymentEvent {
+		return model.PaymentEvent{
+			Provider:  model.PaymentProviderPaystack,
+			Kind:      kind,
+			Status:    status,
+			Reference: reference,
+			Amount:    amount,
+			Fees:      model.ZeroMoney(amount.Currency),
+		}
+	}
+
+	balance := func() model.Money {
+		balance, err := c.GetLedgerAccountBalance(ctx, account.ID)
+		require.NoError(t, err)
+		return balance
+	}
+
+	deposit := transaction(model.TransactionTypeCredit, model.NewMoney(150000, model.DefaultCurrency))
+	require.NoError(t, c.ProcessPaymentWebhook(ctx, event(model.PaymentEventCharge, model.TransactionStatusSuccessful, "crt_"+deposit.ID.String(), deposit.Amount)))
+
+	amount := model.NewMoney(50000, model.DefaultCurrency)
+	withdrawal := transaction(model.TransactionTypeDebit, amount)
+	reference := "dbt_" + withdrawal.ID.String()
+	require.NoError(t, c.ProcessPaymentWebhook(ctx, event(model.PaymentEventTransfer, model.TransactionStatusSuccessful, reference, amount)))
+	require.Equal(t, model.NewMoney(100000, model.DefaultCurrency), balance())
+
+	// a reversal of more than the transfer is refused, a reversal of the transfer gives it back once
+	require.ErrorIs(t, c.ProcessPaymentWebhook(ctx, event(model.PaymentEventTransfer, model.TransactionStatusRefunded, reference, model.NewMoney(60000, model.DefaultCurrency))), ErrReversalExceedsAmount)
+	require.NoError(t, c.ProcessPaymentWebhook(ctx, event(model.PaymentEventTransfer, model.TransactionStatusRefunded, reference, amount)))
+	require.NoError(t, c.ProcessPaymentWebhook(ctx, event(model.PaymentEventTransfer, model.TransactionStatusRefunded, reference, amount)))
+	require.Equal(t, model.NewMoney(150000, model.DefaultCurrency), balance())
+
+	reversed, err := c.GetTransactionByID(ctx, withdrawal.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.TransactionStatusRefunded, reversed.Status)
+
+	got, err := c.GetWalletByUserID(ctx, user.ID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, balance(), got.BalanceAfter)
+
+	// the credit giving the transfer back was never collected by the provider, it cannot be refunded or disputed
+	var credit model.Transaction
+	require.NoError(t, db.Where("related_transaction_id = ? AND transaction_flow = ?", withdrawal.ID, model.TransactionFlowReversal).First(&credit).Error)
+	require.Equal(t, model.PaymentProviderPaystack, credit.Provider)
+
+	_, err = c.RefundTransaction(ctx, tenant.ID, credit.ID, nil, "reversed transfer", uuid.NewString())
+	require.ErrorIs(t, err, ErrTransactionNotRefundable)
+
+	_, err = c.OpenDispute(ctx, model.ActorTenant, tenant.ID, credit.ID, nil, "reversed transfer")
+	require.ErrorIs(t, err, ErrTransactionNotDisputable)
+
+	// a charge can only credit a wallet
+	require.ErrorIs(t, c.ProcessPaymentWebhook(ctx, event(model.PaymentEventCharge, model.TransactionStatusSuccessful, reference, amount)), MismatchedTransactionType)
+}
+
+func Test_ProcessPaymentWebhook_ProviderMismatch(t *testing.T) {
+	c, db := testController(t)
+	ctx := context.Background()
+
+	_, user := testUser(t, c, db)
+	amount := model.NewMoney(500000, model.DefaultCurrency)
+
+	deposit, err := c.CreateTransaction(ctx, model.Transaction{
+		ID:              uuid.New(),
+		UserID:          user.ID,
+		Amount:          amount,
+		Charges:         model.ZeroMoney(amount.Currency),
+		Currency:        amount.Currency,
+		Provider:        model.PaymentProviderFlutterwave,
+		TransactionType: model.TransactionTypeCredit,
+		Status:          model.TransactionStatusPending,
+		TransactionFlow: model.TransactionFlowRevenue,
+	})
+	require.NoError(t, err)
+
+	// another provider's webhook carrying the reference settles nothing
+	err = c.ProcessPaymentWebhook(ctx, model.PaymentEvent{
+		Provider:  model.PaymentProviderPaystack,
+		Kind:      model.PaymentEventCharge,
+		Status:    model.TransactionStatusSuccessful,
+		Reference: "crt_" + deposit.ID.String(),
+		Amount:    amount,
+		Fees:      model.ZeroMoney(amount.Currency),
+	})
+	require.ErrorIs(t, err, ErrWebhookProviderMismatch)
+
+	deposit, err = c.GetTransactionByID(ctx, deposit.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.TransactionStatusPending, deposit.Status)
+}
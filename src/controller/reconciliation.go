@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/thirdparty/payment"
+)
+
+// ReconcileSettlementFile matches the transactions of a provider's settlement file against ours by reference and
+// saves the run with every discrepancy it found. Records we have no transaction of that provider for are missing on
+// our side, successful transactions of the provider created within the period the file covers that it does not list
+// are missing on the provider, matching records that differ in amount or status are mismatches. A file that cannot
+// be read is saved as a failed run, the error wraps ErrInvalidSettlementFile then
+func (c *Controller) ReconcileSettlementFile(ctx context.Context, provider model.PaymentProvider, fileName string, r io.Reader) (model.ReconciliationRun, error) {
+	run := model.ReconciliationRun{
+		ID:       uuid.New(),
+		Provider: provider,
+		FileName: fileName,
+		Status:   model.ReconciliationRunCompleted,
+	}
+
+	records, err := payment.ParseSettlementFile(provider, fileName, r)
+	if err != nil {
+		c.logger.Err(err).Msgf("ReconcileSettlementFile ::: ParseSettlementFile ===> %v", err)
+
+		finishedAt := time.Now()
+		run.Status = model.ReconciliationRunFailed
+		run.Error = err.Error()
+		run.FinishedAt = &finishedAt
+		if _, createErr := c.reconciliationStorage.CreateReconciliationRun(ctx, run); createErr != nil {
+			return model.ReconciliationRun{}, createErr
+		}
+
+		return run, fmt.Errorf("%w: %v", ErrInvalidSettlementFile, err)
+	}
+
+	ids := make([]uuid.UUID, 0, len(records))
+	for _, record := range records {
+		if id, ok := transactionIDFromReference(record.Reference); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	found, err := c.transactionStorage.GetTransactionsByIDs(ctx, ids)
+	if err != nil {
+		return model.ReconciliationRun{}, err
+	}
+
+	run.PeriodStart, run.PeriodEnd = settlementPeriod(records)
+
+	var expected []model.Transaction
+	if run.PeriodStart != nil {
+		expected, err = c.transactionStorage.GetProviderTransactions(ctx, provider, model.TransactionStatusSuccessful, *run.PeriodStart, *run.PeriodEnd)
+		if err != nil {
+			return model.ReconciliationRun{}, err
+		}
+	}
+
+	matched, items := reconcile(provider, records, found, expected)
+
+	run.Records = len(records)
+	run.Matched = matched
+	for i := range items {
+		items[i].ID = uuid.New()
+		items[i].RunID = run.ID
+		run.Count(items[i])
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+
+	err = c.withTx(ctx, func(tc *Controller) error {
+		if _, err := tc.reconciliationStorage.CreateReconciliationRun(ctx, run); err != nil {
+			return err
+		}
+
+		return tc.reconciliationStorage.CreateReconciliationItems(ctx, items)
+	})
+	if err != nil {
+		return model.ReconciliationRun{}, err
+	}
+
+	return run, nil
+}
+
+// GetReconciliationRun returns a reconciliation run with its totals
+func (c *Controller) GetReconciliationRun(ctx context.Context, runID uuid.UUID) (model.ReconciliationRun, error) {
+	run, err := c.reconciliationStorage.GetReconciliationRunByID(ctx, runID)
+	if err != nil {
+		return model.ReconciliationRun{}, ErrRecordNotFound
+	}
+
+	return run, nil
+}
+
+// GetReconciliationRuns returns the reconciliation runs, optionally of one provider, newest first
+func (c *Controller) GetReconciliationRuns(ctx context.Context, provider *model.PaymentProvider, page pagination.Page) ([]model.ReconciliationRun, pagination.PageInfo, error) {
+	return c.reconciliationStorage.GetReconciliationRuns(ctx, provider, page)
+}
+
+// GetReconciliationItems returns the discrepancies a reconciliation run found, optionally of one kind
+func (c *Controller) GetReconciliationItems(ctx context.Context, runID uuid.UUID, kind *model.ReconciliationKind, page pagination.Page) ([]model.ReconciliationItem, pagination.PageInfo, error) {
+	if _, err := c.reconciliationStorage.GetReconciliationRunByID(ctx, runID); err != nil {
+		return nil, pagination.PageInfo{}, ErrRecordNotFound
+	}
+
+	return c.reconciliationStorage.GetReconciliationItems(ctx, runID, kind, page)
+}
+
+// reconcile matches the settlement records of the provider against the transactions they reference (found) and
+// the successful transactions of the provider expected in the file. It returns how many records matched and the
+// discrepancies, a record that differs in both amount and status gives one item of each kind
+func reconcile(provider model.PaymentProvider, records []model.SettlementRecord, found, expected []model.Transaction) (int, []model.ReconciliationItem) {
+	transactions := make(map[uuid.UUID]model.Transaction, len(found))
+	for _, transaction := range found {
+		transactions[transaction.ID] = transaction
+	}
+
+	var (
+		matched int
+		items   []model.ReconciliationItem
+	)
+	settled := make(map[uuid.UUID]bool, len(records))
+
+	for _, record := range records {
+		id, ok := transactionIDFromReference(record.Reference)
+		transaction, exists := transactions[id]
+		if !ok || !exists || transaction.Provider != provider {
+			items = append(items, model.ReconciliationItem{
+				Kind:              model.ReconciliationMissingOnOurSide,
+				ProviderReference: record.Reference,
+				ProviderAmount:    record.Amount,
+				ProviderStatus:    record.Status,
+			})
+			continue
+		}
+
+		settled[transaction.ID] = true
+		item := model.ReconciliationItem{
+			ProviderReference: record.Reference,
+			TransactionID:     &transaction.ID,
+			ProviderAmount:    record.Amount,
+			OurAmount:         transaction.Amount,
+			ProviderStatus:    record.Status,
+			OurStatus:         transaction.Status,
+		}
+
+		if record.Amount != transaction.Amount {
+			item.Kind = model.ReconciliationAmountMismatch
+			items = append(items, item)
+		}
+
+		if record.Status != transaction.Status {
+			item.Kind = model.ReconciliationStatusMismatch
+			items = append(items, item)
+		}
+
+		if record.Amount == transaction.Amount && record.Status == transaction.Status {
+			matched++
+		}
+	}
+
+	for _, transaction := range expected {
+		// refunds and lost disputes are settled against the deposit they reverse, they have no reference of their own
+		if settled[transaction.ID] || transaction.TransactionFlow == model.TransactionFlowRefund || transaction.TransactionFlow == model.TransactionFlowDispute {
+			continue
+		}
+
+		transactionID := transaction.ID
+		items = append(items, model.ReconciliationItem{
+			Kind:          model.ReconciliationMissingOnProvider,
+			TransactionID: &transactionID,
+			OurAmount:     transaction.Amount,
+			OurStatus:     transaction.Status,
+		})
+	}
+
+	return matched, items
+}
+
+// settlementPeriod returns the first and last settlement time of the records, nil when none has one
+func settlementPeriod(records []model.SettlementRecord) (*time.Time, *time.Time) {
+	var start, end *time.Time
+
+	for i := range records {
+		settledAt := records[i].SettledAt
+		if settledAt.IsZero() {
+			continue
+		}
+
+		if start == nil || settledAt.Before(*start) {
+			start = &records[i].SettledAt
+		}
+
+		if end == nil || settledAt.After(*end) {
+			end = &records[i].SettledAt
+		}
+	}
+
+	return start, end
+}
+
+// transactionIDFromReference returns the ID of the transaction a provider reference points at, references are
+// crt_<id> or dbt_<id> like in the payment webhooks, or the bare ID
+func transactionIDFromReference(reference string) (uuid.UUID, bool) {
+	if i := strings.LastIndex(reference, "_"); i >= 0 {
+		reference = reference[i+1:]
+	}
+
+	id, err := uuid.Parse(reference)
+	return id, err == nil
+}
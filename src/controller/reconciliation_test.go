@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+	"codematic/thirdparty/payment"
+)
+
+func Test_Reconcile(t *testing.T) {
+	transaction := func(amount int64, status model.TransactionStatus, flow model.TransactionFlow) model.Transaction {
+		return model.Transaction{
+			ID:              uuid.New(),
+			Amount:          model.NewMoney(amount, "NGN"),
+			Provider:        model.PaymentProviderPaystack,
+			Status:          status,
+			TransactionFlow: flow,
+		}
+	}
+
+	matching := transaction(500000, model.TransactionStatusSuccessful, model.TransactionFlowRevenue)
+	shortPaid := transaction(500000, model.TransactionStatusSuccessful, model.TransactionFlowRevenue)
+	stillPending := transaction(250000, model.TransactionStatusPending, model.TransactionFlowWithdrawal)
+	unsettled := transaction(100000, model.TransactionStatusSuccessful, model.TransactionFlowRevenue)
+	refund := transaction(100000, model.TransactionStatusSuccessful, model.TransactionFlowRefund)
+	otherProvider := transaction(300000, model.TransactionStatusSuccessful, model.TransactionFlowRevenue)
+	otherProvider.Provider = model.PaymentProviderFlutterwave
+
+	records := []model.SettlementRecord{
+		{Reference: "crt_" + matching.ID.String(), Amount: model.NewMoney(500000, "NGN"), Status: model.TransactionStatusSuccessful},
+		{Reference: "crt_" + shortPaid.ID.String(), Amount: model.NewMoney(490000, "NGN"), Status: model.TransactionStatusSuccessful},
+		{Reference: "dbt_" + stillPending.ID.String(), Amount: model.NewMoney(250000, "NGN"), Status: model.TransactionStatusSuccessful},
+		{Reference: "crt_" + otherProvider.ID.String(), Amount: model.NewMoney(300000, "NGN"), Status: model.TransactionStatusSuccessful},
+		{Reference: "T123456789", Amount: model.NewMoney(700000, "NGN"), Status: model.TransactionStatusSuccessful},
+	}
+
+	found := []model.Transaction{matching, shortPaid, stillPending, otherProvider}
+	expected := []model.Transaction{matching, shortPaid, unsettled, refund}
+
+	matched, items := reconcile(model.PaymentProviderPaystack, records, found, expected)
+	require.Equal(t, 1, matched)
+
+	kinds := map[model.ReconciliationKind][]string{}
+	for _, item := range items {
+		reference := item.ProviderReference
+		if item.Kind == model.ReconciliationMissingOnProvider {
+			reference = item.TransactionID.String()
+		}
+		kinds[item.Kind] = append(kinds[item.Kind], reference)
+	}
+
+	// a transaction of another provider cannot be what this provider settled
+	require.Equal(t, []string{"crt_" + otherProvider.ID.String(), "T123456789"}, kinds[model.ReconciliationMissingOnOurSide])
+	require.Equal(t, []string{"crt_" + shortPaid.ID.String()}, kinds[model.ReconciliationAmountMismatch])
+	require.Equal(t, []string{"dbt_" + stillPending.ID.String()}, kinds[model.ReconciliationStatusMismatch])
+	// refunds are settled under the deposit they reverse, they are never missing on their own
+	require.Equal(t, []string{unsettled.ID.String()}, kinds[model.ReconciliationMissingOnProvider])
+}
+
+func Test_ParseSettlementFile(t *testing.T) {
+	id := uuid.New()
+
+	paystackCSV := "reference,amount,currency,status,paid_at\n" +
+		"crt_" + id.String() + ",500025,NGN,success,2024-05-01T10:00:00Z\n"
+	flutterwaveJSON := `{"data":[{"tx_ref":"crt_` + id.String() + `","amount":5000.25,"currency":"NGN","status":"successful","created_at":"2024-05-01T10:00:00Z"}]}`
+
+	for provider, file := range map[model.PaymentProvider][2]string{
+		model.PaymentProviderPaystack:    {"settlement.csv", paystackCSV},
+		model.PaymentProviderFlutterwave: {"settlement.json", flutterwaveJSON},
+	} {
+		records, err := payment.ParseSettlementFile(provider, file[0], strings.NewReader(file[1]))
+		require.NoError(t, err, provider)
+		require.Len(t, records, 1, provider)
+
+		// both providers report the same transaction in their own units and statuses
+		require.Equal(t, model.SettlementRecord{
+			Reference: "crt_" + id.String(),
+			Amount:    model.NewMoney(500025, "NGN"),
+			Status:    model.TransactionStatusSuccessful,
+			SettledAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		}, records[0], provider)
+	}
+
+	_, err := payment.ParseSettlementFile(model.PaymentProviderPaystack, "settlement.xlsx", strings.NewReader(""))
+	require.ErrorIs(t, err, payment.ErrUnsupportedSettlementFile)
+
+	_, err = payment.ParseSettlementFile(model.PaymentProviderPaystack, "settlement.csv", strings.NewReader("reference,amount,status\nabc,12.5,success\n"))
+	require.Error(t, err)
+}
@@ -0,0 +1,423 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/storage"
+)
+
+const (
+	// stuckRefundAfter is how long a refund stays pending before RetryStuckRefunds sends it to the provider again
+	stuckRefundAfter = 10 * time.Minute
+	// stuckRefundsBatch is how many stuck refunds RetryStuckRefunds retries per run
+	stuckRefundsBatch = 100
+)
+
+// RefundTransaction refunds all or part of a successful deposit of one of the tenant's users through the provider
+// that collected it, up to what the deposit credited to the wallet: the tenant's fee on it is not refunded. A nil
+// amount refunds whatever is left to refund. The refund is carried out by a reversing debit transaction linked to the
+// deposit: its amount is held on the wallet while the provider is called, and leaves the wallet when the provider
+// accepts the refund. The deposit is marked refunded once it has been refunded in full.
+// The reference makes the call idempotent, asking again with a reference already used returns the earlier refund
+func (c *Controller) RefundTransaction(ctx context.Context, tenantID, transactionID uuid.UUID, amount *model.Money, reason, reference string) (model.Refund, error) {
+	if refund, ok, err := c.refundByReference(ctx, tenantID, transactionID, amount, reference); ok || err != nil {
+		return refund, err
+	}
+
+	refund, original, err := c.createRefund(ctx, tenantID, transactionID, amount, reason, reference)
+	if err != nil {
+		// a concurrent request with the same reference may have created the refund first
+		if refund, ok, lookupErr := c.refundByReference(ctx, tenantID, transactionID, amount, reference); ok || lookupErr != nil {
+			return refund, lookupErr
+		}
+
+		return model.Refund{}, err
+	}
+
+	return c.sendRefund(ctx, refund, original)
+}
+
+// RetryStuckRefunds sends the refunds that are still pending a while after they were created to their provider again
+// and returns how many it settled. A refund is pending between its creation and the provider's answer, it is only
+// left pending when the process stopped in between, and would otherwise hold its amount on the wallet for good. It is
+// sent with the same idempotency key, a refund the provider paid before the process stopped is not paid again.
+// Refunds another worker is retrying are left to it
+func (c *Controller) RetryStuckRefunds(ctx context.Context) (int, error) {
+	before := time.Now().Add(-stuckRefundAfter)
+	refunds, err := c.refundStorage.GetStuckRefunds(ctx, before, stuckRefundsBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, refund := range refunds {
+		claimed, err := c.refundStorage.ClaimStuckRefund(ctx, refund.ID, before)
+		if err != nil || !claimed {
+			continue
+		}
+
+		original, err := c.GetTransactionByID(ctx, refund.TransactionID)
+		if err != nil {
+			c.logger.Err(err).Msgf("RetryStuckRefunds ::: refund %s ===> %v", refund.ID, err)
+			continue
+		}
+
+		// a refund the provider rejects is settled as failed, only one that could not be settled stays pending
+		if _, err := c.sendRefund(ctx, refund, original); err != nil {
+			c.logger.Err(err).Msgf("RetryStuckRefunds ::: refund %s ===> %v", refund.ID, err)
+		}
+
+		if refund, err = c.refundStorage.GetRefundByReference(ctx, refund.TenantID, refund.Reference); err == nil && refund.Status != model.RefundStatusPending {
+			settled++
+		}
+	}
+
+	return settled, nil
+}
+
+// createRefund creates the pending refund of the amount of one of the tenant's deposits with its reversing debit
+// transaction and the hold of its amount, and returns it with the deposit
+func (c *Controller) createRefund(ctx context.Context, tenantID, transactionID uuid.UUID, amount *model.Money, reason, reference string) (model.Refund, model.Transaction, error) {
+	var (
+		refund   model.Refund
+		original model.Transaction
+	)
+
+	err := c.withTx(ctx, func(tc *Controller) error {
+		var err error
+
+		// lock the deposit, concurrent refunds of it are made one after the other so they cannot exceed it together
+		original, err = tc.transactionStorage.GetTransactionByIDForUpdate(ctx, transactionID)
+		if err != nil {
+			return ErrRecordNotFound
+		}
+
+		user, err := tc.GetUserByID(ctx, original.UserID)
+		if err != nil {
+			return err
+		}
+
+		// a tenant only ever sees the transactions of its own users
+		if user.TenantID != tenantID {
+			return ErrRecordNotFound
+		}
+
+		if !original.IsProviderCollection() {
+			return ErrTransactionNotRefundable
+		}
+
+		remaining, err := tc.reversibleAmount(ctx, original)
+		if err != nil {
+			return err
+		}
+
+		refundAmount := remaining
+		if amount != nil {
+			refundAmount = *amount
+		}
+
+		if err := checkReversalAmount(refundAmount, remaining, ErrRefundExceedsAmount); err != nil {
+			return err
+		}
+
+		wallet, err := tc.walletForDebit(ctx, user.ID, refundAmount.Currency)
+		if err != nil {
+			return err
+		}
+
+		// a refund sends money out of the wallet like a transfer, a wallet frozen or blocked for debits cannot make one
+		if err := checkWalletDebit(wallet); err != nil {
+			return err
+		}
+
+		transaction := model.Transaction{
+			ID:                   uuid.New(),
+			UserID:               user.ID,
+			Amount:               refundAmount,
+			Charges:              model.ZeroMoney(refundAmount.Currency),
+			Currency:             refundAmount.Currency,
+			TransactionType:      model.DebitTransaction,
+			Status:               model.TransactionStatusPending,
+			Provider:             original.Provider,
+			TransactionFlow:      model.TransactionFlowRefund,
+			RelatedTransactionID: &original.ID,
+		}
+
+		if _, err := tc.CreateTransaction(ctx, transaction); err != nil {
+			tc.logger.Err(err).Msgf("createRefund ::: CreateTransaction ===> %v", err)
+			return err
+		}
+
+		// the refunded funds may have been spent already, the hold fails with insufficient funds then
+		if _, err := tc.placeHold(ctx, wallet, transaction); err != nil {
+			tc.logger.Err(err).Msgf("createRefund ::: placeHold ===> %v", err)
+			return err
+		}
+
+		refund, err = tc.refundStorage.CreateRefund(ctx, model.Refund{
+			ID:                  uuid.New(),
+			TenantID:            tenantID,
+			Reference:           reference,
+			UserID:              user.ID,
+			TransactionID:       original.ID,
+			RefundTransactionID: transaction.ID,
+			Amount:              refundAmount,
+			Reason:              reason,
+			Status:              model.RefundStatusPending,
+		})
+		if err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:            uuid.New(),
+			TenantID:      &tenantID,
+			TransactionID: &original.ID,
+			UserID:        &user.ID,
+			Actor:         model.ActorTenant,
+			ActionDone:    model.ActionCreated,
+			Messages:      fmt.Sprintf("refund of %s created: %s", refundAmount, reason),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+	if err != nil {
+		return model.Refund{}, model.Transaction{}, err
+	}
+
+	return refund, original, nil
+}
+
+// sendRefund asks the provider that collected the deposit for the pending refund and settles it with the answer. The
+// refund is its own idempotency key with the provider: the partial refunds of a deposit all refer to the deposit, but
+// sending one of them again, e.g. when RetryStuckRefunds retries it, is the same refund and is not paid twice
+func (c *Controller) sendRefund(ctx context.Context, refund model.Refund, original model.Transaction) (model.Refund, error) {
+	payload := model.InitiateTransaction{
+		Reference:      original.ID.String(),
+		IdempotencyKey: refund.ID.String(),
+		Amount:         refund.Amount,
+	}
+
+	if _, err := c.paymentService.InitiateTransaction(original.Provider, model.PaymentActionRefund, payload); err != nil {
+		c.logger.Err(err).Msgf("sendRefund ::: InitiateTransaction ===> %v", err)
+
+		// the provider never took the refund, give the held funds back
+		if failErr := c.settleRefund(ctx, refund, model.RefundStatusFailed); failErr != nil {
+			c.logger.Err(failErr).Msgf("sendRefund ::: settleRefund ===> %v", failErr)
+		}
+
+		return model.Refund{}, fmt.Errorf("%w: %v", ErrProviderCallFailed, err)
+	}
+
+	if err := c.settleRefund(ctx, refund, model.RefundStatusSucceeded); err != nil {
+		c.logger.Err(err).Msgf("sendRefund ::: settleRefund ===> %v", err)
+		return model.Refund{}, fmt.Errorf("%w: %v", ErrProviderCallFailed, err)
+	}
+
+	refund.Status = model.RefundStatusSucceeded
+	return refund, nil
+}
+
+// GetRefundsByTransactionID returns the refunds of one of the tenant's transactions
+func (c *Controller) GetRefundsByTransactionID(ctx context.Context, tenantID, transactionID uuid.UUID) ([]model.Refund, error) {
+	transaction, err := c.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	user, err := c.GetUserByID(ctx, transaction.UserID)
+	if err != nil || user.TenantID != tenantID {
+		return nil, ErrRecordNotFound
+	}
+
+	return c.refundStorage.GetRefundsByTransactionID(ctx, transactionID)
+}
+
+// refundByReference looks up the tenant's refund with the reference. It reports false when there is none, and
+// fails when the reference was used for a refund of another transaction or of another amount
+func (c *Controller) refundByReference(ctx context.Context, tenantID, transactionID uuid.UUID, amount *model.Money, reference string) (model.Refund, bool, error) {
+	refund, err := c.refundStorage.GetRefundByReference(ctx, tenantID, reference)
+	if err == storage.ErrRecordNotFound {
+		return model.Refund{}, false, nil
+	}
+
+	if err != nil {
+		return model.Refund{}, false, err
+	}
+
+	if refund.TransactionID != transactionID || amount != nil && *amount != refund.Amount {
+		return model.Refund{}, false, ErrRefundReferenceReused
+	}
+
+	return refund, true, nil
+}
+
+// reversibleAmount is what is left of a deposit to refund or dispute: what the deposit credited to the wallet, its
+// amount less its fee, less its refunds that are pending or succeeded and its disputes that are undecided or lost. The
+// fee stays with the tenant, the wallet only ever gives back what it got
+func (c *Controller) reversibleAmount(ctx context.Context, original model.Transaction) (model.Money, error) {
+	credited, err := original.WalletMovement()
+	if err != nil {
+		return model.Money{}, err
+	}
+
+	refunded, err := c.refundStorage.GetRefundedTotal(ctx, original.ID, original.Amount.Currency, model.RefundStatusPending, model.RefundStatusSucceeded)
+	if err != nil {
+		return model.Money{}, err
+	}
+
+	remaining, err := credited.Sub(refunded)
+	if err != nil {
+		return model.Money{}, err
+	}
+
+	disputes, err := c.disputeStorage.GetDisputesByTransactionID(ctx, original.ID,
+		model.DisputeStatusOpened, model.DisputeStatusEvidenceSubmitted, model.DisputeStatusLost)
+	if err != nil {
+		return model.Money{}, err
+	}
+
+	for _, dispute := range disputes {
+		if remaining, err = remaining.Sub(dispute.Amount); err != nil {
+			return model.Money{}, err
+		}
+	}
+
+	return remaining, nil
+}
+
+// checkReversalAmount makes sure a refund or dispute is positive, in the currency of the transaction and within
+// what is left of it, exceeded is returned when it is larger
+func checkReversalAmount(amount, remaining model.Money, exceeded error) error {
+	if amount.Currency != remaining.Currency {
+		return model.ErrCurrencyMismatch
+	}
+
+	if !amount.IsPositive() {
+		if !remaining.IsPositive() {
+			return exceeded
+		}
+
+		return model.ErrInvalidAmount
+	}
+
+	left, err := remaining.Sub(amount)
+	if err != nil {
+		return err
+	}
+
+	if left.IsNegative() {
+		return fmt.Errorf("%w: %s left, requested %s", exceeded, remaining, amount)
+	}
+
+	return nil
+}
+
+// settleRefund applies the provider's answer to a pending refund. A succeeded refund captures its hold and moves
+// the funds from the wallet back to the provider's clearing account, a failed refund releases its hold
+func (c *Controller) settleRefund(ctx context.Context, refund model.Refund, status model.RefundStatus) error {
+	return c.withTx(ctx, func(tc *Controller) error {
+		original, err := tc.transactionStorage.GetTransactionByIDForUpdate(ctx, refund.TransactionID)
+		if err != nil {
+			return err
+		}
+
+		transaction, err := tc.transactionStorage.GetTransactionByIDForUpdate(ctx, refund.RefundTransactionID)
+		if err != nil {
+			return err
+		}
+
+		user, err := tc.GetUserByID(ctx, refund.UserID)
+		if err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:            uuid.New(),
+			TenantID:      &refund.TenantID,
+			TransactionID: &transaction.ID,
+			UserID:        &refund.UserID,
+			Actor:         model.ActorTenant,
+		}
+
+		if status == model.RefundStatusFailed {
+			if err := tc.settleHold(ctx, transaction.ID, model.HoldStatusReleased); err != nil {
+				return err
+			}
+
+			transaction.Status = model.TransactionStatusFailed
+			if err := tc.UpdateTransactionByID(ctx, transaction); err != nil {
+				return err
+			}
+
+			if err := tc.refundStorage.UpdateRefundStatus(ctx, refund.ID, status); err != nil {
+				return err
+			}
+
+			auditLog.ActionDone = model.ActionFailed
+			auditLog.Messages = fmt.Sprintf("refund of %s rejected by provider, hold released", refund.Amount)
+			_, err := tc.CreateAuditLog(ctx, auditLog)
+			return err
+		}
+
+		wallet, err := tc.walletForDebit(ctx, user.ID, refund.Amount.Currency)
+		if err != nil {
+			return err
+		}
+
+		if wallet, err = tc.ensureWalletLedgerAccount(ctx, user, wallet); err != nil {
+			return err
+		}
+
+		if err := tc.settleHold(ctx, transaction.ID, model.HoldStatusCaptured); err != nil {
+			return err
+		}
+
+		if _, err := tc.postProviderTransaction(ctx, refund.TenantID, transaction, model.LedgerAccount{ID: *wallet.LedgerAccountID}, refund.Amount); err != nil {
+			tc.logger.Err(err).Msgf("settleRefund ::: error posting refund to the ledger ===> %v", err)
+			return err
+		}
+
+		if err := tc.recordWalletMovement(ctx, wallet, transaction); err != nil {
+			return err
+		}
+
+		transaction.Status = model.TransactionStatusSuccessful
+		if err := tc.UpdateTransactionByID(ctx, transaction); err != nil {
+			return err
+		}
+
+		if err := tc.refundStorage.UpdateRefundStatus(ctx, refund.ID, status); err != nil {
+			return err
+		}
+
+		// the deposit is refunded once the refunds that went through add up to what it credited to the wallet
+		refunded, err := tc.refundStorage.GetRefundedTotal(ctx, original.ID, original.Amount.Currency, model.RefundStatusSucceeded)
+		if err != nil {
+			return err
+		}
+
+		credited, err := original.WalletMovement()
+		if err != nil {
+			return err
+		}
+
+		if refunded == credited {
+			original.Status = model.TransactionStatusRefunded
+			if err := tc.UpdateTransactionByID(ctx, original); err != nil {
+				return err
+			}
+		}
+
+		auditLog.ActionDone = model.ActionSuccess
+		auditLog.Messages = fmt.Sprintf("refund of %s sent back through %s", refund.Amount, transaction.Provider)
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+}
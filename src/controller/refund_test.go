@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+	"codematic/storage"
+)
+
+// reversalStubs stand in for the refunds and disputes a deposit already has
+type (
+	stubRefundStorage struct {
+		storage.RefundDatabase
+		refunded model.Money
+	}
+
+	stubDisputeStorage struct {
+		storage.DisputeDatabase
+		disputes []model.Dispute
+	}
+)
+
+func (s stubRefundStorage) GetRefundedTotal(_ context.Context, _ uuid.UUID, _ string, _ ...model.RefundStatus) (model.Money, error) {
+	return s.refunded, nil
+}
+
+func (s stubDisputeStorage) GetDisputesByTransactionID(_ context.Context, _ uuid.UUID, _ ...model.DisputeStatus) ([]model.Dispute, error) {
+	return s.disputes, nil
+}
+
+func Test_ReversibleAmount(t *testing.T) {
+	deposit := model.Transaction{
+		ID:              uuid.New(),
+		Amount:          model.NewMoney(500000, "NGN"),
+		Fee:             model.NewMoney(50000, "NGN"),
+		TransactionType: model.TransactionTypeCredit,
+	}
+	c := &Controller{
+		refundStorage:  stubRefundStorage{refunded: model.ZeroMoney("NGN")},
+		disputeStorage: stubDisputeStorage{},
+	}
+
+	// the fee the deposit was charged is not given back, only what it credited to the wallet
+	remaining, err := c.reversibleAmount(context.Background(), deposit)
+	require.NoError(t, err)
+	require.Equal(t, model.NewMoney(450000, "NGN"), remaining)
+
+	c.refundStorage = stubRefundStorage{refunded: model.NewMoney(100000, "NGN")}
+	c.disputeStorage = stubDisputeStorage{disputes: []model.Dispute{{Amount: model.NewMoney(50000, "NGN")}}}
+	remaining, err = c.reversibleAmount(context.Background(), deposit)
+	require.NoError(t, err)
+	require.Equal(t, model.NewMoney(300000, "NGN"), remaining)
+}
+
+func Test_CheckReversalAmount(t *testing.T) {
+	remaining := model.NewMoney(500000, "NGN")
+
+	require.NoError(t, checkReversalAmount(model.NewMoney(200000, "NGN"), remaining, ErrRefundExceedsAmount))
+	require.NoError(t, checkReversalAmount(remaining, remaining, ErrRefundExceedsAmount))
+
+	// refunds and disputes are capped at what is left of the transaction
+	require.ErrorIs(t, checkReversalAmount(model.NewMoney(500001, "NGN"), remaining, ErrRefundExceedsAmount), ErrRefundExceedsAmount)
+	require.ErrorIs(t, checkReversalAmount(model.ZeroMoney("NGN"), model.ZeroMoney("NGN"), ErrRefundExceedsAmount), ErrRefundExceedsAmount)
+
+	require.ErrorIs(t, checkReversalAmount(model.ZeroMoney("NGN"), remaining, ErrRefundExceedsAmount), model.ErrInvalidAmount)
+	require.ErrorIs(t, checkReversalAmount(model.NewMoney(100, "USD"), remaining, ErrRefundExceedsAmount), model.ErrCurrencyMismatch)
+}
+
+func Test_RefundTransaction_WalletStatus(t *testing.T) {
+	c, db := testController(t)
+	ctx := context.Background()
+
+	tenant, user := testUser(t, c, db)
+	deposit := testDeposit(t, c, user, model.NewMoney(500000, model.DefaultCurrency))
+	amount := model.NewMoney(100000, model.DefaultCurrency)
+
+	// a frozen wallet, or one blocked for debits, sends nothing out through a refund or a transfer
+	for _, status := range []model.WalletStatus{model.WalletStatusFrozen, model.WalletStatusPostNoDebit} {
+		_, err := c.SetWalletStatus(ctx, tenant.ID, user.ID, model.DefaultCurrency, status, "compliance review")
+		require.NoError(t, err)
+
+		_, err = c.RefundTransaction(ctx, tenant.ID, deposit.ID, &amount, "customer asked", uuid.NewString())
+		require.ErrorIs(t, err, ErrWalletDebitBlocked)
+
+		require.ErrorIs(t, c.Transfer(ctx, user.ID, "044", "0123456789", amount), ErrWalletDebitBlocked)
+	}
+
+	refunds, err := c.GetRefundsByTransactionID(ctx, tenant.ID, deposit.ID)
+	require.NoError(t, err)
+	require.Empty(t, refunds)
+
+	// once the wallet is active again the refund goes through
+	_, err = c.SetWalletStatus(ctx, tenant.ID, user.ID, model.DefaultCurrency, model.WalletStatusActive, "review cleared")
+	require.NoError(t, err)
+
+	refund, err := c.RefundTransaction(ctx, tenant.ID, deposit.ID, &amount, "customer asked", uuid.NewString())
+	require.NoError(t, err)
+	require.Equal(t, amount, refund.Amount)
+}
+
+func Test_RefundTransaction(t *testing.T) {
+	c, db := testController(t)
+	ctx := context.Background()
+
+	tenant, user := testUser(t, c, db)
+	deposit := testDeposit(t, c, user, model.NewMoney(500000, model.DefaultCurrency))
+	amount := model.NewMoney(200000, model.DefaultCurrency)
+	reference := uuid.NewString()
+
+	refund, err := c.RefundTransaction(ctx, tenant.ID, deposit.ID, &amount, "customer asked", reference)
+	require.NoError(t, err)
+	require.Equal(t, model.RefundStatusSucceeded, refund.Status)
+
+	// the refund's debit went through and its hold was captured
+	debit, err := c.GetTransactionByID(ctx, refund.RefundTransactionID)
+	require.NoError(t, err)
+	require.Equal(t, model.TransactionStatusSuccessful, debit.Status)
+	require.Equal(t, model.TransactionFlowRefund, debit.TransactionFlow)
+	require.Equal(t, deposit.ID, *debit.RelatedTransactionID)
+
+	var hold model.Hold
+	require.NoError(t, db.Where("transaction_id = ?", debit.ID).First(&hold).Error)
+	require.Equal(t, model.HoldStatusCaptured, hold.Status)
+
+	// asking again with the same reference returns the same refund, it is not refunded twice
+	again, err := c.RefundTransaction(ctx, tenant.ID, deposit.ID, &amount, "customer asked", reference)
+	require.NoError(t, err)
+	require.Equal(t, refund.ID, again.ID)
+
+	other := model.NewMoney(100000, model.DefaultCurrency)
+	_, err = c.RefundTransaction(ctx, tenant.ID, deposit.ID, &other, "customer asked", reference)
+	require.ErrorIs(t, err, ErrRefundReferenceReused)
+
+	// the rest of the deposit is refunded without an amount, after which nothing is left
+	rest, err := c.RefundTransaction(ctx, tenant.ID, deposit.ID, nil, "customer asked", uuid.NewString())
+	require.NoError(t, err)
+	require.Equal(t, model.NewMoney(300000, model.DefaultCurrency), rest.Amount)
+
+	_, err = c.RefundTransaction(ctx, tenant.ID, deposit.ID, nil, "customer asked", uuid.NewString())
+	require.ErrorIs(t, err, ErrRefundExceedsAmount)
+
+	deposit, err = c.GetTransactionByID(ctx, deposit.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.TransactionStatusRefunded, deposit.Status)
+
+	account, err := c.ledgerStorage.GetLedgerAccountByCode(ctx, model.UserWalletAccountCode(user.ID, model.DefaultCurrency))
+	require.NoError(t, err)
+	balance, err := c.ledgerStorage.GetLedgerAccountBalance(ctx, account.ID)
+	require.NoError(t, err)
+	require.True(t, balance.IsZero())
+
+	// another tenant cannot refund the deposit
+	otherTenant, _ := testUser(t, c, db)
+	_, err = c.RefundTransaction(ctx, otherTenant.ID, deposit.ID, nil, "customer asked", uuid.NewString())
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func Test_RetryStuckRefunds(t *testing.T) {
+	c, db := testController(t)
+	ctx := context.Background()
+
+	tenant, user := testUser(t, c, db)
+	deposit := testDeposit(t, c, user, model.NewMoney(500000, model.DefaultCurrency))
+	amount := model.NewMoney(200000, model.DefaultCurrency)
+
+	// the process stopped after the refund was created, before the provider was called
+	refund, _, err := c.createRefund(ctx, tenant.ID, deposit.ID, &amount, "customer asked", uuid.NewString())
+	require.NoError(t, err)
+
+	// a refund pending for less than a while may still be answered, it is left alone
+	settled, err := c.RetryStuckRefunds(ctx)
+	require.NoError(t, err)
+	require.Zero(t, settled)
+
+	require.NoError(t, db.Model(&model.Refund{}).Where("id = ?", refund.ID).
+		Update("created_at", time.Now().Add(-stuckRefundAfter-time.Minute)).Error)
+
+	settled, err = c.RetryStuckRefunds(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, settled)
+
+	refunds, err := c.GetRefundsByTransactionID(ctx, tenant.ID, deposit.ID)
+	require.NoError(t, err)
+	require.Len(t, refunds, 1)
+	require.Equal(t, model.RefundStatusSucceeded, refunds[0].Status)
+
+	var hold model.Hold
+	require.NoError(t, db.Where("transaction_id = ?", refund.RefundTransactionID).First(&hold).Error)
+	require.Equal(t, model.HoldStatusCaptured, hold.Status)
+
+	// a settled refund is not sent again
+	settled, err = c.RetryStuckRefunds(ctx)
+	require.NoError(t, err)
+	require.Zero(t, settled)
+}
+
+func Test_RefundTransaction_Fee(t *testing.T) {
+	c, db := testController(t)
+	ctx := context.Background()
+
+	tenant, user := testUser(t, c, db)
+	_, err := c.SetFeeRule(ctx, tenant.ID, model.FeeRule{
+		Action:             model.FeeActionDeposit,
+		Currency:           model.DefaultCurrency,
+		Type:               model.FeeTypePercentage,
+		PercentBasisPoints: 1000,
+	})
+	require.NoError(t, err)
+
+	// the deposit credited its amount less the 10% fee, that is all a full refund takes back from the wallet
+	deposit := testDeposit(t, c, user, model.NewMoney(500000, model.DefaultCurrency))
+	refund, err := c.RefundTransaction(ctx, tenant.ID, deposit.ID, nil, "customer asked", uuid.NewString())
+	require.NoError(t, err)
+	require.Equal(t, model.RefundStatusSucceeded, refund.Status)
+	require.Equal(t, model.NewMoney(450000, model.DefaultCurrency), refund.Amount)
+
+	deposit, err = c.GetTransactionByID(ctx, deposit.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.TransactionStatusRefunded, deposit.Status)
+
+	account, err := c.ledgerStorage.GetLedgerAccountByCode(ctx, model.UserWalletAccountCode(user.ID, model.DefaultCurrency))
+	require.NoError(t, err)
+	balance, err := c.ledgerStorage.GetLedgerAccountBalance(ctx, account.ID)
+	require.NoError(t, err)
+	require.True(t, balance.IsZero())
+}
@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+)
+
+// GetRevenueWallets returns the tenant's revenue wallets, one per currency it has earned fees in
+func (c *Controller) GetRevenueWallets(ctx context.Context, tenantID uuid.UUID) ([]model.RevenueWallet, error) {
+	accounts, err := c.ledgerStorage.GetLedgerAccountsByTenantID(ctx, tenantID, model.LedgerAccountTypeTenantFee)
+	if err != nil {
+		return nil, err
+	}
+
+	wallets := make([]model.RevenueWallet, 0, len(accounts))
+	for _, account := range accounts {
+		balance, err := c.ledgerStorage.GetLedgerAccountBalance(ctx, account.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		wallets = append(wallets, model.RevenueWallet{
+			TenantID:        tenantID,
+			LedgerAccountID: account.ID,
+			Currency:        account.Currency,
+			Balance:         balance,
+		})
+	}
+
+	return wallets, nil
+}
+
+// GetRevenueHistory returns the movements of the tenant's revenue wallet in the currency, newest first
+func (c *Controller) GetRevenueHistory(ctx context.Context, tenantID uuid.UUID, currency string, page pagination.Page) ([]model.RevenueEntry, pagination.PageInfo, error) {
+	account, err := c.ledgerStorage.GetLedgerAccountByCode(ctx, model.TenantFeeAccountCode(tenantID, strings.ToUpper(currency)))
+	if err != nil {
+		return nil, pagination.PageInfo{}, ErrNoWalletForCurrency
+	}
+
+	return c.revenueStorage.GetRevenueEntries(ctx, account.ID, page)
+}
+
+// GetRevenueDays returns what the tenant's revenue wallet in the currency earned and paid out on each day from the
+// first to the last day given, both included. Only the dates of from and to are used, the days are those of the
+// tenant's timezone. Days without movements are listed with zero amounts
+func (c *Controller) GetRevenueDays(ctx context.Context, tenantID uuid.UUID, currency string, from, to time.Time) ([]model.RevenueDay, error) {
+	tenant, err := c.tenantStorage.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	location := tenant.Location()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, location).AddDate(0, 0, 1)
+
+	if !end.After(start) || end.After(start.AddDate(0, 0, maxStatementDays)) {
+		return nil, ErrInvalidRevenuePeriod
+	}
+
+	account, err := c.ledgerStorage.GetLedgerAccountByCode(ctx, model.TenantFeeAccountCode(tenantID, strings.ToUpper(currency)))
+	if err != nil {
+		return nil, ErrNoWalletForCurrency
+	}
+
+	days, err := c.revenueStorage.GetRevenueDays(ctx, account.ID, start, end, location.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return fillRevenueDays(days, start, end, account.Currency), nil
+}
+
+// WithdrawRevenue pays out the amount from the tenant's revenue wallet in its currency to the tenant's settlement
+// account. The amount leaves the revenue wallet for the provider's clearing account before the provider is called, so
+// concurrent withdrawals cannot spend it twice, and comes back when the provider rejects the withdrawal
+func (c *Controller) WithdrawRevenue(ctx context.Context, tenantID uuid.UUID, bankNumber, accountNumber string, amount model.Money) (model.RevenueWithdrawal, error) {
+	withdrawal := model.RevenueWithdrawal{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		Amount:        amount,
+		BankNumber:    bankNumber,
+		AccountNumber: accountNumber,
+		Provider:      model.PaymentProviderFlutterwave,
+		Status:        model.RevenueWithdrawalStatusPending,
+	}
+
+	err := c.withTx(ctx, func(tc *Controller) error {
+		// lock the revenue wallet so concurrent withdrawals are made one after the other
+		account, err := tc.ledgerStorage.GetLedgerAccountByCodeForUpdate(ctx, model.TenantFeeAccountCode(tenantID, amount.Currency))
+		if err != nil {
+			return ErrNoWalletForCurrency
+		}
+
+		balance, err := tc.ledgerStorage.GetLedgerAccountBalance(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		remaining, err := balance.Sub(amount)
+		if err != nil {
+			return err
+		}
+
+		if remaining.IsNegative() {
+			return &InsufficientFundsError{Available: balance, Requested: amount}
+		}
+
+		clearing, err := tc.providerClearingLedgerAccount(ctx, withdrawal.Provider, amount.Currency)
+		if err != nil {
+			return err
+		}
+
+		entry, err := tc.ledgerStorage.PostJournalEntry(ctx, model.JournalEntry{
+			ID:          uuid.New(),
+			Description: model.RevenueWithdrawalEntryDescription,
+			Postings: []model.Posting{
+				{ID: uuid.New(), AccountID: account.ID, Amount: amount.Neg()},
+				{ID: uuid.New(), AccountID: clearing.ID, Amount: amount},
+			},
+		})
+		if err != nil {
+			tc.logger.Err(err).Msgf("WithdrawRevenue ::: unable to post withdrawal to the ledger ===> %v", err)
+			return err
+		}
+
+		withdrawal.LedgerAccountID = account.ID
+		withdrawal.JournalEntryID = entry.ID
+		if withdrawal, err = tc.revenueStorage.CreateRevenueWithdrawal(ctx, withdrawal); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &tenantID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionCreated,
+			Messages:   fmt.Sprintf("revenue withdrawal of %s to %s created", amount, accountNumber),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+	if err != nil {
+		return model.RevenueWithdrawal{}, err
+	}
+
+	payload := model.InitiateTransaction{
+		Reference:     withdrawal.ID.String(),
+		BankNumber:    bankNumber,
+		AccountNumber: accountNumber,
+		Amount:        amount,
+	}
+
+	if _, err := c.paymentService.InitiateTransaction(withdrawal.Provider, model.PaymentActionTransfer, payload); err != nil {
+		c.logger.Err(err).Msgf("WithdrawRevenue ::: InitiateTransaction ===> %v", err)
+
+		// the provider never took the withdrawal, give the amount back to the revenue wallet
+		withdrawal.Status = model.RevenueWithdrawalStatusFailed
+		withdrawal.FailureReason = err.Error()
+		if _, failErr := c.settleRevenueWithdrawal(ctx, withdrawal); failErr != nil {
+			c.logger.Err(failErr).Msgf("WithdrawRevenue ::: settleRevenueWithdrawal ===> %v", failErr)
+		}
+
+		return model.RevenueWithdrawal{}, fmt.Errorf("%w: %v", ErrProviderCallFailed, err)
+	}
+
+	withdrawal.Status = model.RevenueWithdrawalStatusSucceeded
+	withdrawal, err = c.settleRevenueWithdrawal(ctx, withdrawal)
+	if err != nil {
+		// the provider paid the withdrawal out, only saving its answer failed
+		return model.RevenueWithdrawal{}, fmt.Errorf("%w: %v", ErrProviderCallFailed, err)
+	}
+
+	return withdrawal, nil
+}
+
+// GetRevenueWithdrawals returns the tenant's revenue withdrawals, newest first
+func (c *Controller) GetRevenueWithdrawals(ctx context.Context, tenantID uuid.UUID, page pagination.Page) ([]model.RevenueWithdrawal, pagination.PageInfo, error) {
+	return c.revenueStorage.GetRevenueWithdrawals(ctx, tenantID, page)
+}
+
+// settleRevenueWithdrawal saves the provider's answer to a pending withdrawal. A failed withdrawal gets a reversing
+// entry giving its amount back to the revenue wallet
+func (c *Controller) settleRevenueWithdrawal(ctx context.Context, withdrawal model.RevenueWithdrawal) (model.RevenueWithdrawal, error) {
+	err := c.withTx(ctx, func(tc *Controller) error {
+		action, message := model.ActionSuccess, fmt.Sprintf("revenue withdrawal of %s paid out", withdrawal.Amount)
+
+		if withdrawal.Status == model.RevenueWithdrawalStatusFailed {
+			clearing, err := tc.providerClearingLedgerAccount(ctx, withdrawal.Provider, withdrawal.Amount.Currency)
+			if err != nil {
+				return err
+			}
+
+			reversal, err := tc.ledgerStorage.PostJournalEntry(ctx, model.JournalEntry{
+				ID:          uuid.New(),
+				Description: model.RevenueWithdrawalEntryDescription,
+				Postings: []model.Posting{
+					{ID: uuid.New(), AccountID: clearing.ID, Amount: withdrawal.Amount.Neg()},
+					{ID: uuid.New(), AccountID: withdrawal.LedgerAccountID, Amount: withdrawal.Amount},
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			withdrawal.ReversalEntryID = &reversal.ID
+			action, message = model.ActionFailed, fmt.Sprintf("revenue withdrawal of %s rejected by provider, reversed", withdrawal.Amount)
+		}
+
+		if err := tc.revenueStorage.UpdateRevenueWithdrawal(ctx, withdrawal); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &withdrawal.TenantID,
+			Actor:      model.ActorTenant,
+			ActionDone: action,
+			Messages:   message,
+		}
+
+		_, err := tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+
+	return withdrawal, err
+}
+
+// fillRevenueDays lists every day from start to end, the days found with their amounts and the others with zero ones
+func fillRevenueDays(found []model.RevenueDay, start, end time.Time, currency string) []model.RevenueDay {
+	byDay := make(map[string]model.RevenueDay, len(found))
+	for _, day := range found {
+		byDay[day.Day] = day
+	}
+
+	var days []model.RevenueDay
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(model.SnapshotDayLayout)
+
+		revenue, ok := byDay[key]
+		if !ok {
+			revenue = model.RevenueDay{
+				Day:       key,
+				Earned:    model.ZeroMoney(currency),
+				Withdrawn: model.ZeroMoney(currency),
+				Net:       model.ZeroMoney(currency),
+			}
+		}
+
+		days = append(days, revenue)
+	}
+
+	return days
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+func Test_FillRevenueDays(t *testing.T) {
+	lagos, err := time.LoadLocation("Africa/Lagos")
+	require.NoError(t, err)
+
+	start := time.Date(2024, time.March, 30, 0, 0, 0, 0, lagos)
+	end := start.AddDate(0, 0, 3)
+
+	found := []model.RevenueDay{{
+		Day:       "2024-03-31",
+		Earned:    model.NewMoney(15000, "NGN"),
+		Withdrawn: model.NewMoney(10000, "NGN"),
+		Net:       model.NewMoney(5000, "NGN"),
+		Earnings:  3,
+	}}
+
+	days := fillRevenueDays(found, start, end, "NGN")
+	require.Len(t, days, 3)
+	require.Equal(t, []string{"2024-03-30", "2024-03-31", "2024-04-01"}, []string{days[0].Day, days[1].Day, days[2].Day})
+	require.Equal(t, found[0], days[1])
+
+	// the days without movements are listed with zero amounts in the wallet's currency
+	require.Equal(t, model.ZeroMoney("NGN"), days[0].Earned)
+	require.Equal(t, model.ZeroMoney("NGN"), days[2].Net)
+	require.Zero(t, days[2].Earnings)
+}
@@ -0,0 +1,374 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/storage"
+)
+
+// CreateSplit creates a split of the payments into the owner's wallet: a user creates splits of their own payments,
+// a tenant those of its user with the owner email. The users the shares are paid to are named by their email and
+// must be users of the owner's tenant, a tenant share is paid into the revenue wallet of the owner's tenant
+func (c *Controller) CreateSplit(ctx context.Context, actor model.Actor, actorID uuid.UUID, ownerEmail string, split model.Split) (model.Split, error) {
+	var (
+		owner model.User
+		err   error
+	)
+
+	if actor == model.ActorTenant {
+		owner, err = c.userStorage.GetUserByEmail(ctx, strings.ToLower(ownerEmail))
+		if err != nil || owner.TenantID != actorID {
+			return model.Split{}, ErrUserDoesNotExist
+		}
+	} else if owner, err = c.GetUserByID(ctx, actorID); err != nil {
+		c.logger.Err(err).Msgf("CreateSplit ::: error getting user by ID %v", err)
+		return model.Split{}, err
+	}
+
+	if err := split.Prepare(); err != nil {
+		return model.Split{}, err
+	}
+
+	split.ID = uuid.New()
+	split.TenantID = owner.TenantID
+	split.OwnerID = owner.ID
+
+	for n := range split.Shares {
+		share := &split.Shares[n]
+		share.ID = uuid.New()
+		share.SplitID = split.ID
+
+		if share.RecipientType == model.SplitRecipientTenant {
+			share.RecipientID = owner.TenantID
+			continue
+		}
+
+		recipient, err := c.userStorage.GetUserByEmail(ctx, strings.ToLower(share.RecipientEmail))
+		if err != nil || recipient.TenantID != owner.TenantID {
+			return model.Split{}, fmt.Errorf("%w: %s", ErrUserDoesNotExist, share.RecipientEmail)
+		}
+		share.RecipientID = recipient.ID
+	}
+
+	var newSplit model.Split
+	err = c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		if newSplit, err = tc.splitStorage.CreateSplit(ctx, split); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &owner.TenantID,
+			UserID:     &owner.ID,
+			Actor:      actor,
+			ActionDone: model.ActionCreated,
+			Messages:   fmt.Sprintf("split %s of %s payments between %d shares created", split.Name, split.Currency, len(split.Shares)),
+		}
+
+		_, err = tc.CreateAuditLog(ctx, auditLog)
+		return err
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("CreateSplit ::: unable to save split %v", err)
+		return model.Split{}, err
+	}
+
+	return newSplit, nil
+}
+
+// GetSplit returns a split of the user, or of a user of the tenant, with its shares
+func (c *Controller) GetSplit(ctx context.Context, actor model.Actor, actorID, splitID uuid.UUID) (model.Split, error) {
+	split, err := c.splitStorage.GetSplitByID(ctx, splitID)
+	if err != nil || !canSeeSplit(actor, actorID, split) {
+		return model.Split{}, ErrRecordNotFound
+	}
+
+	return split, nil
+}
+
+// GetSplits returns the splits of the user, or of every user of the tenant, newest first
+func (c *Controller) GetSplits(ctx context.Context, actor model.Actor, actorID uuid.UUID, page pagination.Page) ([]model.Split, pagination.PageInfo, error) {
+	filter := storage.SplitFilter{}
+	if actor == model.ActorTenant {
+		filter.TenantID = &actorID
+	} else {
+		filter.OwnerID = &actorID
+	}
+
+	return c.splitStorage.GetSplits(ctx, filter, page)
+}
+
+// checkSplit makes sure the split can divide the user's payments in the currency: it is one of the user's splits,
+// in that currency
+func (c *Controller) checkSplit(ctx context.Context, user model.User, splitID *uuid.UUID, currency string) error {
+	if splitID == nil {
+		return nil
+	}
+
+	split, err := c.splitStorage.GetSplitByID(ctx, *splitID)
+	if err != nil || split.OwnerID != user.ID {
+		return fmt.Errorf("%w: split not found", ErrSplitNotUsable)
+	}
+
+	if !strings.EqualFold(split.Currency, currency) {
+		return fmt.Errorf("%w: split is in %s, payment is in %s", ErrSplitNotUsable, split.Currency, currency)
+	}
+
+	return nil
+}
+
+// runSplit divides a successful credit with a split between the recipients of the split, once the credit is in the
+// owner's locked wallet: the shares of the other recipients leave the owner's wallet in one journal entry, with a
+// debit transaction on the owner and one credit transaction per recipient wallet, related to the credit. A tenant
+// share goes to the tenant's revenue wallet. A credit too small for the fixed shares of its split stays whole in the
+// owner's wallet. It must be called within withTx
+func (c *Controller) runSplit(ctx context.Context, owner model.User, wallet model.Wallet, tx model.Transaction) error {
+	if tx.SplitID == nil || tx.TransactionType != model.CreditTransaction || tx.Status != model.TransactionStatusSuccessful {
+		return nil
+	}
+
+	split, err := c.splitStorage.GetSplitByID(ctx, *tx.SplitID)
+	if err != nil {
+		return err
+	}
+
+	credited, err := tx.WalletMovement()
+	if err != nil {
+		return err
+	}
+
+	allocations, err := split.Allocate(credited)
+	if errors.Is(err, model.ErrSplitExceedsAmount) {
+		auditLog := model.AuditLog{
+			ID:            uuid.New(),
+			TenantID:      &owner.TenantID,
+			UserID:        &owner.ID,
+			TransactionID: &tx.ID,
+			Actor:         model.ActorSystem,
+			ActionDone:    model.ActionFailed,
+			Messages:      fmt.Sprintf("split %s skipped, the credit stays in the wallet: %v", split.Name, err),
+		}
+
+		_, err = c.CreateAuditLog(ctx, auditLog)
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// one credit per recipient, however many shares it has, the owner's own shares stay in its wallet
+	var (
+		recipients []model.SplitShare
+		amounts    = map[uuid.UUID]model.Money{}
+		total      = model.ZeroMoney(credited.Currency)
+	)
+
+	for _, allocation := range allocations {
+		share := allocation.Share
+		if share.RecipientType == model.SplitRecipientUser && share.RecipientID == owner.ID || allocation.Amount.IsZero() {
+			continue
+		}
+
+		if _, ok := amounts[share.RecipientID]; !ok {
+			recipients = append(recipients, share)
+			amounts[share.RecipientID] = model.ZeroMoney(credited.Currency)
+		}
+
+		if amounts[share.RecipientID], err = amounts[share.RecipientID].Add(allocation.Amount); err != nil {
+			return err
+		}
+
+		if total, err = total.Add(allocation.Amount); err != nil {
+			return err
+		}
+	}
+
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	debit := model.Transaction{
+		ID:                   uuid.New(),
+		UserID:               owner.ID,
+		Amount:               total,
+		Charges:              model.ZeroMoney(total.Currency),
+		Fee:                  model.ZeroMoney(total.Currency),
+		FeeVAT:               model.ZeroMoney(total.Currency),
+		Currency:             total.Currency,
+		TransactionType:      model.DebitTransaction,
+		Status:               model.TransactionStatusSuccessful,
+		TransactionFlow:      model.TransactionFlowSplit,
+		RelatedTransactionID: &tx.ID,
+		SplitID:              &split.ID,
+	}
+
+	entry := model.JournalEntry{
+		ID:            uuid.New(),
+		TransactionID: &debit.ID,
+		Description:   string(model.TransactionFlowSplit),
+		Postings: []model.Posting{
+			{ID: uuid.New(), AccountID: *wallet.LedgerAccountID, Amount: total.Neg()},
+		},
+	}
+
+	// open and lock the recipients' wallets before anything is written
+	users := map[uuid.UUID]model.User{}
+	wallets := map[uuid.UUID]model.Wallet{}
+	var walletIDs []uuid.UUID
+
+	for _, share := range recipients {
+		if share.RecipientType == model.SplitRecipientTenant {
+			continue
+		}
+
+		recipient, err := c.GetUserByID(ctx, share.RecipientID)
+		if err != nil {
+			return err
+		}
+
+		recipientWallet, err := c.openWallet(ctx, recipient, total.Currency)
+		if err != nil {
+			return err
+		}
+
+		users[recipient.ID] = recipient
+		wallets[recipient.ID] = recipientWallet
+		walletIDs = append(walletIDs, recipientWallet.ID)
+	}
+
+	locked, err := c.lockWallets(ctx, walletIDs...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.CreateTransaction(ctx, debit); err != nil {
+		c.logger.Err(err).Msgf("runSplit ::: CreateTransaction ===> %v", err)
+		return err
+	}
+
+	credits := map[uuid.UUID]model.Transaction{}
+	for _, share := range recipients {
+		amount := amounts[share.RecipientID]
+
+		if share.RecipientType == model.SplitRecipientTenant {
+			account, err := c.CreateTenantFeeLedgerAccount(ctx, share.RecipientID, total.Currency)
+			if err != nil {
+				return err
+			}
+
+			entry.Postings = append(entry.Postings, model.Posting{ID: uuid.New(), AccountID: account.ID, Amount: amount})
+			continue
+		}
+
+		// a recipient wallet that does not allow credits holds the whole credit back, the provider's next delivery
+		// applies it once the wallet allows credits again
+		recipientWallet := locked[wallets[share.RecipientID].ID]
+		if err := checkWalletCredit(recipientWallet); err != nil {
+			return err
+		}
+
+		if recipientWallet, err = c.ensureWalletLedgerAccount(ctx, users[share.RecipientID], recipientWallet); err != nil {
+			return err
+		}
+		wallets[share.RecipientID] = recipientWallet
+
+		credit := model.Transaction{
+			ID:                   uuid.New(),
+			UserID:               share.RecipientID,
+			Amount:               amount,
+			Charges:              model.ZeroMoney(amount.Currency),
+			Fee:                  model.ZeroMoney(amount.Currency),
+			FeeVAT:               model.ZeroMoney(amount.Currency),
+			Currency:             amount.Currency,
+			TransactionType:      model.CreditTransaction,
+			Status:               model.TransactionStatusSuccessful,
+			TransactionFlow:      model.TransactionFlowSplit,
+			RelatedTransactionID: &tx.ID,
+			SplitID:              &split.ID,
+		}
+
+		if _, err := c.CreateTransaction(ctx, credit); err != nil {
+			c.logger.Err(err).Msgf("runSplit ::: CreateTransaction ===> %v", err)
+			return err
+		}
+		credits[share.RecipientID] = credit
+
+		entry.Postings = append(entry.Postings, model.Posting{ID: uuid.New(), AccountID: *recipientWallet.LedgerAccountID, Amount: amount})
+	}
+
+	if _, err := c.ledgerStorage.PostJournalEntry(ctx, entry); err != nil {
+		c.logger.Err(err).Msgf("runSplit ::: unable to post split to the ledger ===> %v", err)
+		return err
+	}
+
+	if err := c.recordWalletMovement(ctx, wallet, debit); err != nil {
+		return err
+	}
+
+	if err := c.auditSplit(ctx, owner.TenantID, &owner.ID, debit.ID, fmt.Sprintf("%s of a credit split by %s", total, split.Name)); err != nil {
+		return err
+	}
+
+	for _, share := range recipients {
+		amount := amounts[share.RecipientID]
+
+		if share.RecipientType == model.SplitRecipientTenant {
+			if err := c.auditSplit(ctx, owner.TenantID, nil, debit.ID, fmt.Sprintf("%s share of split %s paid to the revenue wallet", amount, split.Name)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		credit := credits[share.RecipientID]
+		if err := c.recordWalletMovement(ctx, wallets[share.RecipientID], credit); err != nil {
+			return err
+		}
+
+		if err := c.auditSplit(ctx, owner.TenantID, &credit.UserID, credit.ID, fmt.Sprintf("received a %s share of split %s", amount, split.Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// auditSplit writes the audit log of a movement of a split
+func (c *Controller) auditSplit(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, transactionID uuid.UUID, message string) error {
+	auditLog := model.AuditLog{
+		ID:            uuid.New(),
+		TenantID:      &tenantID,
+		UserID:        userID,
+		TransactionID: &transactionID,
+		Actor:         model.ActorSystem,
+		ActionDone:    model.ActionSuccess,
+		Messages:      message,
+	}
+
+	if _, err := c.CreateAuditLog(ctx, auditLog); err != nil {
+		c.logger.Err(err).Msgf("auditSplit ::: error creating audit log %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// canSeeSplit reports whether the actor may see the split: its owner or the owner's tenant
+func canSeeSplit(actor model.Actor, actorID uuid.UUID, split model.Split) bool {
+	switch actor {
+	case model.ActorTenant:
+		return actorID == split.TenantID
+	case model.ActorUser:
+		return actorID == split.OwnerID
+	default:
+		return false
+	}
+}
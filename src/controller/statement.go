@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+)
+
+// maxStatementDays is the longest period a statement can cover
+const maxStatementDays = 366
+
+// GetWalletStatement builds the statement of the user's wallet in the currency from the first to the last day given,
+// both included. Only the dates of from and to are used, the days are those of the user's tenant timezone, and so are
+// the times of the statement
+func (c *Controller) GetWalletStatement(ctx context.Context, userID uuid.UUID, currency string, from, to time.Time) (model.Statement, error) {
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil {
+		return model.Statement{}, err
+	}
+
+	tenant, err := c.tenantStorage.GetTenantByID(ctx, user.TenantID)
+	if err != nil {
+		return model.Statement{}, err
+	}
+
+	location := tenant.Location()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, location).AddDate(0, 0, 1)
+
+	if !end.After(start) || end.After(start.AddDate(0, 0, maxStatementDays)) {
+		return model.Statement{}, ErrInvalidStatementPeriod
+	}
+
+	wallet, err := c.walletStorage.GetWalletByUserID(ctx, userID, currency)
+	if err != nil {
+		return model.Statement{}, ErrNoWalletForCurrency
+	}
+
+	opening, err := c.balanceStorage.GetBalanceAt(ctx, userID, wallet.Currency, start)
+	if err != nil {
+		return model.Statement{}, err
+	}
+
+	balances, err := c.balanceStorage.GetBalancesByUserID(ctx, userID, wallet.Currency, start, end)
+	if err != nil {
+		return model.Statement{}, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(balances))
+	for _, balance := range balances {
+		ids = append(ids, balance.TransactionID)
+	}
+
+	transactions, err := c.transactionStorage.GetTransactionsByIDs(ctx, ids)
+	if err != nil {
+		return model.Statement{}, err
+	}
+
+	statement, err := buildStatement(opening.BalanceAfter, balances, transactions, location)
+	if err != nil {
+		return model.Statement{}, err
+	}
+
+	statement.UserID = userID
+	statement.Timezone = location.String()
+	statement.From = start
+	// the statement covers the whole last day, shown as its last instant rather than the next midnight
+	statement.To = end.Add(-time.Nanosecond)
+	statement.GeneratedAt = time.Now().In(location)
+
+	return statement, nil
+}
+
+// buildStatement lists the balance entries of a wallet, oldest first, as statement entries after the opening balance.
+// The amount of an entry is what it moved the wallet by, its fees are the charges and the fee of its transaction
+func buildStatement(opening model.Money, balances []model.Balance, transactions []model.Transaction, location *time.Location) (model.Statement, error) {
+	byID := make(map[uuid.UUID]model.Transaction, len(transactions))
+	for _, transaction := range transactions {
+		byID[transaction.ID] = transaction
+	}
+
+	currency := opening.Currency
+	statement := model.Statement{
+		Currency:       currency,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		TotalCredits:   model.ZeroMoney(currency),
+		TotalDebits:    model.ZeroMoney(currency),
+		TotalFees:      model.ZeroMoney(currency),
+		Entries:        make([]model.StatementEntry, 0, len(balances)),
+	}
+
+	for _, balance := range balances {
+		movement, err := balance.BalanceAfter.Sub(balance.BalanceBefore)
+		if err != nil {
+			return model.Statement{}, err
+		}
+
+		transaction := byID[balance.TransactionID]
+		entry := model.StatementEntry{
+			Date:            balance.CreatedAt.In(location),
+			TransactionID:   balance.TransactionID,
+			TransactionType: balance.TransactionType,
+			TransactionFlow: transaction.TransactionFlow,
+			Status:          transaction.Status,
+			Amount:          movement.Abs(),
+			Fees:            model.ZeroMoney(currency),
+			Balance:         balance.BalanceAfter,
+		}
+
+		if transaction.Charges.Currency == currency {
+			entry.Fees = transaction.Charges
+		}
+
+		if transaction.Fee.Currency == currency {
+			if entry.Fees, err = entry.Fees.Add(transaction.Fee); err != nil {
+				return model.Statement{}, err
+			}
+		}
+
+		if movement.IsNegative() {
+			statement.TotalDebits, err = statement.TotalDebits.Add(entry.Amount)
+		} else {
+			statement.TotalCredits, err = statement.TotalCredits.Add(entry.Amount)
+		}
+		if err != nil {
+			return model.Statement{}, err
+		}
+
+		if statement.TotalFees, err = statement.TotalFees.Add(entry.Fees); err != nil {
+			return model.Statement{}, err
+		}
+
+		statement.ClosingBalance = balance.BalanceAfter
+		statement.Entries = append(statement.Entries, entry)
+	}
+
+	return statement, nil
+}
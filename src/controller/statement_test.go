@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+func Test_BuildStatement(t *testing.T) {
+	lagos, err := time.LoadLocation("Africa/Lagos")
+	require.NoError(t, err)
+
+	deposit := model.Transaction{
+		ID:              uuid.New(),
+		Charges:         model.NewMoney(2500, "NGN"),
+		TransactionFlow: model.TransactionFlowRevenue,
+		Status:          model.TransactionStatusSuccessful,
+	}
+	withdrawal := model.Transaction{
+		ID:              uuid.New(),
+		Charges:         model.NewMoney(1000, "NGN"),
+		TransactionFlow: model.TransactionFlowWithdrawal,
+		Status:          model.TransactionStatusSuccessful,
+	}
+
+	// 23:30 UTC is already the next day in Lagos
+	depositedAt := time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC)
+	balances := []model.Balance{
+		{
+			TransactionID:   deposit.ID,
+			TransactionType: model.CreditTransaction,
+			BalanceBefore:   model.NewMoney(100000, "NGN"),
+			BalanceAfter:    model.NewMoney(600000, "NGN"),
+			CreatedAt:       depositedAt,
+		},
+		{
+			TransactionID:   withdrawal.ID,
+			TransactionType: model.DebitTransaction,
+			BalanceBefore:   model.NewMoney(600000, "NGN"),
+			BalanceAfter:    model.NewMoney(400000, "NGN"),
+			CreatedAt:       depositedAt.Add(time.Hour),
+		},
+	}
+
+	statement, err := buildStatement(model.NewMoney(100000, "NGN"), balances, []model.Transaction{withdrawal, deposit}, lagos)
+	require.NoError(t, err)
+
+	require.Equal(t, model.NewMoney(100000, "NGN"), statement.OpeningBalance)
+	require.Equal(t, model.NewMoney(400000, "NGN"), statement.ClosingBalance)
+	require.Equal(t, model.NewMoney(500000, "NGN"), statement.TotalCredits)
+	require.Equal(t, model.NewMoney(200000, "NGN"), statement.TotalDebits)
+	require.Equal(t, model.NewMoney(3500, "NGN"), statement.TotalFees)
+
+	require.Len(t, statement.Entries, 2)
+	require.Equal(t, "2024-05-02 00:30", statement.Entries[0].Date.Format("2006-01-02 15:04"))
+	require.Equal(t, model.TransactionFlowRevenue, statement.Entries[0].TransactionFlow)
+	require.Equal(t, model.NewMoney(500000, "NGN"), statement.Entries[0].Amount)
+	require.Equal(t, model.NewMoney(600000, "NGN"), statement.Entries[0].Balance)
+	require.Equal(t, model.NewMoney(200000, "NGN"), statement.Entries[1].Amount)
+	require.Equal(t, model.NewMoney(1000, "NGN"), statement.Entries[1].Fees)
+
+	// a period without movements opens and closes on the same balance
+	empty, err := buildStatement(model.NewMoney(100000, "NGN"), nil, nil, lagos)
+	require.NoError(t, err)
+	require.Equal(t, empty.OpeningBalance, empty.ClosingBalance)
+	require.Empty(t, empty.Entries)
+}
@@ -2,7 +2,11 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	"codematic/model"
 )
@@ -10,6 +14,13 @@ import (
 func (c *Controller) CreateTenant(ctx context.Context, tenant model.Tenant) (model.Tenant, error) {
 	tenant.Email = strings.ToLower(tenant.Email)
 
+	if tenant.Timezone == "" {
+		tenant.Timezone = model.DefaultTimezone
+	}
+	if _, err := time.LoadLocation(tenant.Timezone); err != nil {
+		return model.Tenant{}, ErrInvalidTimezone
+	}
+
 	encryptedPass := tenant.Password.Encrypt()
 	tenant.Password = encryptedPass
 
@@ -19,9 +30,24 @@ func (c *Controller) CreateTenant(ctx context.Context, tenant model.Tenant) (mod
 		return model.Tenant{}, ErrEmailAlreadyExists
 	}
 
-	newTenant, err := c.tenantStorage.CreateTenant(ctx, tenant)
+	// the tenant and its fee ledger account are written as one unit of work
+	var newTenant model.Tenant
+	err = c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		newTenant, err = tc.tenantStorage.CreateTenant(ctx, tenant)
+		if err != nil {
+			tc.logger.Err(err).Msgf("CreateTenant::: Unable to insert tenant into db %s", err)
+			return err
+		}
+
+		if _, err := tc.CreateTenantFeeLedgerAccount(ctx, newTenant.ID, model.DefaultCurrency); err != nil {
+			tc.logger.Err(err).Msgf("CreateTenantFeeLedgerAccount::: Unable to create ledger account %s", err)
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		c.logger.Err(err).Msgf("CreateTenant::: Unable to insert tenant into db %s", err)
 		return model.Tenant{}, err
 	}
 
@@ -43,3 +69,71 @@ func (c *Controller) AuthenticateTenant(ctx context.Context, email, password str
 
 	return tenant, nil
 }
+
+// SetAllowCrossTenantTransfers sets whether the tenant's users may make internal transfers with users of other tenants
+func (c *Controller) SetAllowCrossTenantTransfers(ctx context.Context, tenantID uuid.UUID, allow bool) (model.Tenant, error) {
+	var tenant model.Tenant
+	err := c.withTx(ctx, func(tc *Controller) error {
+		if err := tc.tenantStorage.SetAllowCrossTenantTransfers(ctx, tenantID, allow); err != nil {
+			tc.logger.Err(err).Msgf("SetAllowCrossTenantTransfers ::: unable to update tenant %s", err)
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &tenantID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionUpdated,
+			Messages:   fmt.Sprintf("cross tenant transfers allowed set to %t", allow),
+		}
+
+		if _, err := tc.CreateAuditLog(ctx, auditLog); err != nil {
+			return err
+		}
+
+		var err error
+		tenant, err = tc.tenantStorage.GetTenantByID(ctx, tenantID)
+		return err
+	})
+	if err != nil {
+		return model.Tenant{}, err
+	}
+
+	return tenant, nil
+}
+
+// SetTenantTimezone sets the IANA timezone, e.g. Africa/Lagos, the tenant and its users see dates in
+func (c *Controller) SetTenantTimezone(ctx context.Context, tenantID uuid.UUID, timezone string) (model.Tenant, error) {
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" {
+		return model.Tenant{}, ErrInvalidTimezone
+	}
+
+	var tenant model.Tenant
+	err := c.withTx(ctx, func(tc *Controller) error {
+		if err := tc.tenantStorage.UpdateTenantByID(ctx, model.Tenant{ID: tenantID, Timezone: timezone}); err != nil {
+			tc.logger.Err(err).Msgf("SetTenantTimezone ::: unable to update tenant %s", err)
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &tenantID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionUpdated,
+			Messages:   fmt.Sprintf("timezone set to %s", timezone),
+		}
+
+		if _, err := tc.CreateAuditLog(ctx, auditLog); err != nil {
+			return err
+		}
+
+		var err error
+		tenant, err = tc.tenantStorage.GetTenantByID(ctx, tenantID)
+		return err
+	})
+	if err != nil {
+		return model.Tenant{}, err
+	}
+
+	return tenant, nil
+}
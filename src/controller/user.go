@@ -30,37 +30,39 @@ func (c *Controller) CreateUser(ctx context.Context, u model.User) (model.User,
 		return model.User{}, ErrEmailAlreadyExists
 	}
 
-	newUser, err := c.userStorage.CreateUser(ctx, u)
+	// the user, its wallet and the audit log are written as one unit of work, a user never exists without a wallet
+	var newUser model.User
+	err = c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		newUser, err = tc.userStorage.CreateUser(ctx, u)
+		if err != nil {
+			tc.logger.Err(err).Msgf("CreateUser::: Unable to insert user into db %s", err)
+			return err
+		}
+
+		// create a wallet in the default currency for every newly created users
+		if _, err := tc.openWallet(ctx, newUser, model.DefaultCurrency); err != nil {
+			tc.logger.Err(err).Msgf("CreateUser::: Unable to create wallet %s", err)
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &newUser.TenantID,
+			UserID:     &newUser.ID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionSuccess,
+			Messages:   "tenant added a new user",
+		}
+
+		if _, err = tc.CreateAuditLog(ctx, auditLog); err != nil {
+			tc.logger.Err(err).Msgf("error creating audit log")
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		c.logger.Err(err).Msgf("CreateUser::: Unable to insert user into db %s", err)
-		return model.User{}, err
-	}
-
-	// create a wallet for every newly created users
-	wallet := model.Wallet{
-		ID:            uuid.New(),
-		UserID:        newUser.ID,
-		BalanceBefore: 0.0,
-		BalanceAfter:  0.0,
-	}
-
-	_, err = c.CreateWallet(ctx, wallet)
-	if err != nil {
-		c.logger.Err(err).Msgf("CreateWallet::: Unable to create wallet %s", err)
-		return model.User{}, err
-	}
-
-	auditLog := model.AuditLog{
-		ID:         uuid.New(),
-		TenantID:   &newUser.TenantID,
-		UserID:     &newUser.ID,
-		Actor:      model.ActorTenant,
-		ActionDone: model.ActionSuccess,
-		Messages:   "tenant added a new user",
-	}
-
-	if _, err = c.CreateAuditLog(ctx, auditLog); err != nil {
-		c.logger.Err(err).Msgf("error creating audit log")
 		return model.User{}, err
 	}
 
@@ -2,10 +2,15 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
 	"codematic/model"
+	"codematic/storage"
 )
 
 // CreateWallet add a new wallet into the wallet table
@@ -13,12 +18,232 @@ func (c *Controller) CreateWallet(ctx context.Context, wallet model.Wallet) (mod
 	return c.walletStorage.CreateWallet(ctx, wallet)
 }
 
-// GetWalletByUserID gets a wallet by it the user's id from the wallet table
-func (c *Controller) GetWalletByUserID(ctx context.Context, userID uuid.UUID) (model.Wallet, error) {
-	return c.walletStorage.GetWalletByUserID(ctx, userID)
+// GetWalletByUserID gets the user's wallet in the currency from the wallet table
+func (c *Controller) GetWalletByUserID(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error) {
+	return c.walletStorage.GetWalletByUserID(ctx, userID, currency)
+}
+
+// GetWalletsByUserID gets every wallet of the user, one per currency
+func (c *Controller) GetWalletsByUserID(ctx context.Context, userID uuid.UUID) ([]model.Wallet, error) {
+	return c.walletStorage.GetWalletsByUserID(ctx, userID)
 }
 
 // UpdateWalletByID updates users wallet record in the wallet table using the wallet ID
 func (c *Controller) UpdateWalletByID(ctx context.Context, wallet model.Wallet) error {
 	return c.walletStorage.UpdateWalletByID(ctx, wallet)
 }
+
+// OpenWallet returns the user's wallet in the currency, opening it together with its ledger account when the user
+// does not hold that currency yet
+func (c *Controller) OpenWallet(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error) {
+	currency = strings.ToUpper(currency)
+	if !model.IsSupportedCurrency(currency) {
+		return model.Wallet{}, model.ErrUnsupportedCurrency
+	}
+
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil {
+		c.logger.Err(err).Msgf("OpenWallet ::: error getting user by ID %v", err)
+		return model.Wallet{}, err
+	}
+
+	var wallet model.Wallet
+	err = c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		wallet, err = tc.openWallet(ctx, user, currency)
+		return err
+	})
+
+	return wallet, err
+}
+
+// SetWalletStatus restricts the movements of the wallet in the currency of one of the tenant's users, e.g. freezes it
+// for compliance, and records why. The change is audited with the status it replaced
+func (c *Controller) SetWalletStatus(ctx context.Context, tenantID, userID uuid.UUID, currency string, status model.WalletStatus, reason string) (model.Wallet, error) {
+	if !status.IsValid() {
+		return model.Wallet{}, model.ErrInvalidWalletStatus
+	}
+
+	user, err := c.GetUserByID(ctx, userID)
+	if err != nil || user.TenantID != tenantID {
+		return model.Wallet{}, ErrRecordNotFound
+	}
+
+	var wallet model.Wallet
+	err = c.withTx(ctx, func(tc *Controller) error {
+		var err error
+		if wallet, err = tc.walletForDebit(ctx, user.ID, strings.ToUpper(currency)); err != nil {
+			return err
+		}
+
+		if err := tc.walletStorage.UpdateWalletStatus(ctx, wallet.ID, status, reason); err != nil {
+			return err
+		}
+
+		auditLog := model.AuditLog{
+			ID:         uuid.New(),
+			TenantID:   &tenantID,
+			UserID:     &user.ID,
+			Actor:      model.ActorTenant,
+			ActionDone: model.ActionUpdated,
+			Messages:   fmt.Sprintf("%s wallet changed from %s to %s: %s", wallet.Currency, wallet.Status, status, reason),
+		}
+
+		if _, err := tc.CreateAuditLog(ctx, auditLog); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		wallet.Status, wallet.StatusReason, wallet.StatusChangedAt = status, reason, &now
+		return nil
+	})
+	if err != nil {
+		c.logger.Err(err).Msgf("SetWalletStatus ::: unable to change wallet status %v", err)
+		return model.Wallet{}, err
+	}
+
+	return wallet, nil
+}
+
+// checkWalletDebit refuses a debit of a wallet whose status does not allow it
+func checkWalletDebit(wallet model.Wallet) error {
+	if !wallet.CanDebit() {
+		return fmt.Errorf("%w: %s wallet is %s", ErrWalletDebitBlocked, wallet.Currency, wallet.Status)
+	}
+
+	return nil
+}
+
+// checkWalletCredit refuses a credit of a wallet whose status does not allow it
+func checkWalletCredit(wallet model.Wallet) error {
+	if !wallet.CanCredit() {
+		return fmt.Errorf("%w: %s wallet is %s", ErrWalletCreditBlocked, wallet.Currency, wallet.Status)
+	}
+
+	return nil
+}
+
+// openWallet returns the user's wallet in the currency, creating it when it does not exist yet
+func (c *Controller) openWallet(ctx context.Context, user model.User, currency string) (model.Wallet, error) {
+	wallet, err := c.walletStorage.GetWalletByUserID(ctx, user.ID, currency)
+	if err == nil {
+		return wallet, nil
+	}
+
+	// every wallet is backed by a ledger account, the wallet balance is derived from its postings
+	account, err := c.CreateUserWalletLedgerAccount(ctx, user, currency)
+	if err != nil {
+		c.logger.Err(err).Msgf("openWallet ::: unable to create ledger account %v", err)
+		return model.Wallet{}, err
+	}
+
+	wallet = model.Wallet{
+		ID:               uuid.New(),
+		UserID:           user.ID,
+		Currency:         currency,
+		LedgerAccountID:  &account.ID,
+		BalanceBefore:    model.ZeroMoney(currency),
+		BalanceAfter:     model.ZeroMoney(currency),
+		BookBalance:      model.ZeroMoney(currency),
+		AvailableBalance: model.ZeroMoney(currency),
+		Status:           model.WalletStatusActive,
+	}
+
+	if _, err := c.CreateWallet(ctx, wallet); err != nil {
+		c.logger.Err(err).Msgf("openWallet ::: unable to create wallet %v", err)
+		return model.Wallet{}, err
+	}
+
+	return wallet, nil
+}
+
+// lockWallets locks the wallet rows in ID order, so that two units of work locking the same wallets can never
+// deadlock each other, and returns the locked wallets by ID
+func (c *Controller) lockWallets(ctx context.Context, walletIDs ...uuid.UUID) (map[uuid.UUID]model.Wallet, error) {
+	sort.Slice(walletIDs, func(i, j int) bool {
+		return walletIDs[i].String() < walletIDs[j].String()
+	})
+
+	wallets := make(map[uuid.UUID]model.Wallet, len(walletIDs))
+	for _, walletID := range walletIDs {
+		if _, ok := wallets[walletID]; ok {
+			continue
+		}
+
+		wallet, err := c.walletStorage.GetWalletByIDForUpdate(ctx, walletID)
+		if err != nil {
+			return nil, err
+		}
+
+		wallets[walletID] = wallet
+	}
+
+	return wallets, nil
+}
+
+// ensureWalletLedgerAccount opens the ledger account of a wallet created before the ledger existed
+func (c *Controller) ensureWalletLedgerAccount(ctx context.Context, user model.User, wallet model.Wallet) (model.Wallet, error) {
+	if wallet.LedgerAccountID != nil {
+		return wallet, nil
+	}
+
+	account, err := c.CreateUserWalletLedgerAccount(ctx, user, wallet.Currency)
+	if err != nil {
+		return wallet, err
+	}
+
+	wallet.LedgerAccountID = &account.ID
+	return wallet, nil
+}
+
+// walletForDebit locks the user's wallet in the currency for a debit
+func (c *Controller) walletForDebit(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error) {
+	wallet, err := c.walletStorage.GetWalletByUserIDForUpdate(ctx, userID, currency)
+	if err == storage.ErrRecordNotFound {
+		return model.Wallet{}, ErrNoWalletForCurrency
+	}
+
+	return wallet, err
+}
+
+// recordWalletMovement writes the balance history row of a movement already posted to the wallet's ledger account,
+// and points the wallet row at it
+func (c *Controller) recordWalletMovement(ctx context.Context, wallet model.Wallet, tx model.Transaction) error {
+	// current balance, read back from the ledger
+	currentBal, err := c.GetLastBalanceByUserID(ctx, wallet.UserID, wallet.Currency)
+	if err != nil {
+		c.logger.Err(err).Msgf("error getting user last balance ===> %v", err)
+		return err
+	}
+
+	// create new balance
+	balance := model.Balance{
+		ID:              uuid.New(),
+		UserID:          wallet.UserID,
+		TransactionType: tx.TransactionType,
+		TransactionID:   tx.ID,
+		BalanceBefore:   currentBal.BalanceBefore,
+		BalanceAfter:    currentBal.BalanceAfter,
+	}
+
+	newBal, err := c.CreateBalance(ctx, balance)
+	if err != nil {
+		c.logger.Err(err).Msgf("error creating user balance ===> %v", err)
+		return err
+	}
+
+	// keep the wallet row pointing at the latest movement
+	txType := tx.TransactionType
+	wallet.BalanceBefore = newBal.BalanceBefore
+	wallet.BalanceAfter = newBal.BalanceAfter
+	wallet.TransactionID = &tx.ID
+	wallet.TransactionType = &txType
+	wallet.BalanceID = &newBal.ID
+
+	if err := c.UpdateWalletByID(ctx, wallet); err != nil {
+		c.logger.Err(err).Msgf("error updating wallet by ID ===> %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/storage"
+	"codematic/thirdparty/payment"
+)
+
+// ReceiveWebhookEvent stores a webhook delivered by a provider in the inbox before anything else is done with it,
+// keyed by the provider's event ID, then processes it. A redelivery of an event already in the inbox is not stored
+// again, it is processed only when the first delivery was neither processed nor ignored. It returns the event with
+// the state its processing left it in
+func (c *Controller) ReceiveWebhookEvent(ctx context.Context, event model.WebhookEvent) (model.WebhookEvent, error) {
+	event.ID = uuid.New()
+	event.EventID = payment.WebhookEventID(event.Provider, []byte(event.Body))
+	event.Status = model.WebhookEventReceived
+	event.Attempts = 0
+	event.LastError = ""
+	event.ProcessedAt = nil
+
+	created, err := c.webhookEventStorage.CreateWebhookEvent(ctx, event)
+	if err != nil {
+		return model.WebhookEvent{}, err
+	}
+
+	if !created {
+		stored, err := c.webhookEventStorage.GetWebhookEventByProviderEventID(ctx, event.Provider, event.EventID)
+		if err != nil {
+			return model.WebhookEvent{}, err
+		}
+
+		if stored.Status == model.WebhookEventProcessed || stored.Status == model.WebhookEventIgnored {
+			return stored, nil
+		}
+
+		event = stored
+	}
+
+	return c.processWebhookEvent(ctx, event.ID)
+}
+
+// RejectWebhookEvent records a delivery refused before it was processed in the inbox, with the reason. It is keyed by
+// its body so it does not take the place of the event it claims to be, the same rejected body is recorded once. Only
+// the start of the body is kept, and a source sending more than webhookRejectionsPerMinute rejected deliveries has
+// the rest refused with ErrWebhookRejectionsLimited, so forged deliveries cannot fill the inbox
+func (c *Controller) RejectWebhookEvent(ctx context.Context, event model.WebhookEvent, reason string) (model.WebhookEvent, error) {
+	if err := c.limitWebhookRejections(ctx, event.Source); err != nil {
+		return model.WebhookEvent{}, err
+	}
+
+	event.ID = uuid.New()
+	event.EventID = model.RejectedWebhookEventID([]byte(event.Body))
+	event.Body = model.TruncateWebhookBody(event.Body)
+	event.Status = model.WebhookEventRejected
+	event.Attempts = 0
+	event.LastError = reason
+	event.ProcessedAt = nil
+
+	if _, err := c.webhookEventStorage.CreateWebhookEvent(ctx, event); err != nil {
+		return model.WebhookEvent{}, err
+	}
+
+	return event, nil
+}
+
+// webhookRejectionsPerMinute is how many rejected deliveries of a source are recorded in a minute, from
+// WEBHOOK_REJECTIONS_PER_MINUTE
+func (c *Controller) webhookRejectionsPerMinute() int64 {
+	limit, err := strconv.ParseInt(c.env.Get("WEBHOOK_REJECTIONS_PER_MINUTE"), 10, 64)
+	if err != nil || limit <= 0 {
+		return defaultWebhookRejectionsPerMinute
+	}
+
+	return limit
+}
+
+// defaultWebhookRejectionsPerMinute is how many rejected deliveries of a source are recorded in a minute by default
+const defaultWebhookRejectionsPerMinute = 20
+
+// limitWebhookRejections counts a rejected delivery of the source in redis and refuses it with
+// ErrWebhookRejectionsLimited once the source is over its rejections of the minute. When redis cannot count it the
+// rejection is not recorded either, the middleware still logs it
+func (c *Controller) limitWebhookRejections(ctx context.Context, source string) error {
+	if source == "" {
+		source = "unknown"
+	}
+
+	key := fmt.Sprintf("webhook:rejected:%s:%d", source, time.Now().Unix()/60)
+	count, err := c.redis.IncrementBy(ctx, key, 1, 2*time.Minute)
+	if err != nil {
+		c.logger.Err(err).Msgf("limitWebhookRejections ::: unable to count rejected webhooks of %s %v", source, err)
+		return ErrWebhookRejectionsLimited
+	}
+
+	if count > c.webhookRejectionsPerMinute() {
+		return ErrWebhookRejectionsLimited
+	}
+
+	return nil
+}
+
+// ReplayWebhookEvent processes an event of the inbox again. An event that was already applied is refused with
+// ErrWebhookEventProcessed, a rejected delivery with ErrWebhookEventRejected
+func (c *Controller) ReplayWebhookEvent(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error) {
+	event, err := c.webhookEventStorage.GetWebhookEventByID(ctx, eventID)
+	if err != nil {
+		return model.WebhookEvent{}, ErrRecordNotFound
+	}
+
+	switch event.Status {
+	case model.WebhookEventProcessed:
+		return event, ErrWebhookEventProcessed
+	case model.WebhookEventRejected:
+		return event, ErrWebhookEventRejected
+	}
+
+	return c.processWebhookEvent(ctx, event.ID)
+}
+
+// GetWebhookEvent returns an event of the inbox
+func (c *Controller) GetWebhookEvent(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error) {
+	event, err := c.webhookEventStorage.GetWebhookEventByID(ctx, eventID)
+	if err != nil {
+		return model.WebhookEvent{}, ErrRecordNotFound
+	}
+
+	return event, nil
+}
+
+// GetWebhookEvents returns the events of the inbox, of a provider and in a status when they are given, newest first
+func (c *Controller) GetWebhookEvents(ctx context.Context, provider *model.PaymentProvider, status *model.WebhookEventStatus, page pagination.Page) ([]model.WebhookEvent, pagination.PageInfo, error) {
+	return c.webhookEventStorage.GetWebhookEvents(ctx, storage.WebhookEventFilter{Provider: provider, Status: status}, page)
+}
+
+// processWebhookEvent applies an event of the inbox. The event row is locked, the webhook applied and the event
+// marked processed in one database transaction, so concurrent deliveries and replays apply it exactly once. An event
+// whose processing fails is marked failed with the error and applied by its next delivery or replay
+func (c *Controller) processWebhookEvent(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error) {
+	var processed model.WebhookEvent
+
+	err := c.withTx(ctx, func(tc *Controller) error {
+		event, err := tc.webhookEventStorage.GetWebhookEventByIDForUpdate(ctx, eventID)
+		if err != nil {
+			return ErrRecordNotFound
+		}
+
+		processed = event
+		if event.Status == model.WebhookEventProcessed {
+			// a concurrent delivery applied the event while we waited for its lock
+			return nil
+		}
+
+		paymentEvent, err := payment.ParseWebhook(event.Provider, []byte(event.Body))
+		if errors.Is(err, payment.ErrUnsupportedWebhookEvent) {
+			// an event that moves no wallet is acknowledged, it is kept for inspection
+			event.Status = model.WebhookEventIgnored
+			event.Attempts++
+			event.LastError = err.Error()
+			processed = event
+			return tc.webhookEventStorage.UpdateWebhookEvent(ctx, event)
+		}
+		if err != nil {
+			return err
+		}
+
+		prefix, transactionID, err := parsePaymentEvent(paymentEvent)
+		if err != nil {
+			return err
+		}
+
+		if err := tc.applyPaymentEvent(ctx, prefix, transactionID, paymentEvent); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		event.Status = model.WebhookEventProcessed
+		event.Attempts++
+		event.LastError = ""
+		event.ProcessedAt = &now
+		if err := tc.webhookEventStorage.UpdateWebhookEvent(ctx, event); err != nil {
+			return err
+		}
+
+		processed = event
+		return nil
+	})
+	if err == nil {
+		return processed, nil
+	}
+
+	c.logger.Err(err).Msgf("processWebhookEvent ===> unable to process webhook event %s", eventID)
+
+	// the database transaction was rolled back, the failure is recorded in one of its own. A concurrent delivery may
+	// have applied the event since, its outcome is kept
+	var failed model.WebhookEvent
+	recordErr := c.withTx(ctx, func(tc *Controller) error {
+		event, getErr := tc.webhookEventStorage.GetWebhookEventByIDForUpdate(ctx, eventID)
+		if getErr != nil {
+			return getErr
+		}
+
+		failed = event
+		if event.Status == model.WebhookEventProcessed {
+			return nil
+		}
+
+		failed.Status = model.WebhookEventFailed
+		failed.Attempts++
+		failed.LastError = err.Error()
+		return tc.webhookEventStorage.UpdateWebhookEvent(ctx, failed)
+	})
+	if recordErr != nil {
+		c.logger.Err(recordErr).Msgf("processWebhookEvent ===> unable to record failure of webhook event %s", eventID)
+	}
+
+	return failed, err
+}
@@ -24,9 +24,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/audit-log/transaction/{id}": {
+        "/admin/ledger/verifications": {
             "get": {
-                "description": "this endpoint gets all audit logs by the transaction ID",
+                "description": "this endpoint gets the ledger verifications with the breaks they found, newest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -34,22 +34,22 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "audit-log"
+                    "ledger"
                 ],
-                "summary": "getAllAuditLogsByTransactionID",
+                "summary": "getVerifications",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Bearer \u003ctoken\u003e",
-                        "name": "Authorization",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
                         "in": "header",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "transaction id",
-                        "name": "id",
-                        "in": "path"
+                        "description": "tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
                     },
                     {
                         "type": "string",
@@ -62,33 +62,19 @@ const docTemplate = `{
                         "description": "size",
                         "name": "size",
                         "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "sort_by",
-                        "name": "sort_by",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "sort_direction_desc",
-                        "name": "sort_direction_desc",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "audit logs fetched successfully",
+                        "description": "ledger verifications fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
                     }
                 }
-            }
-        },
-        "/audit-log/{id}": {
-            "get": {
-                "description": "this endpoint gets an audit log by ID",
+            },
+            "post": {
+                "description": "this endpoint verifies the balance history of a tenant's wallets against their ledger accounts and settled transactions and saves the report. With repair=true, wallets whose latest balance entry disagrees with their ledger account get an adjustment entry",
                 "consumes": [
                     "application/json"
                 ],
@@ -96,27 +82,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "audit-log"
+                    "ledger"
                 ],
-                "summary": "getAuditLogByID",
+                "summary": "verify",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Bearer \u003ctoken\u003e",
-                        "name": "Authorization",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
                         "in": "header",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "audit id",
-                        "name": "id",
-                        "in": "path"
+                        "description": "tenant ID",
+                        "name": "tenant_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "write adjustment entries",
+                        "name": "repair",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "audit log fetched successfully",
+                    "201": {
+                        "description": "ledger verified",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -124,9 +117,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/login": {
-            "post": {
-                "description": "this endpoint is used to log a user in",
+        "/admin/reconciliation": {
+            "get": {
+                "description": "this endpoint gets the reconciliation runs, newest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -134,64 +127,89 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "reconciliation"
                 ],
-                "summary": "login",
+                "summary": "getRuns",
                 "parameters": [
                     {
-                        "description": "login request body",
-                        "name": "loginRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.loginRequest"
-                        }
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "paystack or flutterwave",
+                        "name": "provider",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "user logged in successfully",
+                        "description": "reconciliation runs fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
                     }
                 }
-            }
-        },
-        "/auth/signup": {
+            },
             "post": {
-                "description": "this endpoint signs up a new user",
+                "description": "this endpoint reconciles a provider's settlement file against our transactions and saves the run. Paystack files have reference, amount (in kobo), currency, status and paid_at, Flutterwave files have tx_ref, amount, currency, status and created_at. CSV files have a header row, JSON files list the transactions under data",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "reconciliation"
                 ],
-                "summary": "user signup",
+                "summary": "reconcile",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Bearer \u003ctoken\u003e. Pass in the tenant access token",
-                        "name": "Authorization",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
                         "in": "header",
                         "required": true
                     },
                     {
-                        "description": "signup request body",
-                        "name": "signupRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.signupRequest"
-                        }
+                        "type": "string",
+                        "description": "paystack or flutterwave",
+                        "name": "provider",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "settlement file, .csv or .json",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "signup successful",
+                        "description": "reconciliation completed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid settlement file",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -199,9 +217,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/user": {
-            "patch": {
-                "description": "this endpoint is used to update any of the users record",
+        "/admin/reconciliation/{id}": {
+            "get": {
+                "description": "this endpoint gets a reconciliation run with its totals",
                 "consumes": [
                     "application/json"
                 ],
@@ -209,30 +227,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "reconciliation"
                 ],
-                "summary": "updateUserByID",
+                "summary": "getRun",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Bearer \u003ctoken\u003e",
-                        "name": "Authorization",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
                         "in": "header",
                         "required": true
                     },
                     {
-                        "description": "update user request body",
-                        "name": "updateUserRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.updateUserRequest"
-                        }
+                        "type": "string",
+                        "description": "reconciliation run ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "user updated successfully",
+                    "200": {
+                        "description": "reconciliation run fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -240,9 +256,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/user/{id}": {
+        "/admin/reconciliation/{id}/items": {
             "get": {
-                "description": "this endpoint gets a user by ID",
+                "description": "this endpoint gets the discrepancies a reconciliation run found",
                 "consumes": [
                     "application/json"
                 ],
@@ -250,27 +266,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "reconciliation"
                 ],
-                "summary": "getUserByID",
+                "summary": "getItems",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Bearer \u003ctoken\u003e",
-                        "name": "Authorization",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
                         "in": "header",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "userID",
+                        "description": "reconciliation run ID",
                         "name": "id",
-                        "in": "path"
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "missing_on_our_side, missing_on_provider, amount_mismatch or status_mismatch",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "user details fetched successfully",
+                        "description": "reconciliation items fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -278,9 +313,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/payment/bank-transfer": {
-            "post": {
-                "description": "this endpoint is used to get a one time virtual account that is to be used top up once wallet",
+        "/admin/webhooks": {
+            "get": {
+                "description": "this endpoint gets the webhooks the payment providers delivered, newest first, with their raw body, headers and processing status",
                 "consumes": [
                     "application/json"
                 ],
@@ -288,23 +323,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "payment"
+                    "webhook"
                 ],
-                "summary": "bankTransfer",
+                "summary": "getEvents",
                 "parameters": [
                     {
-                        "description": "bank transfer request body",
-                        "name": "bankTransferRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/payment.bankTransferRequest"
-                        }
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "paystack or flutterwave",
+                        "name": "provider",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "received, processed, ignored, failed or rejected",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "bank account created successful",
+                        "description": "webhook events fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -312,9 +369,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/payment/deposit": {
-            "post": {
-                "description": "this endpoint is used to make a deposit",
+        "/admin/webhooks/{id}": {
+            "get": {
+                "description": "this endpoint gets a webhook event with its raw body, headers, processing status and last error",
                 "consumes": [
                     "application/json"
                 ],
@@ -322,23 +379,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "payment"
+                    "webhook"
                 ],
-                "summary": "makeDeposit",
+                "summary": "getEvent",
                 "parameters": [
                     {
-                        "description": "deposit request body",
-                        "name": "depositRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/payment.depositRequest"
-                        }
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "webhook event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "wallet top up successful",
+                        "description": "webhook event fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -346,9 +414,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/payment/transfer": {
+        "/admin/webhooks/{id}/replay": {
             "post": {
-                "description": "this endpoint is used to make transfer",
+                "description": "this endpoint processes a webhook event that was not processed again, from the body stored in the inbox. An event that was already processed is never applied twice",
                 "consumes": [
                     "application/json"
                 ],
@@ -356,23 +424,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "payment"
+                    "webhook"
                 ],
-                "summary": "makeTransfer",
+                "summary": "replayEvent",
                 "parameters": [
                     {
-                        "description": "make transfer request body",
-                        "name": "makeTransferRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/payment.makeTransferRequest"
-                        }
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "webhook event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "transfer successful",
+                        "description": "webhook event replayed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "processing failed again",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "webhook event was already processed or was rejected",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -380,9 +471,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tenant": {
+        "/audit-log/transaction/{id}": {
             "get": {
-                "description": "this endpoint gets all users under a tenant",
+                "description": "this endpoint gets all audit logs by the transaction ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -390,9 +481,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tenant"
+                    "audit-log"
                 ],
-                "summary": "getAllUsersByTenantID",
+                "summary": "getAllAuditLogsByTransactionID",
                 "parameters": [
                     {
                         "type": "string",
@@ -403,8 +494,14 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "page",
-                        "name": "page",
+                        "description": "transaction id",
+                        "name": "id",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
                         "in": "query"
                     },
                     {
@@ -428,15 +525,17 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "audit log fetched successfully",
+                        "description": "audit logs fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "this endpoint create a new tenent",
+            }
+        },
+        "/audit-log/{id}": {
+            "get": {
+                "description": "this endpoint gets an audit log by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -444,23 +543,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tenant"
+                    "audit-log"
                 ],
-                "summary": "createTenant",
+                "summary": "getAuditLogByID",
                 "parameters": [
                     {
-                        "description": "tenant request body",
-                        "name": "tenantRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/tenant.tenantRequest"
-                        }
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "audit id",
+                        "name": "id",
+                        "in": "path"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "tenant created successfully",
+                    "200": {
+                        "description": "audit log fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -468,7 +571,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/tenant/login": {
+        "/auth/login": {
             "post": {
                 "description": "this endpoint is used to log a user in",
                 "consumes": [
@@ -488,13 +591,13 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tenant.loginRequest"
+                            "$ref": "#/definitions/auth.loginRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "tenant logged in successfully",
+                        "description": "user logged in successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -502,9 +605,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/transaction": {
-            "get": {
-                "description": "this endpoint is used to get all transactions belonging to a particular user",
+        "/auth/signup": {
+            "post": {
+                "description": "this endpoint signs up a new user",
                 "consumes": [
                     "application/json"
                 ],
@@ -512,40 +615,109 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transaction"
+                    "auth"
                 ],
-                "summary": "getTransactionsByUserID",
+                "summary": "user signup",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "page",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Bearer \u003ctoken\u003e. Pass in the tenant access token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     },
+                    {
+                        "description": "signup request body",
+                        "name": "signupRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.signupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "signup successful",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/user": {
+            "patch": {
+                "description": "this endpoint is used to update any of the users record",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "updateUserByID",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "size",
-                        "name": "size",
-                        "in": "query"
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     },
+                    {
+                        "description": "update user request body",
+                        "name": "updateUserRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.updateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "user updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/user/{id}": {
+            "get": {
+                "description": "this endpoint gets a user by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "getUserByID",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "sort_by",
-                        "name": "sort_by",
-                        "in": "query",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "sort_direction_desc",
-                        "name": "sort_direction_desc",
-                        "in": "query",
-                        "required": true
+                        "description": "userID",
+                        "name": "id",
+                        "in": "path"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "transactions fetched successfully",
+                        "description": "user details fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -553,9 +725,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/transaction/flow": {
+        "/dispute": {
             "get": {
-                "description": "this endpoint is used to get all transactions belonging to a particular user based on the flow of transaction flow",
+                "description": "this endpoint gets the disputes of the user, or of every user of the tenant, newest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -563,57 +735,47 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transaction"
+                    "dispute"
                 ],
-                "summary": "getAllTransactionsByFlow",
+                "summary": "getDisputes",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "page",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "size",
-                        "name": "size",
+                        "description": "opened, evidence_submitted, won, lost or resolved",
+                        "name": "status",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "sort_by",
-                        "name": "sort_by",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "sort_direction_desc",
-                        "name": "sort_direction_desc",
-                        "in": "query",
-                        "required": true
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "flow",
-                        "name": "flow",
-                        "in": "query",
-                        "required": true
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "transactions fetched successfully",
+                        "description": "disputes fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
                     }
                 }
-            }
-        },
-        "/transaction/{id}": {
-            "get": {
-                "description": "this endpoint gets a transaction by it ID",
+            },
+            "post": {
+                "description": "this endpoint opens a dispute against a successful deposit and freezes the disputed amount on the wallet. Leave out the amount to dispute what is left of the deposit",
                 "consumes": [
                     "application/json"
                 ],
@@ -621,20 +783,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transaction"
+                    "dispute"
                 ],
-                "summary": "getTransactionByID",
+                "summary": "openDispute",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "transactionID",
-                        "name": "id",
-                        "in": "path"
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "open dispute request body",
+                        "name": "openDisputeRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dispute.openDisputeRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "transaction details fetched successfully",
+                    "201": {
+                        "description": "dispute opened successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "transaction already has an open dispute",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "transaction cannot be disputed for this amount",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
@@ -642,9 +826,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/wallet": {
+        "/dispute/{id}": {
             "get": {
-                "description": "this endpoint gets gets a users wallet balance",
+                "description": "this endpoint gets a dispute with its evidence",
                 "consumes": [
                     "application/json"
                 ],
@@ -652,9 +836,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wallet"
+                    "dispute"
                 ],
-                "summary": "getWalletByUserID",
+                "summary": "getDispute",
                 "parameters": [
                     {
                         "type": "string",
@@ -662,66 +846,3800 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "dispute ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "wallet balance fetched successfully",
+                        "description": "dispute fetched successfully",
                         "schema": {
                             "$ref": "#/definitions/model.GenericResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "auth.loginRequest": {
-            "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                }
-            }
         },
-        "auth.signupRequest": {
-            "type": "object",
-            "required": [
-                "email",
-                "firstName",
+        "/dispute/{id}/evidence": {
+            "post": {
+                "description": "this endpoint attaches an evidence note to an open dispute",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dispute"
+                ],
+                "summary": "addEvidence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "dispute ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "evidence request body",
+                        "name": "evidenceRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dispute.evidenceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "evidence submitted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "dispute is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/escrow": {
+            "get": {
+                "description": "this endpoint gets the escrows the user is the buyer or the seller of, or those of every user of the tenant, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "getEscrows",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "held, released or refunded",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "escrows fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint moves funds from the buyer's wallet into escrow for the seller with the email. They stay there until the buyer or their tenant releases them to the seller or refunds them, or until autoReleaseAt, when set, passes and they are released to the seller",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "createEscrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "create escrow request body",
+                        "name": "createEscrowRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/escrow.createEscrowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "escrow created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow debits",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "seller does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/escrow/{id}": {
+            "get": {
+                "description": "this endpoint gets an escrow",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "getEscrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "escrow ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "escrow fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/escrow/{id}/refund": {
+            "post": {
+                "description": "this endpoint releases a held escrow to its seller, or refunds it to its buyer. Only the buyer and their tenant can close an escrow, the seller can only see it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "closeEscrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "escrow ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "escrow closed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow credits, or escrow cannot be closed by you",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "escrow is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/escrow/{id}/release": {
+            "post": {
+                "description": "this endpoint releases a held escrow to its seller, or refunds it to its buyer. Only the buyer and their tenant can close an escrow, the seller can only see it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "closeEscrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "escrow ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "escrow closed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow credits, or escrow cannot be closed by you",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "escrow is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/invoices": {
+            "get": {
+                "description": "this endpoint gets the user's invoices, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invoice"
+                ],
+                "summary": "getInvoices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "open, partially_paid, paid or expired",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "invoices fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint creates an invoice from its line items, its amount is the sum of their quantities times their unit prices. Share the reference it returns with the payer, who pays it through /pay/{reference} before it expires, in full or, when allowPartial is set, in parts",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invoice"
+                ],
+                "summary": "createInvoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "create invoice request body",
+                        "name": "createInvoiceRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/invoice.createInvoiceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "invoice created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid invoice",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/invoices/{id}": {
+            "get": {
+                "description": "this endpoint gets one of the user's invoices with the payments made towards it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invoice"
+                ],
+                "summary": "getInvoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "invoice ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "invoice fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "description": "this endpoint gets the user's notifications, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "summary": "getNotifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only the unread notifications",
+                        "name": "unread",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "notifications fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/{id}/read": {
+            "put": {
+                "description": "this endpoint marks one of the user's notifications read",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "summary": "markRead",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "notification ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "notification marked read",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pay/{reference}": {
+            "get": {
+                "description": "this endpoint gets the invoice shared under the reference as the payer sees it, with what is left to pay",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invoice"
+                ],
+                "summary": "getPaymentLink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "invoice reference",
+                        "name": "reference",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "invoice fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint starts a payment of the invoice shared under the reference through the payment provider. Leave out the amount to pay what is left of the invoice, a smaller amount is only allowed when the invoice allows partial payments. The invoice counts the payment once the provider confirms it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invoice"
+                ],
+                "summary": "payInvoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "invoice reference",
+                        "name": "reference",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "pay invoice request body",
+                        "name": "payInvoiceRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/invoice.payInvoiceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "invoice payment started",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid invoice payment",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "invoice cannot be paid",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/bank-transfer": {
+            "post": {
+                "description": "this endpoint is used to get a one time virtual account that is to be used top up once wallet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "bankTransfer",
+                "parameters": [
+                    {
+                        "description": "bank transfer request body",
+                        "name": "bankTransferRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/payment.bankTransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "bank account created successful",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/deposit": {
+            "post": {
+                "description": "this endpoint is used to make a deposit, set splitId to divide it with one of the user's splits once it succeeds",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "makeDeposit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "deposit request body",
+                        "name": "depositRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/payment.depositRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "wallet top up successful",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow credits",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "transaction limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/fees/quote": {
+            "get": {
+                "description": "this endpoint quotes the fee the users tenant charges for an action on an amount, deposit, transfer, internal_transfer or fx",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "quoteFee",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "deposit, transfer, internal_transfer or fx",
+                        "name": "action",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "amount, in major units",
+                        "name": "amount",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "currency of the amount",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "fee quote fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/internal-transfer": {
+            "post": {
+                "description": "this endpoint instantly moves funds to the wallet of another user, no payment provider is involved",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "internalTransfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "internal transfer request body",
+                        "name": "internalTransferRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/payment.internalTransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transfer successful",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "cross tenant transfer not allowed or wallet restricted",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "recipient not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "insufficient funds or transaction limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/payouts": {
+            "get": {
+                "description": "this endpoint gets the user's payout batches, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "getPayoutBatches",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "payout batches fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint uploads a batch of payouts from the user's wallet in the currency. CSV files have a header row with email, bank_number, account_number, amount and narration, JSON files list the same keys under items. Each row pays either a user by email or a bank account. The batch is refused as a whole when a row is invalid or duplicated, the wallet cannot cover the amounts and fees or the rows break a transaction limit, otherwise its items are paid in the background",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "createPayoutBatch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "wallet currency, e.g. NGN",
+                        "name": "currency",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "payout file, .csv or .json",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "payout batch queued",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid payout batch",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow debits",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "insufficient funds or transaction limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/payouts/{id}": {
+            "get": {
+                "description": "this endpoint gets the summary of one of the user's payout batches, its totals and how many items succeeded or failed so far",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "getPayoutBatch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "payout batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "payout batch fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/payouts/{id}/items": {
+            "get": {
+                "description": "this endpoint gets the items of one of the user's payout batches in the order of the file",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "getPayoutItems",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "payout batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, processing, succeeded or failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "payout items fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/payouts/{id}/result": {
+            "get": {
+                "description": "this endpoint downloads the result of a completed payout batch as a csv file, the rows of the upload with the status, fee, transaction and error of each",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "getPayoutResult",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "payout batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "payout result",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "payout batch has not finished yet",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/transfer": {
+            "post": {
+                "description": "this endpoint is used to make transfer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment"
+                ],
+                "summary": "makeTransfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "make transfer request body",
+                        "name": "makeTransferRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/payment.makeTransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transfer successful",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow debits",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "insufficient funds or transaction limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/splits": {
+            "get": {
+                "description": "this endpoint gets the user's splits, or those of every user of the tenant, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "split"
+                ],
+                "summary": "getSplits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "splits fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint creates a split of the payments into the user's wallet, or into the wallet of the tenant's user with ownerEmail. Shares are fixed amounts or percentages in basis points (1% = 100) of what is credited, paid to users of the tenant by email or to the tenant's revenue wallet, and exactly one share takes what the others leave. Attach the split to a deposit or an invoice with its splitId",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "split"
+                ],
+                "summary": "createSplit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "create split request body",
+                        "name": "createSplitRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/split.createSplitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "split created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid split",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "user does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/splits/{id}": {
+            "get": {
+                "description": "this endpoint gets a split with its shares",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "split"
+                ],
+                "summary": "getSplit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "split ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "split fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant": {
+            "get": {
+                "description": "this endpoint gets all users under a tenant",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getAllUsersByTenantID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort_by",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort_direction_desc",
+                        "name": "sort_direction_desc",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "audit log fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint create a new tenent",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "createTenant",
+                "parameters": [
+                    {
+                        "description": "tenant request body",
+                        "name": "tenantRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.tenantRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "tenant created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/balances": {
+            "get": {
+                "description": "this endpoint returns the sum of the closing balances of the tenants wallets at the end of a day of the tenants timezone, one total per currency",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getBalanceTotals",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "day, YYYY-MM-DD",
+                        "name": "day",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "balances fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "day has not ended yet",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/disputes": {
+            "get": {
+                "description": "this endpoint gets the disputes of the user, or of every user of the tenant, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dispute"
+                ],
+                "summary": "getDisputes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "opened, evidence_submitted, won, lost or resolved",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "disputes fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint opens a dispute against a successful deposit and freezes the disputed amount on the wallet. Leave out the amount to dispute what is left of the deposit",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dispute"
+                ],
+                "summary": "openDispute",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "open dispute request body",
+                        "name": "openDisputeRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dispute.openDisputeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "dispute opened successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "transaction already has an open dispute",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "transaction cannot be disputed for this amount",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/disputes/{id}": {
+            "get": {
+                "description": "this endpoint gets a dispute with its evidence",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dispute"
+                ],
+                "summary": "getDispute",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "dispute ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "dispute fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/disputes/{id}/evidence": {
+            "post": {
+                "description": "this endpoint attaches an evidence note to an open dispute",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dispute"
+                ],
+                "summary": "addEvidence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "dispute ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "evidence request body",
+                        "name": "evidenceRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dispute.evidenceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "evidence submitted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "dispute is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/disputes/{id}/status": {
+            "put": {
+                "description": "this endpoint closes a dispute. A lost dispute debits the disputed amount from the wallet, a won or resolved dispute gives the frozen amount back",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dispute"
+                ],
+                "summary": "decideDispute",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "dispute ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "decide dispute request body",
+                        "name": "decideDisputeRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dispute.decideDisputeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "dispute updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "dispute is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/escrows": {
+            "get": {
+                "description": "this endpoint gets the escrows the user is the buyer or the seller of, or those of every user of the tenant, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "getEscrows",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "held, released or refunded",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "escrows fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/escrows/{id}": {
+            "get": {
+                "description": "this endpoint gets an escrow",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "getEscrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "escrow ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "escrow fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/escrows/{id}/refund": {
+            "post": {
+                "description": "this endpoint releases a held escrow to its seller, or refunds it to its buyer. Only the buyer and their tenant can close an escrow, the seller can only see it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "closeEscrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "escrow ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "escrow closed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow credits, or escrow cannot be closed by you",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "escrow is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/escrows/{id}/release": {
+            "post": {
+                "description": "this endpoint releases a held escrow to its seller, or refunds it to its buyer. Only the buyer and their tenant can close an escrow, the seller can only see it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "escrow"
+                ],
+                "summary": "closeEscrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "escrow ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "escrow closed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow credits, or escrow cannot be closed by you",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "escrow is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/fees": {
+            "get": {
+                "description": "this endpoint returns the fee rules the tenant charges with, or every version of them with all",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getFeeRules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include retired versions",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "fee rules fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "this endpoint sets the fee the tenant charges for an action in a currency, as a new version of the rule. The fee is flat, a percentage plus a flat part, or tiered by amount, clamped between minFee and maxFee, with VAT on top",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "setFeeRule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "fee rule request body",
+                        "name": "feeRuleRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.feeRuleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "fee rule saved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "fee rule was changed at the same time",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/fees/quote": {
+            "get": {
+                "description": "this endpoint quotes the fee the tenant charges for an action on an amount",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "quoteFee",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "deposit, transfer, internal_transfer or fx",
+                        "name": "action",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "amount, in major units",
+                        "name": "amount",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "currency of the amount",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "fee quote fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/fees/{action}": {
+            "delete": {
+                "description": "this endpoint retires the fee rule of an action in a currency, the action is free from then on",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "retireFeeRule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "deposit, transfer, internal_transfer or fx",
+                        "name": "action",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "currency of the rule",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "fee rule retired successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "no fee rule for the action",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/fx-rates": {
+            "get": {
+                "description": "this endpoint gets the fx rates the tenants users convert with, the tenants own rates first and then the default rates",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getFXRates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "fx rates fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "this endpoint sets the rate and spread the tenants users convert a currency pair with",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "setFXRate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "fx rate request body",
+                        "name": "fxRateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.fxRateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "fx rate saved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/limits": {
+            "get": {
+                "description": "this endpoint returns the transaction limits the tenant set for its users",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getTransactionLimits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transaction limits fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "this endpoint sets how much each user of the tenant can move with an action in a currency: the largest single transaction, and how many transactions and what amount per day and per month of the tenants timezone. A cap left out or zero is no cap",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "setTransactionLimit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "transaction limit request body",
+                        "name": "transactionLimitRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.transactionLimitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transaction limit saved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid transaction limit",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/limits/{action}": {
+            "delete": {
+                "description": "this endpoint removes the limit of an action in a currency, the tenants users are not limited for it from then on",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "deleteTransactionLimit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "deposit, transfer, internal_transfer or fx",
+                        "name": "action",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "currency of the limit",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transaction limit removed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "no limit for the action",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/login": {
+            "post": {
+                "description": "this endpoint is used to log a user in",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "login",
+                "parameters": [
+                    {
+                        "description": "login request body",
+                        "name": "loginRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.loginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "tenant logged in successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/revenue": {
+            "get": {
+                "description": "this endpoint returns the balance of the tenants revenue wallets, one per currency, which collect the fees of its users transactions and the spread of their conversions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getRevenueWallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "revenue wallets fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/revenue/daily": {
+            "get": {
+                "description": "this endpoint returns what the tenants revenue wallet in a currency earned and paid out on each day between two days of the tenants timezone, both included",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getRevenueDays",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO-4217 currency of the revenue wallet",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "first day, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "last day, YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "revenue fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid revenue period",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/revenue/history": {
+            "get": {
+                "description": "this endpoint returns the movements of the tenants revenue wallet in a currency, the fees earned and the withdrawals, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getRevenueHistory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO-4217 currency of the revenue wallet",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "revenue history fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "no wallet in this currency",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/revenue/withdrawals": {
+            "get": {
+                "description": "this endpoint returns the withdrawals from the tenants revenue wallets, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getRevenueWithdrawals",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "revenue withdrawals fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint pays out part of the tenants revenue wallet in a currency to the tenants settlement account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "withdrawRevenue",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "revenue withdrawal request body",
+                        "name": "revenueWithdrawalRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.revenueWithdrawalRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "revenue withdrawn successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/splits": {
+            "get": {
+                "description": "this endpoint gets the user's splits, or those of every user of the tenant, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "split"
+                ],
+                "summary": "getSplits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "splits fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint creates a split of the payments into the user's wallet, or into the wallet of the tenant's user with ownerEmail. Shares are fixed amounts or percentages in basis points (1% = 100) of what is credited, paid to users of the tenant by email or to the tenant's revenue wallet, and exactly one share takes what the others leave. Attach the split to a deposit or an invoice with its splitId",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "split"
+                ],
+                "summary": "createSplit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "create split request body",
+                        "name": "createSplitRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/split.createSplitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "split created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid split",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "user does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/splits/{id}": {
+            "get": {
+                "description": "this endpoint gets a split with its shares",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "split"
+                ],
+                "summary": "getSplit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "split ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "split fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "record not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/timezone": {
+            "put": {
+                "description": "this endpoint sets the IANA timezone, e.g. Africa/Lagos, the tenant and its users see dates in, e.g. on wallet statements",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "setTimezone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "timezone request body",
+                        "name": "timezoneRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.timezoneRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "timezone saved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid timezone",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/transactions/{id}/refunds": {
+            "get": {
+                "description": "this endpoint gets the refunds of one of the tenants users transactions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getRefunds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "refunds fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint refunds all or part of a successful deposit of one of the tenants users through the provider that collected it. Leave out the amount to refund what is left to refund, the reference makes the request idempotent",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "refundTransaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "refund request body",
+                        "name": "refundRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.refundRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "refund successful",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow debits",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "reference already used",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "transaction cannot be refunded for this amount",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/transfer-settings": {
+            "put": {
+                "description": "this endpoint sets whether the tenants users may make internal transfers with users of other tenants, a cross tenant transfer needs both tenants to allow it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "setTransferSettings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "transfer settings request body",
+                        "name": "transferSettingsRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.transferSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transfer settings saved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/users/{id}/balance": {
+            "get": {
+                "description": "this endpoint returns the balance of one of the tenants users wallet in a currency at a point in time, every movement made before it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "getUserBalanceAsOf",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "user ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO-4217 currency of the wallet",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 time, now when empty",
+                        "name": "as_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "balance fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "user not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenant/users/{id}/wallets/{currency}/status": {
+            "put": {
+                "description": "this endpoint restricts the wallet of one of the tenants users in a currency: frozen allows no movement, post_no_debit allows credits only, post_no_credit allows debits only and active lifts the restriction. The reason is recorded with the change",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenant"
+                ],
+                "summary": "setWalletStatus",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "user ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO-4217 currency of the wallet",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "wallet status request body",
+                        "name": "walletStatusRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tenant.walletStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "wallet status changed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid wallet status or no wallet for the currency",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "user not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction": {
+            "get": {
+                "description": "this endpoint is used to get all transactions belonging to a particular user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transaction"
+                ],
+                "summary": "getTransactionsByUserID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort_by",
+                        "name": "sort_by",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort_direction_desc",
+                        "name": "sort_direction_desc",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transactions fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction/flow": {
+            "get": {
+                "description": "this endpoint is used to get all transactions belonging to a particular user based on the flow of transaction flow",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transaction"
+                ],
+                "summary": "getAllTransactionsByFlow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort_by",
+                        "name": "sort_by",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort_direction_desc",
+                        "name": "sort_direction_desc",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "flow",
+                        "name": "flow",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transactions fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction/{id}": {
+            "get": {
+                "description": "this endpoint gets a transaction by it ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transaction"
+                ],
+                "summary": "getTransactionByID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "transactionID",
+                        "name": "id",
+                        "in": "path"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transaction details fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet": {
+            "get": {
+                "description": "this endpoint gets a users wallets, one per currency, or the wallet of a single currency",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "getWalletByUserID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO-4217 currency of the wallet, all wallets when empty",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "wallet balance fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "this endpoint opens a wallet in another currency for the user, an existing wallet is returned as is",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "openWallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "open wallet request body",
+                        "name": "openWalletRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wallet.openWalletRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "wallet opened successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet/balance": {
+            "get": {
+                "description": "this endpoint returns the balance of the users wallet in a currency at a point in time, every movement made before it. It is computed from the latest daily snapshot before that time and the movements made since",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "getBalanceAsOf",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO-4217 currency of the wallet",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 time, now when empty",
+                        "name": "as_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "balance fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid as_of or no wallet for the currency",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet/convert": {
+            "post": {
+                "description": "this endpoint converts an amount from one of the users wallets into another of the users wallets at the tenants rate",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "convert",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "a retry with the same key gets the first response replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "convert request body",
+                        "name": "convertRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wallet.convertRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "conversion successful",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "wallet does not allow the conversion",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "insufficient funds or transaction limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet/fx/quote": {
+            "get": {
+                "description": "this endpoint quotes a conversion between two of the users wallets at the tenants rate",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "quoteFX",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "amount to convert, in major units",
+                        "name": "amount",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "currency of the amount",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "currency to convert into",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "fx quote fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet/statement": {
+            "get": {
+                "description": "this endpoint builds the statement of the users wallet in a currency between two days of the tenants timezone, both included: the opening balance, every movement with the balance it left and its fees, and the closing balance. It is returned as json, or downloaded as a csv or pdf file",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/pdf"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "getStatement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO-4217 currency of the wallet",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "first day, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "last day, YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default), csv or pdf",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "statement fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid statement period",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhook/flutterwave": {
+            "post": {
+                "description": "this endpoint receives the webhooks of a payment provider as the provider sends them, signed with its x-paystack-signature or verif-hash header. Paystack's charge.success, transfer.success, transfer.failed and transfer.reversed, and Flutterwave's charge.completed and transfer.completed are applied to the transaction their reference points at, other event types are acknowledged and ignored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "processPayment",
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "processing failed, the provider's redelivery processes it again",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "webhook signature is missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "webhook source is not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "webhook body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhook/paystack": {
+            "post": {
+                "description": "this endpoint receives the webhooks of a payment provider as the provider sends them, signed with its x-paystack-signature or verif-hash header. Paystack's charge.success, transfer.success, transfer.failed and transfer.reversed, and Flutterwave's charge.completed and transfer.completed are applied to the transaction their reference points at, other event types are acknowledged and ignored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "processPayment",
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "processing failed, the provider's redelivery processes it again",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "webhook signature is missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "webhook source is not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "webhook body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenericResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.loginRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.signupRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "firstName",
                 "lastName",
                 "password"
             ],
             "properties": {
-                "email": {
+                "email": {
+                    "type": "string"
+                },
+                "firstName": {
+                    "type": "string"
+                },
+                "lastName": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.updateUserRequest": {
+            "type": "object",
+            "properties": {
+                "firstName": {
+                    "type": "string"
+                },
+                "lastName": {
+                    "type": "string"
+                }
+            }
+        },
+        "dispute.decideDisputeRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 2000
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "won",
+                        "lost",
+                        "resolved"
+                    ]
+                }
+            }
+        },
+        "dispute.evidenceRequest": {
+            "type": "object",
+            "required": [
+                "note"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 2000
+                }
+            }
+        },
+        "dispute.openDisputeRequest": {
+            "type": "object",
+            "required": [
+                "reason",
+                "transactionId"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Amount is left out to dispute whatever is left of the transaction",
+                    "type": "number"
+                },
+                "currency": {
                     "type": "string"
                 },
-                "firstName": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "transactionId": {
+                    "type": "string"
+                }
+            }
+        },
+        "escrow.createEscrowRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "sellerEmail"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "autoReleaseAt": {
+                    "description": "AutoReleaseAt is left out to hold the funds until they are released or refunded",
                     "type": "string"
                 },
-                "lastName": {
+                "currency": {
                     "type": "string"
                 },
-                "password": {
+                "description": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "sellerEmail": {
                     "type": "string"
                 }
             }
         },
-        "auth.updateUserRequest": {
+        "invoice.createInvoiceRequest": {
+            "type": "object",
+            "required": [
+                "currency",
+                "expiresAt",
+                "lineItems",
+                "title"
+            ],
+            "properties": {
+                "allowPartial": {
+                    "type": "boolean"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "maxLength": 2000
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "lineItems": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/invoice.invoiceLineRequest"
+                    }
+                },
+                "splitId": {
+                    "description": "SplitID is one of the user's splits to divide every payment with, left out for none",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "invoice.invoiceLineRequest": {
+            "type": "object",
+            "required": [
+                "description",
+                "quantity",
+                "unitPrice"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "unitPrice": {
+                    "type": "number"
+                }
+            }
+        },
+        "invoice.payInvoiceRequest": {
             "type": "object",
             "properties": {
-                "firstName": {
+                "amount": {
+                    "description": "Amount is left out to pay what is left of the invoice",
+                    "type": "number"
+                },
+                "currency": {
                     "type": "string"
                 },
-                "lastName": {
+                "payerEmail": {
                     "type": "string"
+                },
+                "payerName": {
+                    "type": "string",
+                    "maxLength": 255
                 }
             }
         },
@@ -771,6 +4689,35 @@ const docTemplate = `{
             "properties": {
                 "amount": {
                     "type": "number"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "splitId": {
+                    "description": "SplitID is one of the user's splits to divide the deposit with once it succeeds, left out for none",
+                    "type": "string"
+                }
+            }
+        },
+        "payment.internalTransferRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "recipientEmail"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "narration": {
+                    "type": "string",
+                    "maxLength": 140
+                },
+                "recipientEmail": {
+                    "type": "string"
                 }
             }
         },
@@ -790,6 +4737,163 @@ const docTemplate = `{
                 },
                 "bankNumber": {
                     "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "split.createSplitRequest": {
+            "type": "object",
+            "required": [
+                "currency",
+                "name",
+                "shares"
+            ],
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "ownerEmail": {
+                    "description": "OwnerEmail is the user whose payments the split divides, tenants only",
+                    "type": "string"
+                },
+                "shares": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/split.splitShareRequest"
+                    }
+                }
+            }
+        },
+        "split.splitShareRequest": {
+            "type": "object",
+            "required": [
+                "recipient",
+                "type"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Amount is in major units, on fixed shares only",
+                    "type": "number"
+                },
+                "email": {
+                    "description": "Email is the user a user share is paid to",
+                    "type": "string"
+                },
+                "percentBasisPoints": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 0
+                },
+                "recipient": {
+                    "type": "string",
+                    "enum": [
+                        "user",
+                        "tenant"
+                    ]
+                },
+                "remainder": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "fixed",
+                        "percentage"
+                    ]
+                }
+            }
+        },
+        "tenant.feeRuleRequest": {
+            "type": "object",
+            "required": [
+                "action",
+                "currency",
+                "type"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "flatFee": {
+                    "description": "amounts are in major units, left out for none",
+                    "type": "number"
+                },
+                "maxFee": {
+                    "type": "number"
+                },
+                "minFee": {
+                    "type": "number"
+                },
+                "percentBasisPoints": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 0
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tenant.feeTierRequest"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                },
+                "vatBasisPoints": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 0
+                }
+            }
+        },
+        "tenant.feeTierRequest": {
+            "type": "object",
+            "properties": {
+                "flatFee": {
+                    "type": "number"
+                },
+                "percentBasisPoints": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 0
+                },
+                "upTo": {
+                    "description": "UpTo is left out on the last tier for no upper bound",
+                    "type": "number"
+                }
+            }
+        },
+        "tenant.fxRateRequest": {
+            "type": "object",
+            "required": [
+                "baseCurrency",
+                "quoteCurrency",
+                "rate"
+            ],
+            "properties": {
+                "baseCurrency": {
+                    "type": "string"
+                },
+                "quoteCurrency": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "spreadBasisPoints": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 0
                 }
             }
         },
@@ -808,6 +4912,52 @@ const docTemplate = `{
                 }
             }
         },
+        "tenant.refundRequest": {
+            "type": "object",
+            "required": [
+                "reason",
+                "reference"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Amount is left out to refund whatever is left to refund of the transaction",
+                    "type": "number"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 100
+                }
+            }
+        },
+        "tenant.revenueWithdrawalRequest": {
+            "type": "object",
+            "required": [
+                "accountNumber",
+                "amount",
+                "bankNumber"
+            ],
+            "properties": {
+                "accountNumber": {
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "number"
+                },
+                "bankNumber": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                }
+            }
+        },
         "tenant.tenantRequest": {
             "type": "object",
             "required": [
@@ -824,6 +4974,111 @@ const docTemplate = `{
                 },
                 "password": {
                     "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone is an IANA timezone, Africa/Lagos when left out",
+                    "type": "string"
+                }
+            }
+        },
+        "tenant.timezoneRequest": {
+            "type": "object",
+            "required": [
+                "timezone"
+            ],
+            "properties": {
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "tenant.transactionLimitRequest": {
+            "type": "object",
+            "required": [
+                "action",
+                "currency"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "dailyAmount": {
+                    "type": "number"
+                },
+                "dailyCount": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "maxSingle": {
+                    "description": "caps are left out or zero for no cap, amounts are in major units",
+                    "type": "number"
+                },
+                "monthlyAmount": {
+                    "type": "number"
+                },
+                "monthlyCount": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "tenant.transferSettingsRequest": {
+            "type": "object",
+            "required": [
+                "allowCrossTenantTransfers"
+            ],
+            "properties": {
+                "allowCrossTenantTransfers": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "tenant.walletStatusRequest": {
+            "type": "object",
+            "required": [
+                "reason",
+                "status"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.convertRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "currency",
+                "toCurrency"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "toCurrency": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.openWalletRequest": {
+            "type": "object",
+            "required": [
+                "currency"
+            ],
+            "properties": {
+                "currency": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,308 @@
+package dispute
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+)
+
+type disputeHandler struct {
+	logger      zerolog.Logger
+	controller  controller.Operations
+	environment *environment.Env
+}
+
+// New creates a new instance of the dispute rest handler. Users manage the disputes of their own transactions under
+// /dispute, tenants manage the disputes of all their users under /tenant/disputes and decide them
+func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *environment.Env) {
+	dispute := disputeHandler{
+		logger:      l,
+		controller:  c,
+		environment: env,
+	}
+
+	userAuth := dispute.controller.Middleware().AuthMiddleware()
+	disputeGroup := r.Group("/dispute")
+
+	disputeGroup.POST("", userAuth, dispute.openDispute(model.ActorUser))
+	disputeGroup.GET("", userAuth, dispute.getDisputes(model.ActorUser))
+	disputeGroup.GET("/:id", userAuth, dispute.getDispute(model.ActorUser))
+	disputeGroup.POST("/:id/evidence", userAuth, dispute.addEvidence(model.ActorUser))
+
+	tenantAuth := dispute.controller.Middleware().TenantAuthMiddleware()
+	tenantGroup := r.Group("/tenant/disputes")
+
+	tenantGroup.POST("", tenantAuth, dispute.openDispute(model.ActorTenant))
+	tenantGroup.GET("", tenantAuth, dispute.getDisputes(model.ActorTenant))
+	tenantGroup.GET("/:id", tenantAuth, dispute.getDispute(model.ActorTenant))
+	tenantGroup.POST("/:id/evidence", tenantAuth, dispute.addEvidence(model.ActorTenant))
+	tenantGroup.PUT("/:id/status", tenantAuth, dispute.decideDispute())
+}
+
+// openDispute 	godoc
+//
+//	@Summary		openDispute
+//	@Description	this endpoint opens a dispute against a successful deposit and freezes the disputed amount on the wallet. Leave out the amount to dispute what is left of the deposit
+//	@Tags			dispute
+//	@Param			Authorization		header	string				true	"Bearer <token>"
+//	@Param			openDisputeRequest	body	openDisputeRequest	true	"open dispute request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"dispute opened successfully"
+//	@Failure		409	{object}	restModel.GenericResponse	"transaction already has an open dispute"
+//	@Failure		422	{object}	restModel.GenericResponse	"transaction cannot be disputed for this amount"
+//	@Router			/dispute [post]
+//	@Router			/tenant/disputes [post]
+func (d *disputeHandler) openDispute(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request openDisputeRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			d.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			d.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		actorID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			d.logger.Err(err).Msgf("openDispute ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var amount *model.Money
+		if request.Amount != "" {
+			parsed, err := restModel.ParseAmount(request.Amount, request.Currency)
+			if err != nil {
+				d.logger.Err(err).Msgf("openDispute ::: error parsing amount ==> %s", err)
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			amount = &parsed
+		}
+
+		dispute, err := d.controller.OpenDispute(context.Background(), actor, actorID, uuid.MustParse(request.TransactionID), amount, request.Reason)
+		if err != nil {
+			d.logger.Error().Msgf("openDispute ::: %v", err)
+			restModel.ErrorResponse(c, disputeErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "dispute opened successfully", dispute)
+	}
+}
+
+// getDisputes 	godoc
+//
+//	@Summary		getDisputes
+//	@Description	this endpoint gets the disputes of the user, or of every user of the tenant, newest first
+//	@Tags			dispute
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			status			query	string	false	"opened, evidence_submitted, won, lost or resolved"
+//	@Param			page			query	string	false	"page"
+//	@Param			size			query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"disputes fetched successfully"
+//	@Router			/dispute [get]
+//	@Router			/tenant/disputes [get]
+func (d *disputeHandler) getDisputes(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			d.logger.Err(err).Msgf("getDisputes ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var status *model.DisputeStatus
+		if query := c.Query("status"); query != "" {
+			s := model.DisputeStatus(query)
+			status = &s
+		}
+
+		disputes, pageInfo, err := d.controller.GetDisputes(context.Background(), actor, actorID, status, helper.ParsePageParams(c))
+		if err != nil {
+			d.logger.Error().Msgf("getDisputes ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "disputes fetched successfully", disputes, pageInfo)
+	}
+}
+
+// getDispute 	godoc
+//
+//	@Summary		getDispute
+//	@Description	this endpoint gets a dispute with its evidence
+//	@Tags			dispute
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			id				path	string	true	"dispute ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"dispute fetched successfully"
+//	@Router			/dispute/{id} [get]
+//	@Router			/tenant/disputes/{id} [get]
+func (d *disputeHandler) getDispute(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID, disputeID, ok := d.parseIDs(c, "getDispute")
+		if !ok {
+			return
+		}
+
+		dispute, err := d.controller.GetDispute(context.Background(), actor, actorID, disputeID)
+		if err != nil {
+			d.logger.Error().Msgf("getDispute ::: %v", err)
+			restModel.ErrorResponse(c, disputeErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "dispute fetched successfully", dispute)
+	}
+}
+
+// addEvidence 	godoc
+//
+//	@Summary		addEvidence
+//	@Description	this endpoint attaches an evidence note to an open dispute
+//	@Tags			dispute
+//	@Param			Authorization	header	string			true	"Bearer <token>"
+//	@Param			id				path	string			true	"dispute ID"
+//	@Param			evidenceRequest	body	evidenceRequest	true	"evidence request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"evidence submitted successfully"
+//	@Failure		409	{object}	restModel.GenericResponse	"dispute is already closed"
+//	@Router			/dispute/{id}/evidence [post]
+//	@Router			/tenant/disputes/{id}/evidence [post]
+func (d *disputeHandler) addEvidence(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request evidenceRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			d.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			d.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		actorID, disputeID, ok := d.parseIDs(c, "addEvidence")
+		if !ok {
+			return
+		}
+
+		dispute, err := d.controller.AddDisputeEvidence(context.Background(), actor, actorID, disputeID, request.Note)
+		if err != nil {
+			d.logger.Error().Msgf("addEvidence ::: %v", err)
+			restModel.ErrorResponse(c, disputeErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "evidence submitted successfully", dispute)
+	}
+}
+
+// decideDispute 	godoc
+//
+//	@Summary		decideDispute
+//	@Description	this endpoint closes a dispute. A lost dispute debits the disputed amount from the wallet, a won or resolved dispute gives the frozen amount back
+//	@Tags			dispute
+//	@Param			Authorization			header	string					true	"Bearer <token>"
+//	@Param			id						path	string					true	"dispute ID"
+//	@Param			decideDisputeRequest	body	decideDisputeRequest	true	"decide dispute request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"dispute updated successfully"
+//	@Failure		409	{object}	restModel.GenericResponse	"dispute is already closed"
+//	@Router			/tenant/disputes/{id}/status [put]
+func (d *disputeHandler) decideDispute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request decideDisputeRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			d.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			d.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenantID, disputeID, ok := d.parseIDs(c, "decideDispute")
+		if !ok {
+			return
+		}
+
+		dispute, err := d.controller.DecideDispute(context.Background(), tenantID, disputeID, model.DisputeStatus(request.Status), request.Note)
+		if err != nil {
+			d.logger.Error().Msgf("decideDispute ::: %v", err)
+			restModel.ErrorResponse(c, disputeErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "dispute updated successfully", dispute)
+	}
+}
+
+// parseIDs reads the actor ID from the context and the dispute ID from the path, writing the error response when
+// either is not a valid uuid
+func (d *disputeHandler) parseIDs(c *gin.Context, name string) (uuid.UUID, uuid.UUID, bool) {
+	actorID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+	if err != nil {
+		d.logger.Err(err).Msgf("%s ::: error parsing uuid ==> %s", name, err)
+		restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		d.logger.Err(err).Msgf("%s ::: error parsing dispute id ==> %s", name, err)
+		restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return actorID, disputeID, true
+}
+
+// disputeErrorStatus maps a dispute error to its http status
+func disputeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrDisputeAlreadyOpen), errors.Is(err, controller.ErrDisputeClosed):
+		return http.StatusConflict
+	case errors.Is(err, controller.ErrTransactionNotDisputable), errors.Is(err, controller.ErrDisputeExceedsAmount):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, controller.ErrInvalidDisputeStatus), errors.Is(err, model.ErrCurrencyMismatch),
+		errors.Is(err, model.ErrInvalidAmount), errors.Is(err, controller.ErrNoWalletForCurrency):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
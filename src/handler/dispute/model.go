@@ -0,0 +1,24 @@
+package dispute
+
+import (
+	"encoding/json"
+)
+
+type (
+	openDisputeRequest struct {
+		TransactionID string `json:"transactionId" validate:"required,uuid"`
+		// Amount is left out to dispute whatever is left of the transaction
+		Amount   json.Number `json:"amount" swaggertype:"number"`
+		Currency string      `json:"currency"`
+		Reason   string      `json:"reason" validate:"required,max=500"`
+	}
+
+	evidenceRequest struct {
+		Note string `json:"note" validate:"required,max=2000"`
+	}
+
+	decideDisputeRequest struct {
+		Status string `json:"status" validate:"required,oneof=won lost resolved"`
+		Note   string `json:"note" validate:"max=2000"`
+	}
+)
@@ -0,0 +1,264 @@
+// Package escrow exposes the escrows buyers put funds in for sellers, to the buyers and sellers and to their tenant
+package escrow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+)
+
+type escrowHandler struct {
+	logger      zerolog.Logger
+	controller  controller.Operations
+	environment *environment.Env
+}
+
+// New creates a new instance of the escrow rest handler. Buyers create and close their escrows under /escrow, where
+// sellers see theirs too, tenants see and close the escrows of all their users under /tenant/escrows
+func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *environment.Env) {
+	escrow := escrowHandler{
+		logger:      l,
+		controller:  c,
+		environment: env,
+	}
+
+	userAuth := escrow.controller.Middleware().AuthMiddleware()
+	idempotent := escrow.controller.Middleware().IdempotencyMiddleware(escrow.controller)
+	escrowGroup := r.Group("/escrow")
+
+	escrowGroup.POST("", userAuth, idempotent, escrow.createEscrow())
+	escrowGroup.GET("", userAuth, escrow.getEscrows(model.ActorUser))
+	escrowGroup.GET("/:id", userAuth, escrow.getEscrow(model.ActorUser))
+	escrowGroup.POST("/:id/release", userAuth, idempotent, escrow.closeEscrow(model.ActorUser, model.EscrowStatusReleased))
+	escrowGroup.POST("/:id/refund", userAuth, idempotent, escrow.closeEscrow(model.ActorUser, model.EscrowStatusRefunded))
+
+	tenantAuth := escrow.controller.Middleware().TenantAuthMiddleware()
+	tenantGroup := r.Group("/tenant/escrows")
+
+	tenantGroup.GET("", tenantAuth, escrow.getEscrows(model.ActorTenant))
+	tenantGroup.GET("/:id", tenantAuth, escrow.getEscrow(model.ActorTenant))
+	tenantGroup.POST("/:id/release", tenantAuth, idempotent, escrow.closeEscrow(model.ActorTenant, model.EscrowStatusReleased))
+	tenantGroup.POST("/:id/refund", tenantAuth, idempotent, escrow.closeEscrow(model.ActorTenant, model.EscrowStatusRefunded))
+}
+
+// createEscrow 	godoc
+//
+//	@Summary		createEscrow
+//	@Description	this endpoint moves funds from the buyer's wallet into escrow for the seller with the email. They stay there until the buyer or their tenant releases them to the seller or refunds them, or until autoReleaseAt, when set, passes and they are released to the seller
+//	@Tags			escrow
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
+//	@Param			Authorization		header	string				true	"Bearer <token>"
+//	@Param			createEscrowRequest	body	createEscrowRequest	true	"create escrow request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"escrow created successfully"
+//	@Failure		403	{object}	restModel.GenericResponse	"wallet does not allow debits"
+//	@Failure		404	{object}	restModel.GenericResponse	"seller does not exist"
+//	@Failure		422	{object}	restModel.GenericResponse	"insufficient funds"
+//	@Router			/escrow [post]
+func (h *escrowHandler) createEscrow() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request createEscrowRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		amount, err := restModel.ParseAmount(request.Amount, request.Currency)
+		if err != nil {
+			h.logger.Err(err).Msgf("createEscrow ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		buyerID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("createEscrow ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		escrow, err := h.controller.CreateEscrow(context.Background(), buyerID, request.SellerEmail, amount, request.Description, request.AutoReleaseAt)
+		if err != nil {
+			h.logger.Error().Msgf("createEscrow ::: %v", err)
+			restModel.ErrorResponse(c, escrowErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "escrow created successfully", escrow)
+	}
+}
+
+// getEscrows 	godoc
+//
+//	@Summary		getEscrows
+//	@Description	this endpoint gets the escrows the user is the buyer or the seller of, or those of every user of the tenant, newest first
+//	@Tags			escrow
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			status			query	string	false	"held, released or refunded"
+//	@Param			page			query	string	false	"page"
+//	@Param			size			query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"escrows fetched successfully"
+//	@Router			/escrow [get]
+//	@Router			/tenant/escrows [get]
+func (h *escrowHandler) getEscrows(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("getEscrows ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var status *model.EscrowStatus
+		if query := c.Query("status"); query != "" {
+			s := model.EscrowStatus(query)
+			status = &s
+		}
+
+		escrows, pageInfo, err := h.controller.GetEscrows(context.Background(), actor, actorID, status, helper.ParsePageParams(c))
+		if err != nil {
+			h.logger.Error().Msgf("getEscrows ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "escrows fetched successfully", escrows, pageInfo)
+	}
+}
+
+// getEscrow 	godoc
+//
+//	@Summary		getEscrow
+//	@Description	this endpoint gets an escrow
+//	@Tags			escrow
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			id				path	string	true	"escrow ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"escrow fetched successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Router			/escrow/{id} [get]
+//	@Router			/tenant/escrows/{id} [get]
+func (h *escrowHandler) getEscrow(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID, escrowID, ok := h.parseIDs(c, "getEscrow")
+		if !ok {
+			return
+		}
+
+		escrow, err := h.controller.GetEscrow(context.Background(), actor, actorID, escrowID)
+		if err != nil {
+			h.logger.Error().Msgf("getEscrow ::: %v", err)
+			restModel.ErrorResponse(c, escrowErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "escrow fetched successfully", escrow)
+	}
+}
+
+// closeEscrow 	godoc
+//
+//	@Summary		closeEscrow
+//	@Description	this endpoint releases a held escrow to its seller, or refunds it to its buyer. Only the buyer and their tenant can close an escrow, the seller can only see it
+//	@Tags			escrow
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			id				path	string	true	"escrow ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"escrow closed successfully"
+//	@Failure		403	{object}	restModel.GenericResponse	"wallet does not allow credits, or escrow cannot be closed by you"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Failure		409	{object}	restModel.GenericResponse	"escrow is already closed"
+//	@Router			/escrow/{id}/release [post]
+//	@Router			/escrow/{id}/refund [post]
+//	@Router			/tenant/escrows/{id}/release [post]
+//	@Router			/tenant/escrows/{id}/refund [post]
+func (h *escrowHandler) closeEscrow(actor model.Actor, status model.EscrowStatus) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID, escrowID, ok := h.parseIDs(c, "closeEscrow")
+		if !ok {
+			return
+		}
+
+		var (
+			escrow model.Escrow
+			err    error
+		)
+
+		if status == model.EscrowStatusReleased {
+			escrow, err = h.controller.ReleaseEscrow(context.Background(), actor, actorID, escrowID)
+		} else {
+			escrow, err = h.controller.RefundEscrow(context.Background(), actor, actorID, escrowID)
+		}
+		if err != nil {
+			h.logger.Error().Msgf("closeEscrow ::: %v", err)
+			restModel.ErrorResponse(c, escrowErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "escrow closed successfully", escrow)
+	}
+}
+
+// parseIDs reads the actor and the escrow of a request, it responds with a bad request when either is invalid
+func (h *escrowHandler) parseIDs(c *gin.Context, name string) (uuid.UUID, uuid.UUID, bool) {
+	actorID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+	if err != nil {
+		h.logger.Err(err).Msgf("%s ::: error parsing uuid ==> %s", name, err)
+		restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	escrowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.logger.Err(err).Msgf("%s ::: error parsing escrow id ==> %s", name, err)
+		restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return actorID, escrowID, true
+}
+
+// escrowErrorStatus maps an escrow error to its http status
+func escrowErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound), errors.Is(err, controller.ErrUserDoesNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrEscrowClosed):
+		return http.StatusConflict
+	case errors.Is(err, controller.ErrInsufficientFunds):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, controller.ErrCrossTenantTransfer), errors.Is(err, controller.ErrWalletDebitBlocked),
+		errors.Is(err, controller.ErrWalletCreditBlocked), errors.Is(err, controller.ErrEscrowCloseNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, controller.ErrSelfEscrow), errors.Is(err, controller.ErrInvalidEscrowDeadline),
+		errors.Is(err, controller.ErrNoWalletForCurrency):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
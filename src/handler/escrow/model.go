@@ -0,0 +1,17 @@
+package escrow
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	createEscrowRequest struct {
+		SellerEmail string      `json:"sellerEmail" validate:"required,email"`
+		Amount      json.Number `json:"amount" validate:"required" swaggertype:"number"`
+		Currency    string      `json:"currency"`
+		Description string      `json:"description" validate:"max=500"`
+		// AutoReleaseAt is left out to hold the funds until they are released or refunded
+		AutoReleaseAt *time.Time `json:"autoReleaseAt"`
+	}
+)
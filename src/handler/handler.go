@@ -8,8 +8,15 @@ import (
 	"codematic/controller"
 	auditLog "codematic/handler/auditLog"
 	"codematic/handler/auth"
+	"codematic/handler/dispute"
 	"codematic/handler/docs"
+	"codematic/handler/escrow"
+	"codematic/handler/invoice"
+	"codematic/handler/ledger"
+	"codematic/handler/notification"
 	"codematic/handler/payment"
+	"codematic/handler/reconciliation"
+	"codematic/handler/split"
 	"codematic/handler/tenant"
 	"codematic/handler/transaction"
 	"codematic/handler/wallet"
@@ -49,5 +56,12 @@ func (h *Handler) Build() {
 	wallet.New(v1, *h.logger, h.application, h.env)
 	tenant.New(v1, *h.logger, h.application, h.env)
 	payment.New(v1, *h.logger, h.application, h.env)
+	dispute.New(v1, *h.logger, h.application, h.env)
+	reconciliation.New(v1, *h.logger, h.application, h.env)
+	ledger.New(v1, *h.logger, h.application, h.env)
+	invoice.New(v1, *h.logger, h.application, h.env)
+	notification.New(v1, *h.logger, h.application, h.env)
+	escrow.New(v1, *h.logger, h.application, h.env)
+	split.New(v1, *h.logger, h.application, h.env)
 	docs.New(v1)
 }
@@ -0,0 +1,280 @@
+// Package invoice exposes the invoices users share with payers outside the platform, and the payment links payers
+// pay them through
+package invoice
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+)
+
+type invoiceHandler struct {
+	logger      zerolog.Logger
+	controller  controller.Operations
+	environment *environment.Env
+}
+
+// New creates a new instance of the invoice rest handler. Users manage their invoices under /invoices, payers view
+// and pay an invoice by its reference under /pay without an account
+func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *environment.Env) {
+	invoice := invoiceHandler{
+		logger:      l,
+		controller:  c,
+		environment: env,
+	}
+
+	invoiceGroup := r.Group("/invoices", invoice.controller.Middleware().AuthMiddleware())
+
+	invoiceGroup.POST("", invoice.createInvoice())
+	invoiceGroup.GET("", invoice.getInvoices())
+	invoiceGroup.GET("/:id", invoice.getInvoice())
+
+	payGroup := r.Group("/pay")
+
+	payGroup.GET("/:reference", invoice.getPaymentLink())
+	payGroup.POST("/:reference", invoice.payInvoice())
+}
+
+// createInvoice 	godoc
+//
+//	@Summary		createInvoice
+//	@Description	this endpoint creates an invoice from its line items, its amount is the sum of their quantities times their unit prices. Share the reference it returns with the payer, who pays it through /pay/{reference} before it expires, in full or, when allowPartial is set, in parts
+//	@Tags			invoice
+//	@Param			Authorization			header	string					true	"Bearer <token>"
+//	@Param			createInvoiceRequest	body	createInvoiceRequest	true	"create invoice request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"invoice created successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid invoice"
+//	@Router			/invoices [post]
+func (h *invoiceHandler) createInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request createInvoiceRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("createInvoice ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		invoice, err := request.toModel()
+		if err != nil {
+			h.logger.Err(err).Msgf("createInvoice ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		invoice, err = h.controller.CreateInvoice(context.Background(), userID, invoice)
+		if err != nil {
+			h.logger.Error().Msgf("createInvoice ::: %v", err)
+			restModel.ErrorResponse(c, invoiceErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "invoice created successfully", invoice)
+	}
+}
+
+// getInvoices 	godoc
+//
+//	@Summary		getInvoices
+//	@Description	this endpoint gets the user's invoices, newest first
+//	@Tags			invoice
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			status			query	string	false	"open, partially_paid, paid or expired"
+//	@Param			page			query	string	false	"page"
+//	@Param			size			query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"invoices fetched successfully"
+//	@Router			/invoices [get]
+func (h *invoiceHandler) getInvoices() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("getInvoices ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var status *model.InvoiceStatus
+		if query := c.Query("status"); query != "" {
+			s := model.InvoiceStatus(query)
+			status = &s
+		}
+
+		invoices, pageInfo, err := h.controller.GetInvoices(context.Background(), userID, status, helper.ParsePageParams(c))
+		if err != nil {
+			h.logger.Error().Msgf("getInvoices ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "invoices fetched successfully", invoices, pageInfo)
+	}
+}
+
+// getInvoice 	godoc
+//
+//	@Summary		getInvoice
+//	@Description	this endpoint gets one of the user's invoices with the payments made towards it
+//	@Tags			invoice
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			id				path	string	true	"invoice ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"invoice fetched successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Router			/invoices/{id} [get]
+func (h *invoiceHandler) getInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("getInvoice ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		invoiceID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			h.logger.Err(err).Msgf("getInvoice ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		invoice, err := h.controller.GetInvoice(context.Background(), userID, invoiceID)
+		if err != nil {
+			h.logger.Error().Msgf("getInvoice ::: %v", err)
+			restModel.ErrorResponse(c, invoiceErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "invoice fetched successfully", invoice)
+	}
+}
+
+// getPaymentLink 	godoc
+//
+//	@Summary		getPaymentLink
+//	@Description	this endpoint gets the invoice shared under the reference as the payer sees it, with what is left to pay
+//	@Tags			invoice
+//	@Param			reference	path	string	true	"invoice reference"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"invoice fetched successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Router			/pay/{reference} [get]
+func (h *invoiceHandler) getPaymentLink() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoice, err := h.controller.GetInvoiceByReference(context.Background(), c.Param("reference"))
+		if err != nil {
+			h.logger.Error().Msgf("getPaymentLink ::: %v", err)
+			restModel.ErrorResponse(c, invoiceErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "invoice fetched successfully", paymentLinkResponse{
+			Invoice:     invoice,
+			Outstanding: invoice.Outstanding(),
+		})
+	}
+}
+
+// payInvoice 	godoc
+//
+//	@Summary		payInvoice
+//	@Description	this endpoint starts a payment of the invoice shared under the reference through the payment provider. Leave out the amount to pay what is left of the invoice, a smaller amount is only allowed when the invoice allows partial payments. The invoice counts the payment once the provider confirms it
+//	@Tags			invoice
+//	@Param			reference			path	string				true	"invoice reference"
+//	@Param			payInvoiceRequest	body	payInvoiceRequest	true	"pay invoice request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"invoice payment started"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid invoice payment"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Failure		409	{object}	restModel.GenericResponse	"invoice cannot be paid"
+//	@Router			/pay/{reference} [post]
+func (h *invoiceHandler) payInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request payInvoiceRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var amount *model.Money
+		if request.Amount != "" {
+			parsed, err := restModel.ParseAmount(request.Amount, request.Currency)
+			if err != nil {
+				h.logger.Err(err).Msgf("payInvoice ::: error parsing amount ==> %s", err)
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			amount = &parsed
+		}
+
+		payment, err := h.controller.PayInvoice(context.Background(), c.Param("reference"), request.PayerName, request.PayerEmail, amount)
+		if err != nil {
+			h.logger.Error().Msgf("payInvoice ::: %v", err)
+			restModel.ErrorResponse(c, invoiceErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "invoice payment started", paymentResponse{
+			InvoicePayment: payment,
+			Reference:      "crt_" + payment.TransactionID.String(),
+		})
+	}
+}
+
+// invoiceErrorStatus maps an invoice error to its http status
+func invoiceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrInvalidInvoice), errors.Is(err, model.ErrUnsupportedCurrency),
+		errors.Is(err, model.ErrCurrencyMismatch), errors.Is(err, controller.ErrInvalidInvoicePayment),
+		errors.Is(err, controller.ErrFeeExceedsAmount), errors.Is(err, controller.ErrSplitNotUsable):
+		return http.StatusBadRequest
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrInvoiceNotPayable):
+		return http.StatusConflict
+	case errors.Is(err, controller.ErrWalletCreditBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, controller.ErrLimitExceeded):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,78 @@
+package invoice
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	restModel "codematic/handler/model"
+	"codematic/model"
+)
+
+type (
+	createInvoiceRequest struct {
+		Title        string               `json:"title" validate:"required,max=255"`
+		Description  string               `json:"description" validate:"max=2000"`
+		Currency     string               `json:"currency" validate:"required,len=3"`
+		AllowPartial bool                 `json:"allowPartial"`
+		ExpiresAt    time.Time            `json:"expiresAt" validate:"required"`
+		LineItems    []invoiceLineRequest `json:"lineItems" validate:"required,min=1,max=100,dive"`
+		// SplitID is one of the user's splits to divide every payment with, left out for none
+		SplitID *uuid.UUID `json:"splitId" swaggertype:"string"`
+	}
+
+	invoiceLineRequest struct {
+		Description string      `json:"description" validate:"required,max=255"`
+		Quantity    int64       `json:"quantity" validate:"required,min=1"`
+		UnitPrice   json.Number `json:"unitPrice" validate:"required" swaggertype:"number"`
+	}
+
+	payInvoiceRequest struct {
+		PayerName  string `json:"payerName" validate:"max=255"`
+		PayerEmail string `json:"payerEmail" validate:"omitempty,email"`
+		// Amount is left out to pay what is left of the invoice
+		Amount   json.Number `json:"amount" swaggertype:"number"`
+		Currency string      `json:"currency" validate:"required_with=Amount"`
+	}
+)
+
+func (i *createInvoiceRequest) toModel() (model.Invoice, error) {
+	invoice := model.Invoice{
+		Title:        i.Title,
+		Description:  i.Description,
+		Currency:     i.Currency,
+		AllowPartial: i.AllowPartial,
+		ExpiresAt:    i.ExpiresAt,
+		SplitID:      i.SplitID,
+	}
+
+	for _, line := range i.LineItems {
+		unitPrice, err := restModel.ParseAmount(line.UnitPrice, i.Currency)
+		if err != nil {
+			return model.Invoice{}, err
+		}
+
+		invoice.LineItems = append(invoice.LineItems, model.InvoiceLineItem{
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			UnitPrice:   unitPrice,
+		})
+	}
+
+	return invoice, nil
+}
+
+type (
+	// paymentLinkResponse is an invoice as its payer sees it
+	paymentLinkResponse struct {
+		model.Invoice
+		Outstanding model.Money `json:"outstanding"`
+	}
+
+	// paymentResponse is a payment the payer started, Reference is what the provider knows it by
+	paymentResponse struct {
+		model.InvoicePayment
+		Reference string `json:"reference"`
+	}
+)
@@ -0,0 +1,106 @@
+// Package ledger exposes the verifications of the wallets' balance history against the ledger to the operators of
+// the platform
+package ledger
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+)
+
+type ledgerHandler struct {
+	logger      zerolog.Logger
+	controller  controller.Operations
+	environment *environment.Env
+}
+
+// New creates a new instance of the ledger rest handler, its endpoints are for admins only
+func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *environment.Env) {
+	ledger := ledgerHandler{
+		logger:      l,
+		controller:  c,
+		environment: env,
+	}
+
+	ledgerGroup := r.Group("/admin/ledger", ledger.controller.Middleware().AdminAuthMiddleware())
+
+	ledgerGroup.POST("/verifications", ledger.verify())
+	ledgerGroup.GET("/verifications", ledger.getVerifications())
+}
+
+// verify 	godoc
+//
+//	@Summary		verify
+//	@Description	this endpoint verifies the balance history of a tenant's wallets against their ledger accounts and settled transactions and saves the report. With repair=true, wallets whose latest balance entry disagrees with their ledger account get an adjustment entry
+//	@Tags			ledger
+//	@Param			X-Admin-Key	header	string	true	"admin api key"
+//	@Param			tenant_id	query	string	true	"tenant ID"
+//	@Param			repair		query	bool	false	"write adjustment entries"
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"ledger verified"
+//	@Router			/admin/ledger/verifications [post]
+func (h *ledgerHandler) verify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.Query("tenant_id"))
+		if err != nil {
+			h.logger.Err(err).Msgf("verify ::: error parsing tenant id ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		verification, err := h.controller.VerifyTenantLedger(context.Background(), tenantID, c.Query("repair") == "true")
+		if err != nil {
+			h.logger.Error().Msgf("verify ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "ledger verified", verification)
+	}
+}
+
+// getVerifications 	godoc
+//
+//	@Summary		getVerifications
+//	@Description	this endpoint gets the ledger verifications with the breaks they found, newest first
+//	@Tags			ledger
+//	@Param			X-Admin-Key	header	string	true	"admin api key"
+//	@Param			tenant_id	query	string	false	"tenant ID"
+//	@Param			page		query	string	false	"page"
+//	@Param			size		query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"ledger verifications fetched successfully"
+//	@Router			/admin/ledger/verifications [get]
+func (h *ledgerHandler) getVerifications() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tenantID *uuid.UUID
+		if query := c.Query("tenant_id"); query != "" {
+			id, err := uuid.Parse(query)
+			if err != nil {
+				h.logger.Err(err).Msgf("getVerifications ::: error parsing tenant id ==> %s", err)
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			tenantID = &id
+		}
+
+		verifications, pageInfo, err := h.controller.GetLedgerVerifications(context.Background(), tenantID, helper.ParsePageParams(c))
+		if err != nil {
+			h.logger.Error().Msgf("getVerifications ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "ledger verifications fetched successfully", verifications, pageInfo)
+	}
+}
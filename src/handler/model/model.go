@@ -3,13 +3,30 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+
+	coreModel "codematic/model"
 )
 
+// ParseAmount parses a requested amount in major units into minor units, amounts must be greater than zero
+func ParseAmount(amount json.Number, currency string) (coreModel.Money, error) {
+	money, err := coreModel.ParseMoney(amount.String(), currency)
+	if err != nil {
+		return coreModel.Money{}, err
+	}
+
+	if !money.IsPositive() {
+		return coreModel.Money{}, coreModel.ErrInvalidAmount
+	}
+
+	return money, nil
+}
+
 // ValidateRequest validates an incomming request object
 func ValidateRequest(request interface{}) error {
 	validate := validator.New()
@@ -0,0 +1,117 @@
+// Package notification exposes the notifications a user is sent, e.g. when an invoice of theirs is paid
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+)
+
+type notificationHandler struct {
+	logger      zerolog.Logger
+	controller  controller.Operations
+	environment *environment.Env
+}
+
+// New creates a new instance of the notification rest handler
+func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *environment.Env) {
+	notification := notificationHandler{
+		logger:      l,
+		controller:  c,
+		environment: env,
+	}
+
+	notificationGroup := r.Group("/notifications", notification.controller.Middleware().AuthMiddleware())
+
+	notificationGroup.GET("", notification.getNotifications())
+	notificationGroup.PUT("/:id/read", notification.markRead())
+}
+
+// getNotifications 	godoc
+//
+//	@Summary		getNotifications
+//	@Description	this endpoint gets the user's notifications, newest first
+//	@Tags			notification
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			unread			query	bool	false	"only the unread notifications"
+//	@Param			page			query	string	false	"page"
+//	@Param			size			query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"notifications fetched successfully"
+//	@Router			/notifications [get]
+func (h *notificationHandler) getNotifications() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("getNotifications ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		unreadOnly := c.Query("unread") == "true"
+
+		notifications, pageInfo, err := h.controller.GetNotifications(context.Background(), userID, unreadOnly, helper.ParsePageParams(c))
+		if err != nil {
+			h.logger.Error().Msgf("getNotifications ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "notifications fetched successfully", notifications, pageInfo)
+	}
+}
+
+// markRead 	godoc
+//
+//	@Summary		markRead
+//	@Description	this endpoint marks one of the user's notifications read
+//	@Tags			notification
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			id				path	string	true	"notification ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"notification marked read"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Router			/notifications/{id}/read [put]
+func (h *notificationHandler) markRead() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("markRead ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		notificationID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			h.logger.Err(err).Msgf("markRead ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := h.controller.MarkNotificationRead(context.Background(), userID, notificationID); err != nil {
+			h.logger.Error().Msgf("markRead ::: %v", err)
+
+			if errors.Is(err, controller.ErrRecordNotFound) {
+				restModel.ErrorResponse(c, http.StatusNotFound, err.Error())
+				return
+			}
+
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "notification marked read", nil)
+	}
+}
@@ -1,14 +1,31 @@
 package payment
 
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
 type (
 	depositRequest struct {
-		Amount float64 `json:"amount" validate:"required"`
+		Amount   json.Number `json:"amount" validate:"required" swaggertype:"number"`
+		Currency string      `json:"currency"`
+		// SplitID is one of the user's splits to divide the deposit with once it succeeds, left out for none
+		SplitID *uuid.UUID `json:"splitId" swaggertype:"string"`
 	}
 
 	makeTransferRequest struct {
-		BankNumber    string  `json:"bankNumber" validate:"required"`
-		AccountNumber string  `json:"accountNumber" validate:"required"`
-		Amount        float64 `json:"amount" validate:"required"`
+		BankNumber    string      `json:"bankNumber" validate:"required"`
+		AccountNumber string      `json:"accountNumber" validate:"required"`
+		Amount        json.Number `json:"amount" validate:"required" swaggertype:"number"`
+		Currency      string      `json:"currency"`
+	}
+
+	internalTransferRequest struct {
+		RecipientEmail string      `json:"recipientEmail" validate:"required,email"`
+		Amount         json.Number `json:"amount" validate:"required" swaggertype:"number"`
+		Currency       string      `json:"currency"`
+		Narration      string      `json:"narration" validate:"max=140"`
 	}
 
 	bankTransferRequest struct {
@@ -16,3 +33,9 @@ type (
 		BankName string `json:"bankName" validate:"required"`
 	}
 )
+
+type (
+	payoutBatchRequest struct {
+		Currency string `form:"currency" validate:"required,len=3"`
+	}
+)
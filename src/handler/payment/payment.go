@@ -2,6 +2,8 @@ package payment
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +12,7 @@ import (
 
 	"codematic/controller"
 	restModel "codematic/handler/model"
+	"codematic/model"
 	"codematic/pkg/environment"
 	"codematic/pkg/middleware"
 )
@@ -29,21 +32,35 @@ func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *env
 		environment: env,
 	}
 	paymentGroup := r.Group("/payment")
+	idempotent := payment.controller.Middleware().IdempotencyMiddleware(payment.controller)
 
-	paymentGroup.POST("/deposit", payment.controller.Middleware().AuthMiddleware(), payment.makeDeposit())
-	paymentGroup.POST("/transfer", payment.controller.Middleware().AuthMiddleware(), payment.makeTransfer())
+	paymentGroup.POST("/deposit", payment.controller.Middleware().AuthMiddleware(), idempotent, payment.makeDeposit())
+	paymentGroup.POST("/transfer", payment.controller.Middleware().AuthMiddleware(), idempotent, payment.makeTransfer())
+	paymentGroup.POST("/internal-transfer", payment.controller.Middleware().AuthMiddleware(), idempotent, payment.internalTransfer())
 	paymentGroup.POST("/bank-transfer", payment.controller.Middleware().AuthMiddleware(), payment.bankTransfer())
+	paymentGroup.GET("/fees/quote", payment.controller.Middleware().AuthMiddleware(), payment.quoteFee())
+
+	payoutGroup := paymentGroup.Group("/payouts", payment.controller.Middleware().AuthMiddleware())
+
+	payoutGroup.POST("", idempotent, payment.createPayoutBatch())
+	payoutGroup.GET("", payment.getPayoutBatches())
+	payoutGroup.GET("/:id", payment.getPayoutBatch())
+	payoutGroup.GET("/:id/items", payment.getPayoutItems())
+	payoutGroup.GET("/:id/result", payment.getPayoutResult())
 }
 
 // makeDeposit 	godoc
 //
 //	@Summary		makeDeposit
-//	@Description	this endpoint is used to make a deposit
+//	@Description	this endpoint is used to make a deposit, set splitId to divide it with one of the user's splits once it succeeds
 //	@Tags			payment
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
 //	@Accept			json
 //	@Produce		json
 //	@Param			depositRequest	body		depositRequest				true	"deposit request body"
 //	@Success		200				{object}	restModel.GenericResponse	"wallet top up successful"
+//	@Failure		403				{object}	restModel.GenericResponse	"wallet does not allow credits"
+//	@Failure		422				{object}	restModel.GenericResponse	"transaction limit exceeded"
 //	@Router			/payment/deposit [post]
 func (p *paymentHandler) makeDeposit() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -62,6 +79,13 @@ func (p *paymentHandler) makeDeposit() gin.HandlerFunc {
 			return
 		}
 
+		amount, err := restModel.ParseAmount(request.Amount, request.Currency)
+		if err != nil {
+			p.logger.Err(err).Msgf("makeDeposit ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
 		if err != nil {
 			p.logger.Err(err).Msgf("makeDeposit ::: error parsing uuid ==> %s", err)
@@ -69,8 +93,24 @@ func (p *paymentHandler) makeDeposit() gin.HandlerFunc {
 			return
 		}
 
-		if err := p.controller.Deposit(context.Background(), userID, request.Amount); err != nil {
+		if err := p.controller.Deposit(context.Background(), userID, amount, request.SplitID); err != nil {
 			p.logger.Error().Msgf("makeDeposit ::: %v", err)
+
+			if errors.Is(err, controller.ErrFeeExceedsAmount) || errors.Is(err, controller.ErrSplitNotUsable) {
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			if errors.Is(err, controller.ErrLimitExceeded) {
+				restModel.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+
+			if errors.Is(err, controller.ErrWalletCreditBlocked) {
+				restModel.ErrorResponse(c, http.StatusForbidden, err.Error())
+				return
+			}
+
 			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -84,10 +124,13 @@ func (p *paymentHandler) makeDeposit() gin.HandlerFunc {
 //	@Summary		makeTransfer
 //	@Description	this endpoint is used to make transfer
 //	@Tags			payment
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
 //	@Accept			json
 //	@Produce		json
 //	@Param			makeTransferRequest	body		makeTransferRequest				true	"make transfer request body"
 //	@Success		200				{object}	restModel.GenericResponse	"transfer successful"
+//	@Failure		403				{object}	restModel.GenericResponse	"wallet does not allow debits"
+//	@Failure		422				{object}	restModel.GenericResponse	"insufficient funds or transaction limit exceeded"
 //	@Router			/payment/transfer [post]
 func (p *paymentHandler) makeTransfer() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -106,6 +149,13 @@ func (p *paymentHandler) makeTransfer() gin.HandlerFunc {
 			return
 		}
 
+		amount, err := restModel.ParseAmount(request.Amount, request.Currency)
+		if err != nil {
+			p.logger.Err(err).Msgf("makeTransfer ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
 		if err != nil {
 			p.logger.Err(err).Msgf("makeTransfer ::: error parsing uuid ==> %s", err)
@@ -113,8 +163,24 @@ func (p *paymentHandler) makeTransfer() gin.HandlerFunc {
 			return
 		}
 
-		if err := p.controller.Transfer(context.Background(), userID, request.BankNumber, request.AccountNumber, request.Amount); err != nil {
+		if err := p.controller.Transfer(context.Background(), userID, request.BankNumber, request.AccountNumber, amount); err != nil {
 			p.logger.Error().Msgf("makeTransfer ::: %v", err)
+
+			// the provider may already have paid, a retry with the same idempotency key must not pay again
+			if errors.Is(err, controller.ErrProviderCallFailed) {
+				c.Set(middleware.ProviderCalledInContext, true)
+			}
+
+			if errors.Is(err, controller.ErrInsufficientFunds) || errors.Is(err, controller.ErrLimitExceeded) {
+				restModel.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+
+			if errors.Is(err, controller.ErrWalletDebitBlocked) {
+				restModel.ErrorResponse(c, http.StatusForbidden, err.Error())
+				return
+			}
+
 			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -123,6 +189,79 @@ func (p *paymentHandler) makeTransfer() gin.HandlerFunc {
 	}
 }
 
+// internalTransfer 	godoc
+//
+//	@Summary		internalTransfer
+//	@Description	this endpoint instantly moves funds to the wallet of another user, no payment provider is involved
+//	@Tags			payment
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
+//	@Accept			json
+//	@Produce		json
+//	@Param			internalTransferRequest	body		internalTransferRequest		true	"internal transfer request body"
+//	@Success		200						{object}	restModel.GenericResponse	"transfer successful"
+//	@Failure		403						{object}	restModel.GenericResponse	"cross tenant transfer not allowed or wallet restricted"
+//	@Failure		404						{object}	restModel.GenericResponse	"recipient not found"
+//	@Failure		422						{object}	restModel.GenericResponse	"insufficient funds or transaction limit exceeded"
+//	@Router			/payment/internal-transfer [post]
+func (p *paymentHandler) internalTransfer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request internalTransferRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			p.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		err := restModel.ValidateRequest(request)
+		if err != nil {
+			p.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, restModel.ErrIncompleteDetails.Error())
+			return
+		}
+
+		amount, err := restModel.ParseAmount(request.Amount, request.Currency)
+		if err != nil {
+			p.logger.Err(err).Msgf("internalTransfer ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			p.logger.Err(err).Msgf("internalTransfer ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		transaction, err := p.controller.InternalTransfer(context.Background(), userID, request.RecipientEmail, amount, request.Narration)
+		if err != nil {
+			p.logger.Error().Msgf("internalTransfer ::: %v", err)
+			restModel.ErrorResponse(c, internalTransferErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "transfer successful", transaction)
+	}
+}
+
+// internalTransferErrorStatus maps an internal transfer error to its http status
+func internalTransferErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrInsufficientFunds), errors.Is(err, controller.ErrLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, controller.ErrCrossTenantTransfer), errors.Is(err, controller.ErrWalletDebitBlocked),
+		errors.Is(err, controller.ErrWalletCreditBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, controller.ErrUserDoesNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrSelfTransfer), errors.Is(err, controller.ErrNoWalletForCurrency):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // bankTransfer 	godoc
 //
 //	@Summary		bankTransfer
@@ -167,3 +306,49 @@ func (p *paymentHandler) bankTransfer() gin.HandlerFunc {
 		restModel.OkResponse(c, http.StatusOK, "bank account created successful", virtualAccount)
 	}
 }
+
+// quoteFee 	godoc
+//
+//	@Summary		quoteFee
+//	@Description	this endpoint quotes the fee the users tenant charges for an action on an amount, deposit, transfer, internal_transfer or fx
+//	@Tags			payment
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			action			query	string	true	"deposit, transfer, internal_transfer or fx"
+//	@Param			amount			query	number	true	"amount, in major units"
+//	@Param			currency		query	string	true	"currency of the amount"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"fee quote fetched successfully"
+//	@Router			/payment/fees/quote [get]
+func (p *paymentHandler) quoteFee() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			p.logger.Err(err).Msgf("quoteFee ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		amount, err := restModel.ParseAmount(json.Number(c.Query("amount")), c.Query("currency"))
+		if err != nil {
+			p.logger.Err(err).Msgf("quoteFee ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		quote, err := p.controller.QuoteFee(context.Background(), userID, model.FeeAction(c.Query("action")), amount)
+		if err != nil {
+			p.logger.Err(err).Msgf("quoteFee ::: ==> %s", err)
+
+			if errors.Is(err, model.ErrInvalidFeeAction) {
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "fee quote fetched successfully", quote)
+	}
+}
@@ -0,0 +1,294 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+)
+
+// createPayoutBatch 	godoc
+//
+//	@Summary		createPayoutBatch
+//	@Description	this endpoint uploads a batch of payouts from the user's wallet in the currency. CSV files have a header row with email, bank_number, account_number, amount and narration, JSON files list the same keys under items. Each row pays either a user by email or a bank account. The batch is refused as a whole when a row is invalid or duplicated, the wallet cannot cover the amounts and fees or the rows break a transaction limit, otherwise its items are paid in the background
+//	@Tags			payment
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
+//	@Param			currency	formData	string	true	"wallet currency, e.g. NGN"
+//	@Param			file		formData	file	true	"payout file, .csv or .json"
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Success		202	{object}	restModel.GenericResponse	"payout batch queued"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid payout batch"
+//	@Failure		403	{object}	restModel.GenericResponse	"wallet does not allow debits"
+//	@Failure		422	{object}	restModel.GenericResponse	"insufficient funds or transaction limit exceeded"
+//	@Router			/payment/payouts [post]
+func (p *paymentHandler) createPayoutBatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request payoutBatchRequest
+
+		if err := c.ShouldBind(&request); err != nil {
+			p.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			p.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			p.logger.Err(err).Msgf("createPayoutBatch ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			p.logger.Err(err).Msgf("createPayoutBatch ::: error reading payout file ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "the payout file is missing")
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			p.logger.Err(err).Msgf("createPayoutBatch ::: error opening payout file ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer file.Close()
+
+		batch, err := p.controller.CreatePayoutBatch(context.Background(), userID, fileHeader.Filename, request.Currency, file)
+		if err != nil {
+			p.logger.Error().Msgf("createPayoutBatch ::: %v", err)
+			restModel.ErrorResponse(c, payoutErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusAccepted, "payout batch queued", batch)
+	}
+}
+
+// getPayoutBatches 	godoc
+//
+//	@Summary		getPayoutBatches
+//	@Description	this endpoint gets the user's payout batches, newest first
+//	@Tags			payment
+//	@Param			page	query	string	false	"page"
+//	@Param			size	query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"payout batches fetched successfully"
+//	@Router			/payment/payouts [get]
+func (p *paymentHandler) getPayoutBatches() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			p.logger.Err(err).Msgf("getPayoutBatches ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		batches, pageInfo, err := p.controller.GetPayoutBatches(context.Background(), userID, helper.ParsePageParams(c))
+		if err != nil {
+			p.logger.Error().Msgf("getPayoutBatches ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "payout batches fetched successfully", batches, pageInfo)
+	}
+}
+
+// getPayoutBatch 	godoc
+//
+//	@Summary		getPayoutBatch
+//	@Description	this endpoint gets the summary of one of the user's payout batches, its totals and how many items succeeded or failed so far
+//	@Tags			payment
+//	@Param			id	path	string	true	"payout batch ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"payout batch fetched successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Router			/payment/payouts/{id} [get]
+func (p *paymentHandler) getPayoutBatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, batchID, ok := p.payoutIDs(c, "getPayoutBatch")
+		if !ok {
+			return
+		}
+
+		batch, err := p.controller.GetPayoutBatch(context.Background(), userID, batchID)
+		if err != nil {
+			p.logger.Error().Msgf("getPayoutBatch ::: %v", err)
+			restModel.ErrorResponse(c, payoutErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "payout batch fetched successfully", batch)
+	}
+}
+
+// getPayoutItems 	godoc
+//
+//	@Summary		getPayoutItems
+//	@Description	this endpoint gets the items of one of the user's payout batches in the order of the file
+//	@Tags			payment
+//	@Param			id		path	string	true	"payout batch ID"
+//	@Param			status	query	string	false	"pending, processing, succeeded or failed"
+//	@Param			page	query	string	false	"page"
+//	@Param			size	query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"payout items fetched successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Router			/payment/payouts/{id}/items [get]
+func (p *paymentHandler) getPayoutItems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, batchID, ok := p.payoutIDs(c, "getPayoutItems")
+		if !ok {
+			return
+		}
+
+		var status *model.PayoutItemStatus
+		if query := c.Query("status"); query != "" {
+			s := model.PayoutItemStatus(query)
+			status = &s
+		}
+
+		items, pageInfo, err := p.controller.GetPayoutItems(context.Background(), userID, batchID, status, helper.ParsePageParams(c))
+		if err != nil {
+			p.logger.Error().Msgf("getPayoutItems ::: %v", err)
+			restModel.ErrorResponse(c, payoutErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "payout items fetched successfully", items, pageInfo)
+	}
+}
+
+// getPayoutResult 	godoc
+//
+//	@Summary		getPayoutResult
+//	@Description	this endpoint downloads the result of a completed payout batch as a csv file, the rows of the upload with the status, fee, transaction and error of each
+//	@Tags			payment
+//	@Param			id	path	string	true	"payout batch ID"
+//	@Produce		text/csv
+//	@Success		200	{file}		file						"payout result"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Failure		409	{object}	restModel.GenericResponse	"payout batch has not finished yet"
+//	@Router			/payment/payouts/{id}/result [get]
+func (p *paymentHandler) getPayoutResult() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, batchID, ok := p.payoutIDs(c, "getPayoutResult")
+		if !ok {
+			return
+		}
+
+		batch, items, err := p.controller.GetPayoutResult(context.Background(), userID, batchID)
+		if err != nil {
+			p.logger.Error().Msgf("getPayoutResult ::: %v", err)
+			restModel.ErrorResponse(c, payoutErrorStatus(err), err.Error())
+			return
+		}
+
+		file, err := payoutResultCSV(items)
+		if err != nil {
+			p.logger.Err(err).Msgf("getPayoutResult ::: error writing csv ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("payout_result_%s.csv", batch.ID)))
+		c.Data(http.StatusOK, "text/csv", file)
+	}
+}
+
+// payoutIDs reads the user and the batch of a payout request, it responds with a bad request when either is invalid
+func (p *paymentHandler) payoutIDs(c *gin.Context, name string) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+	if err != nil {
+		p.logger.Err(err).Msgf("%s ::: error parsing uuid ==> %s", name, err)
+		restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		p.logger.Err(err).Msgf("%s ::: error parsing uuid ==> %s", name, err)
+		restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, batchID, true
+}
+
+// payoutResultCSV renders the items of a payout batch as a csv file, one row per item in the order of the upload
+func payoutResultCSV(items []model.PayoutItem) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{
+		{"row", "email", "bank_number", "account_number", "amount", "fee", "currency", "narration", "status", "transaction_id", "error"},
+	}
+
+	for _, item := range items {
+		transactionID := ""
+		if item.TransactionID != nil {
+			transactionID = item.TransactionID.String()
+		}
+
+		rows = append(rows, []string{
+			strconv.Itoa(item.Row),
+			item.RecipientEmail,
+			item.BankNumber,
+			item.AccountNumber,
+			item.Amount.Decimal(),
+			item.Fee.Decimal(),
+			item.Amount.Currency,
+			item.Narration,
+			string(item.Status),
+			transactionID,
+			item.Error,
+		})
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// payoutErrorStatus maps a payout error to its http status
+func payoutErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrInvalidPayoutBatch), errors.Is(err, controller.ErrNoWalletForCurrency),
+		errors.Is(err, model.ErrUnsupportedCurrency):
+		return http.StatusBadRequest
+	case errors.Is(err, controller.ErrInsufficientFunds), errors.Is(err, controller.ErrLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, controller.ErrWalletDebitBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrPayoutBatchNotFinished):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,7 @@
+package reconciliation
+
+type (
+	reconcileRequest struct {
+		Provider string `form:"provider" validate:"required,oneof=paystack flutterwave"`
+	}
+)
@@ -0,0 +1,211 @@
+// Package reconciliation exposes the reconciliation of payment providers' settlement files to the operators of the
+// platform
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+)
+
+type reconciliationHandler struct {
+	logger      zerolog.Logger
+	controller  controller.Operations
+	environment *environment.Env
+}
+
+// New creates a new instance of the reconciliation rest handler, its endpoints are for admins only
+func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *environment.Env) {
+	reconciliation := reconciliationHandler{
+		logger:      l,
+		controller:  c,
+		environment: env,
+	}
+
+	reconciliationGroup := r.Group("/admin/reconciliation", reconciliation.controller.Middleware().AdminAuthMiddleware())
+
+	reconciliationGroup.POST("", reconciliation.reconcile())
+	reconciliationGroup.GET("", reconciliation.getRuns())
+	reconciliationGroup.GET("/:id", reconciliation.getRun())
+	reconciliationGroup.GET("/:id/items", reconciliation.getItems())
+}
+
+// reconcile 	godoc
+//
+//	@Summary		reconcile
+//	@Description	this endpoint reconciles a provider's settlement file against our transactions and saves the run. Paystack files have reference, amount (in kobo), currency, status and paid_at, Flutterwave files have tx_ref, amount, currency, status and created_at. CSV files have a header row, JSON files list the transactions under data
+//	@Tags			reconciliation
+//	@Param			X-Admin-Key	header		string	true	"admin api key"
+//	@Param			provider	formData	string	true	"paystack or flutterwave"
+//	@Param			file		formData	file	true	"settlement file, .csv or .json"
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"reconciliation completed"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid settlement file"
+//	@Router			/admin/reconciliation [post]
+func (h *reconciliationHandler) reconcile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request reconcileRequest
+
+		if err := c.ShouldBind(&request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			h.logger.Err(err).Msgf("reconcile ::: error reading settlement file ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "the settlement file is missing")
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.logger.Err(err).Msgf("reconcile ::: error opening settlement file ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer file.Close()
+
+		run, err := h.controller.ReconcileSettlementFile(context.Background(), model.PaymentProvider(request.Provider), fileHeader.Filename, file)
+		if err != nil {
+			h.logger.Error().Msgf("reconcile ::: %v", err)
+			restModel.ErrorResponse(c, reconciliationErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "reconciliation completed", run)
+	}
+}
+
+// getRuns 	godoc
+//
+//	@Summary		getRuns
+//	@Description	this endpoint gets the reconciliation runs, newest first
+//	@Tags			reconciliation
+//	@Param			X-Admin-Key	header	string	true	"admin api key"
+//	@Param			provider	query	string	false	"paystack or flutterwave"
+//	@Param			page		query	string	false	"page"
+//	@Param			size		query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"reconciliation runs fetched successfully"
+//	@Router			/admin/reconciliation [get]
+func (h *reconciliationHandler) getRuns() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var provider *model.PaymentProvider
+		if query := c.Query("provider"); query != "" {
+			p := model.PaymentProvider(query)
+			provider = &p
+		}
+
+		runs, pageInfo, err := h.controller.GetReconciliationRuns(context.Background(), provider, helper.ParsePageParams(c))
+		if err != nil {
+			h.logger.Error().Msgf("getRuns ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "reconciliation runs fetched successfully", runs, pageInfo)
+	}
+}
+
+// getRun 	godoc
+//
+//	@Summary		getRun
+//	@Description	this endpoint gets a reconciliation run with its totals
+//	@Tags			reconciliation
+//	@Param			X-Admin-Key	header	string	true	"admin api key"
+//	@Param			id			path	string	true	"reconciliation run ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"reconciliation run fetched successfully"
+//	@Router			/admin/reconciliation/{id} [get]
+func (h *reconciliationHandler) getRun() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		runID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			h.logger.Err(err).Msgf("getRun ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		run, err := h.controller.GetReconciliationRun(context.Background(), runID)
+		if err != nil {
+			h.logger.Error().Msgf("getRun ::: %v", err)
+			restModel.ErrorResponse(c, reconciliationErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "reconciliation run fetched successfully", run)
+	}
+}
+
+// getItems 	godoc
+//
+//	@Summary		getItems
+//	@Description	this endpoint gets the discrepancies a reconciliation run found
+//	@Tags			reconciliation
+//	@Param			X-Admin-Key	header	string	true	"admin api key"
+//	@Param			id			path	string	true	"reconciliation run ID"
+//	@Param			kind		query	string	false	"missing_on_our_side, missing_on_provider, amount_mismatch or status_mismatch"
+//	@Param			page		query	string	false	"page"
+//	@Param			size		query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"reconciliation items fetched successfully"
+//	@Router			/admin/reconciliation/{id}/items [get]
+func (h *reconciliationHandler) getItems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		runID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			h.logger.Err(err).Msgf("getItems ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var kind *model.ReconciliationKind
+		if query := c.Query("kind"); query != "" {
+			k := model.ReconciliationKind(query)
+			kind = &k
+		}
+
+		items, pageInfo, err := h.controller.GetReconciliationItems(context.Background(), runID, kind, helper.ParsePageParams(c))
+		if err != nil {
+			h.logger.Error().Msgf("getItems ::: %v", err)
+			restModel.ErrorResponse(c, reconciliationErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "reconciliation items fetched successfully", items, pageInfo)
+	}
+}
+
+// reconciliationErrorStatus maps a reconciliation error to its http status
+func reconciliationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrInvalidSettlementFile):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,57 @@
+package split
+
+import (
+	"encoding/json"
+
+	restModel "codematic/handler/model"
+	"codematic/model"
+)
+
+type (
+	createSplitRequest struct {
+		// OwnerEmail is the user whose payments the split divides, tenants only
+		OwnerEmail string              `json:"ownerEmail" validate:"omitempty,email"`
+		Name       string              `json:"name" validate:"required,max=255"`
+		Currency   string              `json:"currency" validate:"required,len=3"`
+		Shares     []splitShareRequest `json:"shares" validate:"required,min=1,max=20,dive"`
+	}
+
+	splitShareRequest struct {
+		Recipient string `json:"recipient" validate:"required,oneof=user tenant"`
+		// Email is the user a user share is paid to
+		Email string `json:"email" validate:"omitempty,email"`
+		Type  string `json:"type" validate:"required,oneof=fixed percentage"`
+		// Amount is in major units, on fixed shares only
+		Amount             json.Number `json:"amount" swaggertype:"number"`
+		PercentBasisPoints int64       `json:"percentBasisPoints" validate:"gte=0,lte=10000"`
+		Remainder          bool        `json:"remainder"`
+	}
+)
+
+func (s *createSplitRequest) toModel() (model.Split, error) {
+	split := model.Split{
+		Name:     s.Name,
+		Currency: s.Currency,
+	}
+
+	for _, share := range s.Shares {
+		amount := model.ZeroMoney(s.Currency)
+		if share.Amount != "" {
+			var err error
+			if amount, err = restModel.ParseAmount(share.Amount, s.Currency); err != nil {
+				return model.Split{}, err
+			}
+		}
+
+		split.Shares = append(split.Shares, model.SplitShare{
+			RecipientType:      model.SplitRecipientType(share.Recipient),
+			RecipientEmail:     share.Email,
+			Type:               model.SplitShareType(share.Type),
+			Amount:             amount,
+			PercentBasisPoints: share.PercentBasisPoints,
+			Remainder:          share.Remainder,
+		})
+	}
+
+	return split, nil
+}
@@ -0,0 +1,196 @@
+// Package split exposes the splits that divide the payments into a user's wallet between recipients, to the user
+// and to their tenant
+package split
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/environment"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+)
+
+type splitHandler struct {
+	logger      zerolog.Logger
+	controller  controller.Operations
+	environment *environment.Env
+}
+
+// New creates a new instance of the split rest handler. Users create and see their own splits under /splits, tenants
+// create and see those of all their users under /tenant/splits
+func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *environment.Env) {
+	split := splitHandler{
+		logger:      l,
+		controller:  c,
+		environment: env,
+	}
+
+	userAuth := split.controller.Middleware().AuthMiddleware()
+	splitGroup := r.Group("/splits")
+
+	splitGroup.POST("", userAuth, split.createSplit(model.ActorUser))
+	splitGroup.GET("", userAuth, split.getSplits(model.ActorUser))
+	splitGroup.GET("/:id", userAuth, split.getSplit(model.ActorUser))
+
+	tenantAuth := split.controller.Middleware().TenantAuthMiddleware()
+	tenantGroup := r.Group("/tenant/splits")
+
+	tenantGroup.POST("", tenantAuth, split.createSplit(model.ActorTenant))
+	tenantGroup.GET("", tenantAuth, split.getSplits(model.ActorTenant))
+	tenantGroup.GET("/:id", tenantAuth, split.getSplit(model.ActorTenant))
+}
+
+// createSplit 	godoc
+//
+//	@Summary		createSplit
+//	@Description	this endpoint creates a split of the payments into the user's wallet, or into the wallet of the tenant's user with ownerEmail. Shares are fixed amounts or percentages in basis points (1% = 100) of what is credited, paid to users of the tenant by email or to the tenant's revenue wallet, and exactly one share takes what the others leave. Attach the split to a deposit or an invoice with its splitId
+//	@Tags			split
+//	@Param			Authorization		header	string				true	"Bearer <token>"
+//	@Param			createSplitRequest	body	createSplitRequest	true	"create split request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"split created successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid split"
+//	@Failure		404	{object}	restModel.GenericResponse	"user does not exist"
+//	@Router			/splits [post]
+//	@Router			/tenant/splits [post]
+func (h *splitHandler) createSplit(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request createSplitRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			h.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if actor == model.ActorTenant && request.OwnerEmail == "" {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "ownerEmail is required")
+			return
+		}
+
+		split, err := request.toModel()
+		if err != nil {
+			h.logger.Err(err).Msgf("createSplit ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		actorID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("createSplit ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		split, err = h.controller.CreateSplit(context.Background(), actor, actorID, request.OwnerEmail, split)
+		if err != nil {
+			h.logger.Error().Msgf("createSplit ::: %v", err)
+			restModel.ErrorResponse(c, splitErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "split created successfully", split)
+	}
+}
+
+// getSplits 	godoc
+//
+//	@Summary		getSplits
+//	@Description	this endpoint gets the user's splits, or those of every user of the tenant, newest first
+//	@Tags			split
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			page			query	string	false	"page"
+//	@Param			size			query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"splits fetched successfully"
+//	@Router			/splits [get]
+//	@Router			/tenant/splits [get]
+func (h *splitHandler) getSplits(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("getSplits ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		splits, pageInfo, err := h.controller.GetSplits(context.Background(), actor, actorID, helper.ParsePageParams(c))
+		if err != nil {
+			h.logger.Error().Msgf("getSplits ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "splits fetched successfully", splits, pageInfo)
+	}
+}
+
+// getSplit 	godoc
+//
+//	@Summary		getSplit
+//	@Description	this endpoint gets a split with its shares
+//	@Tags			split
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			id				path	string	true	"split ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"split fetched successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Router			/splits/{id} [get]
+//	@Router			/tenant/splits/{id} [get]
+func (h *splitHandler) getSplit(actor model.Actor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			h.logger.Err(err).Msgf("getSplit ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		splitID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			h.logger.Err(err).Msgf("getSplit ::: error parsing split id ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		split, err := h.controller.GetSplit(context.Background(), actor, actorID, splitID)
+		if err != nil {
+			h.logger.Error().Msgf("getSplit ::: %v", err)
+			restModel.ErrorResponse(c, splitErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "split fetched successfully", split)
+	}
+}
+
+// splitErrorStatus maps a split error to its http status
+func splitErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrInvalidSplit), errors.Is(err, model.ErrUnsupportedCurrency),
+		errors.Is(err, model.ErrCurrencyMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, controller.ErrRecordNotFound), errors.Is(err, controller.ErrUserDoesNotExist):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,120 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/middleware"
+)
+
+// getUserBalanceAsOf 	godoc
+//
+//	@Summary		getUserBalanceAsOf
+//	@Description	this endpoint returns the balance of one of the tenants users wallet in a currency at a point in time, every movement made before it
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			id				path	string	true	"user ID"
+//	@Param			currency		query	string	true	"ISO-4217 currency of the wallet"
+//	@Param			as_of			query	string	false	"RFC3339 time, now when empty"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"balance fetched successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"user not found"
+//	@Router			/tenant/users/{id}/balance [get]
+func (t *tenantHandler) getUserBalanceAsOf() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getUserBalanceAsOf ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			t.logger.Err(err).Msgf("getUserBalanceAsOf ::: error parsing user id ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		asOf := time.Now()
+		if c.Query("as_of") != "" {
+			if asOf, err = time.Parse(time.RFC3339, c.Query("as_of")); err != nil {
+				restModel.ErrorResponse(c, http.StatusBadRequest, "as_of must be an RFC3339 time")
+				return
+			}
+		}
+
+		if c.Query("currency") == "" {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "currency is required")
+			return
+		}
+
+		balance, err := t.controller.GetTenantUserBalanceAsOf(context.Background(), tenantID, userID, c.Query("currency"), asOf)
+		if err != nil {
+			t.logger.Error().Msgf("getUserBalanceAsOf ::: %v", err)
+			restModel.ErrorResponse(c, balanceErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "balance fetched successfully", balance)
+	}
+}
+
+// getBalanceTotals 	godoc
+//
+//	@Summary		getBalanceTotals
+//	@Description	this endpoint returns the sum of the closing balances of the tenants wallets at the end of a day of the tenants timezone, one total per currency
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			day				query	string	true	"day, YYYY-MM-DD"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"balances fetched successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"day has not ended yet"
+//	@Router			/tenant/balances [get]
+func (t *tenantHandler) getBalanceTotals() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getBalanceTotals ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		day, err := time.Parse(model.SnapshotDayLayout, c.Query("day"))
+		if err != nil {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "day is required as YYYY-MM-DD")
+			return
+		}
+
+		totals, err := t.controller.GetTenantBalanceTotals(context.Background(), tenantID, day)
+		if err != nil {
+			t.logger.Error().Msgf("getBalanceTotals ::: %v", err)
+			restModel.ErrorResponse(c, balanceErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "balances fetched successfully", totals)
+	}
+}
+
+// balanceErrorStatus maps the errors of a balance query to a http status
+func balanceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrDayNotClosed), errors.Is(err, controller.ErrNoWalletForCurrency):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
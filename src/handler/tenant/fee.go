@@ -0,0 +1,192 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/middleware"
+)
+
+// setFeeRule 	godoc
+//
+//	@Summary		setFeeRule
+//	@Description	this endpoint sets the fee the tenant charges for an action in a currency, as a new version of the rule. The fee is flat, a percentage plus a flat part, or tiered by amount, clamped between minFee and maxFee, with VAT on top
+//	@Tags			tenant
+//	@Param			Authorization	header	string			true	"Bearer <token>"
+//	@Param			feeRuleRequest	body	feeRuleRequest	true	"fee rule request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"fee rule saved successfully"
+//	@Failure		409	{object}	restModel.GenericResponse	"fee rule was changed at the same time"
+//	@Router			/tenant/fees [put]
+func (t *tenantHandler) setFeeRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request feeRuleRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("setFeeRule ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rule, err := request.toModel()
+		if err != nil {
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rule, err = t.controller.SetFeeRule(context.Background(), tenantID, rule)
+		if err != nil {
+			t.logger.Error().Msgf("setFeeRule ::: %v", err)
+			restModel.ErrorResponse(c, feeErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "fee rule saved successfully", rule)
+	}
+}
+
+// getFeeRules 	godoc
+//
+//	@Summary		getFeeRules
+//	@Description	this endpoint returns the fee rules the tenant charges with, or every version of them with all
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			all				query	bool	false	"include retired versions"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"fee rules fetched successfully"
+//	@Router			/tenant/fees [get]
+func (t *tenantHandler) getFeeRules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getFeeRules ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rules, err := t.controller.GetFeeRules(context.Background(), tenantID, c.Query("all") == "true")
+		if err != nil {
+			t.logger.Error().Msgf("getFeeRules ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "fee rules fetched successfully", rules)
+	}
+}
+
+// retireFeeRule 	godoc
+//
+//	@Summary		retireFeeRule
+//	@Description	this endpoint retires the fee rule of an action in a currency, the action is free from then on
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			action			path	string	true	"deposit, transfer, internal_transfer or fx"
+//	@Param			currency		query	string	true	"currency of the rule"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"fee rule retired successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"no fee rule for the action"
+//	@Router			/tenant/fees/{action} [delete]
+func (t *tenantHandler) retireFeeRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("retireFeeRule ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if c.Query("currency") == "" {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "currency is required")
+			return
+		}
+
+		err = t.controller.RetireFeeRule(context.Background(), tenantID, model.FeeAction(c.Param("action")), c.Query("currency"))
+		if err != nil {
+			t.logger.Error().Msgf("retireFeeRule ::: %v", err)
+			restModel.ErrorResponse(c, feeErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "fee rule retired successfully", nil)
+	}
+}
+
+// quoteFee 	godoc
+//
+//	@Summary		quoteFee
+//	@Description	this endpoint quotes the fee the tenant charges for an action on an amount
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			action			query	string	true	"deposit, transfer, internal_transfer or fx"
+//	@Param			amount			query	number	true	"amount, in major units"
+//	@Param			currency		query	string	true	"currency of the amount"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"fee quote fetched successfully"
+//	@Router			/tenant/fees/quote [get]
+func (t *tenantHandler) quoteFee() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("quoteFee ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		amount, err := restModel.ParseAmount(json.Number(c.Query("amount")), c.Query("currency"))
+		if err != nil {
+			t.logger.Err(err).Msgf("quoteFee ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		quote, err := t.controller.QuoteTenantFee(context.Background(), tenantID, model.FeeAction(c.Query("action")), amount)
+		if err != nil {
+			t.logger.Error().Msgf("quoteFee ::: %v", err)
+			restModel.ErrorResponse(c, feeErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "fee quote fetched successfully", quote)
+	}
+}
+
+// feeErrorStatus maps the errors of a fee rule or quote to a http status
+func feeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrFeeRuleConflict):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrInvalidFeeRule), errors.Is(err, model.ErrInvalidFeeAction),
+		errors.Is(err, model.ErrUnsupportedCurrency), errors.Is(err, model.ErrCurrencyMismatch):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
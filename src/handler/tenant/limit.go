@@ -0,0 +1,148 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/middleware"
+)
+
+// setTransactionLimit 	godoc
+//
+//	@Summary		setTransactionLimit
+//	@Description	this endpoint sets how much each user of the tenant can move with an action in a currency: the largest single transaction, and how many transactions and what amount per day and per month of the tenants timezone. A cap left out or zero is no cap
+//	@Tags			tenant
+//	@Param			Authorization			header	string					true	"Bearer <token>"
+//	@Param			transactionLimitRequest	body	transactionLimitRequest	true	"transaction limit request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"transaction limit saved successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid transaction limit"
+//	@Router			/tenant/limits [put]
+func (t *tenantHandler) setTransactionLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request transactionLimitRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("setTransactionLimit ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		limit, err := request.toModel()
+		if err != nil {
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		limit, err = t.controller.SetTransactionLimit(context.Background(), tenantID, limit)
+		if err != nil {
+			t.logger.Error().Msgf("setTransactionLimit ::: %v", err)
+			restModel.ErrorResponse(c, limitErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "transaction limit saved successfully", limit)
+	}
+}
+
+// getTransactionLimits 	godoc
+//
+//	@Summary		getTransactionLimits
+//	@Description	this endpoint returns the transaction limits the tenant set for its users
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"transaction limits fetched successfully"
+//	@Router			/tenant/limits [get]
+func (t *tenantHandler) getTransactionLimits() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getTransactionLimits ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		limits, err := t.controller.GetTransactionLimits(context.Background(), tenantID)
+		if err != nil {
+			t.logger.Error().Msgf("getTransactionLimits ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "transaction limits fetched successfully", limits)
+	}
+}
+
+// deleteTransactionLimit 	godoc
+//
+//	@Summary		deleteTransactionLimit
+//	@Description	this endpoint removes the limit of an action in a currency, the tenants users are not limited for it from then on
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			action			path	string	true	"deposit, transfer, internal_transfer or fx"
+//	@Param			currency		query	string	true	"currency of the limit"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"transaction limit removed successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"no limit for the action"
+//	@Router			/tenant/limits/{action} [delete]
+func (t *tenantHandler) deleteTransactionLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("deleteTransactionLimit ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if c.Query("currency") == "" {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "currency is required")
+			return
+		}
+
+		err = t.controller.DeleteTransactionLimit(context.Background(), tenantID, model.LimitAction(c.Param("action")), c.Query("currency"))
+		if err != nil {
+			t.logger.Error().Msgf("deleteTransactionLimit ::: %v", err)
+			restModel.ErrorResponse(c, limitErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "transaction limit removed successfully", nil)
+	}
+}
+
+// limitErrorStatus maps the errors of a transaction limit to a http status
+func limitErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrInvalidTransactionLimit), errors.Is(err, model.ErrInvalidLimitAction),
+		errors.Is(err, model.ErrUnsupportedCurrency), errors.Is(err, model.ErrCurrencyMismatch):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,6 +1,8 @@
 package tenant
 
 import (
+	"encoding/json"
+
 	"codematic/model"
 
 	"github.com/google/uuid"
@@ -11,6 +13,8 @@ type (
 		BusinessName string `json:"businessName" validate:"required"`
 		Email        string `json:"email" validate:"required"`
 		Password     string `json:"password" validate:"required"`
+		// Timezone is an IANA timezone, Africa/Lagos when left out
+		Timezone string `json:"timezone" validate:"omitempty,timezone"`
 	}
 
 	loginRequest struct {
@@ -18,6 +22,72 @@ type (
 		Password string `json:"password" validate:"required"`
 	}
 
+	fxRateRequest struct {
+		BaseCurrency      string      `json:"baseCurrency" validate:"required,len=3"`
+		QuoteCurrency     string      `json:"quoteCurrency" validate:"required,len=3"`
+		Rate              json.Number `json:"rate" validate:"required" swaggertype:"number"`
+		SpreadBasisPoints int64       `json:"spreadBasisPoints" validate:"gte=0,lte=10000"`
+	}
+
+	transferSettingsRequest struct {
+		AllowCrossTenantTransfers *bool `json:"allowCrossTenantTransfers" validate:"required"`
+	}
+
+	timezoneRequest struct {
+		Timezone string `json:"timezone" validate:"required,timezone"`
+	}
+
+	refundRequest struct {
+		// Amount is left out to refund whatever is left to refund of the transaction
+		Amount    json.Number `json:"amount" swaggertype:"number"`
+		Currency  string      `json:"currency"`
+		Reason    string      `json:"reason" validate:"required,max=255"`
+		Reference string      `json:"reference" validate:"required,max=100"`
+	}
+
+	feeRuleRequest struct {
+		Action   string `json:"action" validate:"required"`
+		Currency string `json:"currency" validate:"required,len=3"`
+		Type     string `json:"type" validate:"required"`
+		// amounts are in major units, left out for none
+		FlatFee            json.Number      `json:"flatFee" swaggertype:"number"`
+		PercentBasisPoints int64            `json:"percentBasisPoints" validate:"gte=0,lte=10000"`
+		Tiers              []feeTierRequest `json:"tiers" validate:"dive"`
+		MinFee             json.Number      `json:"minFee" swaggertype:"number"`
+		MaxFee             json.Number      `json:"maxFee" swaggertype:"number"`
+		VATBasisPoints     int64            `json:"vatBasisPoints" validate:"gte=0,lte=10000"`
+	}
+
+	feeTierRequest struct {
+		// UpTo is left out on the last tier for no upper bound
+		UpTo               json.Number `json:"upTo" swaggertype:"number"`
+		FlatFee            json.Number `json:"flatFee" swaggertype:"number"`
+		PercentBasisPoints int64       `json:"percentBasisPoints" validate:"gte=0,lte=10000"`
+	}
+
+	walletStatusRequest struct {
+		Status string `json:"status" validate:"required"`
+		Reason string `json:"reason" validate:"required,max=255"`
+	}
+
+	transactionLimitRequest struct {
+		Action   string `json:"action" validate:"required"`
+		Currency string `json:"currency" validate:"required,len=3"`
+		// caps are left out or zero for no cap, amounts are in major units
+		MaxSingle     json.Number `json:"maxSingle" swaggertype:"number"`
+		DailyCount    int64       `json:"dailyCount" validate:"gte=0"`
+		DailyAmount   json.Number `json:"dailyAmount" swaggertype:"number"`
+		MonthlyCount  int64       `json:"monthlyCount" validate:"gte=0"`
+		MonthlyAmount json.Number `json:"monthlyAmount" swaggertype:"number"`
+	}
+
+	revenueWithdrawalRequest struct {
+		BankNumber    string      `json:"bankNumber" validate:"required"`
+		AccountNumber string      `json:"accountNumber" validate:"required"`
+		Amount        json.Number `json:"amount" validate:"required" swaggertype:"number"`
+		Currency      string      `json:"currency"`
+	}
+
 	loginResponse struct {
 		User               model.Tenant `json:"user"`
 		AccessToken        string       `json:"accessToken"`
@@ -35,5 +105,87 @@ func (t *tenantRequest) toModel() model.Tenant {
 		BusinessName: t.BusinessName,
 		Email:        t.Email,
 		Password:     password,
+		Timezone:     t.Timezone,
 	}
 }
+
+func (f *fxRateRequest) toModel() model.FXRate {
+	return model.FXRate{
+		BaseCurrency:      f.BaseCurrency,
+		QuoteCurrency:     f.QuoteCurrency,
+		Rate:              f.Rate.String(),
+		SpreadBasisPoints: f.SpreadBasisPoints,
+	}
+}
+
+func (f *feeRuleRequest) toModel() (model.FeeRule, error) {
+	rule := model.FeeRule{
+		Action:             model.FeeAction(f.Action),
+		Currency:           f.Currency,
+		Type:               model.FeeType(f.Type),
+		PercentBasisPoints: f.PercentBasisPoints,
+		VATBasisPoints:     f.VATBasisPoints,
+	}
+
+	var err error
+	if rule.FlatFee, err = parseFeeAmount(f.FlatFee, f.Currency); err != nil {
+		return model.FeeRule{}, err
+	}
+
+	if rule.MinFee, err = parseFeeAmount(f.MinFee, f.Currency); err != nil {
+		return model.FeeRule{}, err
+	}
+
+	if rule.MaxFee, err = parseFeeAmount(f.MaxFee, f.Currency); err != nil {
+		return model.FeeRule{}, err
+	}
+
+	for _, t := range f.Tiers {
+		tier := model.FeeTier{PercentBasisPoints: t.PercentBasisPoints}
+
+		if tier.UpTo, err = parseFeeAmount(t.UpTo, f.Currency); err != nil {
+			return model.FeeRule{}, err
+		}
+
+		if tier.FlatFee, err = parseFeeAmount(t.FlatFee, f.Currency); err != nil {
+			return model.FeeRule{}, err
+		}
+
+		rule.Tiers = append(rule.Tiers, tier)
+	}
+
+	return rule, nil
+}
+
+func (l *transactionLimitRequest) toModel() (model.TransactionLimit, error) {
+	limit := model.TransactionLimit{
+		Action:       model.LimitAction(l.Action),
+		Currency:     l.Currency,
+		DailyCount:   l.DailyCount,
+		MonthlyCount: l.MonthlyCount,
+	}
+
+	var err error
+	if limit.MaxSingle, err = parseFeeAmount(l.MaxSingle, l.Currency); err != nil {
+		return model.TransactionLimit{}, err
+	}
+
+	if limit.DailyAmount, err = parseFeeAmount(l.DailyAmount, l.Currency); err != nil {
+		return model.TransactionLimit{}, err
+	}
+
+	if limit.MonthlyAmount, err = parseFeeAmount(l.MonthlyAmount, l.Currency); err != nil {
+		return model.TransactionLimit{}, err
+	}
+
+	return limit, nil
+}
+
+// parseFeeAmount parses an amount of a fee rule or transaction limit, an amount left out is zero
+func parseFeeAmount(amount json.Number, currency string) (model.Money, error) {
+	if amount == "" {
+		return model.ZeroMoney(currency), nil
+	}
+
+	return model.ParseMoney(amount.String(), currency)
+}
@@ -0,0 +1,239 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/helper"
+	"codematic/pkg/middleware"
+)
+
+// getRevenueWallets 	godoc
+//
+//	@Summary		getRevenueWallets
+//	@Description	this endpoint returns the balance of the tenants revenue wallets, one per currency, which collect the fees of its users transactions and the spread of their conversions
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"revenue wallets fetched successfully"
+//	@Router			/tenant/revenue [get]
+func (t *tenantHandler) getRevenueWallets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getRevenueWallets ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		wallets, err := t.controller.GetRevenueWallets(context.Background(), tenantID)
+		if err != nil {
+			t.logger.Error().Msgf("getRevenueWallets ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "revenue wallets fetched successfully", wallets)
+	}
+}
+
+// getRevenueHistory 	godoc
+//
+//	@Summary		getRevenueHistory
+//	@Description	this endpoint returns the movements of the tenants revenue wallet in a currency, the fees earned and the withdrawals, newest first
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			currency		query	string	true	"ISO-4217 currency of the revenue wallet"
+//	@Param			page			query	string	false	"page"
+//	@Param			size			query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"revenue history fetched successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"no wallet in this currency"
+//	@Router			/tenant/revenue/history [get]
+func (t *tenantHandler) getRevenueHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getRevenueHistory ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if c.Query("currency") == "" {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "currency is required")
+			return
+		}
+
+		entries, pageInfo, err := t.controller.GetRevenueHistory(context.Background(), tenantID, c.Query("currency"), helper.ParsePageParams(c))
+		if err != nil {
+			t.logger.Error().Msgf("getRevenueHistory ::: %v", err)
+			restModel.ErrorResponse(c, revenueErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "revenue history fetched successfully", entries, pageInfo)
+	}
+}
+
+// getRevenueDays 	godoc
+//
+//	@Summary		getRevenueDays
+//	@Description	this endpoint returns what the tenants revenue wallet in a currency earned and paid out on each day between two days of the tenants timezone, both included
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			currency		query	string	true	"ISO-4217 currency of the revenue wallet"
+//	@Param			from			query	string	true	"first day, YYYY-MM-DD"
+//	@Param			to				query	string	true	"last day, YYYY-MM-DD"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"revenue fetched successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid revenue period"
+//	@Router			/tenant/revenue/daily [get]
+func (t *tenantHandler) getRevenueDays() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getRevenueDays ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		from, fromErr := time.Parse(model.SnapshotDayLayout, c.Query("from"))
+		to, toErr := time.Parse(model.SnapshotDayLayout, c.Query("to"))
+		if fromErr != nil || toErr != nil {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "from and to are required as YYYY-MM-DD")
+			return
+		}
+
+		if c.Query("currency") == "" {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "currency is required")
+			return
+		}
+
+		days, err := t.controller.GetRevenueDays(context.Background(), tenantID, c.Query("currency"), from, to)
+		if err != nil {
+			t.logger.Error().Msgf("getRevenueDays ::: %v", err)
+			restModel.ErrorResponse(c, revenueErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "revenue fetched successfully", days)
+	}
+}
+
+// withdrawRevenue 	godoc
+//
+//	@Summary		withdrawRevenue
+//	@Description	this endpoint pays out part of the tenants revenue wallet in a currency to the tenants settlement account
+//	@Tags			tenant
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
+//	@Param			Authorization				header	string						true	"Bearer <token>"
+//	@Param			revenueWithdrawalRequest	body	revenueWithdrawalRequest	true	"revenue withdrawal request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"revenue withdrawn successfully"
+//	@Failure		422	{object}	restModel.GenericResponse	"insufficient funds"
+//	@Router			/tenant/revenue/withdrawals [post]
+func (t *tenantHandler) withdrawRevenue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request revenueWithdrawalRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, restModel.ErrIncompleteDetails.Error())
+			return
+		}
+
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("withdrawRevenue ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		amount, err := restModel.ParseAmount(request.Amount, request.Currency)
+		if err != nil {
+			t.logger.Err(err).Msgf("withdrawRevenue ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		withdrawal, err := t.controller.WithdrawRevenue(context.Background(), tenantID, request.BankNumber, request.AccountNumber, amount)
+		if err != nil {
+			t.logger.Error().Msgf("withdrawRevenue ::: %v", err)
+
+			// the provider may already have paid, a retry with the same idempotency key must not pay again
+			if errors.Is(err, controller.ErrProviderCallFailed) {
+				c.Set(middleware.ProviderCalledInContext, true)
+			}
+
+			restModel.ErrorResponse(c, revenueErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "revenue withdrawn successfully", withdrawal)
+	}
+}
+
+// getRevenueWithdrawals 	godoc
+//
+//	@Summary		getRevenueWithdrawals
+//	@Description	this endpoint returns the withdrawals from the tenants revenue wallets, newest first
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			page			query	string	false	"page"
+//	@Param			size			query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"revenue withdrawals fetched successfully"
+//	@Router			/tenant/revenue/withdrawals [get]
+func (t *tenantHandler) getRevenueWithdrawals() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getRevenueWithdrawals ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		withdrawals, pageInfo, err := t.controller.GetRevenueWithdrawals(context.Background(), tenantID, helper.ParsePageParams(c))
+		if err != nil {
+			t.logger.Error().Msgf("getRevenueWithdrawals ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "revenue withdrawals fetched successfully", withdrawals, pageInfo)
+	}
+}
+
+// revenueErrorStatus maps the errors of a revenue query or withdrawal to a http status
+func revenueErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrInsufficientFunds):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrInvalidRevenuePeriod), errors.Is(err, controller.ErrNoWalletForCurrency),
+		errors.Is(err, model.ErrCurrencyMismatch):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
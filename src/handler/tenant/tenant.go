@@ -2,6 +2,7 @@ package tenant
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +11,7 @@ import (
 
 	"codematic/controller"
 	restModel "codematic/handler/model"
+	"codematic/model"
 	"codematic/pkg/environment"
 	"codematic/pkg/helper"
 	"codematic/pkg/middleware"
@@ -30,10 +32,32 @@ func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *env
 		environment: env,
 	}
 	tenantGroup := r.Group("/tenant")
+	idempotent := tenant.controller.Middleware().IdempotencyMiddleware(tenant.controller)
 
 	tenantGroup.POST("", tenant.createTenant())
 	tenantGroup.POST("/login", tenant.login())
 	tenantGroup.GET("", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getAllUsersByTenantID())
+	tenantGroup.GET("/fx-rates", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getFXRates())
+	tenantGroup.PUT("/fx-rates", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.setFXRate())
+	tenantGroup.POST("/transactions/:id/refunds", tenant.controller.Middleware().TenantAuthMiddleware(), idempotent, tenant.refundTransaction())
+	tenantGroup.GET("/transactions/:id/refunds", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getRefunds())
+	tenantGroup.PUT("/transfer-settings", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.setTransferSettings())
+	tenantGroup.PUT("/timezone", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.setTimezone())
+	tenantGroup.GET("/users/:id/balance", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getUserBalanceAsOf())
+	tenantGroup.PUT("/users/:id/wallets/:currency/status", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.setWalletStatus())
+	tenantGroup.GET("/balances", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getBalanceTotals())
+	tenantGroup.GET("/fees", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getFeeRules())
+	tenantGroup.PUT("/fees", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.setFeeRule())
+	tenantGroup.GET("/fees/quote", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.quoteFee())
+	tenantGroup.DELETE("/fees/:action", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.retireFeeRule())
+	tenantGroup.GET("/revenue", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getRevenueWallets())
+	tenantGroup.GET("/revenue/history", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getRevenueHistory())
+	tenantGroup.GET("/revenue/daily", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getRevenueDays())
+	tenantGroup.POST("/revenue/withdrawals", tenant.controller.Middleware().TenantAuthMiddleware(), idempotent, tenant.withdrawRevenue())
+	tenantGroup.GET("/revenue/withdrawals", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getRevenueWithdrawals())
+	tenantGroup.GET("/limits", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.getTransactionLimits())
+	tenantGroup.PUT("/limits", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.setTransactionLimit())
+	tenantGroup.DELETE("/limits/:action", tenant.controller.Middleware().TenantAuthMiddleware(), tenant.deleteTransactionLimit())
 
 }
 
@@ -67,6 +91,12 @@ func (t *tenantHandler) createTenant() gin.HandlerFunc {
 		tenant, err := t.controller.CreateTenant(context.Background(), request.toModel())
 		if err != nil {
 			t.logger.Error().Msgf("CreateTenant ::: %v", err)
+
+			if errors.Is(err, controller.ErrInvalidTimezone) {
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+
 			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -162,3 +192,307 @@ func (t *tenantHandler) getAllUsersByTenantID() gin.HandlerFunc {
 		restModel.OkPaginatedResponse(c, http.StatusOK, "users fetched successfully", users, pagination)
 	}
 }
+
+// getFXRates 	godoc
+//
+//	@Summary		getFXRates
+//	@Description	this endpoint gets the fx rates the tenants users convert with, the tenants own rates first and then the default rates
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"fx rates fetched successfully"
+//	@Router			/tenant/fx-rates [get]
+func (t *tenantHandler) getFXRates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getFXRates ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rates, err := t.controller.GetFXRates(context.Background(), tenantID)
+		if err != nil {
+			t.logger.Error().Msgf("getFXRates ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "fx rates fetched successfully", rates)
+	}
+}
+
+// setFXRate 	godoc
+//
+//	@Summary		setFXRate
+//	@Description	this endpoint sets the rate and spread the tenants users convert a currency pair with
+//	@Tags			tenant
+//	@Param			Authorization	header	string			true	"Bearer <token>"
+//	@Param			fxRateRequest	body	fxRateRequest	true	"fx rate request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"fx rate saved successfully"
+//	@Router			/tenant/fx-rates [put]
+func (t *tenantHandler) setFXRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request fxRateRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("setFXRate ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rate, err := t.controller.SetFXRate(context.Background(), &tenantID, request.toModel())
+		if err != nil {
+			t.logger.Error().Msgf("setFXRate ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "fx rate saved successfully", rate)
+	}
+}
+
+// setTransferSettings 	godoc
+//
+//	@Summary		setTransferSettings
+//	@Description	this endpoint sets whether the tenants users may make internal transfers with users of other tenants, a cross tenant transfer needs both tenants to allow it
+//	@Tags			tenant
+//	@Param			Authorization			header	string					true	"Bearer <token>"
+//	@Param			transferSettingsRequest	body	transferSettingsRequest	true	"transfer settings request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"transfer settings saved successfully"
+//	@Router			/tenant/transfer-settings [put]
+func (t *tenantHandler) setTransferSettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request transferSettingsRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("setTransferSettings ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenant, err := t.controller.SetAllowCrossTenantTransfers(context.Background(), tenantID, *request.AllowCrossTenantTransfers)
+		if err != nil {
+			t.logger.Error().Msgf("setTransferSettings ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "transfer settings saved successfully", tenant)
+	}
+}
+
+// setTimezone 	godoc
+//
+//	@Summary		setTimezone
+//	@Description	this endpoint sets the IANA timezone, e.g. Africa/Lagos, the tenant and its users see dates in, e.g. on wallet statements
+//	@Tags			tenant
+//	@Param			Authorization	header	string			true	"Bearer <token>"
+//	@Param			timezoneRequest	body	timezoneRequest	true	"timezone request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"timezone saved successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid timezone"
+//	@Router			/tenant/timezone [put]
+func (t *tenantHandler) setTimezone() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request timezoneRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("setTimezone ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenant, err := t.controller.SetTenantTimezone(context.Background(), tenantID, request.Timezone)
+		if err != nil {
+			t.logger.Error().Msgf("setTimezone ::: %v", err)
+
+			if errors.Is(err, controller.ErrInvalidTimezone) {
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "timezone saved successfully", tenant)
+	}
+}
+
+// refundTransaction 	godoc
+//
+//	@Summary		refundTransaction
+//	@Description	this endpoint refunds all or part of a successful deposit of one of the tenants users through the provider that collected it. Leave out the amount to refund what is left to refund, the reference makes the request idempotent
+//	@Tags			tenant
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
+//	@Param			Authorization	header	string			true	"Bearer <token>"
+//	@Param			id				path	string			true	"transaction ID"
+//	@Param			refundRequest	body	refundRequest	true	"refund request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"refund successful"
+//	@Failure		403	{object}	restModel.GenericResponse	"wallet does not allow debits"
+//	@Failure		404	{object}	restModel.GenericResponse	"transaction not found"
+//	@Failure		409	{object}	restModel.GenericResponse	"reference already used"
+//	@Failure		422	{object}	restModel.GenericResponse	"transaction cannot be refunded for this amount"
+//	@Router			/tenant/transactions/{id}/refunds [post]
+func (t *tenantHandler) refundTransaction() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request refundRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("refundTransaction ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		transactionID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			t.logger.Err(err).Msgf("refundTransaction ::: error parsing transaction id ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var amount *model.Money
+		if request.Amount != "" {
+			parsed, err := restModel.ParseAmount(request.Amount, request.Currency)
+			if err != nil {
+				t.logger.Err(err).Msgf("refundTransaction ::: error parsing amount ==> %s", err)
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			amount = &parsed
+		}
+
+		refund, err := t.controller.RefundTransaction(context.Background(), tenantID, transactionID, amount, request.Reason, request.Reference)
+		if err != nil {
+			t.logger.Error().Msgf("refundTransaction ::: %v", err)
+
+			// the provider may already have paid, a retry with the same idempotency key must not pay again
+			if errors.Is(err, controller.ErrProviderCallFailed) {
+				c.Set(middleware.ProviderCalledInContext, true)
+			}
+
+			restModel.ErrorResponse(c, refundErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "refund successful", refund)
+	}
+}
+
+// getRefunds 	godoc
+//
+//	@Summary		getRefunds
+//	@Description	this endpoint gets the refunds of one of the tenants users transactions
+//	@Tags			tenant
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			id				path	string	true	"transaction ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"refunds fetched successfully"
+//	@Router			/tenant/transactions/{id}/refunds [get]
+func (t *tenantHandler) getRefunds() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("getRefunds ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		transactionID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			t.logger.Err(err).Msgf("getRefunds ::: error parsing transaction id ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		refunds, err := t.controller.GetRefundsByTransactionID(context.Background(), tenantID, transactionID)
+		if err != nil {
+			t.logger.Error().Msgf("getRefunds ::: %v", err)
+			restModel.ErrorResponse(c, refundErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "refunds fetched successfully", refunds)
+	}
+}
+
+// refundErrorStatus maps a refund error to its http status
+func refundErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrRefundReferenceReused):
+		return http.StatusConflict
+	case errors.Is(err, controller.ErrWalletDebitBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, controller.ErrTransactionNotRefundable), errors.Is(err, controller.ErrRefundExceedsAmount),
+		errors.Is(err, controller.ErrInsufficientFunds):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, model.ErrCurrencyMismatch), errors.Is(err, model.ErrInvalidAmount):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
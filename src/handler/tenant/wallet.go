@@ -0,0 +1,83 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/middleware"
+)
+
+// setWalletStatus 	godoc
+//
+//	@Summary		setWalletStatus
+//	@Description	this endpoint restricts the wallet of one of the tenants users in a currency: frozen allows no movement, post_no_debit allows credits only, post_no_credit allows debits only and active lifts the restriction. The reason is recorded with the change
+//	@Tags			tenant
+//	@Param			Authorization		header	string				true	"Bearer <token>"
+//	@Param			id					path	string				true	"user ID"
+//	@Param			currency			path	string				true	"ISO-4217 currency of the wallet"
+//	@Param			walletStatusRequest	body	walletStatusRequest	true	"wallet status request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"wallet status changed successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid wallet status or no wallet for the currency"
+//	@Failure		404	{object}	restModel.GenericResponse	"user not found"
+//	@Router			/tenant/users/{id}/wallets/{currency}/status [put]
+func (t *tenantHandler) setWalletStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request walletStatusRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			t.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tenantID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			t.logger.Err(err).Msgf("setWalletStatus ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			t.logger.Err(err).Msgf("setWalletStatus ::: error parsing user id ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		wallet, err := t.controller.SetWalletStatus(context.Background(), tenantID, userID, c.Param("currency"), model.WalletStatus(request.Status), request.Reason)
+		if err != nil {
+			t.logger.Error().Msgf("setWalletStatus ::: %v", err)
+			restModel.ErrorResponse(c, walletStatusErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "wallet status changed successfully", wallet)
+	}
+}
+
+// walletStatusErrorStatus maps the errors of a wallet status change to a http status
+func walletStatusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrInvalidWalletStatus), errors.Is(err, controller.ErrNoWalletForCurrency):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,17 @@
+package wallet
+
+import (
+	"encoding/json"
+)
+
+type (
+	openWalletRequest struct {
+		Currency string `json:"currency" validate:"required,len=3"`
+	}
+
+	convertRequest struct {
+		Amount     json.Number `json:"amount" validate:"required" swaggertype:"number"`
+		Currency   string      `json:"currency" validate:"required,len=3"`
+		ToCurrency string      `json:"toCurrency" validate:"required,len=3"`
+	}
+)
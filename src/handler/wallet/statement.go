@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"codematic/model"
+	"codematic/pkg/pdf"
+)
+
+const (
+	// statementDateLayout is how the days of a statement are written, in the tenant's timezone
+	statementDateLayout = "2006-01-02"
+	// statementTimeLayout is how the entries of a statement are dated, in the tenant's timezone
+	statementTimeLayout = "2006-01-02 15:04:05"
+)
+
+// statementFileName is the name a statement is downloaded as
+func statementFileName(statement model.Statement, format model.StatementFormat) string {
+	return fmt.Sprintf("statement_%s_%s_%s.%s", statement.Currency,
+		statement.From.Format(statementDateLayout), statement.To.Format(statementDateLayout), format)
+}
+
+// statementCSV renders a statement as a csv file, one row per entry between an opening and a closing balance row
+func statementCSV(statement model.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{
+		{"date", "transaction_id", "type", "flow", "status", "amount", "fees", "balance", "currency"},
+		{statement.From.Format(statementTimeLayout), "", "", "opening_balance", "", "", "", statement.OpeningBalance.Decimal(), statement.Currency},
+	}
+
+	for _, entry := range statement.Entries {
+		rows = append(rows, []string{
+			entry.Date.Format(statementTimeLayout),
+			entry.TransactionID.String(),
+			string(entry.TransactionType),
+			string(entry.TransactionFlow),
+			string(entry.Status),
+			entry.Amount.Decimal(),
+			entry.Fees.Decimal(),
+			entry.Balance.Decimal(),
+			statement.Currency,
+		})
+	}
+
+	rows = append(rows, []string{statement.To.Format(statementTimeLayout), "", "", "closing_balance", "", "", "", statement.ClosingBalance.Decimal(), statement.Currency})
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// statementPDF renders a statement as a printable pdf file, a summary followed by a table of the entries
+func statementPDF(statement model.Statement) []byte {
+	doc := pdf.New()
+	row := func(columns ...string) string {
+		return fmt.Sprintf("%-19s  %-8s  %-16s  %-10s  %14s  %10s  %14s", columns[0], columns[1], columns[2], columns[3], columns[4], columns[5], columns[6])
+	}
+
+	doc.AddLine(fmt.Sprintf("Wallet statement - %s", statement.Currency))
+	doc.AddLine("")
+	doc.AddLine(fmt.Sprintf("Period:          %s to %s (%s)", statement.From.Format(statementDateLayout), statement.To.Format(statementDateLayout), statement.Timezone))
+	doc.AddLine(fmt.Sprintf("Opening balance: %s", statement.OpeningBalance))
+	doc.AddLine(fmt.Sprintf("Total credits:   %s", statement.TotalCredits))
+	doc.AddLine(fmt.Sprintf("Total debits:    %s", statement.TotalDebits))
+	doc.AddLine(fmt.Sprintf("Total fees:      %s", statement.TotalFees))
+	doc.AddLine(fmt.Sprintf("Closing balance: %s", statement.ClosingBalance))
+	doc.AddLine("")
+
+	header := row("Date", "Type", "Flow", "Status", "Amount", "Fees", "Balance")
+	doc.AddLine(header)
+	doc.AddLine(strings.Repeat("-", len(header)))
+
+	for _, entry := range statement.Entries {
+		doc.AddLine(row(
+			entry.Date.Format(statementTimeLayout),
+			string(entry.TransactionType),
+			string(entry.TransactionFlow),
+			string(entry.Status),
+			entry.Amount.Decimal(),
+			entry.Fees.Decimal(),
+			entry.Balance.Decimal(),
+		))
+	}
+
+	if len(statement.Entries) == 0 {
+		doc.AddLine("No movements in this period")
+	}
+
+	doc.AddLine("")
+	doc.AddLine(fmt.Sprintf("Generated on %s", statement.GeneratedAt.Format(time.RFC1123)))
+
+	return doc.Bytes()
+}
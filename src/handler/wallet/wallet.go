@@ -2,7 +2,11 @@ package wallet
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -10,6 +14,7 @@ import (
 
 	"codematic/controller"
 	restModel "codematic/handler/model"
+	"codematic/model"
 	"codematic/pkg/environment"
 	"codematic/pkg/middleware"
 )
@@ -31,14 +36,20 @@ func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *env
 	walletGroup := r.Group("/wallet")
 
 	walletGroup.GET("", wallet.controller.Middleware().AuthMiddleware(), wallet.getWalletByUserID())
+	walletGroup.POST("", wallet.controller.Middleware().AuthMiddleware(), wallet.openWallet())
+	walletGroup.GET("/fx/quote", wallet.controller.Middleware().AuthMiddleware(), wallet.quoteFX())
+	walletGroup.POST("/convert", wallet.controller.Middleware().AuthMiddleware(), wallet.controller.Middleware().IdempotencyMiddleware(wallet.controller), wallet.convert())
+	walletGroup.GET("/statement", wallet.controller.Middleware().AuthMiddleware(), wallet.getStatement())
+	walletGroup.GET("/balance", wallet.controller.Middleware().AuthMiddleware(), wallet.getBalanceAsOf())
 }
 
 // getWalletByUserID 	godoc
 //
 //	@Summary		getWalletByUserID
-//	@Description	this endpoint gets gets a users wallet balance
+//	@Description	this endpoint gets a users wallets, one per currency, or the wallet of a single currency
 //	@Tags			wallet
 //	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			currency		query	string	false	"ISO-4217 currency of the wallet, all wallets when empty"
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	restModel.GenericResponse	"wallet balance fetched successfully"
@@ -52,13 +63,308 @@ func (w *walletHandler) getWalletByUserID() gin.HandlerFunc {
 			return
 		}
 
-		wallet, err := w.controller.GetWalletByUserID(context.Background(), userID)
+		if currency := c.Query("currency"); currency != "" {
+			wallet, err := w.controller.GetWalletByUserID(context.Background(), userID, currency)
+			if err != nil {
+				w.logger.Err(err).Msgf("getWalletByUserID :::  ==> %s", err)
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			restModel.OkResponse(c, http.StatusOK, "wallet balance fetched successfully", wallet)
+			return
+		}
+
+		wallets, err := w.controller.GetWalletsByUserID(context.Background(), userID)
 		if err != nil {
 			w.logger.Err(err).Msgf("getWalletByUserID :::  ==> %s", err)
 			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		restModel.OkResponse(c, http.StatusOK, "wallet balance fetched successfully", wallet)
+		restModel.OkResponse(c, http.StatusOK, "wallet balance fetched successfully", wallets)
+	}
+}
+
+// openWallet 	godoc
+//
+//	@Summary		openWallet
+//	@Description	this endpoint opens a wallet in another currency for the user, an existing wallet is returned as is
+//	@Tags			wallet
+//	@Param			Authorization		header	string				true	"Bearer <token>"
+//	@Param			openWalletRequest	body	openWalletRequest	true	"open wallet request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	restModel.GenericResponse	"wallet opened successfully"
+//	@Router			/wallet [post]
+func (w *walletHandler) openWallet() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request openWalletRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			w.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			w.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, restModel.ErrIncompleteDetails.Error())
+			return
+		}
+
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			w.logger.Err(err).Msgf("openWallet ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		wallet, err := w.controller.OpenWallet(context.Background(), userID, request.Currency)
+		if err != nil {
+			w.logger.Err(err).Msgf("openWallet ::: ==> %s", err)
+
+			if errors.Is(err, model.ErrUnsupportedCurrency) {
+				restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusCreated, "wallet opened successfully", wallet)
+	}
+}
+
+// quoteFX 	godoc
+//
+//	@Summary		quoteFX
+//	@Description	this endpoint quotes a conversion between two of the users wallets at the tenants rate
+//	@Tags			wallet
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			amount			query	number	true	"amount to convert, in major units"
+//	@Param			from			query	string	true	"currency of the amount"
+//	@Param			to				query	string	true	"currency to convert into"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"fx quote fetched successfully"
+//	@Router			/wallet/fx/quote [get]
+func (w *walletHandler) quoteFX() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			w.logger.Err(err).Msgf("quoteFX ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		amount, err := restModel.ParseAmount(json.Number(c.Query("amount")), c.Query("from"))
+		if err != nil {
+			w.logger.Err(err).Msgf("quoteFX ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		quote, err := w.controller.QuoteFX(context.Background(), userID, amount, c.Query("to"))
+		if err != nil {
+			w.logger.Err(err).Msgf("quoteFX ::: ==> %s", err)
+			restModel.ErrorResponse(c, fxErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "fx quote fetched successfully", quote)
+	}
+}
+
+// convert 	godoc
+//
+//	@Summary		convert
+//	@Description	this endpoint converts an amount from one of the users wallets into another of the users wallets at the tenants rate
+//	@Tags			wallet
+//	@Param			Idempotency-Key	header	string	false	"a retry with the same key gets the first response replayed"
+//	@Param			Authorization	header	string			true	"Bearer <token>"
+//	@Param			convertRequest	body	convertRequest	true	"convert request body"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"conversion successful"
+//	@Failure		403	{object}	restModel.GenericResponse	"wallet does not allow the conversion"
+//	@Failure		422	{object}	restModel.GenericResponse	"insufficient funds or transaction limit exceeded"
+//	@Router			/wallet/convert [post]
+func (w *walletHandler) convert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request convertRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			w.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "incomplete details please fill out the missing details")
+			return
+		}
+
+		if err := restModel.ValidateRequest(request); err != nil {
+			w.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, restModel.ErrIncompleteDetails.Error())
+			return
+		}
+
+		amount, err := restModel.ParseAmount(request.Amount, request.Currency)
+		if err != nil {
+			w.logger.Err(err).Msgf("convert ::: error parsing amount ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			w.logger.Err(err).Msgf("convert ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		conversion, err := w.controller.ConvertFX(context.Background(), userID, amount, request.ToCurrency)
+		if err != nil {
+			w.logger.Err(err).Msgf("convert ::: ==> %s", err)
+			restModel.ErrorResponse(c, fxErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "conversion successful", conversion)
+	}
+}
+
+// getStatement 	godoc
+//
+//	@Summary		getStatement
+//	@Description	this endpoint builds the statement of the users wallet in a currency between two days of the tenants timezone, both included: the opening balance, every movement with the balance it left and its fees, and the closing balance. It is returned as json, or downloaded as a csv or pdf file
+//	@Tags			wallet
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			currency		query	string	true	"ISO-4217 currency of the wallet"
+//	@Param			from			query	string	true	"first day, YYYY-MM-DD"
+//	@Param			to				query	string	true	"last day, YYYY-MM-DD"
+//	@Param			format			query	string	false	"json (default), csv or pdf"
+//	@Accept			json
+//	@Produce		json
+//	@Produce		text/csv
+//	@Produce		application/pdf
+//	@Success		200	{object}	restModel.GenericResponse	"statement fetched successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid statement period"
+//	@Router			/wallet/statement [get]
+func (w *walletHandler) getStatement() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			w.logger.Err(err).Msgf("getStatement ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		from, fromErr := time.Parse(statementDateLayout, c.Query("from"))
+		to, toErr := time.Parse(statementDateLayout, c.Query("to"))
+		if fromErr != nil || toErr != nil || c.Query("currency") == "" {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "currency, from and to are required, from and to as YYYY-MM-DD")
+			return
+		}
+
+		format := model.StatementFormat(c.DefaultQuery("format", string(model.StatementFormatJSON)))
+		if !format.IsValid() {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "format can either be json, csv or pdf")
+			return
+		}
+
+		statement, err := w.controller.GetWalletStatement(context.Background(), userID, c.Query("currency"), from, to)
+		if err != nil {
+			w.logger.Err(err).Msgf("getStatement ::: ==> %s", err)
+			restModel.ErrorResponse(c, statementErrorStatus(err), err.Error())
+			return
+		}
+
+		switch format {
+		case model.StatementFormatCSV:
+			file, err := statementCSV(statement)
+			if err != nil {
+				w.logger.Err(err).Msgf("getStatement ::: error writing csv ==> %s", err)
+				restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", statementFileName(statement, format)))
+			c.Data(http.StatusOK, "text/csv", file)
+		case model.StatementFormatPDF:
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", statementFileName(statement, format)))
+			c.Data(http.StatusOK, "application/pdf", statementPDF(statement))
+		default:
+			restModel.OkResponse(c, http.StatusOK, "statement fetched successfully", statement)
+		}
+	}
+}
+
+// getBalanceAsOf 	godoc
+//
+//	@Summary		getBalanceAsOf
+//	@Description	this endpoint returns the balance of the users wallet in a currency at a point in time, every movement made before it. It is computed from the latest daily snapshot before that time and the movements made since
+//	@Tags			wallet
+//	@Param			Authorization	header	string	true	"Bearer <token>"
+//	@Param			currency		query	string	true	"ISO-4217 currency of the wallet"
+//	@Param			as_of			query	string	false	"RFC3339 time, now when empty"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"balance fetched successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"invalid as_of or no wallet for the currency"
+//	@Router			/wallet/balance [get]
+func (w *walletHandler) getBalanceAsOf() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString(middleware.ActorIDInContext))
+		if err != nil {
+			w.logger.Err(err).Msgf("getBalanceAsOf ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		asOf := time.Now()
+		if c.Query("as_of") != "" {
+			if asOf, err = time.Parse(time.RFC3339, c.Query("as_of")); err != nil {
+				restModel.ErrorResponse(c, http.StatusBadRequest, "as_of must be an RFC3339 time")
+				return
+			}
+		}
+
+		if c.Query("currency") == "" {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "currency is required")
+			return
+		}
+
+		balance, err := w.controller.GetBalanceAsOf(context.Background(), userID, c.Query("currency"), asOf)
+		if err != nil {
+			w.logger.Err(err).Msgf("getBalanceAsOf ::: ==> %s", err)
+			restModel.ErrorResponse(c, statementErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "balance fetched successfully", balance)
+	}
+}
+
+// statementErrorStatus maps the errors of a statement to a http status
+func statementErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrInvalidStatementPeriod), errors.Is(err, controller.ErrNoWalletForCurrency):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// fxErrorStatus maps the errors of a quote or a conversion to a http status
+func fxErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrInsufficientFunds), errors.Is(err, controller.ErrLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, controller.ErrWalletDebitBlocked), errors.Is(err, controller.ErrWalletCreditBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, controller.ErrNoFXRate), errors.Is(err, controller.ErrNoWalletForCurrency),
+		errors.Is(err, model.ErrUnsupportedCurrency), errors.Is(err, model.ErrCurrencyMismatch):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
 	}
 }
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"codematic/controller"
+	restModel "codematic/handler/model"
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// getEvents 	godoc
+//
+//	@Summary		getEvents
+//	@Description	this endpoint gets the webhooks the payment providers delivered, newest first, with their raw body, headers and processing status
+//	@Tags			webhook
+//	@Param			X-Admin-Key	header	string	true	"admin api key"
+//	@Param			provider	query	string	false	"paystack or flutterwave"
+//	@Param			status		query	string	false	"received, processed, ignored, failed or rejected"
+//	@Param			page		query	string	false	"page"
+//	@Param			size		query	string	false	"size"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"webhook events fetched successfully"
+//	@Router			/admin/webhooks [get]
+func (w webhookHandler) getEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var provider *model.PaymentProvider
+		if query := c.Query("provider"); query != "" {
+			p := model.PaymentProvider(query)
+			provider = &p
+		}
+
+		var status *model.WebhookEventStatus
+		if query := c.Query("status"); query != "" {
+			s := model.WebhookEventStatus(query)
+			status = &s
+		}
+
+		events, pageInfo, err := w.controller.GetWebhookEvents(context.Background(), provider, status, helper.ParsePageParams(c))
+		if err != nil {
+			w.logger.Error().Msgf("getEvents ::: %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		restModel.OkPaginatedResponse(c, http.StatusOK, "webhook events fetched successfully", events, pageInfo)
+	}
+}
+
+// getEvent 	godoc
+//
+//	@Summary		getEvent
+//	@Description	this endpoint gets a webhook event with its raw body, headers, processing status and last error
+//	@Tags			webhook
+//	@Param			X-Admin-Key	header	string	true	"admin api key"
+//	@Param			id			path	string	true	"webhook event ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"webhook event fetched successfully"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Router			/admin/webhooks/{id} [get]
+func (w webhookHandler) getEvent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			w.logger.Err(err).Msgf("getEvent ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		event, err := w.controller.GetWebhookEvent(context.Background(), eventID)
+		if err != nil {
+			w.logger.Error().Msgf("getEvent ::: %v", err)
+			restModel.ErrorResponse(c, webhookEventErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "webhook event fetched successfully", event)
+	}
+}
+
+// replayEvent 	godoc
+//
+//	@Summary		replayEvent
+//	@Description	this endpoint processes a webhook event that was not processed again, from the body stored in the inbox. An event that was already processed is never applied twice
+//	@Tags			webhook
+//	@Param			X-Admin-Key	header	string	true	"admin api key"
+//	@Param			id			path	string	true	"webhook event ID"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	restModel.GenericResponse	"webhook event replayed successfully"
+//	@Failure		400	{object}	restModel.GenericResponse	"processing failed again"
+//	@Failure		404	{object}	restModel.GenericResponse	"record not found"
+//	@Failure		409	{object}	restModel.GenericResponse	"webhook event was already processed or was rejected"
+//	@Router			/admin/webhooks/{id}/replay [post]
+func (w webhookHandler) replayEvent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			w.logger.Err(err).Msgf("replayEvent ::: error parsing uuid ==> %s", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		event, err := w.controller.ReplayWebhookEvent(context.Background(), eventID)
+		if err != nil {
+			w.logger.Error().Msgf("replayEvent ::: %v", err)
+			restModel.ErrorResponse(c, webhookEventErrorStatus(err), err.Error())
+			return
+		}
+
+		restModel.OkResponse(c, http.StatusOK, "webhook event replayed successfully", event)
+	}
+}
+
+// webhookEventErrorStatus maps a controller error of the webhook inbox to its http status, an event whose processing
+// failed is a bad request like a failed delivery
+func webhookEventErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrWebhookEventProcessed), errors.Is(err, controller.ErrWebhookEventRejected):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -3,8 +3,8 @@ package webhook
 
 import (
 	"context"
+	"io"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
@@ -13,6 +13,7 @@ import (
 	restModel "codematic/handler/model"
 	"codematic/model"
 	"codematic/pkg/environment"
+	"codematic/pkg/middleware"
 )
 
 type webhookHandler struct {
@@ -30,43 +31,48 @@ func New(r *gin.RouterGroup, l zerolog.Logger, c controller.Operations, env *env
 	}
 
 	webhookGroup := r.Group("/webhook")
-	webhookGroup.POST("/payment", webhk.processPayment())
+	webhookGroup.POST("/paystack", webhk.controller.Middleware().WebhookSignatureMiddleware(webhk.controller, model.PaymentProviderPaystack), webhk.processPayment())
+	webhookGroup.POST("/flutterwave", webhk.controller.Middleware().WebhookSignatureMiddleware(webhk.controller, model.PaymentProviderFlutterwave), webhk.processPayment())
+
+	adminGroup := r.Group("/admin/webhooks", webhk.controller.Middleware().AdminAuthMiddleware())
+	adminGroup.GET("", webhk.getEvents())
+	adminGroup.GET("/:id", webhk.getEvent())
+	adminGroup.POST("/:id/replay", webhk.replayEvent())
 }
 
+// processPayment 	godoc
+//
+//	@Summary		processPayment
+//	@Description	this endpoint receives the webhooks of a payment provider as the provider sends them, signed with its x-paystack-signature or verif-hash header. Paystack's charge.success, transfer.success, transfer.failed and transfer.reversed, and Flutterwave's charge.completed and transfer.completed are applied to the transaction their reference points at, other event types are acknowledged and ignored
+//	@Tags			webhook
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	string						"success"
+//	@Failure		400	{object}	restModel.GenericResponse	"processing failed, the provider's redelivery processes it again"
+//	@Failure		401	{object}	restModel.GenericResponse	"webhook signature is missing or invalid"
+//	@Failure		403	{object}	restModel.GenericResponse	"webhook source is not allowed"
+//	@Failure		413	{object}	restModel.GenericResponse	"webhook body is too large"
+//	@Router			/webhook/paystack [post]
+//	@Router			/webhook/flutterwave [post]
 func (w webhookHandler) processPayment() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var request model.PaymentWebhook
-
-		if err := c.ShouldBindJSON(&request); err != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
 			w.logger.Error().Msgf("%v", err)
+			restModel.ErrorResponse(c, http.StatusBadRequest, "unable to read request body")
 			return
 		}
 
-		// set a dunny authentication to prevent webhook from updating records if it is not coming from the set payment gateway
-		paymentAuth := c.GetHeader("auth")
-		if paymentAuth != "payment" {
-			restModel.ErrorResponse(c, http.StatusBadRequest, "this webhook is not from our payment gateway")
-			return
-		}
-
-		// add some validation since we are simulation the webhook just to ensure everything works as it should.
-		if !strings.Contains(request.Data.Reference, "_") {
-			restModel.ErrorResponse(c, http.StatusBadRequest, "the reference must contain an underscore")
-			return
-		}
-
-		txID := strings.Split(request.Data.Reference, "_")
-		if txID[0] != "dbt" && txID[0] != "crt" {
-			restModel.ErrorResponse(c, http.StatusBadRequest, "the reference format is dbt... or crt...")
-			return
-		}
-
-		if request.Data.Status != "success" && request.Data.Status != "failed" && request.Data.Status != "pending" {
-			restModel.ErrorResponse(c, http.StatusBadRequest, "status can either be success, failed, pending")
-			return
+		// the signature middleware authenticated the provider, the delivery is kept in the inbox as it came before
+		// it is processed
+		event := model.WebhookEvent{
+			Provider: model.PaymentProvider(c.GetString(middleware.WebhookProviderInContext)),
+			Source:   c.ClientIP(),
+			Body:     string(body),
+			Headers:  model.WebhookHeaders(c.Request.Header),
 		}
 
-		if err := w.controller.ProcessPaymentWebhook(context.Background(), request); err != nil {
+		if _, err := w.controller.ReceiveWebhookEvent(context.Background(), event); err != nil {
 			w.logger.Error().Msgf("error performing update on transaction %v", err)
 			restModel.ErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
@@ -10,6 +10,8 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	// embeds the timezone database, tenants pick their timezone and the image may not have one
+	_ "time/tzdata"
 
 	ginzerolog "github.com/dn365/gin-zerolog"
 	"github.com/gin-contrib/cors"
@@ -46,8 +48,6 @@ import (
 // @query.collection.format	multi
 // @securityDefinitions.basic	BasicAuth
 func main() {
-	// set global application timezone
-	_ = os.Setenv("TZ", "Africa/Lagos")
 	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
 	applicationLogger := logger.With().Str(helper.LogStrKeyModule, "app").Logger()
 	r := gin.New()
@@ -72,6 +72,13 @@ func main() {
 		panic(err) // panic - this service should not start up
 	}
 
+	// the client ip is only read from X-Forwarded-For when the request comes through one of our proxies, otherwise
+	// any client could pick its own ip and get past the webhook ip allowlists
+	if err := r.SetTrustedProxies(trustedProxies(env.Get("TRUSTED_PROXIES"))); err != nil {
+		applicationLogger.Fatal().Err(err)
+		panic(err) // panic - this service should not start up either
+	}
+
 	storage := codematicStorage.New(logger, env)
 	defer storage.Close()
 	// run automigration
@@ -86,6 +93,17 @@ func main() {
 	newMiddleware := middleware.NewMiddleware(logger, *env, storage)
 	application := controller.New(logger, storage, newMiddleware)
 
+	// tenants can change their fx rates through the api, the file only seeds them
+	if path := env.Get("FX_RATES_FILE"); path != "" {
+		loaded, err := (*application).LoadFXRatesFromFile(context.Background(), path)
+		if err != nil {
+			applicationLogger.Fatal().Err(err)
+			panic(err) // panic - rates the operator asked for could not be loaded
+		}
+
+		applicationLogger.Info().Msgf("loaded %d fx rates from %s", loaded, path)
+	}
+
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "go ninjas are alive",
@@ -107,6 +125,54 @@ func main() {
 		Handler: r,
 	}
 
+	// background jobs stop with the server
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go runEvery(jobsCtx, applicationLogger, "expire holds", time.Minute, func(ctx context.Context) error {
+		_, err := (*application).ExpireHolds(ctx)
+		return err
+	})
+
+	// refunds are sent to the provider right after they are created, this picks up those a restart interrupted
+	go runEvery(jobsCtx, applicationLogger, "retry stuck refunds", time.Minute, func(ctx context.Context) error {
+		_, err := (*application).RetryStuckRefunds(ctx)
+		return err
+	})
+
+	// the closing balances of a day are taken once it has ended in each tenant's timezone, hourly catches every offset
+	go runEvery(jobsCtx, applicationLogger, "snapshot balances", time.Hour, func(ctx context.Context) error {
+		_, err := (*application).SnapshotBalances(ctx)
+		return err
+	})
+
+	// breaks are only reported here, repairing them is left to the verifyledger command
+	go runEvery(jobsCtx, applicationLogger, "verify ledger", 24*time.Hour, func(ctx context.Context) error {
+		_, err := (*application).VerifyLedger(ctx, false)
+		return err
+	})
+
+	// batches are paid right after they are uploaded, this picks up those a restart interrupted
+	go runEvery(jobsCtx, applicationLogger, "process payout batches", time.Minute, func(ctx context.Context) error {
+		_, err := (*application).ProcessPayoutBatches(ctx)
+		return err
+	})
+
+	go runEvery(jobsCtx, applicationLogger, "expire invoices", time.Minute, func(ctx context.Context) error {
+		_, err := (*application).ExpireInvoices(ctx)
+		return err
+	})
+
+	go runEvery(jobsCtx, applicationLogger, "release due escrows", time.Minute, func(ctx context.Context) error {
+		_, err := (*application).ReleaseDueEscrows(ctx)
+		return err
+	})
+
+	go runEvery(jobsCtx, applicationLogger, "purge idempotency keys", time.Hour, func(ctx context.Context) error {
+		_, err := (*application).PurgeIdempotencyKeys(ctx)
+		return err
+	})
+
 	go func() {
 		// service connections
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -139,6 +205,18 @@ func main() {
 	applicationLogger.Info().Msgf("Server exiting")
 }
 
+// trustedProxies splits the comma separated ips and cidrs of the proxies in front of the service, none when empty
+func trustedProxies(list string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(list, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
+}
+
 // GinContextToContextMiddleware middleware for gin context
 func GinContextToContextMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -147,3 +225,20 @@ func GinContextToContextMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// runEvery runs job on every tick of interval until ctx is done. A failed run is logged and retried on the next tick
+func runEvery(ctx context.Context, logger zerolog.Logger, name string, interval time.Duration, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job(ctx); err != nil {
+				logger.Err(err).Msgf("scheduled job %s failed: %v", name, err)
+			}
+		}
+	}
+}
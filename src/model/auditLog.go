@@ -34,6 +34,8 @@ const (
 	ActorTenant Actor = "tenant"
 	// ActorUser when user makes the action
 	ActorUser Actor = "user"
+	// ActorSystem when a scheduled job makes the action
+	ActorSystem Actor = "system"
 
 	// ActionCreated is the action when the transaction is created
 	ActionCreated AuditLogAction = "created"
@@ -47,4 +49,12 @@ const (
 	ActionInDispute AuditLogAction = "in_dispute"
 	// ActionResolved is the action when the transaction dispute is resolved
 	ActionResolved AuditLogAction = "resolved"
+	// ActionExpired is the action when a hold on the transaction expires
+	ActionExpired AuditLogAction = "expired"
+	// ActionUpdated is the action when a setting is changed
+	ActionUpdated AuditLogAction = "updated"
+	// ActionLimitExceeded is the action when a transaction is refused for breaking a limit of the tenant
+	ActionLimitExceeded AuditLogAction = "limit_exceeded"
+	// ActionReversed is the action when the provider reverses a transfer and its funds come back to the wallet
+	ActionReversed AuditLogAction = "reversed"
 )
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SnapshotDayLayout is how the day of a balance snapshot is written, a day of the tenant's timezone
+const SnapshotDayLayout = "2006-01-02"
+
+type (
+	// BalanceSnapshot schema, the closing balance of a wallet at the end of a day of its tenant's timezone: the sum
+	// of every posting made to the wallet's ledger account before ClosedAt, the following midnight
+	BalanceSnapshot struct {
+		ID              uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		WalletID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_balance_snapshots_wallet_day" json:"wallet_id"`
+		Day             string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_balance_snapshots_wallet_day;index" json:"day"`
+		UserID          uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+		TenantID        uuid.UUID `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		LedgerAccountID uuid.UUID `gorm:"type:uuid;not null" json:"ledger_account_id"`
+		Balance         Money     `gorm:"embedded;embeddedPrefix:balance_" json:"balance"`
+		ClosedAt        time.Time `gorm:"not null;index" json:"closed_at"`
+		CreatedAt       time.Time `gorm:"default:now()" json:"created_at"`
+	}
+
+	// BalanceAsOf is the balance of a wallet at a point in time, every movement made before AsOf. SnapshotDay is
+	// the day of the snapshot it was computed from, empty when there was none
+	BalanceAsOf struct {
+		WalletID    uuid.UUID `json:"wallet_id"`
+		UserID      uuid.UUID `json:"user_id"`
+		AsOf        time.Time `json:"as_of"`
+		Balance     Money     `json:"balance"`
+		SnapshotDay string    `json:"snapshot_day,omitempty"`
+	}
+
+	// BalanceTotal is the sum of the closing balances of a tenant's wallets in one currency at the end of a day
+	BalanceTotal struct {
+		Day      string `json:"day"`
+		Currency string `json:"currency"`
+		Wallets  int64  `json:"wallets"`
+		Total    Money  `json:"total"`
+	}
+)
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DisputeStatusOpened is a dispute just opened, the disputed amount is frozen on the wallet
+	DisputeStatusOpened DisputeStatus = "opened"
+	// DisputeStatusEvidenceSubmitted is a dispute evidence was attached to, waiting for a decision
+	DisputeStatusEvidenceSubmitted DisputeStatus = "evidence_submitted"
+	// DisputeStatusWon is a dispute decided for the user, the frozen amount is given back
+	DisputeStatusWon DisputeStatus = "won"
+	// DisputeStatusLost is a dispute decided against the user, the disputed amount is debited from the wallet
+	DisputeStatusLost DisputeStatus = "lost"
+	// DisputeStatusResolved is a dispute closed without a decision, e.g. withdrawn, the frozen amount is given back
+	DisputeStatusResolved DisputeStatus = "resolved"
+
+	// DisputeHoldTTL is how long the amount of an undecided dispute stays frozen
+	DisputeHoldTTL = 180 * 24 * time.Hour
+)
+
+// disputeTransitions lists the statuses a dispute can move to from each status, won, lost and resolved are final
+var disputeTransitions = map[DisputeStatus][]DisputeStatus{
+	DisputeStatusOpened:            {DisputeStatusEvidenceSubmitted, DisputeStatusWon, DisputeStatusLost, DisputeStatusResolved},
+	DisputeStatusEvidenceSubmitted: {DisputeStatusEvidenceSubmitted, DisputeStatusWon, DisputeStatusLost, DisputeStatusResolved},
+}
+
+type (
+	// DisputeStatus of type string
+	DisputeStatus string
+
+	// Dispute schema. A dispute is opened by a user or their tenant against a successful deposit, e.g. a chargeback
+	// of the card that paid it. The disputed amount is frozen by a hold for the pending debit the dispute posts
+	// if it is lost
+	Dispute struct {
+		ID                 uuid.UUID         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID           uuid.UUID         `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		UserID             uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id"`
+		TransactionID      uuid.UUID         `gorm:"type:uuid;not null;index" json:"transaction_id"`
+		DebitTransactionID uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex" json:"debit_transaction_id"`
+		OpenedBy           Actor             `gorm:"type:varchar(100);not null" json:"opened_by"`
+		Amount             Money             `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		Reason             string            `gorm:"type:text" json:"reason"`
+		Status             DisputeStatus     `gorm:"type:varchar(50);not null;index" json:"status"`
+		Evidence           []DisputeEvidence `gorm:"foreignKey:DisputeID" json:"evidence"`
+		ClosedAt           *time.Time        `json:"closed_at,omitempty"`
+		CreatedAt          time.Time         `gorm:"default:now()" json:"created_at"`
+		UpdatedAt          *time.Time        `json:"updated_at,omitempty"`
+		DeletedAt          gorm.DeletedAt    `gorm:"index" json:"-"`
+	}
+
+	// DisputeEvidence schema, a note attached to a dispute by the user or the tenant
+	DisputeEvidence struct {
+		ID          uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		DisputeID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"dispute_id"`
+		SubmittedBy Actor          `gorm:"type:varchar(100);not null" json:"submitted_by"`
+		Note        string         `gorm:"type:text;not null" json:"note"`
+		CreatedAt   time.Time      `gorm:"default:now()" json:"created_at"`
+		DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+)
+
+// CanMoveTo reports whether a dispute in the status can move to the next status
+func (s DisputeStatus) CanMoveTo(next DisputeStatus) bool {
+	for _, status := range disputeTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsOpen reports whether the dispute is still waiting for a decision
+func (s DisputeStatus) IsOpen() bool {
+	return s == DisputeStatusOpened || s == DisputeStatusEvidenceSubmitted
+}
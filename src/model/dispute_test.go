@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisputeStatusCanMoveTo(t *testing.T) {
+	tests := []struct {
+		from DisputeStatus
+		to   DisputeStatus
+		ok   bool
+	}{
+		{from: DisputeStatusOpened, to: DisputeStatusEvidenceSubmitted, ok: true},
+		{from: DisputeStatusOpened, to: DisputeStatusLost, ok: true},
+		{from: DisputeStatusEvidenceSubmitted, to: DisputeStatusEvidenceSubmitted, ok: true},
+		{from: DisputeStatusEvidenceSubmitted, to: DisputeStatusWon, ok: true},
+		{from: DisputeStatusEvidenceSubmitted, to: DisputeStatusResolved, ok: true},
+		{from: DisputeStatusOpened, to: DisputeStatusOpened, ok: false},
+		{from: DisputeStatusWon, to: DisputeStatusLost, ok: false},
+		{from: DisputeStatusLost, to: DisputeStatusEvidenceSubmitted, ok: false},
+		{from: DisputeStatusResolved, to: DisputeStatusWon, ok: false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.ok, tt.from.CanMoveTo(tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// EscrowStatusHeld is an escrow whose funds are held, waiting to be released or refunded
+	EscrowStatusHeld EscrowStatus = "held"
+	// EscrowStatusReleased is an escrow whose funds were paid to the seller
+	EscrowStatusReleased EscrowStatus = "released"
+	// EscrowStatusRefunded is an escrow whose funds were given back to the buyer
+	EscrowStatusRefunded EscrowStatus = "refunded"
+
+	// MaxEscrowAutoRelease is the furthest in the future an escrow can be released on its own
+	MaxEscrowAutoRelease = 365 * 24 * time.Hour
+)
+
+type (
+	// EscrowStatus of type string
+	EscrowStatus string
+
+	// Escrow schema, funds a buyer set aside for a seller. They leave the buyer's wallet for the buyer's escrow
+	// account when the escrow is created, and go to the seller's wallet on release or back to the buyer's on refund.
+	// An escrow with AutoReleaseAt is released on its own once that time passes
+	Escrow struct {
+		ID                      uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID                uuid.UUID      `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		BuyerID                 uuid.UUID      `gorm:"type:uuid;not null;index" json:"buyer_id"`
+		SellerID                uuid.UUID      `gorm:"type:uuid;not null;index" json:"seller_id"`
+		Amount                  Money          `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		Description             string         `gorm:"type:text" json:"description,omitempty"`
+		Status                  EscrowStatus   `gorm:"type:varchar(50);not null;index" json:"status"`
+		AutoReleaseAt           *time.Time     `gorm:"index" json:"auto_release_at,omitempty"`
+		FundingTransactionID    uuid.UUID      `gorm:"type:uuid;not null" json:"funding_transaction_id"`
+		SettlementTransactionID *uuid.UUID     `gorm:"type:uuid" json:"settlement_transaction_id,omitempty"`
+		ClosedBy                *Actor         `gorm:"type:varchar(100)" json:"closed_by,omitempty"`
+		ClosedAt                *time.Time     `json:"closed_at,omitempty"`
+		CreatedAt               time.Time      `gorm:"default:now()" json:"created_at"`
+		UpdatedAt               *time.Time     `json:"updated_at,omitempty"`
+		DeletedAt               gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+)
+
+// IsDue reports whether a held escrow is to be released on its own at now
+func (e Escrow) IsDue(now time.Time) bool {
+	return e.Status == EscrowStatusHeld && e.AutoReleaseAt != nil && !now.Before(*e.AutoReleaseAt)
+}
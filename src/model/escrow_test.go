@@ -0,0 +1,20 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscrowIsDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	require.False(t, Escrow{Status: EscrowStatusHeld}.IsDue(now), "held without a deadline")
+	require.False(t, Escrow{Status: EscrowStatusHeld, AutoReleaseAt: &future}.IsDue(now))
+	require.True(t, Escrow{Status: EscrowStatusHeld, AutoReleaseAt: &past}.IsDue(now))
+	require.True(t, Escrow{Status: EscrowStatusHeld, AutoReleaseAt: &now}.IsDue(now), "due at the deadline itself")
+	require.False(t, Escrow{Status: EscrowStatusReleased, AutoReleaseAt: &past}.IsDue(now))
+	require.False(t, Escrow{Status: EscrowStatusRefunded, AutoReleaseAt: &past}.IsDue(now))
+}
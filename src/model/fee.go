@@ -0,0 +1,282 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// FeeActionDeposit prices deposits, the fee is taken off the amount credited to the wallet
+	FeeActionDeposit FeeAction = "deposit"
+	// FeeActionTransfer prices withdrawals to a bank account, the fee is debited on top of the amount
+	FeeActionTransfer FeeAction = "transfer"
+	// FeeActionInternalTransfer prices transfers between wallets, the fee is debited from the sender on top of the amount
+	FeeActionInternalTransfer FeeAction = "internal_transfer"
+	// FeeActionFX prices currency conversions, the fee is debited from the source wallet on top of the amount
+	FeeActionFX FeeAction = "fx"
+
+	// FeeTypeFlat charges the flat fee whatever the amount
+	FeeTypeFlat FeeType = "flat"
+	// FeeTypePercentage charges a percentage of the amount plus the flat fee
+	FeeTypePercentage FeeType = "percentage"
+	// FeeTypeTiered charges the flat fee and percentage of the first tier the amount falls in
+	FeeTypeTiered FeeType = "tiered"
+
+	// LegacyFeeBasisPoints is the 10% deposits and transfers were charged in every currency before tenants had fee
+	// rules, the tenants of that time keep it as their first rule for both
+	LegacyFeeBasisPoints int64 = 1000
+)
+
+var (
+	// ErrInvalidFeeRule when a fee rule is not one that can price an amount
+	ErrInvalidFeeRule = errors.New("invalid fee rule")
+	// ErrInvalidFeeAction when a fee action is none of deposit, transfer, internal_transfer or fx
+	ErrInvalidFeeAction = errors.New("fee action can either be deposit, transfer, internal_transfer or fx")
+)
+
+type (
+	// FeeAction is the kind of movement a fee rule prices
+	FeeAction string
+
+	// FeeType is how a fee rule prices an amount
+	FeeType string
+
+	// FeeTier prices the amounts up to UpTo, included, that a previous tier does not. A zero UpTo has no upper bound,
+	// only the last tier can have none
+	FeeTier struct {
+		UpTo               Money `json:"up_to"`
+		FlatFee            Money `json:"flat_fee"`
+		PercentBasisPoints int64 `json:"percent_basis_points"`
+	}
+
+	// FeeRule schema, how a tenant prices an action in a currency. Rules are never updated: setting a rule adds a
+	// new version and retires the previous one, so a transaction keeps pointing at the version it was priced with.
+	// The fee is clamped between MinFee and MaxFee, a zero bound is no bound, and VAT is charged on top of it
+	FeeRule struct {
+		ID                 uuid.UUID  `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID           uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_fee_rules_version" json:"tenant_id"`
+		Action             FeeAction  `gorm:"type:varchar(50);not null;uniqueIndex:idx_fee_rules_version" json:"action"`
+		Currency           string     `gorm:"type:varchar(3);not null;uniqueIndex:idx_fee_rules_version" json:"currency"`
+		Version            int        `gorm:"not null;uniqueIndex:idx_fee_rules_version" json:"version"`
+		Type               FeeType    `gorm:"type:varchar(50);not null" json:"type"`
+		FlatFee            Money      `gorm:"embedded;embeddedPrefix:flat_fee_" json:"flat_fee"`
+		PercentBasisPoints int64      `gorm:"not null;default:0" json:"percent_basis_points"`
+		Tiers              []FeeTier  `gorm:"type:jsonb;serializer:json" json:"tiers,omitempty"`
+		MinFee             Money      `gorm:"embedded;embeddedPrefix:min_fee_" json:"min_fee"`
+		MaxFee             Money      `gorm:"embedded;embeddedPrefix:max_fee_" json:"max_fee"`
+		VATBasisPoints     int64      `gorm:"not null;default:0" json:"vat_basis_points"`
+		CreatedAt          time.Time  `gorm:"default:now()" json:"created_at"`
+		RetiredAt          *time.Time `gorm:"index" json:"retired_at,omitempty"`
+	}
+
+	// FeeQuote is what an action on an amount costs. Total is the fee with its VAT, RuleID and RuleVersion are
+	// empty when the tenant has no rule for the action, which is free then
+	FeeQuote struct {
+		Action      FeeAction  `json:"action"`
+		Amount      Money      `json:"amount"`
+		Fee         Money      `json:"fee"`
+		VAT         Money      `json:"vat"`
+		Total       Money      `json:"total"`
+		RuleID      *uuid.UUID `json:"rule_id,omitempty"`
+		RuleVersion int        `json:"rule_version,omitempty"`
+	}
+)
+
+// IsValid reports whether the fee action is one of the known actions
+func (a FeeAction) IsValid() bool {
+	switch a {
+	case FeeActionDeposit, FeeActionTransfer, FeeActionInternalTransfer, FeeActionFX:
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate checks the action, currency, type, amounts and basis points of a fee rule, and normalises its currency
+func (r *FeeRule) Validate() error {
+	if !r.Action.IsValid() {
+		return ErrInvalidFeeAction
+	}
+
+	r.Currency = strings.ToUpper(r.Currency)
+	if !IsSupportedCurrency(r.Currency) {
+		return ErrUnsupportedCurrency
+	}
+
+	for _, amount := range []*Money{&r.FlatFee, &r.MinFee, &r.MaxFee} {
+		if err := r.validateAmount(amount); err != nil {
+			return err
+		}
+	}
+
+	if !validBasisPoints(r.PercentBasisPoints) || !validBasisPoints(r.VATBasisPoints) {
+		return fmt.Errorf("%w: basis points must be between 0 and 10000", ErrInvalidFeeRule)
+	}
+
+	if r.MaxFee.IsPositive() && r.MaxFee.Minor < r.MinFee.Minor {
+		return fmt.Errorf("%w: max fee is less than min fee", ErrInvalidFeeRule)
+	}
+
+	switch r.Type {
+	case FeeTypeFlat, FeeTypePercentage:
+		if len(r.Tiers) > 0 {
+			return fmt.Errorf("%w: only tiered rules have tiers", ErrInvalidFeeRule)
+		}
+	case FeeTypeTiered:
+		return r.validateTiers()
+	default:
+		return fmt.Errorf("%w: fee type can either be flat, percentage or tiered", ErrInvalidFeeRule)
+	}
+
+	return nil
+}
+
+// Quote prices the amount with the rule
+func (r FeeRule) Quote(amount Money) (FeeQuote, error) {
+	if !strings.EqualFold(amount.Currency, r.Currency) {
+		return FeeQuote{}, ErrCurrencyMismatch
+	}
+
+	flat, basisPoints := r.FlatFee, r.PercentBasisPoints
+	switch r.Type {
+	case FeeTypeFlat:
+		basisPoints = 0
+	case FeeTypeTiered:
+		tier := r.tier(amount)
+		flat, basisPoints = tier.FlatFee, tier.PercentBasisPoints
+	}
+
+	fee, err := amount.Percent(basisPoints).Add(withCurrency(flat, r.Currency))
+	if err != nil {
+		return FeeQuote{}, err
+	}
+
+	if fee.Minor < r.MinFee.Minor {
+		fee.Minor = r.MinFee.Minor
+	}
+
+	if r.MaxFee.IsPositive() && fee.Minor > r.MaxFee.Minor {
+		fee.Minor = r.MaxFee.Minor
+	}
+
+	vat := fee.Percent(r.VATBasisPoints)
+	total, err := fee.Add(vat)
+	if err != nil {
+		return FeeQuote{}, err
+	}
+
+	ruleID := r.ID
+	return FeeQuote{
+		Action:      r.Action,
+		Amount:      amount,
+		Fee:         fee,
+		VAT:         vat,
+		Total:       total,
+		RuleID:      &ruleID,
+		RuleVersion: r.Version,
+	}, nil
+}
+
+// FreeQuote is the quote of an action the tenant has no fee rule for
+func FreeQuote(action FeeAction, amount Money) FeeQuote {
+	return FeeQuote{
+		Action: action,
+		Amount: amount,
+		Fee:    ZeroMoney(amount.Currency),
+		VAT:    ZeroMoney(amount.Currency),
+		Total:  ZeroMoney(amount.Currency),
+	}
+}
+
+// LegacyFeeRules returns the rules that keep charging the tenant the legacy fee on deposits and transfers in every
+// supported currency
+func LegacyFeeRules(tenantID uuid.UUID) []FeeRule {
+	var rules []FeeRule
+	for _, action := range []FeeAction{FeeActionDeposit, FeeActionTransfer} {
+		for _, currency := range SupportedCurrencies() {
+			rules = append(rules, FeeRule{
+				ID:                 uuid.New(),
+				TenantID:           tenantID,
+				Action:             action,
+				Currency:           currency,
+				Version:            1,
+				Type:               FeeTypePercentage,
+				FlatFee:            ZeroMoney(currency),
+				PercentBasisPoints: LegacyFeeBasisPoints,
+				MinFee:             ZeroMoney(currency),
+				MaxFee:             ZeroMoney(currency),
+			})
+		}
+	}
+
+	return rules
+}
+
+// tier returns the first tier the amount falls in, the last one when it is above every bound
+func (r FeeRule) tier(amount Money) FeeTier {
+	for _, tier := range r.Tiers {
+		if tier.UpTo.IsZero() || amount.Minor <= tier.UpTo.Minor {
+			return tier
+		}
+	}
+
+	return r.Tiers[len(r.Tiers)-1]
+}
+
+// validateTiers checks that a tiered rule has tiers with increasing bounds, only the last one unbounded
+func (r *FeeRule) validateTiers() error {
+	if len(r.Tiers) == 0 {
+		return fmt.Errorf("%w: a tiered rule needs at least one tier", ErrInvalidFeeRule)
+	}
+
+	var previous int64
+	for i := range r.Tiers {
+		tier := &r.Tiers[i]
+		if err := r.validateAmount(&tier.UpTo); err != nil {
+			return err
+		}
+
+		if err := r.validateAmount(&tier.FlatFee); err != nil {
+			return err
+		}
+
+		if !validBasisPoints(tier.PercentBasisPoints) {
+			return fmt.Errorf("%w: basis points must be between 0 and 10000", ErrInvalidFeeRule)
+		}
+
+		last := i == len(r.Tiers)-1
+		if tier.UpTo.IsZero() && !last || !tier.UpTo.IsZero() && tier.UpTo.Minor <= previous {
+			return fmt.Errorf("%w: tier bounds must increase, only the last tier can be unbounded", ErrInvalidFeeRule)
+		}
+		previous = tier.UpTo.Minor
+	}
+
+	return nil
+}
+
+// validateAmount checks that an amount of the rule is not negative and in its currency, a zero amount takes it
+func (r *FeeRule) validateAmount(amount *Money) error {
+	if amount.IsNegative() {
+		return fmt.Errorf("%w: fee amounts cannot be negative", ErrInvalidFeeRule)
+	}
+
+	if amount.Currency != "" && !strings.EqualFold(amount.Currency, r.Currency) {
+		return ErrCurrencyMismatch
+	}
+
+	*amount = withCurrency(*amount, r.Currency)
+	return nil
+}
+
+// withCurrency returns the amount in the currency, for amounts that were given without one
+func withCurrency(amount Money, currency string) Money {
+	return Money{Minor: amount.Minor, Currency: strings.ToUpper(currency)}
+}
+
+func validBasisPoints(basisPoints int64) bool {
+	return basisPoints >= 0 && basisPoints <= 10000
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeRuleQuote(t *testing.T) {
+	tiers := []FeeTier{
+		{UpTo: NewMoney(100000, "NGN"), FlatFee: NewMoney(1000, "NGN")},
+		{UpTo: NewMoney(1000000, "NGN"), PercentBasisPoints: 100},
+		{FlatFee: NewMoney(5000, "NGN"), PercentBasisPoints: 50},
+	}
+
+	tests := []struct {
+		name   string
+		rule   FeeRule
+		amount int64
+		fee    int64
+		vat    int64
+	}{
+		{name: "flat", rule: FeeRule{Type: FeeTypeFlat, FlatFee: NewMoney(5000, "NGN"), PercentBasisPoints: 100}, amount: 1000000, fee: 5000},
+		{name: "percentage", rule: FeeRule{Type: FeeTypePercentage, PercentBasisPoints: 150}, amount: 1000000, fee: 15000},
+		{name: "percentage plus flat", rule: FeeRule{Type: FeeTypePercentage, FlatFee: NewMoney(10000, "NGN"), PercentBasisPoints: 150}, amount: 1000000, fee: 25000},
+		{name: "first tier", rule: FeeRule{Type: FeeTypeTiered, Tiers: tiers}, amount: 100000, fee: 1000},
+		{name: "second tier", rule: FeeRule{Type: FeeTypeTiered, Tiers: tiers}, amount: 500000, fee: 5000},
+		{name: "unbounded tier", rule: FeeRule{Type: FeeTypeTiered, Tiers: tiers}, amount: 2000000, fee: 15000},
+		{name: "min fee", rule: FeeRule{Type: FeeTypePercentage, PercentBasisPoints: 100, MinFee: NewMoney(5000, "NGN")}, amount: 100000, fee: 5000},
+		{name: "max fee", rule: FeeRule{Type: FeeTypePercentage, PercentBasisPoints: 100, MaxFee: NewMoney(200000, "NGN")}, amount: 50000000, fee: 200000},
+		{name: "vat", rule: FeeRule{Type: FeeTypeFlat, FlatFee: NewMoney(10000, "NGN"), VATBasisPoints: 750}, amount: 1000000, fee: 10000, vat: 750},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Action = FeeActionTransfer
+			tt.rule.Currency = "NGN"
+			require.NoError(t, tt.rule.Validate())
+
+			quote, err := tt.rule.Quote(NewMoney(tt.amount, "NGN"))
+			require.NoError(t, err)
+			require.Equal(t, NewMoney(tt.fee, "NGN"), quote.Fee)
+			require.Equal(t, NewMoney(tt.vat, "NGN"), quote.VAT)
+			require.Equal(t, NewMoney(tt.fee+tt.vat, "NGN"), quote.Total)
+		})
+	}
+
+	_, err := FeeRule{Type: FeeTypeFlat, Currency: "NGN"}.Quote(NewMoney(100, "USD"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestFeeRuleValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule FeeRule
+		err  error
+	}{
+		{name: "unknown action", rule: FeeRule{Action: "payout", Currency: "NGN", Type: FeeTypeFlat}, err: ErrInvalidFeeAction},
+		{name: "unknown currency", rule: FeeRule{Action: FeeActionDeposit, Currency: "XYZ", Type: FeeTypeFlat}, err: ErrUnsupportedCurrency},
+		{name: "unknown type", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: "capped"}, err: ErrInvalidFeeRule},
+		{name: "negative fee", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: FeeTypeFlat, FlatFee: NewMoney(-1, "NGN")}, err: ErrInvalidFeeRule},
+		{name: "fee in another currency", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: FeeTypeFlat, FlatFee: NewMoney(100, "USD")}, err: ErrCurrencyMismatch},
+		{name: "basis points over 100%", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: FeeTypePercentage, PercentBasisPoints: 10001}, err: ErrInvalidFeeRule},
+		{name: "max below min", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: FeeTypePercentage, MinFee: NewMoney(500, "NGN"), MaxFee: NewMoney(100, "NGN")}, err: ErrInvalidFeeRule},
+		{name: "tiered without tiers", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: FeeTypeTiered}, err: ErrInvalidFeeRule},
+		{name: "tiers on a flat rule", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: FeeTypeFlat, Tiers: []FeeTier{{}}}, err: ErrInvalidFeeRule},
+		{name: "unbounded tier before the last", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: FeeTypeTiered, Tiers: []FeeTier{{}, {UpTo: NewMoney(100, "NGN")}}}, err: ErrInvalidFeeRule},
+		{name: "decreasing tiers", rule: FeeRule{Action: FeeActionDeposit, Currency: "NGN", Type: FeeTypeTiered, Tiers: []FeeTier{{UpTo: NewMoney(500, "NGN")}, {UpTo: NewMoney(100, "NGN")}}}, err: ErrInvalidFeeRule},
+		{name: "valid", rule: FeeRule{Action: FeeActionDeposit, Currency: "ngn", Type: FeeTypeTiered, Tiers: []FeeTier{{UpTo: NewMoney(100, "")}, {}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.err == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestLegacyFeeRules(t *testing.T) {
+	tenantID := uuid.New()
+	rules := LegacyFeeRules(tenantID)
+	require.Len(t, rules, 2*len(SupportedCurrencies()))
+
+	// the tenants of before fee rules keep paying 10% on deposits and transfers, whatever the currency
+	for _, rule := range rules {
+		require.Equal(t, tenantID, rule.TenantID)
+		require.Contains(t, []FeeAction{FeeActionDeposit, FeeActionTransfer}, rule.Action)
+		require.NoError(t, rule.Validate())
+
+		quote, err := rule.Quote(NewMoney(100000, rule.Currency))
+		require.NoError(t, err)
+		require.Equal(t, NewMoney(10000, rule.Currency), quote.Total)
+	}
+}
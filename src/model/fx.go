@@ -0,0 +1,171 @@
+package model
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidFXRate when an FX rate is not a positive decimal number
+	ErrInvalidFXRate = errors.New("fx rate must be a positive decimal number")
+	// ErrInvalidSpread when an FX spread is outside 0 to 10000 basis points
+	ErrInvalidSpread = errors.New("fx spread must be between 0 and 10000 basis points")
+)
+
+type (
+	// FXRate schema. One unit of the base currency buys Rate units of the quote currency, and the spread is kept
+	// by the tenant as fee revenue. A rate without a tenant applies to every tenant that has not set its own
+	FXRate struct {
+		ID                uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID          *uuid.UUID     `gorm:"type:uuid;index" json:"tenant_id"`
+		BaseCurrency      string         `gorm:"type:varchar(3);not null;index" json:"base_currency"`
+		QuoteCurrency     string         `gorm:"type:varchar(3);not null;index" json:"quote_currency"`
+		Rate              string         `gorm:"type:numeric(24,10);not null" json:"rate"`
+		SpreadBasisPoints int64          `gorm:"not null;default:0" json:"spread_basis_points"`
+		CreatedAt         time.Time      `gorm:"default:now()" json:"created_at"`
+		UpdatedAt         *time.Time     `json:"updated_at,omitempty"`
+		DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+
+	// FXQuote is the outcome of converting an amount at a tenant's rate. Gross is the amount at the mid rate,
+	// Fee is the spread the tenant keeps and To is what lands in the target wallet
+	FXQuote struct {
+		From              Money  `json:"from"`
+		To                Money  `json:"to"`
+		Gross             Money  `json:"gross"`
+		Fee               Money  `json:"fee"`
+		Rate              string `json:"rate"`
+		SpreadBasisPoints int64  `json:"spread_basis_points"`
+	}
+
+	// FXConversion schema, a conversion between two wallets of the same user
+	FXConversion struct {
+		ID                  uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID            uuid.UUID      `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		UserID              uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+		DebitTransactionID  uuid.UUID      `gorm:"type:uuid;not null" json:"debit_transaction_id"`
+		CreditTransactionID uuid.UUID      `gorm:"type:uuid;not null" json:"credit_transaction_id"`
+		From                Money          `gorm:"embedded;embeddedPrefix:from_" json:"from"`
+		To                  Money          `gorm:"embedded;embeddedPrefix:to_" json:"to"`
+		Fee                 Money          `gorm:"embedded;embeddedPrefix:fee_" json:"fee"`
+		Rate                string         `gorm:"type:numeric(24,10);not null" json:"rate"`
+		SpreadBasisPoints   int64          `gorm:"not null;default:0" json:"spread_basis_points"`
+		CreatedAt           time.Time      `gorm:"default:now()" json:"created_at"`
+		DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+)
+
+// Validate checks the currencies, rate and spread of an FX rate
+func (r FXRate) Validate() error {
+	if !IsSupportedCurrency(r.BaseCurrency) || !IsSupportedCurrency(r.QuoteCurrency) {
+		return ErrUnsupportedCurrency
+	}
+
+	if strings.EqualFold(r.BaseCurrency, r.QuoteCurrency) {
+		return ErrCurrencyMismatch
+	}
+
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok || rate.Sign() <= 0 {
+		return ErrInvalidFXRate
+	}
+
+	if r.SpreadBasisPoints < 0 || r.SpreadBasisPoints > 10000 {
+		return ErrInvalidSpread
+	}
+
+	return nil
+}
+
+// Quote converts the amount into the other currency of the rate pair. The rate is applied as is when the amount
+// is in the base currency and inverted when it is in the quote currency
+func (r FXRate) Quote(amount Money) (FXQuote, error) {
+	if err := r.Validate(); err != nil {
+		return FXQuote{}, err
+	}
+
+	rate, _ := new(big.Rat).SetString(r.Rate)
+
+	var to string
+	switch {
+	case strings.EqualFold(amount.Currency, r.BaseCurrency):
+		to = strings.ToUpper(r.QuoteCurrency)
+	case strings.EqualFold(amount.Currency, r.QuoteCurrency):
+		to = strings.ToUpper(r.BaseCurrency)
+		rate.Inv(rate)
+	default:
+		return FXQuote{}, ErrCurrencyMismatch
+	}
+
+	gross, err := convertMinor(amount, to, rate)
+	if err != nil {
+		return FXQuote{}, err
+	}
+
+	fee := gross.Percent(r.SpreadBasisPoints)
+	net, err := gross.Sub(fee)
+	if err != nil {
+		return FXQuote{}, err
+	}
+
+	return FXQuote{
+		From:              amount,
+		To:                net,
+		Gross:             gross,
+		Fee:               fee,
+		Rate:              rate.FloatString(10),
+		SpreadBasisPoints: r.SpreadBasisPoints,
+	}, nil
+}
+
+// convertMinor converts an amount into the currency at the rate, rounding half away from zero to the
+// target currency's minor unit
+func convertMinor(amount Money, currency string, rate *big.Rat) (Money, error) {
+	fromExponent, err := CurrencyExponent(amount.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	toExponent, err := CurrencyExponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Minor), rate)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(toExponent-fromExponent))), nil))
+	if toExponent >= fromExponent {
+		value.Mul(value, scale)
+	} else {
+		value.Quo(value, scale)
+	}
+
+	// round half away from zero: (2|n| + d) / 2d, then put the sign back
+	num := new(big.Int).Abs(value.Num())
+	den := value.Denom()
+	rounded := new(big.Int).Quo(
+		new(big.Int).Add(new(big.Int).Mul(num, big.NewInt(2)), den),
+		new(big.Int).Mul(den, big.NewInt(2)),
+	)
+	if value.Sign() < 0 {
+		rounded.Neg(rounded)
+	}
+
+	if !rounded.IsInt64() {
+		return Money{}, ErrInvalidAmount
+	}
+
+	return NewMoney(rounded.Int64(), currency), nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// HoldStatusActive is a hold still reserving funds on the wallet
+	HoldStatusActive HoldStatus = "active"
+	// HoldStatusCaptured is a hold whose funds left the wallet when the transaction succeeded
+	HoldStatusCaptured HoldStatus = "captured"
+	// HoldStatusReleased is a hold whose funds were given back to the wallet when the transaction failed
+	HoldStatusReleased HoldStatus = "released"
+	// HoldStatusExpired is a hold that was not captured or released before it expired
+	HoldStatusExpired HoldStatus = "expired"
+
+	// DefaultHoldTTL is how long a hold reserves funds when HOLD_TTL_MINUTES is not set
+	DefaultHoldTTL = 24 * time.Hour
+)
+
+type (
+	// HoldStatus of type string
+	HoldStatus string
+
+	// Hold schema. A hold reserves funds of a wallet for a pending debit, the reserved amount is taken off the
+	// available balance but stays in the book balance until the debit is captured
+	Hold struct {
+		ID            uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		WalletID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"wallet_id"`
+		UserID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+		TransactionID uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex" json:"transaction_id"`
+		Amount        Money          `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		Status        HoldStatus     `gorm:"type:varchar(50);not null;index" json:"status"`
+		ExpiresAt     time.Time      `gorm:"not null;index" json:"expires_at"`
+		CreatedAt     time.Time      `gorm:"default:now()" json:"created_at"`
+		UpdatedAt     *time.Time     `json:"updated_at,omitempty"`
+		DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+)
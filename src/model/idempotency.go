@@ -0,0 +1,72 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// IdempotencyStatusInFlight is a request whose first attempt is still being handled
+	IdempotencyStatusInFlight IdempotencyStatus = "in_flight"
+	// IdempotencyStatusCompleted is a request whose first response is stored, retries get it replayed
+	IdempotencyStatusCompleted IdempotencyStatus = "completed"
+
+	// IdempotencyKeyTTL is how long the response of a request is kept for its retries
+	IdempotencyKeyTTL = 24 * time.Hour
+	// IdempotencyInFlightTTL is how long a first attempt can hold its key, an attempt that has not answered by then
+	// is taken to have died with its server and the key can be claimed again
+	IdempotencyInFlightTTL = 5 * time.Minute
+)
+
+var (
+	// ErrIdempotencyKeyReused when an idempotency key is sent again with another request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with another request")
+	// ErrIdempotentRequestInFlight when a request is retried while its first attempt is still being handled
+	ErrIdempotentRequestInFlight = errors.New("a request with this idempotency key is still in progress")
+)
+
+type (
+	// IdempotencyStatus of type string
+	IdempotencyStatus string
+
+	// IdempotencyKey schema, a request made with an Idempotency-Key header. Keys are scoped by tenant and user, a
+	// tenant's own requests have no user. Fingerprint identifies the request the key was first used with, the
+	// response of that request is stored once it is handled and replayed to the retries until ExpiresAt
+	IdempotencyKey struct {
+		ID           uuid.UUID         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID     uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_keys_scope" json:"tenant_id"`
+		UserID       uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_keys_scope" json:"user_id"`
+		Key          string            `gorm:"size:255;not null;uniqueIndex:idx_idempotency_keys_scope" json:"key"`
+		Fingerprint  string            `gorm:"size:64;not null" json:"fingerprint"`
+		Status       IdempotencyStatus `gorm:"type:varchar(50);not null" json:"status"`
+		ResponseCode int               `gorm:"not null;default:0" json:"response_code"`
+		ContentType  string            `gorm:"size:255" json:"content_type"`
+		ResponseBody []byte            `gorm:"type:bytea" json:"response_body"`
+		ExpiresAt    time.Time         `gorm:"not null;index" json:"expires_at"`
+		CreatedAt    time.Time         `gorm:"default:now()" json:"created_at"`
+		UpdatedAt    time.Time         `json:"updated_at"`
+	}
+)
+
+// RequestFingerprint identifies a request by its method, path and body
+func RequestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// IsStale reports whether the key can be claimed again at now: its response expired, or its first attempt held it
+// for longer than IdempotencyInFlightTTL without answering
+func (k IdempotencyKey) IsStale(now time.Time) bool {
+	if !now.Before(k.ExpiresAt) {
+		return true
+	}
+
+	return k.Status == IdempotencyStatusInFlight && !now.Before(k.UpdatedAt.Add(IdempotencyInFlightTTL))
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeyIsStale(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	key := IdempotencyKey{Status: IdempotencyStatusInFlight, UpdatedAt: now, ExpiresAt: now.Add(IdempotencyKeyTTL)}
+	require.False(t, key.IsStale(now.Add(time.Minute)), "first attempt still running")
+	require.True(t, key.IsStale(now.Add(IdempotencyInFlightTTL)), "first attempt died")
+
+	key.Status = IdempotencyStatusCompleted
+	require.False(t, key.IsStale(now.Add(IdempotencyInFlightTTL)), "response kept for retries")
+	require.True(t, key.IsStale(now.Add(IdempotencyKeyTTL)), "response expired")
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	fingerprint := RequestFingerprint("POST", "/api/v1/payment/deposit", []byte(`{"amount":5000}`))
+
+	require.Len(t, fingerprint, 64)
+	require.Equal(t, fingerprint, RequestFingerprint("POST", "/api/v1/payment/deposit", []byte(`{"amount":5000}`)))
+	require.NotEqual(t, fingerprint, RequestFingerprint("POST", "/api/v1/payment/deposit", []byte(`{"amount":5001}`)))
+	require.NotEqual(t, fingerprint, RequestFingerprint("POST", "/api/v1/payment/transfer", []byte(`{"amount":5000}`)))
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// InvoiceStatusOpen is an invoice nothing was paid on yet
+	InvoiceStatusOpen InvoiceStatus = "open"
+	// InvoiceStatusPartiallyPaid is an invoice part of which was paid
+	InvoiceStatusPartiallyPaid InvoiceStatus = "partially_paid"
+	// InvoiceStatusPaid is an invoice paid in full
+	InvoiceStatusPaid InvoiceStatus = "paid"
+	// InvoiceStatusExpired is an invoice that was not paid in full before it expired
+	InvoiceStatusExpired InvoiceStatus = "expired"
+
+	// InvoicePaymentPending is a payment the payer started, waiting for the provider's webhook
+	InvoicePaymentPending InvoicePaymentStatus = "pending"
+	// InvoicePaymentSuccessful is a payment the provider settled, it counts towards the invoice
+	InvoicePaymentSuccessful InvoicePaymentStatus = "successful"
+	// InvoicePaymentFailed is a payment the provider did not settle
+	InvoicePaymentFailed InvoicePaymentStatus = "failed"
+
+	// MaxInvoiceTTL is the furthest in the future an invoice can expire
+	MaxInvoiceTTL = 365 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidInvoice when an invoice cannot be created as it is
+	ErrInvalidInvoice = errors.New("invalid invoice")
+)
+
+type (
+	// InvoiceStatus of type string
+	InvoiceStatus string
+
+	// InvoicePaymentStatus of type string
+	InvoicePaymentStatus string
+
+	// Invoice schema, a request for payment a user shares through its Reference. The payer pays it with a deposit into
+	// the user's wallet, in one go or, when AllowPartial is set, in parts until AmountPaid covers Amount. Every payment
+	// is divided by the user's split SplitID, when set
+	Invoice struct {
+		ID           uuid.UUID         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID     uuid.UUID         `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		UserID       uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id"`
+		Reference    string            `gorm:"size:50;not null;uniqueIndex" json:"reference"`
+		Title        string            `gorm:"size:255;not null" json:"title"`
+		Description  string            `gorm:"type:text" json:"description,omitempty"`
+		Currency     string            `gorm:"type:varchar(3);not null" json:"currency"`
+		Amount       Money             `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		AmountPaid   Money             `gorm:"embedded;embeddedPrefix:amount_paid_" json:"amount_paid"`
+		AllowPartial bool              `gorm:"not null;default:false" json:"allow_partial"`
+		SplitID      *uuid.UUID        `gorm:"type:uuid" json:"split_id,omitempty"`
+		Status       InvoiceStatus     `gorm:"type:varchar(50);not null;index" json:"status"`
+		LineItems    []InvoiceLineItem `gorm:"foreignKey:InvoiceID" json:"line_items"`
+		Payments     []InvoicePayment  `gorm:"foreignKey:InvoiceID" json:"payments,omitempty"`
+		ExpiresAt    time.Time         `gorm:"not null;index" json:"expires_at"`
+		PaidAt       *time.Time        `json:"paid_at,omitempty"`
+		CreatedAt    time.Time         `gorm:"default:now()" json:"created_at"`
+		UpdatedAt    *time.Time        `json:"updated_at,omitempty"`
+		DeletedAt    gorm.DeletedAt    `gorm:"index" json:"-"`
+	}
+
+	// InvoiceLineItem schema, one line of an invoice, Amount is Quantity times UnitPrice
+	InvoiceLineItem struct {
+		ID          uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		InvoiceID   uuid.UUID `gorm:"type:uuid;not null;index" json:"invoice_id"`
+		Description string    `gorm:"size:255;not null" json:"description"`
+		Quantity    int64     `gorm:"not null" json:"quantity"`
+		UnitPrice   Money     `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+		Amount      Money     `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	}
+
+	// InvoicePayment schema, a deposit a payer made towards an invoice, the deposit's transaction credits the wallet
+	// of the invoice's creator
+	InvoicePayment struct {
+		ID            uuid.UUID            `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		InvoiceID     uuid.UUID            `gorm:"type:uuid;not null;index" json:"invoice_id"`
+		TransactionID uuid.UUID            `gorm:"type:uuid;not null;uniqueIndex" json:"transaction_id"`
+		PayerName     string               `gorm:"size:255" json:"payer_name,omitempty"`
+		PayerEmail    string               `gorm:"size:255" json:"payer_email,omitempty"`
+		Amount        Money                `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		Status        InvoicePaymentStatus `gorm:"type:varchar(50);not null" json:"status"`
+		CreatedAt     time.Time            `gorm:"default:now()" json:"created_at"`
+		UpdatedAt     *time.Time           `json:"updated_at,omitempty"`
+	}
+)
+
+// Prepare validates a new invoice and prices it: each line's amount and the invoice's total are worked out from the
+// quantities and unit prices, in the invoice's currency
+func (i *Invoice) Prepare(now time.Time) error {
+	i.Currency = strings.ToUpper(i.Currency)
+	if !IsSupportedCurrency(i.Currency) {
+		return ErrUnsupportedCurrency
+	}
+
+	if strings.TrimSpace(i.Title) == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidInvoice)
+	}
+
+	if len(i.LineItems) == 0 {
+		return fmt.Errorf("%w: at least one line item is required", ErrInvalidInvoice)
+	}
+
+	if !i.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expiry must be in the future", ErrInvalidInvoice)
+	}
+
+	if i.ExpiresAt.After(now.Add(MaxInvoiceTTL)) {
+		return fmt.Errorf("%w: expiry cannot be more than a year away", ErrInvalidInvoice)
+	}
+
+	i.Amount = ZeroMoney(i.Currency)
+	i.AmountPaid = ZeroMoney(i.Currency)
+
+	for n := range i.LineItems {
+		item := &i.LineItems[n]
+		if strings.TrimSpace(item.Description) == "" {
+			return fmt.Errorf("%w: line %d: description is required", ErrInvalidInvoice, n+1)
+		}
+
+		if item.Quantity <= 0 {
+			return fmt.Errorf("%w: line %d: quantity must be positive", ErrInvalidInvoice, n+1)
+		}
+
+		if item.UnitPrice.Currency != "" && !strings.EqualFold(item.UnitPrice.Currency, i.Currency) {
+			return ErrCurrencyMismatch
+		}
+		item.UnitPrice = withCurrency(item.UnitPrice, i.Currency)
+
+		if !item.UnitPrice.IsPositive() {
+			return fmt.Errorf("%w: line %d: unit price must be positive", ErrInvalidInvoice, n+1)
+		}
+
+		// guard the multiplication, a line this large is a mistake rather than an invoice
+		if item.UnitPrice.Minor > (1<<62)/item.Quantity {
+			return fmt.Errorf("%w: line %d: amount is too large", ErrInvalidInvoice, n+1)
+		}
+		item.Amount = NewMoney(item.UnitPrice.Minor*item.Quantity, i.Currency)
+
+		total, err := i.Amount.Add(item.Amount)
+		if err != nil {
+			return err
+		}
+
+		if total.Minor < i.Amount.Minor {
+			return fmt.Errorf("%w: total is too large", ErrInvalidInvoice)
+		}
+		i.Amount = total
+	}
+
+	return nil
+}
+
+// Outstanding is what is left to pay on the invoice
+func (i Invoice) Outstanding() Money {
+	outstanding := NewMoney(i.Amount.Minor-i.AmountPaid.Minor, i.Currency)
+	if outstanding.IsNegative() {
+		return ZeroMoney(i.Currency)
+	}
+
+	return outstanding
+}
+
+// IsPayable reports whether a payer can still start a payment on the invoice
+func (i Invoice) IsPayable(now time.Time) bool {
+	if i.Status != InvoiceStatusOpen && i.Status != InvoiceStatusPartiallyPaid {
+		return false
+	}
+
+	return now.Before(i.ExpiresAt)
+}
+
+// RecordPayment counts a settled payment towards the invoice. A payment the payer started before the invoice expired
+// is counted still, the money is in the wallet already, and pays the invoice when it covers it
+func (i *Invoice) RecordPayment(amount Money, now time.Time) {
+	i.AmountPaid = NewMoney(i.AmountPaid.Minor+amount.Minor, i.Currency)
+
+	switch {
+	case i.AmountPaid.Minor >= i.Amount.Minor:
+		i.Status = InvoiceStatusPaid
+		i.PaidAt = &now
+	case i.Status == InvoiceStatusOpen:
+		i.Status = InvoiceStatusPartiallyPaid
+	}
+}
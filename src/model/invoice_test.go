@@ -0,0 +1,93 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoicePrepare(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	invoice := Invoice{
+		Title:     "May retainer",
+		Currency:  "ngn",
+		ExpiresAt: now.Add(7 * 24 * time.Hour),
+		LineItems: []InvoiceLineItem{
+			{Description: "design", Quantity: 3, UnitPrice: NewMoney(250000, "")},
+			{Description: "hosting", Quantity: 1, UnitPrice: NewMoney(100050, "NGN")},
+		},
+	}
+	require.NoError(t, invoice.Prepare(now))
+	require.Equal(t, "NGN", invoice.Currency)
+	require.Equal(t, NewMoney(750000, "NGN"), invoice.LineItems[0].Amount)
+	require.Equal(t, NewMoney(850050, "NGN"), invoice.Amount)
+	require.Equal(t, ZeroMoney("NGN"), invoice.AmountPaid)
+
+	valid := func(change func(i *Invoice)) Invoice {
+		i := Invoice{
+			Title:     "May retainer",
+			Currency:  "NGN",
+			ExpiresAt: now.Add(time.Hour),
+			LineItems: []InvoiceLineItem{{Description: "design", Quantity: 1, UnitPrice: NewMoney(100, "NGN")}},
+		}
+		change(&i)
+		return i
+	}
+
+	tests := []struct {
+		name    string
+		invoice Invoice
+		err     error
+	}{
+		{name: "unsupported currency", invoice: valid(func(i *Invoice) { i.Currency = "XYZ" }), err: ErrUnsupportedCurrency},
+		{name: "no title", invoice: valid(func(i *Invoice) { i.Title = " " }), err: ErrInvalidInvoice},
+		{name: "no line items", invoice: valid(func(i *Invoice) { i.LineItems = nil }), err: ErrInvalidInvoice},
+		{name: "expired", invoice: valid(func(i *Invoice) { i.ExpiresAt = now }), err: ErrInvalidInvoice},
+		{name: "expires too late", invoice: valid(func(i *Invoice) { i.ExpiresAt = now.Add(MaxInvoiceTTL + time.Hour) }), err: ErrInvalidInvoice},
+		{name: "zero quantity", invoice: valid(func(i *Invoice) { i.LineItems[0].Quantity = 0 }), err: ErrInvalidInvoice},
+		{name: "zero unit price", invoice: valid(func(i *Invoice) { i.LineItems[0].UnitPrice = ZeroMoney("NGN") }), err: ErrInvalidInvoice},
+		{name: "other currency", invoice: valid(func(i *Invoice) { i.LineItems[0].UnitPrice = NewMoney(100, "USD") }), err: ErrCurrencyMismatch},
+		{name: "too large", invoice: valid(func(i *Invoice) { i.LineItems[0].Quantity = 1 << 40; i.LineItems[0].UnitPrice = NewMoney(1<<40, "NGN") }), err: ErrInvalidInvoice},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, test.invoice.Prepare(now), test.err)
+		})
+	}
+}
+
+func TestInvoiceRecordPayment(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	invoice := Invoice{
+		Currency:   "NGN",
+		Amount:     NewMoney(100000, "NGN"),
+		AmountPaid: ZeroMoney("NGN"),
+		Status:     InvoiceStatusOpen,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	require.True(t, invoice.IsPayable(now))
+	require.False(t, invoice.IsPayable(now.Add(time.Hour)))
+
+	invoice.RecordPayment(NewMoney(40000, "NGN"), now)
+	require.Equal(t, InvoiceStatusPartiallyPaid, invoice.Status)
+	require.Equal(t, NewMoney(60000, "NGN"), invoice.Outstanding())
+	require.Nil(t, invoice.PaidAt)
+	require.True(t, invoice.IsPayable(now))
+
+	invoice.RecordPayment(NewMoney(60000, "NGN"), now)
+	require.Equal(t, InvoiceStatusPaid, invoice.Status)
+	require.Equal(t, ZeroMoney("NGN"), invoice.Outstanding())
+	require.Equal(t, &now, invoice.PaidAt)
+	require.False(t, invoice.IsPayable(now))
+
+	// a payment started before the invoice expired still counts, and pays it when it covers what was left
+	expired := Invoice{Currency: "NGN", Amount: NewMoney(100000, "NGN"), AmountPaid: NewMoney(50000, "NGN"), Status: InvoiceStatusExpired}
+	expired.RecordPayment(NewMoney(20000, "NGN"), now)
+	require.Equal(t, InvoiceStatusExpired, expired.Status)
+	expired.RecordPayment(NewMoney(30000, "NGN"), now)
+	require.Equal(t, InvoiceStatusPaid, expired.Status)
+}
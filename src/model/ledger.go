@@ -0,0 +1,100 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// LedgerAccountTypeUserWallet is the ledger account backing a user's wallet
+	LedgerAccountTypeUserWallet LedgerAccountType = "user_wallet"
+	// LedgerAccountTypeTenantFee is the ledger account that collects a tenant's fees
+	LedgerAccountTypeTenantFee LedgerAccountType = "tenant_fee"
+	// LedgerAccountTypeTenantVAT is the ledger account that collects the VAT charged on a tenant's fees
+	LedgerAccountTypeTenantVAT LedgerAccountType = "tenant_vat"
+	// LedgerAccountTypeProviderClearing is the ledger account that mirrors funds held at a payment provider
+	LedgerAccountTypeProviderClearing LedgerAccountType = "provider_clearing"
+	// LedgerAccountTypeOpeningBalance is the equity account used to bring pre-ledger balances into the ledger
+	LedgerAccountTypeOpeningBalance LedgerAccountType = "opening_balance"
+	// LedgerAccountTypeFXPosition is the account a tenant's currency conversions go through, one per currency
+	LedgerAccountTypeFXPosition LedgerAccountType = "fx_position"
+	// LedgerAccountTypeEscrow is the account a buyer's escrowed funds are held in until they are released or refunded
+	LedgerAccountTypeEscrow LedgerAccountType = "escrow"
+)
+
+type (
+	// LedgerAccountType is the kind of account in the ledger
+	LedgerAccountType string
+
+	// LedgerAccount schema. Every account is identified by a unique code so it can be looked up
+	// (or lazily created) without knowing its ID, e.g. "user_wallet:<userID>:NGN"
+	LedgerAccount struct {
+		ID        uuid.UUID         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		Code      string            `gorm:"size:150;uniqueIndex;not null" json:"code"`
+		Type      LedgerAccountType `gorm:"type:varchar(50);not null;index" json:"type"`
+		TenantID  *uuid.UUID        `gorm:"type:uuid;index" json:"tenant_id"`
+		UserID    *uuid.UUID        `gorm:"type:uuid;index" json:"user_id"`
+		Currency  string            `gorm:"type:varchar(3);not null" json:"currency"`
+		CreatedAt time.Time         `gorm:"default:now()" json:"created_at"`
+		UpdatedAt *time.Time        `json:"updated_at,omitempty"`
+		DeletedAt gorm.DeletedAt    `gorm:"index" json:"-"`
+	}
+
+	// JournalEntry schema. A journal entry groups the postings of one money movement, the postings must sum to zero
+	JournalEntry struct {
+		ID            uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TransactionID *uuid.UUID     `gorm:"type:uuid;index" json:"transaction_id"`
+		Description   string         `gorm:"type:text" json:"description"`
+		Postings      []Posting      `gorm:"foreignKey:JournalEntryID" json:"postings"`
+		CreatedAt     time.Time      `gorm:"default:now()" json:"created_at"`
+		DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+
+	// Posting schema. A positive amount increases the balance of the account, a negative amount decreases it
+	Posting struct {
+		ID             uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		JournalEntryID uuid.UUID      `gorm:"type:uuid;not null;index" json:"journal_entry_id"`
+		AccountID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"account_id"`
+		Amount         Money          `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		CreatedAt      time.Time      `gorm:"default:now()" json:"created_at"`
+		DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+)
+
+// UserWalletAccountCode returns the ledger account code of a user's wallet
+func UserWalletAccountCode(userID uuid.UUID, currency string) string {
+	return fmt.Sprintf("%s:%s:%s", LedgerAccountTypeUserWallet, userID, currency)
+}
+
+// TenantFeeAccountCode returns the ledger account code that collects a tenant's fees
+func TenantFeeAccountCode(tenantID uuid.UUID, currency string) string {
+	return fmt.Sprintf("%s:%s:%s", LedgerAccountTypeTenantFee, tenantID, currency)
+}
+
+// TenantVATAccountCode returns the ledger account code that collects the VAT on a tenant's fees
+func TenantVATAccountCode(tenantID uuid.UUID, currency string) string {
+	return fmt.Sprintf("%s:%s:%s", LedgerAccountTypeTenantVAT, tenantID, currency)
+}
+
+// ProviderClearingAccountCode returns the ledger account code of a payment provider's clearing account
+func ProviderClearingAccountCode(provider PaymentProvider, currency string) string {
+	return fmt.Sprintf("%s:%s:%s", LedgerAccountTypeProviderClearing, provider, currency)
+}
+
+// OpeningBalanceAccountCode returns the ledger account code used for opening balances
+func OpeningBalanceAccountCode(currency string) string {
+	return fmt.Sprintf("%s:%s", LedgerAccountTypeOpeningBalance, currency)
+}
+
+// FXPositionAccountCode returns the ledger account code of a tenant's FX position in a currency
+func FXPositionAccountCode(tenantID uuid.UUID, currency string) string {
+	return fmt.Sprintf("%s:%s:%s", LedgerAccountTypeFXPosition, tenantID, currency)
+}
+
+// EscrowAccountCode returns the ledger account code holding a buyer's escrowed funds in the currency
+func EscrowAccountCode(buyerID uuid.UUID, currency string) string {
+	return fmt.Sprintf("%s:%s:%s", LedgerAccountTypeEscrow, buyerID, currency)
+}
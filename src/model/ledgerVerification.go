@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// LedgerBreakChain is a balance entry whose balance before is not the balance after of the entry preceding it
+	LedgerBreakChain LedgerBreakKind = "chain_break"
+	// LedgerBreakAmount is a balance entry that moved the wallet by another amount than its transaction's amount and fee
+	LedgerBreakAmount LedgerBreakKind = "amount_mismatch"
+	// LedgerBreakOrphanEntry is a balance entry whose transaction does not exist or belongs to another wallet
+	LedgerBreakOrphanEntry LedgerBreakKind = "orphan_entry"
+	// LedgerBreakDuplicateEntry is a balance entry for a transaction that already has one
+	LedgerBreakDuplicateEntry LedgerBreakKind = "duplicate_entry"
+	// LedgerBreakMissingEntry is a settled transaction of the wallet without a balance entry
+	LedgerBreakMissingEntry LedgerBreakKind = "missing_entry"
+	// LedgerBreakWallet is a wallet whose ledger balance is not the balance after of its latest balance entry
+	LedgerBreakWallet LedgerBreakKind = "wallet_mismatch"
+)
+
+type (
+	// LedgerBreakKind is the kind of inconsistency the ledger verifier found in a wallet's balance history
+	LedgerBreakKind string
+
+	// LedgerVerification schema, the outcome of verifying the balance history of every wallet of a tenant against
+	// the wallets' ledger accounts and their settled transactions, as they were at VerifiedUntil
+	LedgerVerification struct {
+		ID            uuid.UUID     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID      uuid.UUID     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		Repair        bool          `gorm:"not null;default:false" json:"repair"`
+		Wallets       int           `gorm:"not null;default:0" json:"wallets"`
+		Entries       int           `gorm:"not null;default:0" json:"entries"`
+		Breaks        int           `gorm:"not null;default:0" json:"breaks"`
+		Repaired      int           `gorm:"not null;default:0" json:"repaired"`
+		VerifiedUntil time.Time     `gorm:"not null" json:"verified_until"`
+		CreatedAt     time.Time     `gorm:"default:now()" json:"created_at"`
+		FinishedAt    *time.Time    `json:"finished_at,omitempty"`
+		Items         []LedgerBreak `gorm:"foreignKey:VerificationID" json:"items,omitempty"`
+	}
+
+	// LedgerBreak schema, one inconsistency found by a ledger verification. Expected is what the wallet's ledger,
+	// the previous entry or the transaction says, Actual is what the balance entry says
+	LedgerBreak struct {
+		ID             uuid.UUID       `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		VerificationID uuid.UUID       `gorm:"type:uuid;not null;index" json:"verification_id"`
+		TenantID       uuid.UUID       `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		UserID         uuid.UUID       `gorm:"type:uuid;not null;index" json:"user_id"`
+		WalletID       uuid.UUID       `gorm:"type:uuid;not null" json:"wallet_id"`
+		Kind           LedgerBreakKind `gorm:"type:varchar(50);not null;index" json:"kind"`
+		BalanceID      *uuid.UUID      `gorm:"type:uuid" json:"balance_id,omitempty"`
+		TransactionID  *uuid.UUID      `gorm:"type:uuid" json:"transaction_id,omitempty"`
+		Expected       Money           `gorm:"embedded;embeddedPrefix:expected_" json:"expected"`
+		Actual         Money           `gorm:"embedded;embeddedPrefix:actual_" json:"actual"`
+		Repaired       bool            `gorm:"not null;default:false" json:"repaired"`
+		CreatedAt      time.Time       `gorm:"default:now()" json:"created_at"`
+	}
+)
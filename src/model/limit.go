@@ -0,0 +1,142 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// LimitActionDeposit limits the deposits of a user
+	LimitActionDeposit LimitAction = "deposit"
+	// LimitActionTransfer limits the withdrawals of a user to a bank account
+	LimitActionTransfer LimitAction = "transfer"
+	// LimitActionInternalTransfer limits the transfers a user sends to other wallets
+	LimitActionInternalTransfer LimitAction = "internal_transfer"
+	// LimitActionFX limits the conversions of a user, by the amount converted
+	LimitActionFX LimitAction = "fx"
+
+	// LimitSingle caps the amount of one transaction
+	LimitSingle LimitKind = "single"
+	// LimitDailyCount caps how many transactions a user makes in a day of the tenant's timezone
+	LimitDailyCount LimitKind = "daily_count"
+	// LimitDailyAmount caps the amount a user moves in a day of the tenant's timezone
+	LimitDailyAmount LimitKind = "daily_amount"
+	// LimitMonthlyCount caps how many transactions a user makes in a month of the tenant's timezone
+	LimitMonthlyCount LimitKind = "monthly_count"
+	// LimitMonthlyAmount caps the amount a user moves in a month of the tenant's timezone
+	LimitMonthlyAmount LimitKind = "monthly_amount"
+)
+
+var (
+	// ErrInvalidLimitAction when a limit action is none of deposit, transfer, internal_transfer or fx
+	ErrInvalidLimitAction = errors.New("limit action can either be deposit, transfer, internal_transfer or fx")
+	// ErrInvalidTransactionLimit when a transaction limit has negative caps
+	ErrInvalidTransactionLimit = errors.New("invalid transaction limit")
+)
+
+type (
+	// LimitAction is the kind of transaction a limit applies to
+	LimitAction string
+
+	// LimitKind is which cap of a limit a transaction is checked against
+	LimitKind string
+
+	// TransactionLimit schema, how much each user of a tenant can move with an action in a currency. A zero cap is no
+	// cap. Days and months are those of the tenant's timezone
+	TransactionLimit struct {
+		ID            uuid.UUID   `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID      uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_transaction_limits_action" json:"tenant_id"`
+		Action        LimitAction `gorm:"type:varchar(50);not null;uniqueIndex:idx_transaction_limits_action" json:"action"`
+		Currency      string      `gorm:"type:varchar(3);not null;uniqueIndex:idx_transaction_limits_action" json:"currency"`
+		MaxSingle     Money       `gorm:"embedded;embeddedPrefix:max_single_" json:"max_single"`
+		DailyCount    int64       `gorm:"not null;default:0" json:"daily_count"`
+		DailyAmount   Money       `gorm:"embedded;embeddedPrefix:daily_amount_" json:"daily_amount"`
+		MonthlyCount  int64       `gorm:"not null;default:0" json:"monthly_count"`
+		MonthlyAmount Money       `gorm:"embedded;embeddedPrefix:monthly_amount_" json:"monthly_amount"`
+		CreatedAt     time.Time   `gorm:"default:now()" json:"created_at"`
+		UpdatedAt     *time.Time  `json:"updated_at,omitempty"`
+	}
+
+	// LimitUsage is how many transactions of an action a user made in a period and the amount they moved
+	LimitUsage struct {
+		Count  int64 `json:"count"`
+		Amount Money `json:"amount"`
+	}
+)
+
+// IsValid reports whether the limit action is one of the known actions
+func (a LimitAction) IsValid() bool {
+	switch a {
+	case LimitActionDeposit, LimitActionTransfer, LimitActionInternalTransfer, LimitActionFX:
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate checks the action, currency and caps of a transaction limit, and normalises its currency
+func (l *TransactionLimit) Validate() error {
+	if !l.Action.IsValid() {
+		return ErrInvalidLimitAction
+	}
+
+	l.Currency = strings.ToUpper(l.Currency)
+	if !IsSupportedCurrency(l.Currency) {
+		return ErrUnsupportedCurrency
+	}
+
+	if l.DailyCount < 0 || l.MonthlyCount < 0 {
+		return fmt.Errorf("%w: counts cannot be negative", ErrInvalidTransactionLimit)
+	}
+
+	for _, amount := range []*Money{&l.MaxSingle, &l.DailyAmount, &l.MonthlyAmount} {
+		if amount.IsNegative() {
+			return fmt.Errorf("%w: amounts cannot be negative", ErrInvalidTransactionLimit)
+		}
+
+		if amount.Currency != "" && !strings.EqualFold(amount.Currency, l.Currency) {
+			return ErrCurrencyMismatch
+		}
+
+		*amount = withCurrency(*amount, l.Currency)
+	}
+
+	return nil
+}
+
+// Check returns the first cap the amount breaks, given what the user already used today and this month. It reports
+// false when the amount is within every cap
+func (l TransactionLimit) Check(amount Money, daily, monthly LimitUsage) (LimitKind, bool) {
+	switch {
+	case l.MaxSingle.IsPositive() && amount.Minor > l.MaxSingle.Minor:
+		return LimitSingle, true
+	case l.DailyCount > 0 && daily.Count+1 > l.DailyCount:
+		return LimitDailyCount, true
+	case l.DailyAmount.IsPositive() && daily.Amount.Minor+amount.Minor > l.DailyAmount.Minor:
+		return LimitDailyAmount, true
+	case l.MonthlyCount > 0 && monthly.Count+1 > l.MonthlyCount:
+		return LimitMonthlyCount, true
+	case l.MonthlyAmount.IsPositive() && monthly.Amount.Minor+amount.Minor > l.MonthlyAmount.Minor:
+		return LimitMonthlyAmount, true
+	default:
+		return "", false
+	}
+}
+
+// Flow returns the flow and type of the transactions the action makes, to count a user's usage from their history
+func (a LimitAction) Flow() (TransactionFlow, TransactionType) {
+	switch a {
+	case LimitActionDeposit:
+		return TransactionFlowRevenue, CreditTransaction
+	case LimitActionTransfer:
+		return TransactionFlowWithdrawal, DebitTransaction
+	case LimitActionInternalTransfer:
+		return TransactionFlowInternalTransfer, DebitTransaction
+	default:
+		return TransactionFlowConversion, DebitTransaction
+	}
+}
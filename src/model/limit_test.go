@@ -0,0 +1,78 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionLimitCheck(t *testing.T) {
+	limit := TransactionLimit{
+		Action:        LimitActionTransfer,
+		Currency:      "NGN",
+		MaxSingle:     NewMoney(100000, "NGN"),
+		DailyCount:    3,
+		DailyAmount:   NewMoney(200000, "NGN"),
+		MonthlyCount:  10,
+		MonthlyAmount: NewMoney(500000, "NGN"),
+	}
+	require.NoError(t, limit.Validate())
+
+	usage := func(count, amount int64) LimitUsage {
+		return LimitUsage{Count: count, Amount: NewMoney(amount, "NGN")}
+	}
+
+	tests := []struct {
+		name    string
+		limit   TransactionLimit
+		amount  int64
+		daily   LimitUsage
+		monthly LimitUsage
+		kind    LimitKind
+	}{
+		{name: "within every cap", limit: limit, amount: 50000, daily: usage(2, 150000), monthly: usage(9, 450000)},
+		{name: "single", limit: limit, amount: 100001, kind: LimitSingle},
+		{name: "daily count", limit: limit, amount: 100, daily: usage(3, 300), kind: LimitDailyCount},
+		{name: "daily amount", limit: limit, amount: 50001, daily: usage(1, 150000), kind: LimitDailyAmount},
+		{name: "monthly count", limit: limit, amount: 100, monthly: usage(10, 1000), kind: LimitMonthlyCount},
+		{name: "monthly amount", limit: limit, amount: 50001, monthly: usage(5, 450000), kind: LimitMonthlyAmount},
+		{name: "no caps", limit: TransactionLimit{Action: LimitActionTransfer, Currency: "NGN"}, amount: 1000000000, daily: usage(100, 1000000000), monthly: usage(1000, 1000000000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, exceeded := tt.limit.Check(NewMoney(tt.amount, "NGN"), tt.daily, tt.monthly)
+			require.Equal(t, tt.kind != "", exceeded)
+			require.Equal(t, tt.kind, kind)
+		})
+	}
+}
+
+func TestTransactionLimitValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit TransactionLimit
+		err   error
+	}{
+		{name: "unknown action", limit: TransactionLimit{Action: "payout", Currency: "NGN"}, err: ErrInvalidLimitAction},
+		{name: "unknown currency", limit: TransactionLimit{Action: LimitActionDeposit, Currency: "XYZ"}, err: ErrUnsupportedCurrency},
+		{name: "negative count", limit: TransactionLimit{Action: LimitActionDeposit, Currency: "NGN", DailyCount: -1}, err: ErrInvalidTransactionLimit},
+		{name: "negative amount", limit: TransactionLimit{Action: LimitActionDeposit, Currency: "NGN", MaxSingle: NewMoney(-1, "NGN")}, err: ErrInvalidTransactionLimit},
+		{name: "amount in another currency", limit: TransactionLimit{Action: LimitActionDeposit, Currency: "NGN", DailyAmount: NewMoney(100, "USD")}, err: ErrCurrencyMismatch},
+		{name: "valid", limit: TransactionLimit{Action: LimitActionFX, Currency: "ngn", MonthlyAmount: NewMoney(100, "")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limit.Validate()
+			if tt.err == nil {
+				require.NoError(t, err)
+				require.Equal(t, "NGN", tt.limit.Currency)
+				require.Equal(t, "NGN", tt.limit.MonthlyAmount.Currency)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
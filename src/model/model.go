@@ -10,7 +10,15 @@ const (
 	ActorTypeUser ActorType = "user"
 	// ActorTypeTenant is an ActorType of tenant
 	ActorTypeTenant ActorType = "tenant"
+	// ActorTypeAdmin is an ActorType of an operator of the platform
+	ActorTypeAdmin ActorType = "admin"
 
 	// ActionSignup defined the action signup
 	ActionSignup string = "signup"
+
+	// DefaultCurrency is the currency used when none is specified
+	DefaultCurrency string = "NGN"
+
+	// DefaultTimezone is the IANA timezone of a tenant that did not set one
+	DefaultTimezone string = "Africa/Lagos"
 )
@@ -0,0 +1,307 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrCurrencyMismatch when two amounts of different currencies are combined
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrUnsupportedCurrency when the currency is not an ISO-4217 code we support
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrInvalidAmount when an amount cannot be parsed
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// currencyExponents holds the number of minor unit digits of every supported ISO-4217 currency
+var currencyExponents = map[string]int{
+	"NGN": 2,
+	"GHS": 2,
+	"KES": 2,
+	"ZAR": 2,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"XOF": 0,
+	"JPY": 0,
+	"KWD": 3,
+}
+
+type (
+	// Money is an amount of money held as an integer number of minor units (e.g. kobo) of an ISO-4217 currency.
+	// It is stored on a schema as an embedded struct, e.g. `gorm:"embedded;embeddedPrefix:amount_"`
+	// gives the amount_minor and amount_currency columns
+	Money struct {
+		Minor    int64  `gorm:"not null;default:0"`
+		Currency string `gorm:"type:varchar(3)"`
+	}
+
+	// moneyJSON is the wire format of Money, the amount is an exact decimal in major units
+	moneyJSON struct {
+		Amount   json.Number `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+)
+
+// CurrencyExponent returns the number of minor unit digits of the currency
+func CurrencyExponent(currency string) (int, error) {
+	exponent, ok := currencyExponents[strings.ToUpper(currency)]
+	if !ok {
+		return 0, ErrUnsupportedCurrency
+	}
+
+	return exponent, nil
+}
+
+// SupportedCurrencies returns the ISO-4217 codes of every supported currency, sorted
+func SupportedCurrencies() []string {
+	currencies := make([]string, 0, len(currencyExponents))
+	for currency := range currencyExponents {
+		currencies = append(currencies, currency)
+	}
+
+	sort.Strings(currencies)
+	return currencies
+}
+
+// IsSupportedCurrency reports whether the currency is a supported ISO-4217 code
+func IsSupportedCurrency(currency string) bool {
+	_, err := CurrencyExponent(currency)
+	return err == nil
+}
+
+// NewMoney creates Money from an amount already expressed in minor units
+func NewMoney(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: strings.ToUpper(currency)}
+}
+
+// ZeroMoney returns a zero amount of the currency
+func ZeroMoney(currency string) Money {
+	return NewMoney(0, currency)
+}
+
+// ParseMoney parses a decimal amount in major units (e.g. "5000.25"), with at most one leading sign, into Money.
+// Digits beyond the currency's minor unit are rounded half away from zero
+func ParseMoney(amount, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	exponent, err := CurrencyExponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	// at most one sign, and at least one digit on either side of the point
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	if negative || strings.HasPrefix(amount, "+") {
+		amount = amount[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, ErrInvalidAmount
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	// pad the fraction so that we always have one digit past the minor unit to round with
+	fraction += strings.Repeat("0", exponent+1)
+	roundingDigit := fraction[exponent]
+	fraction = fraction[:exponent]
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+
+	if roundingDigit >= '5' {
+		minor++
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return NewMoney(minor, currency), nil
+}
+
+// NewMoneyFromFloat converts a float amount in major units, e.g. from a provider payload, into Money
+func NewMoneyFromFloat(amount float64, currency string) (Money, error) {
+	return ParseMoney(strconv.FormatFloat(amount, 'f', -1, 64), currency)
+}
+
+// Add returns the sum of both amounts
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return Money{}, err
+	}
+
+	return Money{Minor: m.Minor + o.Minor, Currency: m.currencyWith(o)}, nil
+}
+
+// Sub returns the difference of both amounts
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return Money{}, err
+	}
+
+	return Money{Minor: m.Minor - o.Minor, Currency: m.currencyWith(o)}, nil
+}
+
+// Neg returns the amount with its sign flipped
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// Abs returns the absolute amount
+func (m Money) Abs() Money {
+	if m.Minor < 0 {
+		return m.Neg()
+	}
+
+	return m
+}
+
+// Percent returns the given percentage of the amount, expressed in basis points (1% = 100),
+// rounded half away from zero to the nearest minor unit
+func (m Money) Percent(basisPoints int64) Money {
+	return Money{Minor: divRound(m.Minor*basisPoints, 10000), Currency: m.Currency}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// Float64 returns the amount in major units. Only use it for display, never for arithmetic
+func (m Money) Float64() float64 {
+	f, _ := strconv.ParseFloat(m.Decimal(), 64)
+	return f
+}
+
+// Decimal formats the amount in major units with the currency's minor unit digits, e.g. "5000.25"
+func (m Money) Decimal() string {
+	exponent, err := CurrencyExponent(m.Currency)
+	if err != nil {
+		exponent = currencyExponents[DefaultCurrency]
+	}
+
+	sign := ""
+	minor := m.Minor
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	digits := strconv.FormatInt(minor, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String formats the amount with its currency, e.g. "NGN 5000.25"
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Currency, m.Decimal())
+}
+
+// MarshalJSON formats Money as {"amount": 5000.25, "currency": "NGN"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{
+		Amount:   json.Number(m.Decimal()),
+		Currency: m.Currency,
+	})
+}
+
+// UnmarshalJSON parses {"amount": 5000.25, "currency": "NGN"}, the amount can either be a number or a string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	// an amount left out is zero
+	amount := strings.Trim(string(raw.Amount), `"`)
+	if amount == "" {
+		amount = "0"
+	}
+
+	money, err := ParseMoney(amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	*m = money
+	return nil
+}
+
+func (m Money) checkCurrency(o Money) error {
+	// a zero value Money has no currency yet and can be combined with any currency
+	if m.Currency == "" || o.Currency == "" || strings.EqualFold(m.Currency, o.Currency) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+}
+
+func (m Money) currencyWith(o Money) string {
+	if m.Currency != "" {
+		return m.Currency
+	}
+
+	return o.Currency
+}
+
+// divRound divides rounding half away from zero
+func divRound(n, d int64) int64 {
+	q, r := n/d, n%d
+	if r < 0 {
+		r = -r
+	}
+
+	if 2*r >= d {
+		if n < 0 {
+			return q - 1
+		}
+		return q + 1
+	}
+
+	return q
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		minor    int64
+		err      error
+	}{
+		{amount: "5000", currency: "NGN", minor: 500000},
+		{amount: "5000.5", currency: "NGN", minor: 500050},
+		{amount: "0.105", currency: "NGN", minor: 11},
+		{amount: "0.104", currency: "NGN", minor: 10},
+		{amount: "-0.105", currency: "NGN", minor: -11},
+		{amount: ".5", currency: "USD", minor: 50},
+		{amount: "1500.6", currency: "JPY", minor: 1501},
+		{amount: "1.2345", currency: "KWD", minor: 1235},
+		{amount: "12", currency: "", minor: 1200},
+		{amount: "+5", currency: "NGN", minor: 500},
+		{amount: "5.", currency: "NGN", minor: 500},
+		{amount: "12a", currency: "NGN", err: ErrInvalidAmount},
+		{amount: "--5", currency: "NGN", err: ErrInvalidAmount},
+		{amount: "+-5", currency: "NGN", err: ErrInvalidAmount},
+		{amount: "-+5", currency: "NGN", err: ErrInvalidAmount},
+		{amount: ".", currency: "NGN", err: ErrInvalidAmount},
+		{amount: "-", currency: "NGN", err: ErrInvalidAmount},
+		{amount: "+", currency: "NGN", err: ErrInvalidAmount},
+		{amount: "", currency: "NGN", err: ErrInvalidAmount},
+		{amount: "-.", currency: "NGN", err: ErrInvalidAmount},
+		{amount: "12", currency: "XYZ", err: ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		money, err := ParseMoney(tt.amount, tt.currency)
+		if tt.err != nil {
+			require.ErrorIs(t, err, tt.err, tt.amount)
+			continue
+		}
+
+		require.NoError(t, err, tt.amount)
+		require.Equal(t, tt.minor, money.Minor, tt.amount)
+	}
+}
+
+func TestNewMoneyFromFloat(t *testing.T) {
+	// 0.1 + 0.2 is the classic float drift, it must still land on 30 kobo
+	money, err := NewMoneyFromFloat(0.1+0.2, "NGN")
+	require.NoError(t, err)
+	require.Equal(t, int64(30), money.Minor)
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a := NewMoney(1050, "NGN")
+	b := NewMoney(25, "NGN")
+
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1075, "NGN"), sum)
+
+	diff, err := a.Sub(b)
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1025, "NGN"), diff)
+
+	_, err = a.Add(NewMoney(1, "USD"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// 10% of 10.50 is 1.05, 7.5% of 0.25 is 0.01875 which rounds to 0.02
+	require.Equal(t, NewMoney(105, "NGN"), a.Percent(1000))
+	require.Equal(t, NewMoney(2, "NGN"), b.Percent(750))
+	require.Equal(t, NewMoney(-2, "NGN"), b.Neg().Percent(750))
+}
+
+func TestMoneyFormatting(t *testing.T) {
+	require.Equal(t, "10.50", NewMoney(1050, "NGN").Decimal())
+	require.Equal(t, "0.05", NewMoney(5, "NGN").Decimal())
+	require.Equal(t, "-0.05", NewMoney(-5, "NGN").Decimal())
+	require.Equal(t, "1501", NewMoney(1501, "JPY").Decimal())
+	require.Equal(t, "USD 1.00", NewMoney(100, "USD").String())
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(NewMoney(500025, "NGN"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amount": 5000.25, "currency": "NGN"}`, string(data))
+
+	var fromNumber, fromString Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": 5000.25, "currency": "NGN"}`), &fromNumber))
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": "5000.25", "currency": "NGN"}`), &fromString))
+	require.Equal(t, NewMoney(500025, "NGN"), fromNumber)
+	require.Equal(t, fromNumber, fromString)
+}
+
+func TestFXRateQuote(t *testing.T) {
+	rate := FXRate{BaseCurrency: "USD", QuoteCurrency: "NGN", Rate: "1550.5", SpreadBasisPoints: 150}
+
+	// 10 USD at 1550.5 is 15505 NGN, the 1.5% spread is 232.58 NGN
+	quote, err := rate.Quote(NewMoney(1000, "USD"))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1550500, "NGN"), quote.Gross)
+	require.Equal(t, NewMoney(23258, "NGN"), quote.Fee)
+	require.Equal(t, NewMoney(1527242, "NGN"), quote.To)
+
+	// the other way round the rate is inverted, 15505 NGN is 10 USD
+	quote, err = rate.Quote(NewMoney(1550500, "NGN"))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1000, "USD"), quote.Gross)
+	require.Equal(t, NewMoney(15, "USD"), quote.Fee)
+
+	// JPY has no minor unit, 1.50 USD at 150.255 is 225.3825 JPY which rounds to 225
+	quote, err = FXRate{BaseCurrency: "USD", QuoteCurrency: "JPY", Rate: "150.255"}.Quote(NewMoney(150, "USD"))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(225, "JPY"), quote.To)
+
+	_, err = rate.Quote(NewMoney(100, "GHS"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = FXRate{BaseCurrency: "USD", QuoteCurrency: "NGN", Rate: "-1"}.Quote(NewMoney(100, "USD"))
+	require.ErrorIs(t, err, ErrInvalidFXRate)
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// NotificationInvoicePaid is sent to the creator of an invoice when it is paid in full
+	NotificationInvoicePaid NotificationKind = "invoice_paid"
+	// NotificationInvoicePartiallyPaid is sent to the creator of an invoice when part of it is paid
+	NotificationInvoicePartiallyPaid NotificationKind = "invoice_partially_paid"
+)
+
+type (
+	// NotificationKind of type string
+	NotificationKind string
+
+	// Notification schema, something that happened which a user is told about, Reference points at what it is about
+	Notification struct {
+		ID        uuid.UUID        `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID  uuid.UUID        `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		UserID    uuid.UUID        `gorm:"type:uuid;not null;index" json:"user_id"`
+		Kind      NotificationKind `gorm:"type:varchar(50);not null" json:"kind"`
+		Reference string           `gorm:"size:255" json:"reference,omitempty"`
+		Message   string           `gorm:"type:text;not null" json:"message"`
+		ReadAt    *time.Time       `json:"read_at,omitempty"`
+		CreatedAt time.Time        `gorm:"default:now()" json:"created_at"`
+	}
+)
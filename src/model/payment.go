@@ -9,6 +9,7 @@ const (
 	PaymentActionTransfer       PaymentAction = "transfer"
 	PaymentActionDeposit        PaymentAction = "deposit"
 	PaymentActionVirtualAccount PaymentAction = "virtual_account"
+	PaymentActionRefund         PaymentAction = "refund"
 
 	PaymentProviderPaystack    PaymentProvider = "paystack"
 	PaymentProviderFlutterwave PaymentProvider = "flutterwave"
@@ -23,8 +24,11 @@ type (
 	InitiateTransaction struct {
 		AccountNumber string
 		BankName      string
-		Amount        float64
+		Amount        Money
 		BankNumber    string
 		FullName      string
+		Reference     string
+		// IdempotencyKey identifies the request to the provider, a retry with the same key is not carried out twice
+		IdempotencyKey string
 	}
 )
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// PayoutBatchQueued is a batch whose items have not started yet
+	PayoutBatchQueued PayoutBatchStatus = "queued"
+	// PayoutBatchProcessing is a batch whose items are being paid
+	PayoutBatchProcessing PayoutBatchStatus = "processing"
+	// PayoutBatchCompleted is a batch every item of which succeeded or failed
+	PayoutBatchCompleted PayoutBatchStatus = "completed"
+
+	// PayoutItemPending is an item waiting to be paid
+	PayoutItemPending PayoutItemStatus = "pending"
+	// PayoutItemProcessing is an item being paid
+	PayoutItemProcessing PayoutItemStatus = "processing"
+	// PayoutItemSucceeded is an item whose transfer was made, its transaction tells whether the provider settled it
+	PayoutItemSucceeded PayoutItemStatus = "succeeded"
+	// PayoutItemFailed is an item whose transfer was refused, Error tells why
+	PayoutItemFailed PayoutItemStatus = "failed"
+)
+
+type (
+	// PayoutBatchStatus of type string
+	PayoutBatchStatus string
+
+	// PayoutItemStatus of type string
+	PayoutItemStatus string
+
+	// PayoutBatch schema, a file of payouts a user makes from their wallet in one currency. The totals are those
+	// validated when the batch was uploaded, the counts and PaidAmount are filled in as the items are paid
+	PayoutBatch struct {
+		ID          uuid.UUID         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID    uuid.UUID         `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		UserID      uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id"`
+		FileName    string            `gorm:"size:255;not null" json:"file_name"`
+		Currency    string            `gorm:"type:varchar(3);not null" json:"currency"`
+		Status      PayoutBatchStatus `gorm:"type:varchar(50);not null;index" json:"status"`
+		Items       int               `gorm:"not null;default:0" json:"items"`
+		TotalAmount Money             `gorm:"embedded;embeddedPrefix:total_amount_" json:"total_amount"`
+		TotalFees   Money             `gorm:"embedded;embeddedPrefix:total_fees_" json:"total_fees"`
+		Succeeded   int               `gorm:"not null;default:0" json:"succeeded"`
+		Failed      int               `gorm:"not null;default:0" json:"failed"`
+		PaidAmount  Money             `gorm:"embedded;embeddedPrefix:paid_amount_" json:"paid_amount"`
+		CreatedAt   time.Time         `gorm:"default:now()" json:"created_at"`
+		FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+		DeletedAt   gorm.DeletedAt    `gorm:"index" json:"-"`
+	}
+
+	// PayoutItem schema, one row of a payout batch. It pays either the user with RecipientEmail or the bank account
+	PayoutItem struct {
+		ID             uuid.UUID        `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		BatchID        uuid.UUID        `gorm:"type:uuid;not null;index" json:"batch_id"`
+		Row            int              `gorm:"not null" json:"row"`
+		RecipientEmail string           `gorm:"size:255" json:"recipient_email,omitempty"`
+		BankNumber     string           `gorm:"size:50" json:"bank_number,omitempty"`
+		AccountNumber  string           `gorm:"size:50" json:"account_number,omitempty"`
+		Amount         Money            `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		Fee            Money            `gorm:"embedded;embeddedPrefix:fee_" json:"fee"`
+		Narration      string           `gorm:"size:140" json:"narration,omitempty"`
+		Status         PayoutItemStatus `gorm:"type:varchar(50);not null;index" json:"status"`
+		TransactionID  *uuid.UUID       `gorm:"type:uuid" json:"transaction_id,omitempty"`
+		Error          string           `gorm:"type:text" json:"error,omitempty"`
+		CreatedAt      time.Time        `gorm:"default:now()" json:"created_at"`
+		UpdatedAt      *time.Time       `json:"updated_at,omitempty"`
+	}
+)
+
+// IsInternal reports whether the item pays another user's wallet rather than a bank account
+func (i PayoutItem) IsInternal() bool {
+	return i.RecipientEmail != ""
+}
+
+// Recipient identifies who the item pays, the email of a user or the bank and account number
+func (i PayoutItem) Recipient() string {
+	if i.IsInternal() {
+		return strings.ToLower(i.RecipientEmail)
+	}
+
+	return i.BankNumber + "/" + i.AccountNumber
+}
+
+// LimitAction is the limit the item is checked against
+func (i PayoutItem) LimitAction() LimitAction {
+	if i.IsInternal() {
+		return LimitActionInternalTransfer
+	}
+
+	return LimitActionTransfer
+}
+
+// FeeAction is the fee the item is priced with
+func (i PayoutItem) FeeAction() FeeAction {
+	if i.IsInternal() {
+		return FeeActionInternalTransfer
+	}
+
+	return FeeActionTransfer
+}
+
+// Count adds a paid item to the batch's counts
+func (b *PayoutBatch) Count(item PayoutItem) {
+	switch item.Status {
+	case PayoutItemSucceeded:
+		b.Succeeded++
+		b.PaidAmount.Minor += item.Amount.Minor
+	case PayoutItemFailed:
+		b.Failed++
+	}
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// ReconciliationMissingOnOurSide is a settled record with no matching transaction of that provider
+	ReconciliationMissingOnOurSide ReconciliationKind = "missing_on_our_side"
+	// ReconciliationMissingOnProvider is a successful transaction of the period the provider did not settle
+	ReconciliationMissingOnProvider ReconciliationKind = "missing_on_provider"
+	// ReconciliationAmountMismatch is a settled record whose amount differs from the transaction's
+	ReconciliationAmountMismatch ReconciliationKind = "amount_mismatch"
+	// ReconciliationStatusMismatch is a settled record whose status differs from the transaction's
+	ReconciliationStatusMismatch ReconciliationKind = "status_mismatch"
+
+	// ReconciliationRunCompleted is a run that went through the whole settlement file
+	ReconciliationRunCompleted ReconciliationRunStatus = "completed"
+	// ReconciliationRunFailed is a run that stopped, e.g. on a settlement file that could not be read
+	ReconciliationRunFailed ReconciliationRunStatus = "failed"
+)
+
+type (
+	// ReconciliationKind is the kind of discrepancy between a provider's settlement and our transactions
+	ReconciliationKind string
+
+	// ReconciliationRunStatus of type string
+	ReconciliationRunStatus string
+
+	// SettlementRecord is one transaction a provider reports as settled in its settlement file, in our terms
+	SettlementRecord struct {
+		Reference string
+		Amount    Money
+		Status    TransactionStatus
+		SettledAt time.Time
+	}
+
+	// ReconciliationRun schema, the outcome of matching one settlement file against our transactions. Transactions
+	// created between PeriodStart and PeriodEnd are expected in the file
+	ReconciliationRun struct {
+		ID                uuid.UUID               `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		Provider          PaymentProvider         `gorm:"type:varchar(50);not null;index" json:"provider"`
+		FileName          string                  `gorm:"size:255;not null" json:"file_name"`
+		Status            ReconciliationRunStatus `gorm:"type:varchar(50);not null" json:"status"`
+		Error             string                  `gorm:"type:text" json:"error,omitempty"`
+		PeriodStart       *time.Time              `json:"period_start"`
+		PeriodEnd         *time.Time              `json:"period_end"`
+		Records           int                     `gorm:"not null;default:0" json:"records"`
+		Matched           int                     `gorm:"not null;default:0" json:"matched"`
+		MissingOnOurSide  int                     `gorm:"not null;default:0" json:"missing_on_our_side"`
+		MissingOnProvider int                     `gorm:"not null;default:0" json:"missing_on_provider"`
+		AmountMismatches  int                     `gorm:"not null;default:0" json:"amount_mismatches"`
+		StatusMismatches  int                     `gorm:"not null;default:0" json:"status_mismatches"`
+		CreatedAt         time.Time               `gorm:"default:now()" json:"created_at"`
+		FinishedAt        *time.Time              `json:"finished_at,omitempty"`
+		DeletedAt         gorm.DeletedAt          `gorm:"index" json:"-"`
+	}
+
+	// ReconciliationItem schema, one discrepancy found by a reconciliation run. The provider side is empty for
+	// transactions missing on the provider, our side is empty for records missing on our side
+	ReconciliationItem struct {
+		ID                uuid.UUID          `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		RunID             uuid.UUID          `gorm:"type:uuid;not null;index" json:"run_id"`
+		Kind              ReconciliationKind `gorm:"type:varchar(50);not null;index" json:"kind"`
+		ProviderReference string             `gorm:"size:255" json:"provider_reference,omitempty"`
+		TransactionID     *uuid.UUID         `gorm:"type:uuid;index" json:"transaction_id,omitempty"`
+		ProviderAmount    Money              `gorm:"embedded;embeddedPrefix:provider_amount_" json:"provider_amount"`
+		OurAmount         Money              `gorm:"embedded;embeddedPrefix:our_amount_" json:"our_amount"`
+		ProviderStatus    TransactionStatus  `gorm:"type:varchar(50)" json:"provider_status,omitempty"`
+		OurStatus         TransactionStatus  `gorm:"type:varchar(50)" json:"our_status,omitempty"`
+		CreatedAt         time.Time          `gorm:"default:now()" json:"created_at"`
+	}
+)
+
+// Count adds an item to the run's totals of its kind
+func (r *ReconciliationRun) Count(item ReconciliationItem) {
+	switch item.Kind {
+	case ReconciliationMissingOnOurSide:
+		r.MissingOnOurSide++
+	case ReconciliationMissingOnProvider:
+		r.MissingOnProvider++
+	case ReconciliationAmountMismatch:
+		r.AmountMismatches++
+	case ReconciliationStatusMismatch:
+		r.StatusMismatches++
+	}
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// RefundStatusPending is a refund sent to the payment provider, its amount is held on the wallet
+	RefundStatusPending RefundStatus = "pending"
+	// RefundStatusSucceeded is a refund the provider accepted, its amount left the wallet
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	// RefundStatusFailed is a refund the provider rejected, its hold was released
+	RefundStatusFailed RefundStatus = "failed"
+)
+
+type (
+	// RefundStatus of type string
+	RefundStatus string
+
+	// Refund schema. A refund gives back all or part of a successful credit transaction through the provider that
+	// collected it, it is carried out by a reversing debit transaction. The reference is the tenant's idempotency key,
+	// a tenant asking for a refund with a reference it used before gets the earlier refund back
+	Refund struct {
+		ID                  uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID            uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_refunds_tenant_reference" json:"tenant_id"`
+		Reference           string         `gorm:"size:100;not null;uniqueIndex:idx_refunds_tenant_reference" json:"reference"`
+		UserID              uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+		TransactionID       uuid.UUID      `gorm:"type:uuid;not null;index" json:"transaction_id"`
+		RefundTransactionID uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex" json:"refund_transaction_id"`
+		Amount              Money          `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		Reason              string         `gorm:"type:text" json:"reason"`
+		Status              RefundStatus   `gorm:"type:varchar(50);not null;index" json:"status"`
+		CreatedAt           time.Time      `gorm:"default:now()" json:"created_at"`
+		UpdatedAt           *time.Time     `json:"updated_at,omitempty"`
+		DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+)
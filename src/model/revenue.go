@@ -0,0 +1,75 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// RevenueWithdrawalEntryDescription describes the journal entries of revenue withdrawals and of their reversals
+	RevenueWithdrawalEntryDescription = "revenue_withdrawal"
+
+	// RevenueWithdrawalStatusPending is a withdrawal sent to the payment provider, its amount left the revenue wallet
+	RevenueWithdrawalStatusPending RevenueWithdrawalStatus = "pending"
+	// RevenueWithdrawalStatusSucceeded is a withdrawal the provider accepted
+	RevenueWithdrawalStatusSucceeded RevenueWithdrawalStatus = "succeeded"
+	// RevenueWithdrawalStatusFailed is a withdrawal the provider rejected, its amount went back to the revenue wallet
+	RevenueWithdrawalStatusFailed RevenueWithdrawalStatus = "failed"
+)
+
+type (
+	// RevenueWithdrawalStatus of type string
+	RevenueWithdrawalStatus string
+
+	// RevenueWallet is what a tenant earned in a currency and has not withdrawn yet, the balance of its fee ledger
+	// account. The fees of its users' transactions and the spread of their conversions are credited to it as they
+	// succeed, the VAT charged on the fees is not revenue and is kept apart
+	RevenueWallet struct {
+		TenantID        uuid.UUID `json:"tenant_id"`
+		LedgerAccountID uuid.UUID `json:"ledger_account_id"`
+		Currency        string    `json:"currency"`
+		Balance         Money     `json:"balance"`
+	}
+
+	// RevenueEntry is a movement of a revenue wallet: a fee earned on a transaction, a withdrawal or its reversal
+	RevenueEntry struct {
+		PostingID      uuid.UUID  `json:"posting_id"`
+		JournalEntryID uuid.UUID  `json:"journal_entry_id"`
+		TransactionID  *uuid.UUID `json:"transaction_id,omitempty"`
+		Description    string     `json:"description"`
+		Amount         Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		CreatedAt      time.Time  `json:"created_at"`
+	}
+
+	// RevenueDay is what a revenue wallet earned and paid out on a day of the tenant's timezone. Earnings counts the
+	// fees earned, a withdrawal that failed is not counted as paid out
+	RevenueDay struct {
+		Day       string `json:"day"`
+		Earned    Money  `json:"earned"`
+		Withdrawn Money  `json:"withdrawn"`
+		Net       Money  `json:"net"`
+		Earnings  int    `json:"earnings"`
+	}
+
+	// RevenueWithdrawal schema. A withdrawal pays out part of a revenue wallet to the tenant's settlement account:
+	// its amount leaves the wallet for the provider's clearing account before the provider is called, and comes back
+	// with a reversing entry when the provider rejects it
+	RevenueWithdrawal struct {
+		ID              uuid.UUID               `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID        uuid.UUID               `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		LedgerAccountID uuid.UUID               `gorm:"type:uuid;not null" json:"ledger_account_id"`
+		Amount          Money                   `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		BankNumber      string                  `gorm:"size:20;not null" json:"bank_number"`
+		AccountNumber   string                  `gorm:"size:20;not null" json:"account_number"`
+		Provider        PaymentProvider         `gorm:"type:varchar(50);not null" json:"provider"`
+		Status          RevenueWithdrawalStatus `gorm:"type:varchar(50);not null;index" json:"status"`
+		JournalEntryID  uuid.UUID               `gorm:"type:uuid;not null" json:"journal_entry_id"`
+		ReversalEntryID *uuid.UUID              `gorm:"type:uuid" json:"reversal_entry_id,omitempty"`
+		FailureReason   string                  `gorm:"type:text" json:"failure_reason,omitempty"`
+		CreatedAt       time.Time               `gorm:"default:now()" json:"created_at"`
+		UpdatedAt       *time.Time              `json:"updated_at,omitempty"`
+		DeletedAt       gorm.DeletedAt          `gorm:"index" json:"-"`
+	}
+)
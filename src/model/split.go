@@ -0,0 +1,175 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// SplitShareTypeFixed is a share of a fixed amount of every payment
+	SplitShareTypeFixed SplitShareType = "fixed"
+	// SplitShareTypePercentage is a share of a percentage of every payment
+	SplitShareTypePercentage SplitShareType = "percentage"
+
+	// SplitRecipientUser is a share paid into the wallet of a user of the tenant
+	SplitRecipientUser SplitRecipientType = "user"
+	// SplitRecipientTenant is a share paid into the tenant's revenue wallet
+	SplitRecipientTenant SplitRecipientType = "tenant"
+
+	// MaxSplitShares is the most recipients a payment can be split between
+	MaxSplitShares = 20
+)
+
+var (
+	// ErrInvalidSplit when a split cannot be created as it is
+	ErrInvalidSplit = errors.New("invalid split")
+	// ErrSplitExceedsAmount when the shares of a split add up to more than the payment it splits
+	ErrSplitExceedsAmount = errors.New("split shares exceed the amount")
+)
+
+type (
+	// SplitShareType is how a share of a split is worked out
+	SplitShareType string
+
+	// SplitRecipientType is who a share of a split is paid to
+	SplitRecipientType string
+
+	// Split schema, how the payments into the wallet of OwnerID are divided between recipients. A split is attached to
+	// a deposit or an invoice of its owner and runs when the deposit's credit succeeds: the fixed shares are taken
+	// first, then the percentages of what was credited, each rounded down, and what is left goes to the share marked
+	// Remainder. The owner can be a recipient too, its share stays in its wallet
+	Split struct {
+		ID        uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		TenantID  uuid.UUID      `gorm:"type:uuid;not null;index" json:"tenant_id"`
+		OwnerID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"owner_id"`
+		Name      string         `gorm:"size:255;not null" json:"name"`
+		Currency  string         `gorm:"type:varchar(3);not null" json:"currency"`
+		Shares    []SplitShare   `gorm:"foreignKey:SplitID" json:"shares"`
+		CreatedAt time.Time      `gorm:"default:now()" json:"created_at"`
+		UpdatedAt *time.Time     `json:"updated_at,omitempty"`
+		DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	}
+
+	// SplitShare schema, what one recipient of a split gets. RecipientID is the user for a user share and the tenant
+	// for a tenant share, Amount is set on fixed shares and PercentBasisPoints (1% = 100) on percentage shares.
+	// RecipientEmail names the user of a new user share, it is not stored
+	SplitShare struct {
+		ID                 uuid.UUID          `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		SplitID            uuid.UUID          `gorm:"type:uuid;not null;index" json:"split_id"`
+		RecipientType      SplitRecipientType `gorm:"type:varchar(50);not null" json:"recipient_type"`
+		RecipientID        uuid.UUID          `gorm:"type:uuid;not null" json:"recipient_id"`
+		RecipientEmail     string             `gorm:"-" json:"recipient_email,omitempty"`
+		Type               SplitShareType     `gorm:"type:varchar(50);not null" json:"type"`
+		Amount             Money              `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+		PercentBasisPoints int64              `gorm:"not null;default:0" json:"percent_basis_points"`
+		Remainder          bool               `gorm:"not null;default:false" json:"remainder"`
+	}
+
+	// SplitAllocation is what a share of a split gets of a payment
+	SplitAllocation struct {
+		Share  SplitShare `json:"share"`
+		Amount Money      `json:"amount"`
+	}
+)
+
+// Prepare validates a new split and normalises its currency: it needs between one and MaxSplitShares shares, exactly
+// one of which takes the remainder, fixed shares of a positive amount in the split's currency and percentage shares
+// that add up to no more than 100%
+func (s *Split) Prepare() error {
+	s.Currency = strings.ToUpper(s.Currency)
+	if !IsSupportedCurrency(s.Currency) {
+		return ErrUnsupportedCurrency
+	}
+
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSplit)
+	}
+
+	if len(s.Shares) == 0 || len(s.Shares) > MaxSplitShares {
+		return fmt.Errorf("%w: a split has between 1 and %d shares", ErrInvalidSplit, MaxSplitShares)
+	}
+
+	var remainders, basisPoints int64
+	for n := range s.Shares {
+		share := &s.Shares[n]
+		if share.RecipientType != SplitRecipientUser && share.RecipientType != SplitRecipientTenant {
+			return fmt.Errorf("%w: share %d: recipient can either be user or tenant", ErrInvalidSplit, n+1)
+		}
+
+		if share.Remainder {
+			remainders++
+		}
+
+		switch share.Type {
+		case SplitShareTypeFixed:
+			if share.Amount.Currency != "" && !strings.EqualFold(share.Amount.Currency, s.Currency) {
+				return ErrCurrencyMismatch
+			}
+			share.Amount = withCurrency(share.Amount, s.Currency)
+
+			if !share.Amount.IsPositive() || share.PercentBasisPoints != 0 {
+				return fmt.Errorf("%w: share %d: a fixed share has a positive amount and no percentage", ErrInvalidSplit, n+1)
+			}
+		case SplitShareTypePercentage:
+			if share.PercentBasisPoints <= 0 || share.PercentBasisPoints > 10000 || !share.Amount.IsZero() {
+				return fmt.Errorf("%w: share %d: a percentage share has between 1 and 10000 basis points and no amount", ErrInvalidSplit, n+1)
+			}
+			share.Amount = ZeroMoney(s.Currency)
+			basisPoints += share.PercentBasisPoints
+		default:
+			return fmt.Errorf("%w: share %d: share type can either be fixed or percentage", ErrInvalidSplit, n+1)
+		}
+	}
+
+	if remainders != 1 {
+		return fmt.Errorf("%w: exactly one share takes the remainder", ErrInvalidSplit)
+	}
+
+	if basisPoints > 10000 {
+		return fmt.Errorf("%w: percentages add up to more than 100%%", ErrInvalidSplit)
+	}
+
+	return nil
+}
+
+// Allocate divides the amount between the shares of the split, in the order of the shares. The fixed shares get
+// their amount and the percentage shares their percentage of the amount rounded down to the minor unit, what is left
+// goes to the remainder share on top of its own. It returns ErrSplitExceedsAmount when the amount cannot cover the
+// shares
+func (s Split) Allocate(amount Money) ([]SplitAllocation, error) {
+	if !strings.EqualFold(amount.Currency, s.Currency) {
+		return nil, ErrCurrencyMismatch
+	}
+
+	allocations := make([]SplitAllocation, len(s.Shares))
+	left, remainder := amount.Minor, -1
+
+	for n, share := range s.Shares {
+		minor := share.Amount.Minor
+		if share.Type == SplitShareTypePercentage {
+			minor = amount.Minor * share.PercentBasisPoints / 10000
+		}
+
+		if minor > left {
+			return nil, fmt.Errorf("%w: %s cannot cover the shares", ErrSplitExceedsAmount, amount)
+		}
+		left -= minor
+
+		allocations[n] = SplitAllocation{Share: share, Amount: NewMoney(minor, amount.Currency)}
+		if share.Remainder {
+			remainder = n
+		}
+	}
+
+	if remainder < 0 {
+		return nil, fmt.Errorf("%w: no share takes the remainder", ErrInvalidSplit)
+	}
+	allocations[remainder].Amount.Minor += left
+
+	return allocations, nil
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitPrepare(t *testing.T) {
+	valid := func(change func(s *Split)) Split {
+		s := Split{
+			Name:     "marketplace",
+			Currency: "ngn",
+			Shares: []SplitShare{
+				{RecipientType: SplitRecipientUser, Type: SplitShareTypePercentage, PercentBasisPoints: 8500, Remainder: true},
+				{RecipientType: SplitRecipientTenant, Type: SplitShareTypePercentage, PercentBasisPoints: 1000},
+				{RecipientType: SplitRecipientUser, Type: SplitShareTypeFixed, Amount: NewMoney(50000, "")},
+			},
+		}
+		if change != nil {
+			change(&s)
+		}
+		return s
+	}
+
+	split := valid(nil)
+	require.NoError(t, split.Prepare())
+	require.Equal(t, "NGN", split.Currency)
+	require.Equal(t, NewMoney(50000, "NGN"), split.Shares[2].Amount)
+	require.Equal(t, ZeroMoney("NGN"), split.Shares[0].Amount)
+
+	cases := map[string]func(s *Split){
+		"no shares":             func(s *Split) { s.Shares = nil },
+		"no remainder":          func(s *Split) { s.Shares[0].Remainder = false },
+		"two remainders":        func(s *Split) { s.Shares[1].Remainder = true },
+		"over 100%":             func(s *Split) { s.Shares[1].PercentBasisPoints = 1501 },
+		"zero percentage":       func(s *Split) { s.Shares[1].PercentBasisPoints = 0 },
+		"zero fixed amount":     func(s *Split) { s.Shares[2].Amount = ZeroMoney("NGN") },
+		"fixed with percentage": func(s *Split) { s.Shares[2].PercentBasisPoints = 100 },
+		"unknown recipient":     func(s *Split) { s.Shares[1].RecipientType = "bank" },
+		"unknown type":          func(s *Split) { s.Shares[1].Type = "tiered" },
+		"no name":               func(s *Split) { s.Name = " " },
+	}
+
+	for name, change := range cases {
+		t.Run(name, func(t *testing.T) {
+			split := valid(change)
+			require.ErrorIs(t, split.Prepare(), ErrInvalidSplit)
+		})
+	}
+
+	split = valid(func(s *Split) { s.Shares[2].Amount = NewMoney(100, "USD") })
+	require.ErrorIs(t, split.Prepare(), ErrCurrencyMismatch)
+}
+
+func TestSplitAllocate(t *testing.T) {
+	split := Split{
+		Currency: "NGN",
+		Shares: []SplitShare{
+			{Type: SplitShareTypePercentage, PercentBasisPoints: 8500, Remainder: true},
+			{Type: SplitShareTypePercentage, PercentBasisPoints: 1000},
+			{Type: SplitShareTypePercentage, PercentBasisPoints: 500},
+		},
+	}
+
+	// 85%, 10% and 5% of 999 round down to 849, 99 and 49, the 2 left go to the remainder share
+	allocations, err := split.Allocate(NewMoney(999, "NGN"))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(851, "NGN"), allocations[0].Amount)
+	require.Equal(t, NewMoney(99, "NGN"), allocations[1].Amount)
+	require.Equal(t, NewMoney(49, "NGN"), allocations[2].Amount)
+
+	// fixed shares are taken whole, percentages under 100% leave the rest to the remainder share
+	split.Shares = []SplitShare{
+		{Type: SplitShareTypeFixed, Amount: NewMoney(300, "NGN")},
+		{Type: SplitShareTypePercentage, PercentBasisPoints: 2000, Remainder: true},
+	}
+
+	allocations, err = split.Allocate(NewMoney(1000, "NGN"))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(300, "NGN"), allocations[0].Amount)
+	require.Equal(t, NewMoney(700, "NGN"), allocations[1].Amount)
+
+	_, err = split.Allocate(NewMoney(299, "NGN"))
+	require.ErrorIs(t, err, ErrSplitExceedsAmount)
+
+	_, err = split.Allocate(NewMoney(1000, "USD"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
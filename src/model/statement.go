@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// StatementFormatJSON is a statement returned in the usual json response
+	StatementFormatJSON StatementFormat = "json"
+	// StatementFormatCSV is a statement downloaded as a csv file, one row per entry
+	StatementFormatCSV StatementFormat = "csv"
+	// StatementFormatPDF is a statement downloaded as a printable pdf file
+	StatementFormatPDF StatementFormat = "pdf"
+)
+
+type (
+	// StatementFormat is the format a statement is rendered in
+	StatementFormat string
+
+	// Statement of a wallet between two dates of the tenant's timezone. Every entry is a movement of the wallet with
+	// the balance it left, the opening balance is the balance before the first day and the closing balance the
+	// balance after the last day
+	Statement struct {
+		UserID         uuid.UUID        `json:"user_id"`
+		Currency       string           `json:"currency"`
+		Timezone       string           `json:"timezone"`
+		From           time.Time        `json:"from"`
+		To             time.Time        `json:"to"`
+		OpeningBalance Money            `json:"opening_balance"`
+		ClosingBalance Money            `json:"closing_balance"`
+		TotalCredits   Money            `json:"total_credits"`
+		TotalDebits    Money            `json:"total_debits"`
+		TotalFees      Money            `json:"total_fees"`
+		Entries        []StatementEntry `json:"entries"`
+		GeneratedAt    time.Time        `json:"generated_at"`
+	}
+
+	// StatementEntry is one movement of a wallet on its statement
+	StatementEntry struct {
+		Date            time.Time         `json:"date"`
+		TransactionID   uuid.UUID         `json:"transaction_id"`
+		TransactionType TransactionType   `json:"transaction_type"`
+		TransactionFlow TransactionFlow   `json:"transaction_flow"`
+		Status          TransactionStatus `json:"status"`
+		Amount          Money             `json:"amount"`
+		Fees            Money             `json:"fees"`
+		Balance         Money             `json:"balance"`
+	}
+)
+
+// IsValid reports whether the format is one statements are rendered in
+func (f StatementFormat) IsValid() bool {
+	return f == StatementFormatJSON || f == StatementFormatCSV || f == StatementFormatPDF
+}
@@ -9,13 +9,40 @@ import (
 
 type (
 	Tenant struct {
-		ID           uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-		BusinessName string         `gorm:"size:100;not null" json:"businessName"`
-		Email        string         `gorm:"size:100;uniqueIndex;not null" json:"email"`
-		Password     Password       `gorm:"not null" json:"-"`
-		CreatedAt    time.Time      `json:"createdAt"`
-		UpdatedAt    time.Time      `json:"updatedAt"`
-		DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
-		Users        []User         `gorm:"foreignKey:TenantID" json:"-"`
+		ID                        uuid.UUID      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		BusinessName              string         `gorm:"size:100;not null" json:"businessName"`
+		Email                     string         `gorm:"size:100;uniqueIndex;not null" json:"email"`
+		Password                  Password       `gorm:"not null" json:"-"`
+		AllowCrossTenantTransfers bool           `gorm:"not null;default:false" json:"allowCrossTenantTransfers"`
+		Timezone                  string         `gorm:"size:64;not null;default:'Africa/Lagos'" json:"timezone"`
+		CreatedAt                 time.Time      `json:"createdAt"`
+		UpdatedAt                 time.Time      `json:"updatedAt"`
+		DeletedAt                 gorm.DeletedAt `gorm:"index" json:"-"`
+		Users                     []User         `gorm:"foreignKey:TenantID" json:"-"`
 	}
 )
+
+// AllowsTransfersWith reports whether users of the tenant may make internal transfers with users of the other tenant.
+// Users of one tenant can always pay each other, across tenants both tenants must allow it
+func (t Tenant) AllowsTransfersWith(other Tenant) bool {
+	if t.ID == other.ID {
+		return true
+	}
+
+	return t.AllowCrossTenantTransfers && other.AllowCrossTenantTransfers
+}
+
+// Location returns the tenant's timezone, dates shown to the tenant and its users are in it. A tenant without a
+// valid timezone gets DefaultTimezone
+func (t Tenant) Location() *time.Location {
+	if location, err := time.LoadLocation(t.Timezone); err == nil && t.Timezone != "" {
+		return location
+	}
+
+	location, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
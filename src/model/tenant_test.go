@@ -0,0 +1,35 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantAllowsTransfersWith(t *testing.T) {
+	tenant := Tenant{ID: uuid.New()}
+	other := Tenant{ID: uuid.New()}
+
+	// users of one tenant can always pay each other
+	require.True(t, tenant.AllowsTransfersWith(tenant))
+
+	require.False(t, tenant.AllowsTransfersWith(other))
+
+	// one side allowing it is not enough
+	tenant.AllowCrossTenantTransfers = true
+	require.False(t, tenant.AllowsTransfersWith(other))
+	require.False(t, other.AllowsTransfersWith(tenant))
+
+	other.AllowCrossTenantTransfers = true
+	require.True(t, tenant.AllowsTransfersWith(other))
+	require.True(t, other.AllowsTransfersWith(tenant))
+}
+
+func TestTenantLocation(t *testing.T) {
+	require.Equal(t, "America/New_York", Tenant{Timezone: "America/New_York"}.Location().String())
+
+	// tenants without a valid timezone see dates in the default one
+	require.Equal(t, DefaultTimezone, Tenant{}.Location().String())
+	require.Equal(t, DefaultTimezone, Tenant{Timezone: "Mars/Olympus"}.Location().String())
+}
@@ -31,6 +31,25 @@ const (
 	TransactionFlowRevenue TransactionFlow = "revenue"
 	// TransactionFlowWithdrawal represents a withdrawal transaction
 	TransactionFlowWithdrawal TransactionFlow = "withdrawal"
+	// TransactionFlowConversion represents a conversion between two wallets of the same user
+	TransactionFlowConversion TransactionFlow = "conversion"
+	// TransactionFlowInternalTransfer represents a transfer between the wallets of two users, no provider involved
+	TransactionFlowInternalTransfer TransactionFlow = "internal_transfer"
+	// TransactionFlowRefund represents a refund of a credit back through the provider that collected it
+	TransactionFlowRefund TransactionFlow = "refund"
+	// TransactionFlowDispute represents the debit a lost dispute posts, it is canceled when the dispute is not lost
+	TransactionFlowDispute TransactionFlow = "dispute"
+	// TransactionFlowAdjustment represents a correcting entry the ledger verifier writes to bring a wallet's balance
+	// history back in line with its ledger account, it moves no funds
+	TransactionFlowAdjustment TransactionFlow = "adjustment"
+	// TransactionFlowEscrow represents funds moving into escrow from the buyer's wallet, or out of it to the seller on
+	// release or back to the buyer on refund
+	TransactionFlowEscrow TransactionFlow = "escrow"
+	// TransactionFlowSplit represents the shares of a deposit a split moves from the wallet credited to its recipients
+	TransactionFlowSplit TransactionFlow = "split"
+	// TransactionFlowReversal represents the credit that gives a wallet back a transfer the provider reversed after
+	// it settled
+	TransactionFlowReversal TransactionFlow = "reversal"
 )
 
 type (
@@ -44,21 +63,29 @@ type (
 	// TransactionFlow string
 	TransactionFlow string
 
-	// Transaction schema
+	// Transaction schema. Charges are what the provider or the conversion spread took, Fee is the tenant's fee with
+	// its VAT, FeeVAT, priced by the FeeRuleID version of the tenant's fee rule. A credit with a SplitID is divided
+	// between the recipients of the split once it succeeds
 	Transaction struct {
-		ID              uuid.UUID         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-		UserID          uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id" validate:"required"`
-		User            *User             `gorm:"foreignKey:UserID;references:ID"`
-		Amount          float64           `gorm:"not null" json:"amount" validate:"required"`
-		Charges         float64           `json:"charges"`
-		MetaData        *postgres.Jsonb   `gorm:"type:jsonb" json:"meta_data"`
-		Currency        string            `json:"currency"`
-		TransactionType TransactionType   `gorm:"type:varchar(50);not null" json:"transaction_type"`
-		Status          TransactionStatus `gorm:"type:varchar(50);not null" json:"status"`
-		TransactionFlow TransactionFlow   `gorm:"type:varchar(50)" json:"transaction_flow"`
-		CreatedAt       time.Time         `gorm:"default:now()" json:"created_at"`
-		UpdatedAt       *time.Time        `json:"updated_at,omitempty"`
-		DeletedAt       gorm.DeletedAt    `gorm:"index" json:"-"`
+		ID                   uuid.UUID         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		UserID               uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id" validate:"required"`
+		User                 *User             `gorm:"foreignKey:UserID;references:ID"`
+		Amount               Money             `gorm:"embedded;embeddedPrefix:amount_" json:"amount" validate:"required"`
+		Charges              Money             `gorm:"embedded;embeddedPrefix:charges_" json:"charges"`
+		Fee                  Money             `gorm:"embedded;embeddedPrefix:fee_" json:"fee"`
+		FeeVAT               Money             `gorm:"embedded;embeddedPrefix:fee_vat_" json:"fee_vat"`
+		FeeRuleID            *uuid.UUID        `gorm:"type:uuid" json:"fee_rule_id,omitempty"`
+		SplitID              *uuid.UUID        `gorm:"type:uuid" json:"split_id,omitempty"`
+		MetaData             *postgres.Jsonb   `gorm:"type:jsonb" json:"meta_data"`
+		Currency             string            `json:"currency"`
+		Provider             PaymentProvider   `gorm:"type:varchar(50)" json:"provider"`
+		TransactionType      TransactionType   `gorm:"type:varchar(50);not null" json:"transaction_type"`
+		Status               TransactionStatus `gorm:"type:varchar(50);not null" json:"status"`
+		TransactionFlow      TransactionFlow   `gorm:"type:varchar(50)" json:"transaction_flow"`
+		RelatedTransactionID *uuid.UUID        `gorm:"type:uuid;index" json:"related_transaction_id,omitempty"`
+		CreatedAt            time.Time         `gorm:"default:now()" json:"created_at"`
+		UpdatedAt            *time.Time        `json:"updated_at,omitempty"`
+		DeletedAt            gorm.DeletedAt    `gorm:"index" json:"-"`
 	}
 )
 
@@ -84,3 +111,29 @@ func (t *Transaction) SetMetaData(md MetaData) error {
 	t.MetaData = &postgres.Jsonb{RawMessage: d}
 	return nil
 }
+
+// SetFee prices the transaction with the fee quote
+func (t *Transaction) SetFee(quote FeeQuote) {
+	t.Fee = quote.Total
+	t.FeeVAT = quote.VAT
+	t.FeeRuleID = quote.RuleID
+}
+
+// IsProviderCollection reports whether the transaction is money a provider collected from a payer into the wallet, a
+// successful deposit. Only those can be refunded through the provider or disputed, a reversal credit carries the
+// provider of the transfer it gives back but the provider never collected it
+func (t Transaction) IsProviderCollection() bool {
+	return t.TransactionType == TransactionTypeCredit && t.Status == TransactionStatusSuccessful &&
+		t.TransactionFlow == TransactionFlowRevenue && t.Provider != ""
+}
+
+// WalletMovement is what the transaction moves its wallet by: a credit lands less its fee, a debit takes its fee on
+// top of the amount
+func (t Transaction) WalletMovement() (Money, error) {
+	if t.TransactionType == TransactionTypeDebit {
+		movement, err := t.Amount.Add(t.Fee)
+		return movement.Neg(), err
+	}
+
+	return t.Amount.Sub(t.Fee)
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionIsProviderCollection(t *testing.T) {
+	deposit := Transaction{
+		TransactionType: TransactionTypeCredit,
+		Status:          TransactionStatusSuccessful,
+		TransactionFlow: TransactionFlowRevenue,
+		Provider:        PaymentProviderPaystack,
+	}
+	require.True(t, deposit.IsProviderCollection())
+
+	// a reversal credit gives back a transfer, the provider never collected it from a payer
+	reversal := deposit
+	reversal.TransactionFlow = TransactionFlowReversal
+	require.False(t, reversal.IsProviderCollection())
+
+	pending := deposit
+	pending.Status = TransactionStatusPending
+	require.False(t, pending.IsProviderCollection())
+
+	internal := deposit
+	internal.Provider = ""
+	require.False(t, internal.IsProviderCollection())
+
+	withdrawal := deposit
+	withdrawal.TransactionType = TransactionTypeDebit
+	require.False(t, withdrawal.IsProviderCollection())
+}
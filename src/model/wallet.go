@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,20 +9,32 @@ import (
 )
 
 type (
-	// Wallet schema
+	// WalletStatus is which movements a wallet allows
+	WalletStatus string
+
+	// Wallet schema. A user has one wallet per currency
 	Wallet struct {
 		ID              uuid.UUID        `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-		UserID          uuid.UUID        `gorm:"type:uuid;not null;index" json:"user_id"`
+		UserID          uuid.UUID        `gorm:"type:uuid;not null;index;uniqueIndex:idx_wallets_user_currency" json:"user_id"`
+		Currency        string           `gorm:"type:varchar(3);not null;default:'NGN';uniqueIndex:idx_wallets_user_currency" json:"currency"`
 		User            *User            `gorm:"foreignKey:UserID;references:ID"`
 		TransactionID   *uuid.UUID       `json:"transaction_id"`
 		Transaction     *Transaction     `gorm:"foreignKey:TransactionID;references:ID"`
 		TransactionType *TransactionType `gorm:"type:varchar(50)" json:"transaction_type"`
 		BalanceID       *uuid.UUID       `gorm:"type:uuid" json:"balance_id"`
-		BalanceBefore   float64          `gorm:"not null" json:"balance_before"`
-		BalanceAfter    float64          `gorm:"not null" json:"balance_after"`
-		CreatedAt       time.Time        `gorm:"default:now()" json:"created_at"`
-		UpdatedAt       *time.Time       `json:"updated_at"`
-		DeletedAt       gorm.DeletedAt   `gorm:"index" json:"-"`
+		LedgerAccountID *uuid.UUID       `gorm:"type:uuid;index" json:"ledger_account_id"`
+		BalanceBefore   Money            `gorm:"embedded;embeddedPrefix:balance_before_" json:"balance_before"`
+		BalanceAfter    Money            `gorm:"embedded;embeddedPrefix:balance_after_" json:"balance_after"`
+		// BookBalance is every settled movement of the wallet, AvailableBalance is the book balance less active holds
+		BookBalance      Money `gorm:"-" json:"book_balance"`
+		AvailableBalance Money `gorm:"-" json:"available_balance"`
+		// Status restricts the movements of the wallet, StatusReason is why it was last changed
+		Status          WalletStatus   `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
+		StatusReason    string         `gorm:"type:varchar(255)" json:"status_reason,omitempty"`
+		StatusChangedAt *time.Time     `json:"status_changed_at,omitempty"`
+		CreatedAt       time.Time      `gorm:"default:now()" json:"created_at"`
+		UpdatedAt       *time.Time     `json:"updated_at"`
+		DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 	}
 )
 
@@ -31,3 +44,37 @@ const (
 	// CreditTransaction represents a credit transaction
 	CreditTransaction TransactionType = "credit"
 )
+
+const (
+	// WalletStatusActive allows every movement
+	WalletStatusActive WalletStatus = "active"
+	// WalletStatusFrozen allows no movement
+	WalletStatusFrozen WalletStatus = "frozen"
+	// WalletStatusPostNoDebit allows credits only
+	WalletStatusPostNoDebit WalletStatus = "post_no_debit"
+	// WalletStatusPostNoCredit allows debits only
+	WalletStatusPostNoCredit WalletStatus = "post_no_credit"
+)
+
+// ErrInvalidWalletStatus when a wallet status is none of active, frozen, post_no_debit or post_no_credit
+var ErrInvalidWalletStatus = errors.New("wallet status can either be active, frozen, post_no_debit or post_no_credit")
+
+// IsValid reports whether the wallet status is one of the known statuses
+func (s WalletStatus) IsValid() bool {
+	switch s {
+	case WalletStatusActive, WalletStatusFrozen, WalletStatusPostNoDebit, WalletStatusPostNoCredit:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanDebit reports whether funds can leave the wallet
+func (w Wallet) CanDebit() bool {
+	return w.Status != WalletStatusFrozen && w.Status != WalletStatusPostNoDebit
+}
+
+// CanCredit reports whether funds can enter the wallet
+func (w Wallet) CanCredit() bool {
+	return w.Status != WalletStatusFrozen && w.Status != WalletStatusPostNoCredit
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletStatus(t *testing.T) {
+	tests := []struct {
+		status    WalletStatus
+		canDebit  bool
+		canCredit bool
+	}{
+		{status: WalletStatusActive, canDebit: true, canCredit: true},
+		{status: WalletStatusFrozen},
+		{status: WalletStatusPostNoDebit, canCredit: true},
+		{status: WalletStatusPostNoCredit, canDebit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			require.True(t, tt.status.IsValid())
+
+			wallet := Wallet{Status: tt.status}
+			require.Equal(t, tt.canDebit, wallet.CanDebit())
+			require.Equal(t, tt.canCredit, wallet.CanCredit())
+		})
+	}
+
+	require.False(t, WalletStatus("locked").IsValid())
+}
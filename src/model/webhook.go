@@ -1,15 +1,29 @@
 package model
 
+const (
+	// PaymentEventCharge is money a provider collected from a payer into a wallet
+	PaymentEventCharge PaymentEventKind = "charge"
+	// PaymentEventTransfer is money a provider paid out of a wallet to a bank account
+	PaymentEventTransfer PaymentEventKind = "transfer"
+)
+
 type (
-	PaymentWebhook struct {
-		Event string `json:"event"` // success, failed, pending
-		Data  struct {
-			Status    string         `json:"status"`
-			Reference string         `json:"reference"`
-			Amount    float64        `json:"amount"`
-			Currency  string         `json:"currency"`
-			Metadata  map[string]any `json:"metadata"`
-			Fees      float64        `json:"fees"`
-		} `json:"data"`
+	// PaymentEventKind of type string
+	PaymentEventKind string
+
+	// PaymentEvent is a provider's webhook normalised by the provider's adapter, it is what gets applied to the
+	// transaction the reference points at whatever the provider. Status is successful, failed or pending, or
+	// refunded for a transfer the provider reversed. Amount and Fees are what the provider reports, in the
+	// currency of the event
+	PaymentEvent struct {
+		Provider  PaymentProvider   `json:"provider"`
+		ID        string            `json:"id"`
+		Type      string            `json:"type"`
+		Kind      PaymentEventKind  `json:"kind"`
+		Status    TransactionStatus `json:"status"`
+		Reference string            `json:"reference"`
+		Amount    Money             `json:"amount"`
+		Fees      Money             `json:"fees"`
+		Metadata  map[string]any    `json:"metadata,omitempty"`
 	}
 )
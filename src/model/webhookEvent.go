@@ -0,0 +1,128 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// WebhookEventReceived is an event stored in the inbox and not processed yet
+	WebhookEventReceived WebhookEventStatus = "received"
+	// WebhookEventProcessed is an event applied to the transaction it references, it is never applied again
+	WebhookEventProcessed WebhookEventStatus = "processed"
+	// WebhookEventFailed is an event whose processing failed, it is processed again when the provider redelivers it
+	// or an admin replays it
+	WebhookEventFailed WebhookEventStatus = "failed"
+	// WebhookEventRejected is a delivery refused before it was read, its signature or source did not check out. It is
+	// kept for inspection and never processed
+	WebhookEventRejected WebhookEventStatus = "rejected"
+	// WebhookEventIgnored is an event of a type that moves no wallet, it is acknowledged and kept for inspection
+	WebhookEventIgnored WebhookEventStatus = "ignored"
+
+	// MaxRejectedWebhookBody is how much of a rejected delivery's body the inbox keeps, in bytes. A rejected body is
+	// sent by anyone, only enough of it to tell what it was is stored
+	MaxRejectedWebhookBody = 2 << 10
+
+	// RedactedWebhookHeader is what the inbox keeps of a header carrying a secret or a credential
+	RedactedWebhookHeader = "[redacted]"
+)
+
+// RedactedWebhookHeaders are the headers whose value is never stored in the inbox. Flutterwave's verif-hash is the
+// secret hash of the account itself, the others are signatures and credentials
+var RedactedWebhookHeaders = []string{
+	"Verif-Hash",
+	"X-Paystack-Signature",
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Admin-Key",
+}
+
+type (
+	// WebhookEventStatus of type string
+	WebhookEventStatus string
+
+	// WebhookEvent schema, a webhook delivered by a payment provider. Every delivery is stored in this inbox, keyed by
+	// the provider's event ID, with its raw body and headers before it is processed. Processing an event and marking
+	// it processed are committed together, so an event is applied exactly once however often it is delivered
+	WebhookEvent struct {
+		ID          uuid.UUID          `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+		Provider    PaymentProvider    `gorm:"type:varchar(50);not null;uniqueIndex:idx_webhook_events_event" json:"provider"`
+		EventID     string             `gorm:"size:255;not null;uniqueIndex:idx_webhook_events_event" json:"event_id"`
+		Source      string             `gorm:"size:64" json:"source,omitempty"`
+		Body        string             `gorm:"type:text;not null" json:"body"`
+		Headers     map[string]string  `gorm:"type:jsonb;serializer:json" json:"headers"`
+		Status      WebhookEventStatus `gorm:"type:varchar(50);not null;index" json:"status"`
+		Attempts    int                `gorm:"not null;default:0" json:"attempts"`
+		LastError   string             `gorm:"type:text" json:"last_error,omitempty"`
+		ProcessedAt *time.Time         `json:"processed_at,omitempty"`
+		CreatedAt   time.Time          `gorm:"default:now()" json:"created_at"`
+		UpdatedAt   *time.Time         `json:"updated_at,omitempty"`
+	}
+)
+
+// WebhookEventID returns the ID a provider gave the event in the body, its "id" or its data's "id". A body without
+// one is identified by its hash, so only the exact same delivery is taken for the same event
+func WebhookEventID(body []byte) string {
+	var event struct {
+		ID   json.RawMessage `json:"id"`
+		Data struct {
+			ID json.RawMessage `json:"id"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &event); err == nil {
+		for _, id := range []json.RawMessage{event.ID, event.Data.ID} {
+			if value := strings.Trim(string(bytes.TrimSpace(id)), `"`); value != "" && value != "null" {
+				return value
+			}
+		}
+	}
+
+	return webhookBodyHash(body)
+}
+
+// RejectedWebhookEventID is the key of a rejected delivery in the inbox. It is its body's hash and never the ID the
+// body claims, so a forged delivery cannot take the place of the genuine event
+func RejectedWebhookEventID(body []byte) string {
+	return "rejected:" + webhookBodyHash(body)
+}
+
+// WebhookHeaders flattens the headers of a webhook delivery for the inbox, one value per header. The headers in
+// RedactedWebhookHeaders are kept with their value redacted, so it shows they were sent
+func WebhookHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	for _, name := range RedactedWebhookHeaders {
+		if _, ok := headers[name]; ok {
+			headers[name] = RedactedWebhookHeader
+		}
+	}
+
+	return headers
+}
+
+// TruncateWebhookBody cuts the body of a rejected delivery down to MaxRejectedWebhookBody bytes of valid UTF-8
+func TruncateWebhookBody(body string) string {
+	if len(body) > MaxRejectedWebhookBody {
+		body = body[:MaxRejectedWebhookBody]
+	}
+
+	return strings.ToValidUTF8(body, "")
+}
+
+// webhookBodyHash is the hex sha256 of a webhook's body
+func webhookBodyHash(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookEventID(t *testing.T) {
+	require.Equal(t, "evt_1", WebhookEventID([]byte(`{"id":"evt_1","data":{"id":42}}`)))
+	require.Equal(t, "42", WebhookEventID([]byte(`{"event":"charge.success","data":{"id":42}}`)), "the data's id when the event has none")
+	require.Equal(t, "42", WebhookEventID([]byte(`{"id":null,"data":{"id":42}}`)))
+
+	// without an id the body is its own identity, only the exact same delivery is the same event
+	body := []byte(`{"data":{"reference":"crt_1"}}`)
+	require.Len(t, WebhookEventID(body), 64)
+	require.Equal(t, WebhookEventID(body), WebhookEventID(body))
+	require.NotEqual(t, WebhookEventID(body), WebhookEventID([]byte(`{"data":{"reference":"crt_2"}}`)))
+	require.Len(t, WebhookEventID([]byte("not json")), 64)
+}
+
+func TestWebhookHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Add("Auth", "payment")
+	header.Add("X-Forwarded-For", "10.0.0.1")
+	header.Add("X-Forwarded-For", "10.0.0.2")
+
+	require.Equal(t, map[string]string{"Auth": "payment", "X-Forwarded-For": "10.0.0.1, 10.0.0.2"}, WebhookHeaders(header))
+
+	// secrets and credentials are never stored, only that they were sent
+	header.Set("verif-hash", "flw-secret-hash")
+	header.Set("x-paystack-signature", "a1b2c3")
+	header.Set("Authorization", "Bearer token")
+	header.Set("Cookie", "session=1")
+
+	headers := WebhookHeaders(header)
+	require.Equal(t, RedactedWebhookHeader, headers["Verif-Hash"])
+	require.Equal(t, RedactedWebhookHeader, headers["X-Paystack-Signature"])
+	require.Equal(t, RedactedWebhookHeader, headers["Authorization"])
+	require.Equal(t, RedactedWebhookHeader, headers["Cookie"])
+	require.Equal(t, "payment", headers["Auth"])
+}
+
+func TestRejectedWebhookEventID(t *testing.T) {
+	body := []byte(`{"id":"evt_1","data":{"id":42}}`)
+
+	// a rejected delivery is keyed by its body, never by the event it claims to be
+	require.NotEqual(t, WebhookEventID(body), RejectedWebhookEventID(body))
+	require.Equal(t, RejectedWebhookEventID(body), RejectedWebhookEventID(body))
+	require.NotEqual(t, RejectedWebhookEventID(body), RejectedWebhookEventID([]byte(`{"id":"evt_1"}`)))
+}
+
+func TestTruncateWebhookBody(t *testing.T) {
+	require.Equal(t, `{"id":1}`, TruncateWebhookBody(`{"id":1}`))
+	require.Len(t, TruncateWebhookBody(strings.Repeat("a", MaxRejectedWebhookBody+1)), MaxRejectedWebhookBody)
+
+	// a rune cut in half is dropped, the body stays valid text
+	body := strings.Repeat("a", MaxRejectedWebhookBody-1) + "₦"
+	require.Equal(t, strings.Repeat("a", MaxRejectedWebhookBody-1), TruncateWebhookBody(body))
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	restModel "codematic/handler/model"
+	"codematic/model"
+)
+
+const (
+	// IdempotencyKeyHeader is the header a client sends the idempotency key of a request in
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on a response replayed from the first attempt of a request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength is the longest idempotency key accepted
+	maxIdempotencyKeyLength = 255
+)
+
+type (
+	// IdempotencyStore keeps the idempotency keys of requests and the responses of their first attempts
+	IdempotencyStore interface {
+		BeginIdempotentRequest(ctx context.Context, request model.IdempotencyKey) (model.IdempotencyKey, error)
+		CompleteIdempotentRequest(ctx context.Context, key model.IdempotencyKey) error
+		AbandonIdempotentRequest(ctx context.Context, key model.IdempotencyKey) error
+	}
+
+	// responseRecorder keeps a copy of the response written through it
+	responseRecorder struct {
+		gin.ResponseWriter
+		body bytes.Buffer
+	}
+)
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes a request sent with an Idempotency-Key header safe to retry. It runs after the auth
+// middleware, keys are scoped by the tenant and the user making the request. The first attempt is handled and its
+// response stored, a retry with the same body gets that response replayed without reaching the handler. A key sent
+// with another body is refused with 422, a retry while the first attempt is still being handled with 409. A first
+// attempt that fails with a 5xx does not keep its key, unless its handler set ProviderCalledInContext: the provider may
+// have carried the request out, so its 5xx is stored and replayed like any other response rather than sent to the
+// provider again. Requests without the header are handled as they are
+func (m *Middleware) IdempotencyMiddleware(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "idempotency key is too long")
+			return
+		}
+
+		tenantID, userID, err := idempotencyScope(c)
+		if err != nil {
+			restModel.ErrorResponse(c, http.StatusBadRequest, ErrInvalidToken.Error())
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "unable to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		request := model.IdempotencyKey{
+			TenantID:    tenantID,
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: model.RequestFingerprint(c.Request.Method, c.Request.URL.Path, body),
+		}
+
+		ctx := c.Request.Context()
+		stored, err := store.BeginIdempotentRequest(ctx, request)
+		switch {
+		case errors.Is(err, model.ErrIdempotencyKeyReused):
+			restModel.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		case errors.Is(err, model.ErrIdempotentRequestInFlight):
+			restModel.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		case err != nil:
+			m.logger.Err(err).Msgf("IdempotencyMiddleware ::: unable to begin request %v", err)
+			restModel.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if stored.Status == model.IdempotencyStatusCompleted {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.ResponseCode, stored.ContentType, stored.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError && !c.GetBool(ProviderCalledInContext) {
+			if err := store.AbandonIdempotentRequest(ctx, stored); err != nil {
+				m.logger.Err(err).Msgf("IdempotencyMiddleware ::: unable to abandon request %v", err)
+			}
+			return
+		}
+
+		stored.ResponseCode = recorder.Status()
+		stored.ContentType = recorder.Header().Get("Content-Type")
+		stored.ResponseBody = recorder.body.Bytes()
+
+		if err := store.CompleteIdempotentRequest(ctx, stored); err != nil {
+			m.logger.Err(err).Msgf("IdempotencyMiddleware ::: unable to complete request %v", err)
+		}
+	}
+}
+
+// idempotencyScope returns the tenant and the user an idempotency key belongs to: the user's tenant and the user for
+// a user, the tenant and no user for a tenant
+func idempotencyScope(c *gin.Context) (uuid.UUID, uuid.UUID, error) {
+	actorID, err := uuid.Parse(c.GetString(ActorIDInContext))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	tenantID := c.GetString(TenantIDInContext)
+	if tenantID == "" {
+		return actorID, uuid.Nil, nil
+	}
+
+	tenant, err := uuid.Parse(tenantID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	return tenant, actorID, nil
+}
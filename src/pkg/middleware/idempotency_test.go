@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+// memoryIdempotencyStore keeps idempotency keys in memory, with the rules of the controller's store
+type memoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]model.IdempotencyKey
+}
+
+func (s *memoryIdempotencyStore) BeginIdempotentRequest(_ context.Context, request model.IdempotencyKey) (model.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scope := request.TenantID.String() + request.UserID.String() + request.Key
+	key, ok := s.keys[scope]
+	switch {
+	case !ok:
+		request.ID = uuid.New()
+		request.Status = model.IdempotencyStatusInFlight
+		s.keys[scope] = request
+		return request, nil
+	case key.Fingerprint != request.Fingerprint:
+		return model.IdempotencyKey{}, model.ErrIdempotencyKeyReused
+	case key.Status == model.IdempotencyStatusInFlight:
+		return model.IdempotencyKey{}, model.ErrIdempotentRequestInFlight
+	default:
+		return key, nil
+	}
+}
+
+func (s *memoryIdempotencyStore) CompleteIdempotentRequest(_ context.Context, key model.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key.Status = model.IdempotencyStatusCompleted
+	s.keys[key.TenantID.String()+key.UserID.String()+key.Key] = key
+	return nil
+}
+
+func (s *memoryIdempotencyStore) AbandonIdempotentRequest(_ context.Context, key model.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key.TenantID.String()+key.UserID.String()+key.Key)
+	return nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &memoryIdempotencyStore{keys: map[string]model.IdempotencyKey{}}
+	userID, tenantID := uuid.New(), uuid.New()
+
+	var (
+		calls          int
+		release        chan struct{}
+		status         = http.StatusCreated
+		providerCalled bool
+	)
+
+	router := gin.New()
+	router.POST("/payment/deposit", func(c *gin.Context) {
+		c.Set(ActorIDInContext, userID.String())
+		c.Set(TenantIDInContext, tenantID.String())
+	}, (&Middleware{}).IdempotencyMiddleware(store), func(c *gin.Context) {
+		calls++
+		if release != nil {
+			<-release
+		}
+		if providerCalled {
+			c.Set(ProviderCalledInContext, true)
+		}
+		c.JSON(status, gin.H{"call": calls})
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/payment/deposit", strings.NewReader(body))
+		if key != "" {
+			request.Header.Set(IdempotencyKeyHeader, key)
+		}
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	// the first attempt is handled, its retry gets the same response without reaching the handler
+	first := send("key-1", `{"amount":5000}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.JSONEq(t, `{"call":1}`, first.Body.String())
+
+	retry := send("key-1", `{"amount":5000}`)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.JSONEq(t, `{"call":1}`, retry.Body.String())
+	require.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	require.Equal(t, 1, calls)
+
+	// the same key with another body is refused
+	require.Equal(t, http.StatusUnprocessableEntity, send("key-1", `{"amount":6000}`).Code)
+
+	// requests without a key are handled every time
+	send("", `{"amount":5000}`)
+	send("", `{"amount":5000}`)
+	require.Equal(t, 3, calls)
+
+	// a retry while the first attempt is still being handled is refused
+	release = make(chan struct{})
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send("key-2", `{"amount":5000}`) }()
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.keys) == 2
+	}, time.Second, time.Millisecond)
+
+	require.Equal(t, http.StatusConflict, send("key-2", `{"amount":5000}`).Code)
+	close(release)
+	require.Equal(t, http.StatusCreated, (<-done).Code)
+	release = nil
+
+	// a first attempt that fails on the server does not keep its key, the retry is handled
+	status = http.StatusInternalServerError
+	require.Equal(t, http.StatusInternalServerError, send("key-3", `{"amount":5000}`).Code)
+
+	status = http.StatusCreated
+	require.Equal(t, http.StatusCreated, send("key-3", `{"amount":5000}`).Code)
+	require.Equal(t, 6, calls)
+
+	// a first attempt that fails after reaching the provider keeps its key, the retry gets the failure replayed
+	status, providerCalled = http.StatusInternalServerError, true
+	require.Equal(t, http.StatusInternalServerError, send("key-4", `{"amount":5000}`).Code)
+
+	status, providerCalled = http.StatusCreated, false
+	retry = send("key-4", `{"amount":5000}`)
+	require.Equal(t, http.StatusInternalServerError, retry.Code)
+	require.JSONEq(t, `{"call":7}`, retry.Body.String())
+	require.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	require.Equal(t, 7, calls)
+}
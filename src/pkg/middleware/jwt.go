@@ -36,6 +36,9 @@ var (
 	ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
 	ErrInvalidToken            = errors.New("token is invalid")
 	ErrInvalidTenant           = errors.New("invalid tenant")
+	ErrInvalidAdminKey         = errors.New("admin key is invalid")
+	ErrAdminDisabled           = errors.New("admin api is disabled")
+	ErrInactiveUser            = errors.New("user is deactivated")
 )
 
 func jwtAccessTokenExpiry(env *environment.Env) time.Duration {
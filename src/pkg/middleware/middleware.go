@@ -22,8 +22,12 @@ const (
 	TenantIDInContext = "tenant_id_in_context"
 	// ActorTypeInContext context key holder
 	ActorTypeInContext = "actor_type_in_context"
+	// AdminKeyHeader is the header the admin api key is sent in
+	AdminKeyHeader = "X-Admin-Key"
 	// UserInContext context key holder
 	UserInContext = "user_in_context"
+	// ProviderCalledInContext is set by a handler whose request failed after it reached the payment provider
+	ProviderCalledInContext = "provider_called_in_context"
 	// packageName name of this package
 	packageName = "middleware"
 )
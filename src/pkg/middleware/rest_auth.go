@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -46,6 +47,11 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if !user.IsActive {
+			restModel.ErrorResponse(c, http.StatusForbidden, ErrInactiveUser.Error())
+			return
+		}
+
 		//validate the tenant
 		tenant := model.Tenant{}
 		db = m.storage.DB.WithContext(ctx).Where("id = ?", tenantID).First(&tenant)
@@ -139,6 +145,11 @@ func (m *Middleware) CheckAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if !user.IsActive {
+			restModel.ErrorResponse(c, http.StatusForbidden, ErrInactiveUser.Error())
+			return
+		}
+
 		actorID = user.ID.String()
 		actorType = string(model.ActorTypeUser)
 		tenantID := user.TenantID.String()
@@ -194,3 +205,26 @@ func (m *Middleware) CheckTenantAuthMiddleware() gin.HandlerFunc {
 func (m *Middleware) CorsMiddleware() gin.HandlerFunc {
 	return cors.New(cors.DefaultConfig())
 }
+
+// AdminAuthMiddleware authenticates an operator of the platform by the X-Admin-Key header, compared against the
+// ADMIN_API_KEY environment variable. Every request is rejected while ADMIN_API_KEY is not set
+func (m *Middleware) AdminAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminKey := m.env.Get("ADMIN_API_KEY")
+		if adminKey == "" {
+			restModel.ErrorResponse(c, http.StatusForbidden, ErrAdminDisabled.Error())
+			return
+		}
+
+		key := c.GetHeader(AdminKeyHeader)
+		if subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) != 1 {
+			restModel.ErrorResponse(c, http.StatusUnauthorized, ErrInvalidAdminKey.Error())
+			return
+		}
+
+		c.Set(ActorIDInContext, "")
+		c.Set(ActorTypeInContext, model.ActorTypeAdmin)
+
+		c.Next()
+	}
+}
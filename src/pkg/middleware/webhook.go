@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	restModel "codematic/handler/model"
+	"codematic/model"
+)
+
+const (
+	// PaystackSignatureHeader is the header Paystack sends the HMAC-SHA512 of a webhook's body in
+	PaystackSignatureHeader = "x-paystack-signature"
+	// FlutterwaveSignatureHeader is the header Flutterwave sends the secret hash of the account in
+	FlutterwaveSignatureHeader = "verif-hash"
+	// WebhookProviderInContext context key holder of the provider whose signature a webhook carries
+	WebhookProviderInContext = "webhook_provider_in_context"
+	// MaxWebhookBodyBytes is the largest webhook body read, the providers' events are a few kilobytes
+	MaxWebhookBodyBytes = 64 << 10
+)
+
+var (
+	// ErrWebhookSignatureMissing when a webhook carries the signature of none of the providers accepted
+	ErrWebhookSignatureMissing = errors.New("webhook signature is missing")
+	// ErrWebhookSignatureInvalid when a webhook's signature does not match its body
+	ErrWebhookSignatureInvalid = errors.New("webhook signature is invalid")
+	// ErrWebhookSourceNotAllowed when a webhook comes from an address the provider's allowlist does not have
+	ErrWebhookSourceNotAllowed = errors.New("webhook source is not allowed")
+	// ErrWebhookDisabled when a webhook comes for a provider whose secret is not set
+	ErrWebhookDisabled = errors.New("webhooks of this provider are disabled")
+	// ErrWebhookBodyTooLarge when a webhook's body is over MaxWebhookBodyBytes
+	ErrWebhookBodyTooLarge = errors.New("webhook body is too large")
+)
+
+type (
+	// WebhookRejectionStore records the webhooks refused before they were processed
+	WebhookRejectionStore interface {
+		RejectWebhookEvent(ctx context.Context, event model.WebhookEvent, reason string) (model.WebhookEvent, error)
+	}
+
+	// webhookProvider is how the webhooks of a provider are authenticated
+	webhookProvider struct {
+		signatureHeader string
+		secretKey       string
+		allowedIPsKey   string
+		verify          func(secret string, body []byte, signature string) bool
+	}
+)
+
+var webhookProviders = map[model.PaymentProvider]webhookProvider{
+	model.PaymentProviderPaystack: {
+		signatureHeader: PaystackSignatureHeader,
+		secretKey:       "PAYSTACK_SECRET_KEY",
+		allowedIPsKey:   "PAYSTACK_WEBHOOK_ALLOWED_IPS",
+		verify:          verifyPaystackSignature,
+	},
+	model.PaymentProviderFlutterwave: {
+		signatureHeader: FlutterwaveSignatureHeader,
+		secretKey:       "FLUTTERWAVE_SECRET_HASH",
+		allowedIPsKey:   "FLUTTERWAVE_WEBHOOK_ALLOWED_IPS",
+		verify:          verifyFlutterwaveSignature,
+	},
+}
+
+// WebhookSignatureMiddleware authenticates a webhook from one of the providers before its body is read as JSON. The
+// provider is the one whose signature header the request carries: Paystack's x-paystack-signature is checked against
+// the HMAC-SHA512 of the raw body with PAYSTACK_SECRET_KEY, Flutterwave's verif-hash against FLUTTERWAVE_SECRET_HASH.
+// When <PROVIDER>_WEBHOOK_ALLOWED_IPS lists addresses or CIDR ranges, a request from anywhere else is refused too, as
+// is a body over MaxWebhookBodyBytes. Every refusal is recorded in the store, the provider of an accepted webhook is
+// set in the context
+func (m *Middleware) WebhookSignatureMiddleware(store WebhookRejectionStore, providers ...model.PaymentProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxWebhookBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			m.rejectWebhook(c, store, providers[0], body, http.StatusRequestEntityTooLarge, ErrWebhookBodyTooLarge)
+			return
+		}
+
+		if err != nil {
+			restModel.ErrorResponse(c, http.StatusBadRequest, "unable to read request body")
+			return
+		}
+
+		provider, config, signature, found := signedWebhookProvider(c, providers)
+		if !found {
+			m.rejectWebhook(c, store, providers[0], body, http.StatusUnauthorized, ErrWebhookSignatureMissing)
+			return
+		}
+
+		if !webhookSourceAllowed(m.env.Get(config.allowedIPsKey), c.ClientIP()) {
+			m.rejectWebhook(c, store, provider, body, http.StatusForbidden, ErrWebhookSourceNotAllowed)
+			return
+		}
+
+		secret := m.env.Get(config.secretKey)
+		if secret == "" {
+			m.rejectWebhook(c, store, provider, body, http.StatusForbidden, ErrWebhookDisabled)
+			return
+		}
+
+		if !config.verify(secret, body, signature) {
+			m.rejectWebhook(c, store, provider, body, http.StatusUnauthorized, ErrWebhookSignatureInvalid)
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Set(WebhookProviderInContext, string(provider))
+
+		c.Next()
+	}
+}
+
+// rejectWebhook refuses a webhook and records the refusal with the delivery as it came and the address it came from
+func (m *Middleware) rejectWebhook(c *gin.Context, store WebhookRejectionStore, provider model.PaymentProvider, body []byte, status int, reason error) {
+	event := model.WebhookEvent{
+		Provider: provider,
+		Source:   c.ClientIP(),
+		Body:     string(body),
+		Headers:  model.WebhookHeaders(c.Request.Header),
+	}
+
+	if _, err := store.RejectWebhookEvent(c.Request.Context(), event, reason.Error()); err != nil {
+		m.logger.Err(err).Msgf("WebhookSignatureMiddleware ::: unable to record rejected webhook %v", err)
+	}
+
+	m.logger.Warn().Msgf("WebhookSignatureMiddleware ::: %s webhook from %s rejected: %v", provider, c.ClientIP(), reason)
+	restModel.ErrorResponse(c, status, reason.Error())
+}
+
+// signedWebhookProvider returns the first of the providers whose signature header the request carries
+func signedWebhookProvider(c *gin.Context, providers []model.PaymentProvider) (model.PaymentProvider, webhookProvider, string, bool) {
+	for _, provider := range providers {
+		config, ok := webhookProviders[provider]
+		if !ok {
+			continue
+		}
+
+		if signature := c.GetHeader(config.signatureHeader); signature != "" {
+			return provider, config, signature, true
+		}
+	}
+
+	return "", webhookProvider{}, "", false
+}
+
+// verifyPaystackSignature checks the hex HMAC-SHA512 of the body with the secret key, in constant time
+func verifyPaystackSignature(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// verifyFlutterwaveSignature checks the secret hash Flutterwave sends as it is, in constant time
+func verifyFlutterwaveSignature(secret string, _ []byte, signature string) bool {
+	return subtle.ConstantTimeCompare([]byte(signature), []byte(secret)) == 1
+}
+
+// webhookSourceAllowed reports whether the address is on the comma separated allowlist of addresses and CIDR
+// ranges. An empty allowlist allows every address
+func webhookSourceAllowed(allowlist, address string) bool {
+	if strings.TrimSpace(allowlist) == "" {
+		return true
+	}
+
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+
+	for _, entry := range strings.Split(allowlist, ",") {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			if prefix.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		if allowed, err := netip.ParseAddr(entry); err == nil && allowed.Unmap() == ip {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+// memoryRejectionStore keeps the rejected webhooks in memory
+type memoryRejectionStore struct {
+	rejected []model.WebhookEvent
+}
+
+func (s *memoryRejectionStore) RejectWebhookEvent(_ context.Context, event model.WebhookEvent, reason string) (model.WebhookEvent, error) {
+	event.Status = model.WebhookEventRejected
+	event.LastError = reason
+	s.rejected = append(s.rejected, event)
+	return event, nil
+}
+
+func TestWebhookSignatureMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("PAYSTACK_SECRET_KEY", "sk_test_secret")
+	t.Setenv("FLUTTERWAVE_SECRET_HASH", "flw-hash")
+	t.Setenv("PAYSTACK_WEBHOOK_ALLOWED_IPS", "")
+	t.Setenv("FLUTTERWAVE_WEBHOOK_ALLOWED_IPS", "")
+
+	store := &memoryRejectionStore{}
+	var provider, body string
+
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(nil))
+	router.POST("/webhook/payment", (&Middleware{}).WebhookSignatureMiddleware(store, model.PaymentProviderPaystack, model.PaymentProviderFlutterwave),
+		func(c *gin.Context) {
+			raw, _ := io.ReadAll(c.Request.Body)
+			provider, body = c.GetString(WebhookProviderInContext), string(raw)
+			c.JSON(http.StatusOK, "success")
+		})
+
+	send := func(payload string, headers map[string]string, remoteAddr string) int {
+		request := httptest.NewRequest(http.MethodPost, "/webhook/payment", strings.NewReader(payload))
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		if remoteAddr != "" {
+			request.RemoteAddr = remoteAddr
+		}
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	payload := `{"event":"charge.success","data":{"reference":"crt_1"}}`
+	mac := hmac.New(sha512.New, []byte("sk_test_secret"))
+	mac.Write([]byte(payload))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	// a body signed with the secret reaches the handler whole, with its provider
+	require.Equal(t, http.StatusOK, send(payload, map[string]string{PaystackSignatureHeader: signature}, ""))
+	require.Equal(t, string(model.PaymentProviderPaystack), provider)
+	require.Equal(t, payload, body)
+
+	require.Equal(t, http.StatusOK, send(payload, map[string]string{FlutterwaveSignatureHeader: "flw-hash"}, ""))
+	require.Equal(t, string(model.PaymentProviderFlutterwave), provider)
+	require.Empty(t, store.rejected)
+
+	// a signature of another body, another secret or none at all is refused and recorded
+	require.Equal(t, http.StatusUnauthorized, send(payload+" ", map[string]string{PaystackSignatureHeader: signature}, ""))
+	require.Equal(t, http.StatusUnauthorized, send(payload, map[string]string{PaystackSignatureHeader: "not-hex"}, ""))
+	require.Equal(t, http.StatusUnauthorized, send(payload, map[string]string{FlutterwaveSignatureHeader: "flw-has"}, ""))
+	require.Equal(t, http.StatusUnauthorized, send(payload, map[string]string{"auth": "payment"}, ""))
+	require.Len(t, store.rejected, 4)
+	require.Equal(t, ErrWebhookSignatureInvalid.Error(), store.rejected[0].LastError)
+	require.Equal(t, model.PaymentProviderFlutterwave, store.rejected[2].Provider)
+	require.Equal(t, ErrWebhookSignatureMissing.Error(), store.rejected[3].LastError)
+	require.Equal(t, payload, store.rejected[3].Body)
+	require.Equal(t, "192.0.2.1", store.rejected[3].Source)
+
+	// with an allowlist only its addresses get through, even with a valid signature
+	t.Setenv("PAYSTACK_WEBHOOK_ALLOWED_IPS", "52.31.139.75, 10.0.0.0/24")
+	require.Equal(t, http.StatusOK, send(payload, map[string]string{PaystackSignatureHeader: signature}, "52.31.139.75:443"))
+	require.Equal(t, http.StatusOK, send(payload, map[string]string{PaystackSignatureHeader: signature}, "10.0.0.7:443"))
+	require.Equal(t, http.StatusForbidden, send(payload, map[string]string{PaystackSignatureHeader: signature}, "192.0.2.1:443"))
+	require.Equal(t, ErrWebhookSourceNotAllowed.Error(), store.rejected[4].LastError)
+
+	// a client that is not a trusted proxy cannot claim an allowed address, nor another source for its rejections
+	spoofed := map[string]string{PaystackSignatureHeader: signature, "X-Forwarded-For": "52.31.139.75"}
+	require.Equal(t, http.StatusForbidden, send(payload, spoofed, "192.0.2.1:443"))
+	require.Equal(t, "192.0.2.1", store.rejected[5].Source)
+
+	// a body over the limit is not read whole, whoever sends it
+	large := `{"event":"charge.success","data":{"reference":"` + strings.Repeat("a", MaxWebhookBodyBytes) + `"}}`
+	require.Equal(t, http.StatusRequestEntityTooLarge, send(large, map[string]string{PaystackSignatureHeader: signature}, "52.31.139.75:443"))
+	require.Equal(t, ErrWebhookBodyTooLarge.Error(), store.rejected[6].LastError)
+	require.Len(t, store.rejected[6].Body, MaxWebhookBodyBytes)
+
+	// a provider without a secret accepts nothing
+	t.Setenv("FLUTTERWAVE_SECRET_HASH", "")
+	require.Equal(t, http.StatusForbidden, send(payload, map[string]string{FlutterwaveSignatureHeader: "flw-hash"}, ""))
+}
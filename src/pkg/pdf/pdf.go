@@ -0,0 +1,135 @@
+// Package pdf writes plain text pdf documents, e.g. statements, in a monospaced font so columns padded with spaces
+// line up
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// page size of A4 in points
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 40
+	fontSize   = 9
+	leading    = 12
+
+	// LinesPerPage is how many lines fit on a page, longer documents continue on the next page
+	LinesPerPage = (pageHeight - 2*margin) / leading
+	// LineWidth is how many characters fit on a line, Courier characters are 0.6 of the font size wide
+	LineWidth = (pageWidth - 2*margin) * 10 / (fontSize * 6)
+)
+
+// Document is a pdf document being written, line by line
+type Document struct {
+	pages [][]string
+}
+
+// New creates an empty document
+func New() *Document {
+	return &Document{}
+}
+
+// AddLine writes a line of text, on a new page once the current page is full. Characters outside of printable ASCII
+// are replaced with ?, lines longer than LineWidth are cut
+func (d *Document) AddLine(text string) {
+	if len(d.pages) == 0 || len(d.pages[len(d.pages)-1]) >= LinesPerPage {
+		d.AddPage()
+	}
+
+	last := len(d.pages) - 1
+	d.pages[last] = append(d.pages[last], text)
+}
+
+// AddPage starts a new page, the following lines are written on it
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, nil)
+}
+
+// Bytes returns the document as a pdf file
+func (d *Document) Bytes() []byte {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = [][]string{nil}
+	}
+
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// objects 1 to 3 are the catalog, the page tree and the font, each page is followed by its content stream
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+
+		content := pageContent(lines)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pageContent is the content stream writing the lines of a page from its top left corner
+func pageContent(lines []string) string {
+	var content strings.Builder
+
+	fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, leading, margin, pageHeight-margin-fontSize)
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) '\n", escape(line))
+	}
+	content.WriteString("ET")
+
+	return content.String()
+}
+
+// escape makes a line safe to write in a pdf string
+func escape(text string) string {
+	var escaped strings.Builder
+
+	count := 0
+	for _, r := range text {
+		if count == LineWidth {
+			break
+		}
+		count++
+
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			escaped.WriteRune('\\')
+			escaped.WriteRune(r)
+		case r < ' ' || r > '~':
+			escaped.WriteRune('?')
+		default:
+			escaped.WriteRune(r)
+		}
+	}
+
+	return escaped.String()
+}
@@ -2,12 +2,11 @@ package storage
 
 import (
 	"context"
-	"errors"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
-	"gorm.io/gorm"
 
 	"codematic/model"
 	"codematic/pkg/helper"
@@ -17,7 +16,9 @@ import (
 type BalanceDatabase interface {
 	CreateBalance(ctx context.Context, balance model.Balance) (model.Balance, error)
 	UpdateUserBalance(ctx context.Context, balance model.Balance) (model.Balance, error)
-	GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID) (model.Balance, error)
+	GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID, currency string) (model.Balance, error)
+	GetBalancesByUserID(ctx context.Context, userID uuid.UUID, currency string, from, to time.Time) ([]model.Balance, error)
+	GetBalanceAt(ctx context.Context, userID uuid.UUID, currency string, at time.Time) (model.Balance, error)
 }
 
 // Balance object
@@ -66,21 +67,36 @@ func (b *Balance) UpdateUserBalance(ctx context.Context, balance model.Balance)
 	return balance, nil
 }
 
-// GetLastBalanceByUserID returns the latest balance for a user, or 0.00 if none exists.
-func (b *Balance) GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
-	var balance model.Balance
+// GetLastBalanceByUserID returns the latest balance of the user's wallet in the currency, read from the wallet's
+// ledger account, or 0.00 if none exists.
+func (b *Balance) GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID, currency string) (model.Balance, error) {
+	var account model.LedgerAccount
 
+	currency = strings.ToUpper(currency)
 	err := b.storage.DB.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Order("created_at DESC").
-		First(&balance).Error
+		Where("user_id = ? AND type = ? AND currency = ?", userID, model.LedgerAccountTypeUserWallet, currency).
+		First(&account).Error
+
+	if isRecordNotFound(err) {
+		// No ledger account exists, return zero balance
+		return model.Balance{
+			UserID:        userID,
+			BalanceBefore: model.ZeroMoney(currency),
+			BalanceAfter:  model.ZeroMoney(currency),
+		}, nil
+	}
 
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		// No balance record exists, return zero balance
+	if err != nil {
+		return model.Balance{}, err
+	}
+
+	posting, err := lastPosting(b.storage.DB.WithContext(ctx), account.ID)
+	if isRecordNotFound(err) {
+		// Nothing has been posted to the account yet, return zero balance
 		return model.Balance{
 			UserID:        userID,
-			BalanceBefore: 0.00,
-			BalanceAfter:  0.00,
+			BalanceBefore: model.ZeroMoney(currency),
+			BalanceAfter:  model.ZeroMoney(currency),
 		}, nil
 	}
 
@@ -88,5 +104,77 @@ func (b *Balance) GetLastBalanceByUserID(ctx context.Context, userID uuid.UUID)
 		return model.Balance{}, err
 	}
 
+	var entry model.JournalEntry
+	if err := b.storage.DB.WithContext(ctx).Where("id = ?", posting.JournalEntryID).First(&entry).Error; err != nil {
+		return model.Balance{}, err
+	}
+
+	balance, err := ledgerAccountBalance(b.storage.DB.WithContext(ctx), account.ID)
+	if err != nil {
+		return model.Balance{}, err
+	}
+
+	balanceBefore, err := balance.Sub(posting.Amount)
+	if err != nil {
+		return model.Balance{}, err
+	}
+
+	lastBalance := model.Balance{
+		UserID:          userID,
+		TransactionType: model.TransactionTypeCredit,
+		BalanceBefore:   balanceBefore,
+		BalanceAfter:    balance,
+		CreatedAt:       posting.CreatedAt,
+	}
+
+	if posting.Amount.IsNegative() {
+		lastBalance.TransactionType = model.TransactionTypeDebit
+	}
+
+	if entry.TransactionID != nil {
+		lastBalance.TransactionID = *entry.TransactionID
+	}
+
+	return lastBalance, nil
+}
+
+// GetBalancesByUserID returns the balance entries of the user's wallet in the currency written from (inclusive) to
+// (exclusive), oldest first
+func (b *Balance) GetBalancesByUserID(ctx context.Context, userID uuid.UUID, currency string, from, to time.Time) ([]model.Balance, error) {
+	var balances []model.Balance
+
+	db := b.storage.DB.WithContext(ctx).
+		Where("user_id = ? AND balance_after_currency = ? AND created_at >= ? AND created_at < ?", userID, strings.ToUpper(currency), from, to).
+		Order("created_at ASC").Find(&balances)
+	if db.Error != nil {
+		b.logger.Err(db.Error).Msgf("GetBalancesByUserID error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return balances, nil
+}
+
+// GetBalanceAt returns the last balance entry of the user's wallet in the currency written before at, or a zero
+// balance if there is none
+func (b *Balance) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency string, at time.Time) (model.Balance, error) {
+	var balance model.Balance
+
+	currency = strings.ToUpper(currency)
+	err := b.storage.DB.WithContext(ctx).
+		Where("user_id = ? AND balance_after_currency = ? AND created_at < ?", userID, currency, at).
+		Order("created_at DESC").First(&balance).Error
+	if isRecordNotFound(err) {
+		return model.Balance{
+			UserID:        userID,
+			BalanceBefore: model.ZeroMoney(currency),
+			BalanceAfter:  model.ZeroMoney(currency),
+		}, nil
+	}
+
+	if err != nil {
+		b.logger.Err(err).Msgf("GetBalanceAt error: %v", err)
+		return model.Balance{}, ErrGeneric
+	}
+
 	return balance, nil
 }
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm/clause"
+
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// BalanceSnapshotDatabase enlists all possible operations on the daily closing balances of wallets
+type BalanceSnapshotDatabase interface {
+	SaveBalanceSnapshot(ctx context.Context, snapshot model.BalanceSnapshot) error
+	GetLatestBalanceSnapshot(ctx context.Context, walletID uuid.UUID, at time.Time) (model.BalanceSnapshot, error)
+	GetSnapshottedWalletIDs(ctx context.Context, tenantID uuid.UUID, day string) ([]uuid.UUID, error)
+	GetBalanceTotals(ctx context.Context, tenantID uuid.UUID, day string) ([]model.BalanceTotal, error)
+}
+
+// BalanceSnapshot object
+type BalanceSnapshot struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewBalanceSnapshot creates a new reference to the BalanceSnapshot storage entity
+func NewBalanceSnapshot(s *Storage) *BalanceSnapshotDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "balance_snapshot").Logger()
+	balanceSnapshot := &BalanceSnapshot{
+		logger:  l,
+		storage: s,
+	}
+
+	balanceSnapshotDatabase := BalanceSnapshotDatabase(balanceSnapshot)
+	return &balanceSnapshotDatabase
+}
+
+// SaveBalanceSnapshot writes the closing balance of a wallet for a day, replacing the one already taken that day
+func (b *BalanceSnapshot) SaveBalanceSnapshot(ctx context.Context, snapshot model.BalanceSnapshot) error {
+	db := b.storage.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "wallet_id"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"balance_minor", "balance_currency", "closed_at"}),
+	}).Create(&snapshot)
+	if db.Error != nil {
+		b.logger.Err(db.Error).Msgf("SaveBalanceSnapshot error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return ErrRecordCreatingFailed
+	}
+
+	return nil
+}
+
+// GetLatestBalanceSnapshot returns the latest snapshot of the wallet closed at or before at
+func (b *BalanceSnapshot) GetLatestBalanceSnapshot(ctx context.Context, walletID uuid.UUID, at time.Time) (model.BalanceSnapshot, error) {
+	var snapshot model.BalanceSnapshot
+
+	err := b.storage.DB.WithContext(ctx).Where("wallet_id = ? AND closed_at <= ?", walletID, at).
+		Order("closed_at DESC").First(&snapshot).Error
+	if isRecordNotFound(err) {
+		return snapshot, ErrRecordNotFound
+	}
+
+	if err != nil {
+		b.logger.Err(err).Msgf("GetLatestBalanceSnapshot error: %v", err)
+		return snapshot, ErrGeneric
+	}
+
+	return snapshot, nil
+}
+
+// GetSnapshottedWalletIDs returns the IDs of the tenant's wallets that have a snapshot for the day
+func (b *BalanceSnapshot) GetSnapshottedWalletIDs(ctx context.Context, tenantID uuid.UUID, day string) ([]uuid.UUID, error) {
+	var walletIDs []uuid.UUID
+
+	db := b.storage.DB.WithContext(ctx).Model(&model.BalanceSnapshot{}).
+		Where("tenant_id = ? AND day = ?", tenantID, day).Pluck("wallet_id", &walletIDs)
+	if db.Error != nil {
+		b.logger.Err(db.Error).Msgf("GetSnapshottedWalletIDs error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return walletIDs, nil
+}
+
+// GetBalanceTotals sums the snapshots of the tenant's wallets for the day, one total per currency
+func (b *BalanceSnapshot) GetBalanceTotals(ctx context.Context, tenantID uuid.UUID, day string) ([]model.BalanceTotal, error) {
+	var rows []struct {
+		Currency string
+		Wallets  int64
+		Minor    int64
+	}
+
+	db := b.storage.DB.WithContext(ctx).Model(&model.BalanceSnapshot{}).
+		Select("balance_currency AS currency, COUNT(*) AS wallets, COALESCE(SUM(balance_minor), 0) AS minor").
+		Where("tenant_id = ? AND day = ?", tenantID, day).
+		Group("balance_currency").Order("balance_currency ASC").Scan(&rows)
+	if db.Error != nil {
+		b.logger.Err(db.Error).Msgf("GetBalanceTotals error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	totals := make([]model.BalanceTotal, 0, len(rows))
+	for _, row := range rows {
+		totals = append(totals, model.BalanceTotal{
+			Day:      day,
+			Currency: row.Currency,
+			Wallets:  row.Wallets,
+			Total:    model.NewMoney(row.Minor, row.Currency),
+		})
+	}
+
+	return totals, nil
+}
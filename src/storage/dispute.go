@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// DisputeFilter narrows the disputes GetDisputes returns, nil fields are not filtered on
+type DisputeFilter struct {
+	TenantID *uuid.UUID
+	UserID   *uuid.UUID
+	Status   *model.DisputeStatus
+}
+
+// DisputeDatabase enlists all possible operations on disputes
+type DisputeDatabase interface {
+	CreateDispute(ctx context.Context, dispute model.Dispute) (model.Dispute, error)
+	GetDisputeByID(ctx context.Context, disputeID uuid.UUID) (model.Dispute, error)
+	GetDisputeByIDForUpdate(ctx context.Context, disputeID uuid.UUID) (model.Dispute, error)
+	GetDisputes(ctx context.Context, filter DisputeFilter, page pagination.Page) ([]model.Dispute, pagination.PageInfo, error)
+	GetDisputesByTransactionID(ctx context.Context, transactionID uuid.UUID, statuses ...model.DisputeStatus) ([]model.Dispute, error)
+	UpdateDisputeStatus(ctx context.Context, disputeID uuid.UUID, status model.DisputeStatus, closedAt *time.Time) error
+	CreateDisputeEvidence(ctx context.Context, evidence model.DisputeEvidence) (model.DisputeEvidence, error)
+}
+
+// Dispute object
+type Dispute struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewDispute creates a new reference to the Dispute storage entity
+func NewDispute(s *Storage) *DisputeDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "dispute").Logger()
+	dispute := &Dispute{
+		logger:  l,
+		storage: s,
+	}
+
+	disputeDatabase := DisputeDatabase(dispute)
+	return &disputeDatabase
+}
+
+// CreateDispute adds a new dispute into the disputes table
+func (d *Dispute) CreateDispute(ctx context.Context, dispute model.Dispute) (model.Dispute, error) {
+	db := d.storage.DB.WithContext(ctx).Omit("Evidence").Create(&dispute)
+	if db.Error != nil {
+		d.logger.Err(db.Error).Msgf("CreateDispute error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.Dispute{}, ErrRecordCreatingFailed
+	}
+
+	return dispute, nil
+}
+
+// GetDisputeByID returns a dispute with its evidence, oldest evidence first
+func (d *Dispute) GetDisputeByID(ctx context.Context, disputeID uuid.UUID) (model.Dispute, error) {
+	var dispute model.Dispute
+
+	db := d.storage.DB.WithContext(ctx).Preload("Evidence", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("id = ?", disputeID).First(&dispute)
+	if db.Error != nil {
+		d.logger.Err(db.Error).Msgf("GetDisputeByID error: %v (%v)", ErrRecordNotFound, db.Error)
+		return dispute, ErrRecordNotFound
+	}
+
+	return dispute, nil
+}
+
+// GetDisputeByIDForUpdate returns a dispute and locks its row (SELECT ... FOR UPDATE) until the surrounding database
+// transaction ends. It must be called on a Storage bound to a database transaction
+func (d *Dispute) GetDisputeByIDForUpdate(ctx context.Context, disputeID uuid.UUID) (model.Dispute, error) {
+	var dispute model.Dispute
+
+	db := d.storage.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", disputeID).First(&dispute)
+	if db.Error != nil {
+		d.logger.Err(db.Error).Msgf("GetDisputeByIDForUpdate error: %v (%v)", ErrRecordNotFound, db.Error)
+		return dispute, ErrRecordNotFound
+	}
+
+	return dispute, nil
+}
+
+// GetDisputes returns the disputes matching the filter, newest first
+func (d *Dispute) GetDisputes(ctx context.Context, filter DisputeFilter, page pagination.Page) ([]model.Dispute, pagination.PageInfo, error) {
+	var disputes []model.Dispute
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := d.storage.DB.WithContext(ctx).Model(&model.Dispute{})
+	if filter.TenantID != nil {
+		query = query.Where("tenant_id = ?", *filter.TenantID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&disputes)
+	if db.Error != nil {
+		d.logger.Err(db.Error).Msgf("GetDisputes error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return disputes, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// GetDisputesByTransactionID returns the disputes of a transaction in the given statuses
+func (d *Dispute) GetDisputesByTransactionID(ctx context.Context, transactionID uuid.UUID, statuses ...model.DisputeStatus) ([]model.Dispute, error) {
+	var disputes []model.Dispute
+
+	db := d.storage.DB.WithContext(ctx).Where("transaction_id = ? AND status IN ?", transactionID, statuses).Find(&disputes)
+	if db.Error != nil {
+		d.logger.Err(db.Error).Msgf("GetDisputesByTransactionID error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return disputes, nil
+}
+
+// UpdateDisputeStatus moves a dispute to the status, closedAt is set when the status is final
+func (d *Dispute) UpdateDisputeStatus(ctx context.Context, disputeID uuid.UUID, status model.DisputeStatus, closedAt *time.Time) error {
+	db := d.storage.DB.WithContext(ctx).Model(&model.Dispute{}).Where("id = ?", disputeID).
+		Updates(map[string]interface{}{"status": status, "closed_at": closedAt, "updated_at": time.Now()})
+	if db.Error != nil {
+		d.logger.Err(db.Error).Msgf("UpdateDisputeStatus error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// CreateDisputeEvidence attaches an evidence note to a dispute
+func (d *Dispute) CreateDisputeEvidence(ctx context.Context, evidence model.DisputeEvidence) (model.DisputeEvidence, error) {
+	db := d.storage.DB.WithContext(ctx).Create(&evidence)
+	if db.Error != nil {
+		d.logger.Err(db.Error).Msgf("CreateDisputeEvidence error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.DisputeEvidence{}, ErrRecordCreatingFailed
+	}
+
+	return evidence, nil
+}
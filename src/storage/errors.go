@@ -33,4 +33,6 @@ var (
 	ErrRatePlan = errors.New("you can only rate one plan in a month")
 	// ErrInvalidDateDuration if the date put is invalid
 	ErrInvalidDateDuration = errors.New("invalid period value")
+	// ErrUnbalancedJournalEntry if the postings of a journal entry do not sum to zero
+	ErrUnbalancedJournalEntry = errors.New("journal entry postings must sum to zero")
 )
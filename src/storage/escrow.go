@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm/clause"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// EscrowFilter narrows the escrows GetEscrows returns, nil fields are not filtered on. UserID matches the escrows the
+// user is the buyer or the seller of
+type EscrowFilter struct {
+	TenantID *uuid.UUID
+	UserID   *uuid.UUID
+	Status   *model.EscrowStatus
+}
+
+// EscrowDatabase enlists all possible operations on escrows
+type EscrowDatabase interface {
+	CreateEscrow(ctx context.Context, escrow model.Escrow) (model.Escrow, error)
+	GetEscrowByID(ctx context.Context, escrowID uuid.UUID) (model.Escrow, error)
+	GetEscrowByIDForUpdate(ctx context.Context, escrowID uuid.UUID) (model.Escrow, error)
+	GetEscrows(ctx context.Context, filter EscrowFilter, page pagination.Page) ([]model.Escrow, pagination.PageInfo, error)
+	GetDueEscrows(ctx context.Context, now time.Time, limit int) ([]model.Escrow, error)
+	UpdateEscrow(ctx context.Context, escrow model.Escrow) error
+}
+
+// Escrow object
+type Escrow struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewEscrow creates a new reference to the Escrow storage entity
+func NewEscrow(s *Storage) *EscrowDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "escrow").Logger()
+	escrow := &Escrow{
+		logger:  l,
+		storage: s,
+	}
+
+	escrowDatabase := EscrowDatabase(escrow)
+	return &escrowDatabase
+}
+
+// CreateEscrow adds a new escrow into the escrows table
+func (e *Escrow) CreateEscrow(ctx context.Context, escrow model.Escrow) (model.Escrow, error) {
+	db := e.storage.DB.WithContext(ctx).Create(&escrow)
+	if db.Error != nil {
+		e.logger.Err(db.Error).Msgf("CreateEscrow error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.Escrow{}, ErrRecordCreatingFailed
+	}
+
+	return escrow, nil
+}
+
+// GetEscrowByID returns an escrow by its ID
+func (e *Escrow) GetEscrowByID(ctx context.Context, escrowID uuid.UUID) (model.Escrow, error) {
+	var escrow model.Escrow
+
+	db := e.storage.DB.WithContext(ctx).Where("id = ?", escrowID).First(&escrow)
+	if db.Error != nil {
+		e.logger.Err(db.Error).Msgf("GetEscrowByID error: %v (%v)", ErrRecordNotFound, db.Error)
+		return escrow, ErrRecordNotFound
+	}
+
+	return escrow, nil
+}
+
+// GetEscrowByIDForUpdate returns an escrow and locks its row (SELECT ... FOR UPDATE) until the surrounding database
+// transaction ends. It must be called on a Storage bound to a database transaction
+func (e *Escrow) GetEscrowByIDForUpdate(ctx context.Context, escrowID uuid.UUID) (model.Escrow, error) {
+	var escrow model.Escrow
+
+	db := e.storage.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", escrowID).First(&escrow)
+	if db.Error != nil {
+		e.logger.Err(db.Error).Msgf("GetEscrowByIDForUpdate error: %v (%v)", ErrRecordNotFound, db.Error)
+		return escrow, ErrRecordNotFound
+	}
+
+	return escrow, nil
+}
+
+// GetEscrows returns the escrows matching the filter, newest first
+func (e *Escrow) GetEscrows(ctx context.Context, filter EscrowFilter, page pagination.Page) ([]model.Escrow, pagination.PageInfo, error) {
+	var escrows []model.Escrow
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := e.storage.DB.WithContext(ctx).Model(&model.Escrow{})
+	if filter.TenantID != nil {
+		query = query.Where("tenant_id = ?", *filter.TenantID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("buyer_id = ? OR seller_id = ?", *filter.UserID, *filter.UserID)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&escrows)
+	if db.Error != nil {
+		e.logger.Err(db.Error).Msgf("GetEscrows error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return escrows, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// GetDueEscrows returns up to limit held escrows whose auto release time passed, the longest due first
+func (e *Escrow) GetDueEscrows(ctx context.Context, now time.Time, limit int) ([]model.Escrow, error) {
+	var escrows []model.Escrow
+
+	db := e.storage.DB.WithContext(ctx).
+		Where("status = ? AND auto_release_at IS NOT NULL AND auto_release_at <= ?", model.EscrowStatusHeld, now).
+		Order("auto_release_at ASC").Limit(limit).Find(&escrows)
+	if db.Error != nil {
+		e.logger.Err(db.Error).Msgf("GetDueEscrows error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return escrows, nil
+}
+
+// UpdateEscrow saves how an escrow was closed
+func (e *Escrow) UpdateEscrow(ctx context.Context, escrow model.Escrow) error {
+	now := time.Now()
+	escrow.UpdatedAt = &now
+
+	db := e.storage.DB.WithContext(ctx).Model(&model.Escrow{}).Where("id = ?", escrow.ID).
+		Select("status", "settlement_transaction_id", "closed_by", "closed_at", "updated_at").
+		Updates(&escrow)
+	if db.Error != nil {
+		e.logger.Err(db.Error).Msgf("UpdateEscrow error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
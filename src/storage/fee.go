@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// FeeDatabase enlists all possible operations on the tenants' fee rules
+type FeeDatabase interface {
+	CreateFeeRule(ctx context.Context, rule model.FeeRule) (model.FeeRule, error)
+	GetActiveFeeRule(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, currency string) (model.FeeRule, error)
+	GetLatestFeeRuleVersion(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, currency string) (int, error)
+	RetireFeeRule(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, currency string, at time.Time) (bool, error)
+	GetFeeRules(ctx context.Context, tenantID uuid.UUID, withRetired bool) ([]model.FeeRule, error)
+}
+
+// Fee object
+type Fee struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewFee creates a new reference to the Fee storage entity
+func NewFee(s *Storage) *FeeDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "fee").Logger()
+	fee := &Fee{
+		logger:  l,
+		storage: s,
+	}
+
+	feeDatabase := FeeDatabase(fee)
+	return &feeDatabase
+}
+
+// CreateFeeRule adds a new version of a fee rule into the fee_rules table
+func (f *Fee) CreateFeeRule(ctx context.Context, rule model.FeeRule) (model.FeeRule, error) {
+	db := f.storage.DB.WithContext(ctx).Create(&rule)
+	if db.Error != nil {
+		f.logger.Err(db.Error).Msgf("CreateFeeRule error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+
+		if strings.Contains(db.Error.Error(), "duplicate key value") {
+			return model.FeeRule{}, ErrDuplicateRecord
+		}
+		return model.FeeRule{}, ErrRecordCreatingFailed
+	}
+
+	return rule, nil
+}
+
+// GetActiveFeeRule returns the version of the tenant's rule for the action in the currency that is not retired
+func (f *Fee) GetActiveFeeRule(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, currency string) (model.FeeRule, error) {
+	var rule model.FeeRule
+
+	err := f.storage.DB.WithContext(ctx).
+		Where("tenant_id = ? AND action = ? AND currency = ? AND retired_at IS NULL", tenantID, action, strings.ToUpper(currency)).
+		Order("version DESC").First(&rule).Error
+	if isRecordNotFound(err) {
+		return rule, ErrRecordNotFound
+	}
+
+	if err != nil {
+		f.logger.Err(err).Msgf("GetActiveFeeRule error: %v", err)
+		return rule, ErrGeneric
+	}
+
+	return rule, nil
+}
+
+// GetLatestFeeRuleVersion returns the highest version of the tenant's rule for the action in the currency, retired
+// or not, 0 when there is none
+func (f *Fee) GetLatestFeeRuleVersion(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, currency string) (int, error) {
+	var version int
+
+	db := f.storage.DB.WithContext(ctx).Model(&model.FeeRule{}).
+		Where("tenant_id = ? AND action = ? AND currency = ?", tenantID, action, strings.ToUpper(currency)).
+		Select("COALESCE(MAX(version), 0)").Scan(&version)
+	if db.Error != nil {
+		f.logger.Err(db.Error).Msgf("GetLatestFeeRuleVersion error: %v", db.Error)
+		return 0, ErrGeneric
+	}
+
+	return version, nil
+}
+
+// RetireFeeRule retires the active version of the tenant's rule for the action in the currency, it reports whether
+// there was one
+func (f *Fee) RetireFeeRule(ctx context.Context, tenantID uuid.UUID, action model.FeeAction, currency string, at time.Time) (bool, error) {
+	db := f.storage.DB.WithContext(ctx).Model(&model.FeeRule{}).
+		Where("tenant_id = ? AND action = ? AND currency = ? AND retired_at IS NULL", tenantID, action, strings.ToUpper(currency)).
+		Update("retired_at", at)
+	if db.Error != nil {
+		f.logger.Err(db.Error).Msgf("RetireFeeRule error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return false, ErrRecordUpdateFailed
+	}
+
+	return db.RowsAffected > 0, nil
+}
+
+// GetFeeRules returns the tenant's fee rules by action, currency and newest version first, only the active ones
+// unless withRetired
+func (f *Fee) GetFeeRules(ctx context.Context, tenantID uuid.UUID, withRetired bool) ([]model.FeeRule, error) {
+	var rules []model.FeeRule
+
+	query := f.storage.DB.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if !withRetired {
+		query = query.Where("retired_at IS NULL")
+	}
+
+	db := query.Order("action ASC, currency ASC, version DESC").Find(&rules)
+	if db.Error != nil {
+		f.logger.Err(db.Error).Msgf("GetFeeRules error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return rules, nil
+}
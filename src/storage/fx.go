@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// FXDatabase enlists all possible operations on FX rates and conversions
+type FXDatabase interface {
+	UpsertFXRate(ctx context.Context, rate model.FXRate) (model.FXRate, error)
+	GetFXRate(ctx context.Context, tenantID uuid.UUID, from, to string) (model.FXRate, error)
+	GetFXRatesByTenantID(ctx context.Context, tenantID uuid.UUID) ([]model.FXRate, error)
+	CreateFXConversion(ctx context.Context, conversion model.FXConversion) (model.FXConversion, error)
+}
+
+// FX object
+type FX struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewFX creates a new reference to the FX storage entity
+func NewFX(s *Storage) *FXDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "fx").Logger()
+	fx := &FX{
+		logger:  l,
+		storage: s,
+	}
+
+	fxDatabase := FXDatabase(fx)
+	return &fxDatabase
+}
+
+// UpsertFXRate sets the rate of a currency pair, replacing the rate the tenant (or the default rates when the
+// tenant is nil) already had for the pair
+func (f *FX) UpsertFXRate(ctx context.Context, rate model.FXRate) (model.FXRate, error) {
+	rate.BaseCurrency = strings.ToUpper(rate.BaseCurrency)
+	rate.QuoteCurrency = strings.ToUpper(rate.QuoteCurrency)
+
+	var existing model.FXRate
+	err := tenantScope(f.storage.DB.WithContext(ctx), rate.TenantID).
+		Where("base_currency = ? AND quote_currency = ?", rate.BaseCurrency, rate.QuoteCurrency).
+		First(&existing).Error
+
+	if isRecordNotFound(err) {
+		if rate.ID == uuid.Nil {
+			rate.ID = uuid.New()
+		}
+
+		if err := f.storage.DB.WithContext(ctx).Create(&rate).Error; err != nil {
+			f.logger.Err(err).Msgf("UpsertFXRate error: %v, (%v)", ErrRecordCreatingFailed, err)
+			return model.FXRate{}, ErrRecordCreatingFailed
+		}
+
+		return rate, nil
+	}
+
+	if err != nil {
+		f.logger.Err(err).Msgf("UpsertFXRate error: %v", err)
+		return model.FXRate{}, ErrGeneric
+	}
+
+	now := time.Now()
+	existing.Rate = rate.Rate
+	existing.SpreadBasisPoints = rate.SpreadBasisPoints
+	existing.UpdatedAt = &now
+
+	if err := f.storage.DB.WithContext(ctx).Save(&existing).Error; err != nil {
+		f.logger.Err(err).Msgf("UpsertFXRate error: %v, (%v)", ErrRecordUpdateFailed, err)
+		return model.FXRate{}, ErrRecordUpdateFailed
+	}
+
+	return existing, nil
+}
+
+// GetFXRate returns the rate a tenant converts between both currencies with, in either direction.
+// The tenant's own rates take precedence over the default rates
+func (f *FX) GetFXRate(ctx context.Context, tenantID uuid.UUID, from, to string) (model.FXRate, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+
+	var rates []model.FXRate
+	err := f.storage.DB.WithContext(ctx).
+		Where("tenant_id = ? OR tenant_id IS NULL", tenantID).
+		Where("(base_currency = ? AND quote_currency = ?) OR (base_currency = ? AND quote_currency = ?)", from, to, to, from).
+		Find(&rates).Error
+	if err != nil {
+		f.logger.Err(err).Msgf("GetFXRate error: %v", err)
+		return model.FXRate{}, ErrGeneric
+	}
+
+	if len(rates) == 0 {
+		return model.FXRate{}, ErrRecordNotFound
+	}
+
+	// the tenant's own rate wins over a default rate, and a direct pair over an inverted one
+	best := rates[0]
+	for _, rate := range rates[1:] {
+		if fxRateRank(rate, from) < fxRateRank(best, from) {
+			best = rate
+		}
+	}
+
+	return best, nil
+}
+
+// fxRateRank orders candidate rates for a conversion from the currency, lower ranks first
+func fxRateRank(rate model.FXRate, from string) int {
+	rank := 0
+	if rate.TenantID == nil {
+		rank += 2
+	}
+
+	if rate.BaseCurrency != from {
+		rank++
+	}
+
+	return rank
+}
+
+// GetFXRatesByTenantID returns the tenant's own rates followed by the default rates
+func (f *FX) GetFXRatesByTenantID(ctx context.Context, tenantID uuid.UUID) ([]model.FXRate, error) {
+	var rates []model.FXRate
+
+	err := f.storage.DB.WithContext(ctx).
+		Where("tenant_id = ? OR tenant_id IS NULL", tenantID).
+		Order("tenant_id IS NULL, base_currency, quote_currency").
+		Find(&rates).Error
+	if err != nil {
+		f.logger.Err(err).Msgf("GetFXRatesByTenantID error: %v", err)
+		return nil, ErrGeneric
+	}
+
+	return rates, nil
+}
+
+// CreateFXConversion records a conversion between two wallets
+func (f *FX) CreateFXConversion(ctx context.Context, conversion model.FXConversion) (model.FXConversion, error) {
+	if err := f.storage.DB.WithContext(ctx).Create(&conversion).Error; err != nil {
+		f.logger.Err(err).Msgf("CreateFXConversion error: %v, (%v)", ErrRecordCreatingFailed, err)
+		return model.FXConversion{}, ErrRecordCreatingFailed
+	}
+
+	return conversion, nil
+}
+
+// tenantScope filters rows of a tenant, or the rows without a tenant when tenantID is nil
+func tenantScope(db *gorm.DB, tenantID *uuid.UUID) *gorm.DB {
+	if tenantID == nil {
+		return db.Where("tenant_id IS NULL")
+	}
+
+	return db.Where("tenant_id = ?", *tenantID)
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// HoldDatabase enlists all possible operations on wallet holds
+type HoldDatabase interface {
+	CreateHold(ctx context.Context, hold model.Hold) (model.Hold, error)
+	GetActiveHoldByTransactionID(ctx context.Context, transactionID uuid.UUID) (model.Hold, error)
+	UpdateHoldStatus(ctx context.Context, holdID uuid.UUID, status model.HoldStatus) error
+	GetActiveHoldsTotal(ctx context.Context, walletID uuid.UUID, currency string) (model.Money, error)
+	GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]model.Hold, error)
+}
+
+// Hold object
+type Hold struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewHold creates a new reference to the Hold storage entity
+func NewHold(s *Storage) *HoldDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "hold").Logger()
+	hold := &Hold{
+		logger:  l,
+		storage: s,
+	}
+
+	holdDatabase := HoldDatabase(hold)
+	return &holdDatabase
+}
+
+// CreateHold reserves funds on a wallet
+func (h *Hold) CreateHold(ctx context.Context, hold model.Hold) (model.Hold, error) {
+	db := h.storage.DB.WithContext(ctx).Create(&hold)
+	if db.Error != nil {
+		h.logger.Err(db.Error).Msgf("CreateHold error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.Hold{}, ErrRecordCreatingFailed
+	}
+
+	return hold, nil
+}
+
+// GetActiveHoldByTransactionID returns the active hold placed for a transaction
+func (h *Hold) GetActiveHoldByTransactionID(ctx context.Context, transactionID uuid.UUID) (model.Hold, error) {
+	var hold model.Hold
+
+	db := h.storage.DB.WithContext(ctx).Where("transaction_id = ? AND status = ?", transactionID, model.HoldStatusActive).First(&hold)
+	if db.Error != nil {
+		h.logger.Err(db.Error).Msgf("GetActiveHoldByTransactionID error: %v (%v)", ErrRecordNotFound, db.Error)
+		return hold, ErrRecordNotFound
+	}
+
+	return hold, nil
+}
+
+// UpdateHoldStatus moves an active hold to its final status
+func (h *Hold) UpdateHoldStatus(ctx context.Context, holdID uuid.UUID, status model.HoldStatus) error {
+	db := h.storage.DB.WithContext(ctx).Model(&model.Hold{}).
+		Where("id = ? AND status = ?", holdID, model.HoldStatusActive).
+		Updates(map[string]interface{}{"status": status, "updated_at": time.Now()})
+	if db.Error != nil {
+		h.logger.Err(db.Error).Msgf("UpdateHoldStatus error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// GetActiveHoldsTotal returns the funds reserved on a wallet by holds that are active and not expired yet, or whose
+// transaction is still pending with a provider
+func (h *Hold) GetActiveHoldsTotal(ctx context.Context, walletID uuid.UUID, currency string) (model.Money, error) {
+	total, err := activeHoldsTotal(h.storage.DB.WithContext(ctx), walletID, currency)
+	if err != nil {
+		h.logger.Err(err).Msgf("GetActiveHoldsTotal error: %v", err)
+		return model.Money{}, ErrGeneric
+	}
+
+	return total, nil
+}
+
+// holdPendingWithProviderSQL matches the holds whose transaction other than a transfer is still pending with a
+// provider, a refund being sent or a dispute being decided. Such a hold keeps its funds reserved past its expiry until
+// the refund or the dispute is settled. A transfer's hold does expire, and fails the transfer with it
+const holdPendingWithProviderSQL = "EXISTS (SELECT 1 FROM transactions WHERE transactions.id = holds.transaction_id AND transactions.status = ? AND transactions.provider <> '' AND transactions.transaction_flow <> '" + string(model.TransactionFlowWithdrawal) + "')"
+
+// GetExpiredHolds returns active holds whose expiry has passed, oldest first. Holds of refunds and disputes still
+// pending with a provider never expire
+func (h *Hold) GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]model.Hold, error) {
+	var holds []model.Hold
+
+	db := h.storage.DB.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", model.HoldStatusActive, now).
+		Where("NOT "+holdPendingWithProviderSQL, model.TransactionStatusPending).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&holds)
+	if db.Error != nil {
+		h.logger.Err(db.Error).Msgf("GetExpiredHolds error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return holds, nil
+}
+
+// activeHoldsTotal sums the active holds of a wallet in the currency that are unexpired or still pending with a provider
+func activeHoldsTotal(db *gorm.DB, walletID uuid.UUID, currency string) (model.Money, error) {
+	var minor int64
+	err := db.Model(&model.Hold{}).
+		Where("wallet_id = ? AND status = ? AND amount_currency = ?", walletID, model.HoldStatusActive, currency).
+		Where("expires_at > ? OR "+holdPendingWithProviderSQL, time.Now(), model.TransactionStatusPending).
+		Select("COALESCE(SUM(amount_minor), 0)").
+		Scan(&minor).Error
+
+	return model.NewMoney(minor, currency), err
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm/clause"
+
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// IdempotencyDatabase enlists all possible operations on idempotency keys
+type IdempotencyDatabase interface {
+	CreateIdempotencyKey(ctx context.Context, key model.IdempotencyKey) (bool, error)
+	GetIdempotencyKey(ctx context.Context, tenantID, userID uuid.UUID, key string) (model.IdempotencyKey, error)
+	ClaimIdempotencyKey(ctx context.Context, stale, key model.IdempotencyKey) (bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key model.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, keyID uuid.UUID) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Idempotency object
+type Idempotency struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewIdempotency creates a new reference to the Idempotency storage entity
+func NewIdempotency(s *Storage) *IdempotencyDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "idempotency").Logger()
+	idempotency := &Idempotency{
+		logger:  l,
+		storage: s,
+	}
+
+	idempotencyDatabase := IdempotencyDatabase(idempotency)
+	return &idempotencyDatabase
+}
+
+// CreateIdempotencyKey adds a new idempotency key into the idempotency keys table and reports whether it was added,
+// it is not when the tenant and user already hold the key
+func (i *Idempotency) CreateIdempotencyKey(ctx context.Context, key model.IdempotencyKey) (bool, error) {
+	db := i.storage.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&key)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("CreateIdempotencyKey error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return false, ErrRecordCreatingFailed
+	}
+
+	return db.RowsAffected == 1, nil
+}
+
+// GetIdempotencyKey returns the idempotency key the tenant and user hold
+func (i *Idempotency) GetIdempotencyKey(ctx context.Context, tenantID, userID uuid.UUID, key string) (model.IdempotencyKey, error) {
+	var idempotencyKey model.IdempotencyKey
+
+	db := i.storage.DB.WithContext(ctx).Where("tenant_id = ? AND user_id = ? AND key = ?", tenantID, userID, key).
+		First(&idempotencyKey)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("GetIdempotencyKey error: %v (%v)", ErrRecordNotFound, db.Error)
+		return idempotencyKey, ErrRecordNotFound
+	}
+
+	return idempotencyKey, nil
+}
+
+// ClaimIdempotencyKey hands a stale idempotency key over to a new request and reports whether it did. Only one of
+// the requests claiming the same stale key at the same time gets it, the key must not have changed since it was read
+func (i *Idempotency) ClaimIdempotencyKey(ctx context.Context, stale, key model.IdempotencyKey) (bool, error) {
+	db := i.storage.DB.WithContext(ctx).Model(&model.IdempotencyKey{}).
+		Where("id = ? AND updated_at = ?", stale.ID, stale.UpdatedAt).
+		Updates(map[string]interface{}{
+			"fingerprint":   key.Fingerprint,
+			"status":        key.Status,
+			"response_code": 0,
+			"content_type":  "",
+			"response_body": nil,
+			"expires_at":    key.ExpiresAt,
+			"updated_at":    key.UpdatedAt,
+		})
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("ClaimIdempotencyKey error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return false, ErrRecordUpdateFailed
+	}
+
+	return db.RowsAffected == 1, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request that holds an idempotency key
+func (i *Idempotency) CompleteIdempotencyKey(ctx context.Context, key model.IdempotencyKey) error {
+	db := i.storage.DB.WithContext(ctx).Model(&model.IdempotencyKey{}).Where("id = ?", key.ID).
+		Select("status", "response_code", "content_type", "response_body", "updated_at").
+		Updates(&key)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("CompleteIdempotencyKey error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// DeleteIdempotencyKey removes an idempotency key, the next request with the key is handled as a first attempt
+func (i *Idempotency) DeleteIdempotencyKey(ctx context.Context, keyID uuid.UUID) error {
+	db := i.storage.DB.WithContext(ctx).Where("id = ?", keyID).Delete(&model.IdempotencyKey{})
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("DeleteIdempotencyKey error: %v", db.Error)
+		return ErrGeneric
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes the idempotency keys that expired by now and returns how many
+func (i *Idempotency) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	db := i.storage.DB.WithContext(ctx).Where("expires_at <= ?", now).Delete(&model.IdempotencyKey{})
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("DeleteExpiredIdempotencyKeys error: %v", db.Error)
+		return 0, ErrGeneric
+	}
+
+	return db.RowsAffected, nil
+}
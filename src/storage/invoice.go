@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// InvoiceDatabase enlists all possible operations on invoices and their payments
+type InvoiceDatabase interface {
+	CreateInvoice(ctx context.Context, invoice model.Invoice) (model.Invoice, error)
+	GetInvoiceByID(ctx context.Context, invoiceID uuid.UUID) (model.Invoice, error)
+	GetInvoiceByReference(ctx context.Context, reference string) (model.Invoice, error)
+	GetInvoiceByIDForUpdate(ctx context.Context, invoiceID uuid.UUID) (model.Invoice, error)
+	GetInvoices(ctx context.Context, userID uuid.UUID, status *model.InvoiceStatus, page pagination.Page) ([]model.Invoice, pagination.PageInfo, error)
+	UpdateInvoice(ctx context.Context, invoice model.Invoice) error
+	ExpireInvoices(ctx context.Context, now time.Time) (int64, error)
+	CreateInvoicePayment(ctx context.Context, payment model.InvoicePayment) (model.InvoicePayment, error)
+	GetInvoicePaymentByTransactionID(ctx context.Context, transactionID uuid.UUID) (model.InvoicePayment, error)
+	UpdateInvoicePayment(ctx context.Context, payment model.InvoicePayment) error
+}
+
+// Invoice object
+type Invoice struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewInvoice creates a new reference to the Invoice storage entity
+func NewInvoice(s *Storage) *InvoiceDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "invoice").Logger()
+	invoice := &Invoice{
+		logger:  l,
+		storage: s,
+	}
+
+	invoiceDatabase := InvoiceDatabase(invoice)
+	return &invoiceDatabase
+}
+
+// CreateInvoice adds a new invoice with its line items into the invoices table
+func (i *Invoice) CreateInvoice(ctx context.Context, invoice model.Invoice) (model.Invoice, error) {
+	db := i.storage.DB.WithContext(ctx).Create(&invoice)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("CreateInvoice error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.Invoice{}, ErrRecordCreatingFailed
+	}
+
+	return invoice, nil
+}
+
+// GetInvoiceByID returns an invoice with its line items and its payments, oldest payment first
+func (i *Invoice) GetInvoiceByID(ctx context.Context, invoiceID uuid.UUID) (model.Invoice, error) {
+	var invoice model.Invoice
+
+	db := i.storage.DB.WithContext(ctx).Preload("LineItems").Preload("Payments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("id = ?", invoiceID).First(&invoice)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("GetInvoiceByID error: %v (%v)", ErrRecordNotFound, db.Error)
+		return invoice, ErrRecordNotFound
+	}
+
+	return invoice, nil
+}
+
+// GetInvoiceByReference returns the invoice shared under the reference with its line items
+func (i *Invoice) GetInvoiceByReference(ctx context.Context, reference string) (model.Invoice, error) {
+	var invoice model.Invoice
+
+	db := i.storage.DB.WithContext(ctx).Preload("LineItems").Where("reference = ?", reference).First(&invoice)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("GetInvoiceByReference error: %v (%v)", ErrRecordNotFound, db.Error)
+		return invoice, ErrRecordNotFound
+	}
+
+	return invoice, nil
+}
+
+// GetInvoiceByIDForUpdate returns an invoice without its line items and locks its row (SELECT ... FOR UPDATE) until
+// the surrounding database transaction ends. It must be called on a Storage bound to a database transaction
+func (i *Invoice) GetInvoiceByIDForUpdate(ctx context.Context, invoiceID uuid.UUID) (model.Invoice, error) {
+	var invoice model.Invoice
+
+	db := i.storage.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", invoiceID).First(&invoice)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("GetInvoiceByIDForUpdate error: %v (%v)", ErrRecordNotFound, db.Error)
+		return invoice, ErrRecordNotFound
+	}
+
+	return invoice, nil
+}
+
+// GetInvoices returns the user's invoices with their line items, optionally of one status, newest first
+func (i *Invoice) GetInvoices(ctx context.Context, userID uuid.UUID, status *model.InvoiceStatus, page pagination.Page) ([]model.Invoice, pagination.PageInfo, error) {
+	var invoices []model.Invoice
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := i.storage.DB.WithContext(ctx).Model(&model.Invoice{}).Where("user_id = ?", userID)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Preload("LineItems").Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&invoices)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("GetInvoices error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return invoices, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// UpdateInvoice saves the status and the amount paid of an invoice
+func (i *Invoice) UpdateInvoice(ctx context.Context, invoice model.Invoice) error {
+	now := time.Now()
+	invoice.UpdatedAt = &now
+
+	db := i.storage.DB.WithContext(ctx).Model(&model.Invoice{}).Where("id = ?", invoice.ID).
+		Select("status", "amount_paid_minor", "amount_paid_currency", "paid_at", "updated_at").
+		Updates(&invoice)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("UpdateInvoice error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// ExpireInvoices moves the open and partially paid invoices whose expiry passed to expired and returns how many
+func (i *Invoice) ExpireInvoices(ctx context.Context, now time.Time) (int64, error) {
+	db := i.storage.DB.WithContext(ctx).Model(&model.Invoice{}).
+		Where("status IN ? AND expires_at <= ?", []model.InvoiceStatus{model.InvoiceStatusOpen, model.InvoiceStatusPartiallyPaid}, now).
+		Updates(map[string]interface{}{"status": model.InvoiceStatusExpired, "updated_at": now})
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("ExpireInvoices error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return 0, ErrRecordUpdateFailed
+	}
+
+	return db.RowsAffected, nil
+}
+
+// CreateInvoicePayment adds a new payment into the invoice_payments table
+func (i *Invoice) CreateInvoicePayment(ctx context.Context, payment model.InvoicePayment) (model.InvoicePayment, error) {
+	db := i.storage.DB.WithContext(ctx).Create(&payment)
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("CreateInvoicePayment error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.InvoicePayment{}, ErrRecordCreatingFailed
+	}
+
+	return payment, nil
+}
+
+// GetInvoicePaymentByTransactionID returns the invoice payment the transaction was made for
+func (i *Invoice) GetInvoicePaymentByTransactionID(ctx context.Context, transactionID uuid.UUID) (model.InvoicePayment, error) {
+	var payment model.InvoicePayment
+
+	db := i.storage.DB.WithContext(ctx).Where("transaction_id = ?", transactionID).First(&payment)
+	if db.Error == gorm.ErrRecordNotFound {
+		return payment, ErrRecordNotFound
+	}
+
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("GetInvoicePaymentByTransactionID error: %v", db.Error)
+		return payment, ErrGeneric
+	}
+
+	return payment, nil
+}
+
+// UpdateInvoicePayment saves the status and the amount of an invoice payment
+func (i *Invoice) UpdateInvoicePayment(ctx context.Context, payment model.InvoicePayment) error {
+	db := i.storage.DB.WithContext(ctx).Model(&model.InvoicePayment{}).Where("id = ?", payment.ID).
+		Updates(map[string]interface{}{
+			"status":          payment.Status,
+			"amount_minor":    payment.Amount.Minor,
+			"amount_currency": payment.Amount.Currency,
+			"updated_at":      time.Now(),
+		})
+	if db.Error != nil {
+		i.logger.Err(db.Error).Msgf("UpdateInvoicePayment error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
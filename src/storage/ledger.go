@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// LedgerDatabase enlists all possible operations on the double-entry ledger
+type LedgerDatabase interface {
+	GetOrCreateLedgerAccount(ctx context.Context, account model.LedgerAccount) (model.LedgerAccount, error)
+	GetLedgerAccountByCode(ctx context.Context, code string) (model.LedgerAccount, error)
+	GetLedgerAccountByCodeForUpdate(ctx context.Context, code string) (model.LedgerAccount, error)
+	GetLedgerAccountsByTenantID(ctx context.Context, tenantID uuid.UUID, accountType model.LedgerAccountType) ([]model.LedgerAccount, error)
+	PostJournalEntry(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error)
+	GetLedgerAccountBalance(ctx context.Context, accountID uuid.UUID) (model.Money, error)
+	GetLastPostingByAccountID(ctx context.Context, accountID uuid.UUID) (model.Posting, error)
+	GetPostingsTotal(ctx context.Context, accountID uuid.UUID, from, to time.Time) (model.Money, error)
+}
+
+// Ledger object
+type Ledger struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewLedger creates a new reference to the Ledger storage entity
+func NewLedger(s *Storage) *LedgerDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "ledger").Logger()
+	ledger := &Ledger{
+		logger:  l,
+		storage: s,
+	}
+
+	ledgerDatabase := LedgerDatabase(ledger)
+	return &ledgerDatabase
+}
+
+// GetOrCreateLedgerAccount returns the ledger account matching the account code, creating it if it does not exist yet.
+// Concurrent callers opening the same account all get the one row back
+func (l *Ledger) GetOrCreateLedgerAccount(ctx context.Context, account model.LedgerAccount) (model.LedgerAccount, error) {
+	db := l.storage.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoNothing: true,
+	}).Create(&account)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("GetOrCreateLedgerAccount error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.LedgerAccount{}, ErrRecordCreatingFailed
+	}
+
+	var existing model.LedgerAccount
+	if err := l.storage.DB.WithContext(ctx).Where("code = ?", account.Code).First(&existing).Error; err != nil {
+		l.logger.Err(err).Msgf("GetOrCreateLedgerAccount error: %v, (%v)", ErrRecordNotFound, err)
+		return model.LedgerAccount{}, ErrRecordNotFound
+	}
+
+	return existing, nil
+}
+
+// GetLedgerAccountByCode returns the ledger account matching the account code
+func (l *Ledger) GetLedgerAccountByCode(ctx context.Context, code string) (model.LedgerAccount, error) {
+	var account model.LedgerAccount
+
+	db := l.storage.DB.WithContext(ctx).Where("code = ?", code).First(&account)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("GetLedgerAccountByCode error: %v (%v)", ErrRecordNotFound, db.Error)
+		return account, ErrRecordNotFound
+	}
+
+	return account, nil
+}
+
+// GetLedgerAccountByCodeForUpdate returns the ledger account matching the account code and locks its row
+// (SELECT ... FOR UPDATE) until the surrounding database transaction ends, so that two debits of an account that has no
+// wallet cannot both spend its balance. It must be called on a Storage bound to a database transaction
+func (l *Ledger) GetLedgerAccountByCodeForUpdate(ctx context.Context, code string) (model.LedgerAccount, error) {
+	var account model.LedgerAccount
+
+	db := l.storage.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&account)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("GetLedgerAccountByCodeForUpdate error: %v (%v)", ErrRecordNotFound, db.Error)
+		return account, ErrRecordNotFound
+	}
+
+	return account, nil
+}
+
+// GetLedgerAccountsByTenantID returns the tenant's ledger accounts of the type, by currency
+func (l *Ledger) GetLedgerAccountsByTenantID(ctx context.Context, tenantID uuid.UUID, accountType model.LedgerAccountType) ([]model.LedgerAccount, error) {
+	var accounts []model.LedgerAccount
+
+	db := l.storage.DB.WithContext(ctx).Where("tenant_id = ? AND type = ?", tenantID, accountType).Order("currency ASC").Find(&accounts)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("GetLedgerAccountsByTenantID error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return accounts, nil
+}
+
+// PostJournalEntry writes a journal entry together with its postings. The postings must sum to zero
+func (l *Ledger) PostJournalEntry(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error) {
+	if err := validateJournalEntry(entry); err != nil {
+		l.logger.Err(err).Msgf("PostJournalEntry ::: invalid journal entry %v", entry.ID)
+		return model.JournalEntry{}, err
+	}
+
+	// gorm creates the entry and its postings inside a single database transaction
+	db := l.storage.DB.WithContext(ctx).Create(&entry)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("PostJournalEntry error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.JournalEntry{}, ErrRecordCreatingFailed
+	}
+
+	return entry, nil
+}
+
+// GetLedgerAccountBalance returns the balance of a ledger account as the sum of all its postings
+func (l *Ledger) GetLedgerAccountBalance(ctx context.Context, accountID uuid.UUID) (model.Money, error) {
+	balance, err := ledgerAccountBalance(l.storage.DB.WithContext(ctx), accountID)
+	if err != nil {
+		l.logger.Err(err).Msgf("GetLedgerAccountBalance error: %v", err)
+		return model.Money{}, ErrGeneric
+	}
+
+	return balance, nil
+}
+
+// GetLastPostingByAccountID returns the most recent posting made to a ledger account
+func (l *Ledger) GetLastPostingByAccountID(ctx context.Context, accountID uuid.UUID) (model.Posting, error) {
+	posting, err := lastPosting(l.storage.DB.WithContext(ctx), accountID)
+	if err != nil {
+		l.logger.Err(err).Msgf("GetLastPostingByAccountID error: %v (%v)", ErrRecordNotFound, err)
+		return posting, ErrRecordNotFound
+	}
+
+	return posting, nil
+}
+
+// validateJournalEntry makes sure a journal entry is balanced before it is written
+func validateJournalEntry(entry model.JournalEntry) error {
+	if len(entry.Postings) < 2 {
+		return ErrUnbalancedJournalEntry
+	}
+
+	var sum model.Money
+	for _, posting := range entry.Postings {
+		var err error
+		if sum, err = sum.Add(posting.Amount); err != nil {
+			return ErrUnbalancedJournalEntry
+		}
+	}
+
+	if !sum.IsZero() {
+		return ErrUnbalancedJournalEntry
+	}
+
+	return nil
+}
+
+// ledgerAccountBalance sums every posting made to the ledger account
+func ledgerAccountBalance(db *gorm.DB, accountID uuid.UUID) (model.Money, error) {
+	var account model.LedgerAccount
+	if err := db.Where("id = ?", accountID).First(&account).Error; err != nil {
+		return model.Money{}, err
+	}
+
+	var minor int64
+	err := db.Model(&model.Posting{}).
+		Where("account_id = ?", accountID).
+		Select("COALESCE(SUM(amount_minor), 0)").
+		Scan(&minor).Error
+
+	return model.NewMoney(minor, account.Currency), err
+}
+
+// lastPosting returns the most recent posting made to the ledger account
+func lastPosting(db *gorm.DB, accountID uuid.UUID) (model.Posting, error) {
+	var posting model.Posting
+
+	err := db.Where("account_id = ?", accountID).
+		Order("created_at DESC").
+		First(&posting).Error
+
+	return posting, err
+}
+
+// isRecordNotFound reports whether the gorm error is a not found error
+func isRecordNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// GetPostingsTotal returns the sum of the postings made to a ledger account from (inclusive) to (exclusive), a zero
+// from sums every posting made before to
+func (l *Ledger) GetPostingsTotal(ctx context.Context, accountID uuid.UUID, from, to time.Time) (model.Money, error) {
+	var account model.LedgerAccount
+	if err := l.storage.DB.WithContext(ctx).Where("id = ?", accountID).First(&account).Error; err != nil {
+		l.logger.Err(err).Msgf("GetPostingsTotal error: %v (%v)", ErrRecordNotFound, err)
+		return model.Money{}, ErrRecordNotFound
+	}
+
+	query := l.storage.DB.WithContext(ctx).Model(&model.Posting{}).Where("account_id = ? AND created_at < ?", accountID, to)
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+
+	var minor int64
+	if err := query.Select("COALESCE(SUM(amount_minor), 0)").Scan(&minor).Error; err != nil {
+		l.logger.Err(err).Msgf("GetPostingsTotal error: %v", err)
+		return model.Money{}, ErrGeneric
+	}
+
+	return model.NewMoney(minor, account.Currency), nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// LedgerVerificationDatabase enlists all possible operations on ledger verifications and the breaks they found
+type LedgerVerificationDatabase interface {
+	CreateLedgerVerification(ctx context.Context, verification model.LedgerVerification) (model.LedgerVerification, error)
+	GetLedgerVerifications(ctx context.Context, tenantID *uuid.UUID, page pagination.Page) ([]model.LedgerVerification, pagination.PageInfo, error)
+}
+
+// LedgerVerification object
+type LedgerVerification struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewLedgerVerification creates a new reference to the LedgerVerification storage entity
+func NewLedgerVerification(s *Storage) *LedgerVerificationDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "ledger_verification").Logger()
+	ledgerVerification := &LedgerVerification{
+		logger:  l,
+		storage: s,
+	}
+
+	ledgerVerificationDatabase := LedgerVerificationDatabase(ledgerVerification)
+	return &ledgerVerificationDatabase
+}
+
+// CreateLedgerVerification adds a verification and the breaks it found into the ledger_verifications and
+// ledger_breaks tables
+func (l *LedgerVerification) CreateLedgerVerification(ctx context.Context, verification model.LedgerVerification) (model.LedgerVerification, error) {
+	db := l.storage.DB.WithContext(ctx).Create(&verification)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("CreateLedgerVerification error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.LedgerVerification{}, ErrRecordCreatingFailed
+	}
+
+	return verification, nil
+}
+
+// GetLedgerVerifications returns the verifications, optionally of one tenant, newest first with the breaks they found
+func (l *LedgerVerification) GetLedgerVerifications(ctx context.Context, tenantID *uuid.UUID, page pagination.Page) ([]model.LedgerVerification, pagination.PageInfo, error) {
+	var verifications []model.LedgerVerification
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := l.storage.DB.WithContext(ctx).Model(&model.LedgerVerification{})
+	if tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Preload("Items").Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&verifications)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("GetLedgerVerifications error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return verifications, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm/clause"
+
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// LimitDatabase enlists all possible operations on the tenants' transaction limits
+type LimitDatabase interface {
+	UpsertTransactionLimit(ctx context.Context, limit model.TransactionLimit) (model.TransactionLimit, error)
+	GetTransactionLimit(ctx context.Context, tenantID uuid.UUID, action model.LimitAction, currency string) (model.TransactionLimit, error)
+	GetTransactionLimits(ctx context.Context, tenantID uuid.UUID) ([]model.TransactionLimit, error)
+	DeleteTransactionLimit(ctx context.Context, tenantID uuid.UUID, action model.LimitAction, currency string) (bool, error)
+}
+
+// Limit object
+type Limit struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewLimit creates a new reference to the Limit storage entity
+func NewLimit(s *Storage) *LimitDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "limit").Logger()
+	limit := &Limit{
+		logger:  l,
+		storage: s,
+	}
+
+	limitDatabase := LimitDatabase(limit)
+	return &limitDatabase
+}
+
+// UpsertTransactionLimit sets the tenant's limit for the action in the currency, replacing the caps of the one it had
+func (l *Limit) UpsertTransactionLimit(ctx context.Context, limit model.TransactionLimit) (model.TransactionLimit, error) {
+	now := time.Now()
+	limit.UpdatedAt = &now
+
+	db := l.storage.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "tenant_id"}, {Name: "action"}, {Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"max_single_minor", "max_single_currency", "daily_count", "daily_amount_minor", "daily_amount_currency",
+			"monthly_count", "monthly_amount_minor", "monthly_amount_currency", "updated_at",
+		}),
+	}).Create(&limit)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("UpsertTransactionLimit error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.TransactionLimit{}, ErrRecordCreatingFailed
+	}
+
+	return l.GetTransactionLimit(ctx, limit.TenantID, limit.Action, limit.Currency)
+}
+
+// GetTransactionLimit returns the tenant's limit for the action in the currency
+func (l *Limit) GetTransactionLimit(ctx context.Context, tenantID uuid.UUID, action model.LimitAction, currency string) (model.TransactionLimit, error) {
+	var limit model.TransactionLimit
+
+	err := l.storage.DB.WithContext(ctx).
+		Where("tenant_id = ? AND action = ? AND currency = ?", tenantID, action, strings.ToUpper(currency)).First(&limit).Error
+	if isRecordNotFound(err) {
+		return limit, ErrRecordNotFound
+	}
+
+	if err != nil {
+		l.logger.Err(err).Msgf("GetTransactionLimit error: %v", err)
+		return limit, ErrGeneric
+	}
+
+	return limit, nil
+}
+
+// GetTransactionLimits returns the tenant's limits by action and currency
+func (l *Limit) GetTransactionLimits(ctx context.Context, tenantID uuid.UUID) ([]model.TransactionLimit, error) {
+	var limits []model.TransactionLimit
+
+	db := l.storage.DB.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("action ASC, currency ASC").Find(&limits)
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("GetTransactionLimits error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return limits, nil
+}
+
+// DeleteTransactionLimit removes the tenant's limit for the action in the currency, it reports whether there was one
+func (l *Limit) DeleteTransactionLimit(ctx context.Context, tenantID uuid.UUID, action model.LimitAction, currency string) (bool, error) {
+	db := l.storage.DB.WithContext(ctx).
+		Where("tenant_id = ? AND action = ? AND currency = ?", tenantID, action, strings.ToUpper(currency)).
+		Delete(&model.TransactionLimit{})
+	if db.Error != nil {
+		l.logger.Err(db.Error).Msgf("DeleteTransactionLimit error: %v", db.Error)
+		return false, ErrGeneric
+	}
+
+	return db.RowsAffected > 0, nil
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"codematic/model"
+)
+
+// legacyMoneyColumn is a float column in major units that has been replaced by a model.Money column pair
+type legacyMoneyColumn struct {
+	table    string
+	column   string
+	prefix   string
+	currency string // SQL expression that yields the currency of the row
+}
+
+var legacyMoneyColumns = []legacyMoneyColumn{
+	{table: "transactions", column: "amount", prefix: "amount_", currency: fmt.Sprintf("COALESCE(NULLIF(currency, ''), '%s')", model.DefaultCurrency)},
+	{table: "transactions", column: "charges", prefix: "charges_", currency: fmt.Sprintf("COALESCE(NULLIF(currency, ''), '%s')", model.DefaultCurrency)},
+	{table: "balances", column: "balance_before", prefix: "balance_before_", currency: fmt.Sprintf("'%s'", model.DefaultCurrency)},
+	{table: "balances", column: "balance_after", prefix: "balance_after_", currency: fmt.Sprintf("'%s'", model.DefaultCurrency)},
+	{table: "wallets", column: "balance_before", prefix: "balance_before_", currency: fmt.Sprintf("'%s'", model.DefaultCurrency)},
+	{table: "wallets", column: "balance_after", prefix: "balance_after_", currency: fmt.Sprintf("'%s'", model.DefaultCurrency)},
+	{table: "postings", column: "amount", prefix: "amount_", currency: "(SELECT ledger_accounts.currency FROM ledger_accounts WHERE ledger_accounts.id = postings.account_id)"},
+}
+
+// migrateMoneyColumns converts the legacy float columns into integer minor units and drops them afterwards.
+// It is a no-op once every legacy column is gone
+func (s *Storage) migrateMoneyColumns() error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, legacy := range legacyMoneyColumns {
+			if !tx.Migrator().HasColumn(legacy.table, legacy.column) {
+				continue
+			}
+
+			query := fmt.Sprintf(
+				"UPDATE %s SET %scurrency = %s, %sminor = ROUND(COALESCE(%s, 0) * %s)::bigint",
+				legacy.table,
+				legacy.prefix, legacy.currency,
+				legacy.prefix, legacy.column, minorUnitFactorSQL(legacy.currency),
+			)
+			if err := tx.Exec(query).Error; err != nil {
+				s.Logger.Err(err).Msgf("migrateMoneyColumns ::: unable to convert %s.%s", legacy.table, legacy.column)
+				return err
+			}
+
+			if err := tx.Migrator().DropColumn(legacy.table, legacy.column); err != nil {
+				s.Logger.Err(err).Msgf("migrateMoneyColumns ::: unable to drop %s.%s", legacy.table, legacy.column)
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// redactWebhookHeaders redacts the secrets the webhook inbox stored with the headers of the deliveries it received
+// before they were redacted on receipt. It is a no-op once none is left
+func (s *Storage) redactWebhookHeaders() error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, name := range model.RedactedWebhookHeaders {
+			err := tx.Exec(
+				"UPDATE webhook_events SET headers = jsonb_set(headers, ARRAY[?], to_jsonb(?::text)) WHERE jsonb_exists(headers, ?) AND headers->>? <> ?",
+				name, model.RedactedWebhookHeader, name, name, model.RedactedWebhookHeader,
+			).Error
+			if err != nil {
+				s.Logger.Err(err).Msgf("redactWebhookHeaders ::: unable to redact %s", name)
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// migrateFeeRules creates the fee rules table and gives every tenant the legacy fee rules in the same transaction, so
+// the tenants that existed before fee rules keep being charged what they were. Once the table exists it is only kept
+// up to date, later tenants start without rules
+func (s *Storage) migrateFeeRules() error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		seed := !tx.Migrator().HasTable(&model.FeeRule{})
+		if err := tx.AutoMigrate(&model.FeeRule{}); err != nil || !seed {
+			return err
+		}
+
+		var tenants []model.Tenant
+		if err := tx.Select("id").Find(&tenants).Error; err != nil {
+			return err
+		}
+
+		for _, tenant := range tenants {
+			if err := tx.Create(model.LegacyFeeRules(tenant.ID)).Error; err != nil {
+				s.Logger.Err(err).Msgf("migrateFeeRules ::: unable to seed the fee rules of tenant %s", tenant.ID)
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// minorUnitFactorSQL builds the SQL expression giving the minor unit factor (e.g. 100 for NGN) of a currency expression
+func minorUnitFactorSQL(currency string) string {
+	var sb strings.Builder
+
+	sb.WriteString("CASE UPPER(" + currency + ")")
+	for _, code := range model.SupportedCurrencies() {
+		exponent, _ := model.CurrencyExponent(code)
+		sb.WriteString(fmt.Sprintf(" WHEN '%s' THEN 1%s", code, strings.Repeat("0", exponent)))
+	}
+	sb.WriteString(" ELSE 100 END")
+
+	return sb.String()
+}
+
+// migrateWalletsToLedger opens a ledger account for every wallet created before the ledger existed.
+// Whatever balance the wallet holds is brought into the ledger as an opening balance entry
+func (s *Storage) migrateWalletsToLedger() error {
+	var wallets []model.Wallet
+	if err := s.DB.Preload("User").Where("ledger_account_id IS NULL").Find(&wallets).Error; err != nil {
+		return err
+	}
+
+	for _, wallet := range wallets {
+		if wallet.User == nil {
+			continue
+		}
+
+		currency := wallet.Currency
+		if currency == "" {
+			currency = model.DefaultCurrency
+		}
+
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			account := model.LedgerAccount{
+				ID:       uuid.New(),
+				Code:     model.UserWalletAccountCode(wallet.UserID, currency),
+				Type:     model.LedgerAccountTypeUserWallet,
+				TenantID: &wallet.User.TenantID,
+				UserID:   &wallet.UserID,
+				Currency: currency,
+			}
+			if err := tx.Where("code = ?", account.Code).FirstOrCreate(&account).Error; err != nil {
+				return err
+			}
+
+			if !wallet.BalanceAfter.IsZero() {
+				opening := model.LedgerAccount{
+					ID:       uuid.New(),
+					Code:     model.OpeningBalanceAccountCode(currency),
+					Type:     model.LedgerAccountTypeOpeningBalance,
+					Currency: currency,
+				}
+				if err := tx.Where("code = ?", opening.Code).FirstOrCreate(&opening).Error; err != nil {
+					return err
+				}
+
+				entry := model.JournalEntry{
+					ID:          uuid.New(),
+					Description: "opening balance",
+					Postings: []model.Posting{
+						{ID: uuid.New(), AccountID: account.ID, Amount: wallet.BalanceAfter},
+						{ID: uuid.New(), AccountID: opening.ID, Amount: wallet.BalanceAfter.Neg()},
+					},
+				}
+				if err := tx.Create(&entry).Error; err != nil {
+					return err
+				}
+			}
+
+			return tx.Model(&model.Wallet{}).Where("id = ?", wallet.ID).Update("ledger_account_id", account.ID).Error
+		})
+		if err != nil {
+			s.Logger.Err(err).Msgf("migrateWalletsToLedger ::: unable to migrate wallet %v", wallet.ID)
+			return err
+		}
+	}
+
+	return nil
+}
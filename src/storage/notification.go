@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// NotificationDatabase enlists all possible operations on notifications
+type NotificationDatabase interface {
+	CreateNotification(ctx context.Context, notification model.Notification) (model.Notification, error)
+	GetNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, page pagination.Page) ([]model.Notification, pagination.PageInfo, error)
+	MarkNotificationRead(ctx context.Context, userID, notificationID uuid.UUID) (bool, error)
+}
+
+// Notification object
+type Notification struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewNotification creates a new reference to the Notification storage entity
+func NewNotification(s *Storage) *NotificationDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "notification").Logger()
+	notification := &Notification{
+		logger:  l,
+		storage: s,
+	}
+
+	notificationDatabase := NotificationDatabase(notification)
+	return &notificationDatabase
+}
+
+// CreateNotification adds a new notification into the notifications table
+func (n *Notification) CreateNotification(ctx context.Context, notification model.Notification) (model.Notification, error) {
+	db := n.storage.DB.WithContext(ctx).Create(&notification)
+	if db.Error != nil {
+		n.logger.Err(db.Error).Msgf("CreateNotification error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.Notification{}, ErrRecordCreatingFailed
+	}
+
+	return notification, nil
+}
+
+// GetNotifications returns the user's notifications, optionally the unread ones only, newest first
+func (n *Notification) GetNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, page pagination.Page) ([]model.Notification, pagination.PageInfo, error) {
+	var notifications []model.Notification
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := n.storage.DB.WithContext(ctx).Model(&model.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&notifications)
+	if db.Error != nil {
+		n.logger.Err(db.Error).Msgf("GetNotifications error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return notifications, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// MarkNotificationRead marks one of the user's notifications read. It reports false when the user has no such
+// notification
+func (n *Notification) MarkNotificationRead(ctx context.Context, userID, notificationID uuid.UUID) (bool, error) {
+	db := n.storage.DB.WithContext(ctx).Model(&model.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Updates(map[string]interface{}{"read_at": gorm.Expr("COALESCE(read_at, ?)", time.Now())})
+	if db.Error != nil {
+		n.logger.Err(db.Error).Msgf("MarkNotificationRead error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return false, ErrRecordUpdateFailed
+	}
+
+	return db.RowsAffected == 1, nil
+}
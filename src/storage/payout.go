@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// PayoutDatabase enlists all possible operations on payout batches and their items
+type PayoutDatabase interface {
+	CreatePayoutBatch(ctx context.Context, batch model.PayoutBatch) (model.PayoutBatch, error)
+	UpdatePayoutBatch(ctx context.Context, batch model.PayoutBatch) error
+	GetPayoutBatchByID(ctx context.Context, batchID uuid.UUID) (model.PayoutBatch, error)
+	GetPayoutBatches(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.PayoutBatch, pagination.PageInfo, error)
+	GetUnfinishedPayoutBatches(ctx context.Context) ([]model.PayoutBatch, error)
+	CreatePayoutItems(ctx context.Context, items []model.PayoutItem) error
+	ClaimPayoutItem(ctx context.Context, itemID uuid.UUID) (bool, error)
+	UpdatePayoutItem(ctx context.Context, item model.PayoutItem) error
+	FailStalePayoutItems(ctx context.Context, batchID uuid.UUID, before time.Time, reason string) error
+	GetPayoutItems(ctx context.Context, batchID uuid.UUID, status *model.PayoutItemStatus, page pagination.Page) ([]model.PayoutItem, pagination.PageInfo, error)
+	GetAllPayoutItems(ctx context.Context, batchID uuid.UUID) ([]model.PayoutItem, error)
+}
+
+// Payout object
+type Payout struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewPayout creates a new reference to the Payout storage entity
+func NewPayout(s *Storage) *PayoutDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "payout").Logger()
+	payout := &Payout{
+		logger:  l,
+		storage: s,
+	}
+
+	payoutDatabase := PayoutDatabase(payout)
+	return &payoutDatabase
+}
+
+// CreatePayoutBatch adds a new batch into the payout_batches table
+func (p *Payout) CreatePayoutBatch(ctx context.Context, batch model.PayoutBatch) (model.PayoutBatch, error) {
+	db := p.storage.DB.WithContext(ctx).Create(&batch)
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("CreatePayoutBatch error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.PayoutBatch{}, ErrRecordCreatingFailed
+	}
+
+	return batch, nil
+}
+
+// UpdatePayoutBatch saves the status and counts of a batch
+func (p *Payout) UpdatePayoutBatch(ctx context.Context, batch model.PayoutBatch) error {
+	db := p.storage.DB.WithContext(ctx).Model(&model.PayoutBatch{}).Where("id = ?", batch.ID).
+		Select("status", "succeeded", "failed", "paid_amount_minor", "paid_amount_currency", "finished_at").
+		Updates(&batch)
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("UpdatePayoutBatch error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// GetPayoutBatchByID returns a batch by its ID
+func (p *Payout) GetPayoutBatchByID(ctx context.Context, batchID uuid.UUID) (model.PayoutBatch, error) {
+	var batch model.PayoutBatch
+
+	db := p.storage.DB.WithContext(ctx).Where("id = ?", batchID).First(&batch)
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("GetPayoutBatchByID error: %v, (%v)", ErrRecordNotFound, db.Error)
+		return model.PayoutBatch{}, ErrRecordNotFound
+	}
+
+	return batch, nil
+}
+
+// GetPayoutBatches returns the user's batches, newest first
+func (p *Payout) GetPayoutBatches(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.PayoutBatch, pagination.PageInfo, error) {
+	var batches []model.PayoutBatch
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := p.storage.DB.WithContext(ctx).Model(&model.PayoutBatch{}).Where("user_id = ?", userID)
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&batches)
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("GetPayoutBatches error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return batches, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// GetUnfinishedPayoutBatches returns the batches that are queued or processing, oldest first
+func (p *Payout) GetUnfinishedPayoutBatches(ctx context.Context) ([]model.PayoutBatch, error) {
+	var batches []model.PayoutBatch
+
+	db := p.storage.DB.WithContext(ctx).
+		Where("status IN ?", []model.PayoutBatchStatus{model.PayoutBatchQueued, model.PayoutBatchProcessing}).
+		Order("created_at ASC").Find(&batches)
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("GetUnfinishedPayoutBatches error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return batches, nil
+}
+
+// CreatePayoutItems adds the items of a batch into the payout_items table
+func (p *Payout) CreatePayoutItems(ctx context.Context, items []model.PayoutItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	db := p.storage.DB.WithContext(ctx).CreateInBatches(&items, 500)
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("CreatePayoutItems error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return ErrRecordCreatingFailed
+	}
+
+	return nil
+}
+
+// ClaimPayoutItem moves a pending item to processing. It reports false when the item is not pending anymore, e.g.
+// another worker claimed it first
+func (p *Payout) ClaimPayoutItem(ctx context.Context, itemID uuid.UUID) (bool, error) {
+	db := p.storage.DB.WithContext(ctx).Model(&model.PayoutItem{}).
+		Where("id = ? AND status = ?", itemID, model.PayoutItemPending).
+		Updates(map[string]interface{}{"status": model.PayoutItemProcessing, "updated_at": time.Now()})
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("ClaimPayoutItem error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return false, ErrRecordUpdateFailed
+	}
+
+	return db.RowsAffected == 1, nil
+}
+
+// UpdatePayoutItem saves the outcome of a processing item
+func (p *Payout) UpdatePayoutItem(ctx context.Context, item model.PayoutItem) error {
+	db := p.storage.DB.WithContext(ctx).Model(&model.PayoutItem{}).
+		Where("id = ? AND status = ?", item.ID, model.PayoutItemProcessing).
+		Updates(map[string]interface{}{
+			"status":         item.Status,
+			"transaction_id": item.TransactionID,
+			"error":          item.Error,
+			"updated_at":     time.Now(),
+		})
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("UpdatePayoutItem error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// FailStalePayoutItems fails the items of a batch that were claimed before the time and never finished
+func (p *Payout) FailStalePayoutItems(ctx context.Context, batchID uuid.UUID, before time.Time, reason string) error {
+	db := p.storage.DB.WithContext(ctx).Model(&model.PayoutItem{}).
+		Where("batch_id = ? AND status = ? AND updated_at < ?", batchID, model.PayoutItemProcessing, before).
+		Updates(map[string]interface{}{"status": model.PayoutItemFailed, "error": reason, "updated_at": time.Now()})
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("FailStalePayoutItems error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// GetPayoutItems returns the items of a batch, optionally of one status, in the order of the file
+func (p *Payout) GetPayoutItems(ctx context.Context, batchID uuid.UUID, status *model.PayoutItemStatus, page pagination.Page) ([]model.PayoutItem, pagination.PageInfo, error) {
+	var items []model.PayoutItem
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := p.storage.DB.WithContext(ctx).Model(&model.PayoutItem{}).Where("batch_id = ?", batchID)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("row ASC").Find(&items)
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("GetPayoutItems error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return items, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// GetAllPayoutItems returns every item of a batch in the order of the file
+func (p *Payout) GetAllPayoutItems(ctx context.Context, batchID uuid.UUID) ([]model.PayoutItem, error) {
+	var items []model.PayoutItem
+
+	db := p.storage.DB.WithContext(ctx).Where("batch_id = ?", batchID).Order("row ASC").Find(&items)
+	if db.Error != nil {
+		p.logger.Err(db.Error).Msgf("GetAllPayoutItems error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return items, nil
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// ReconciliationDatabase enlists all possible operations on reconciliation runs and their items
+type ReconciliationDatabase interface {
+	CreateReconciliationRun(ctx context.Context, run model.ReconciliationRun) (model.ReconciliationRun, error)
+	UpdateReconciliationRun(ctx context.Context, run model.ReconciliationRun) error
+	GetReconciliationRunByID(ctx context.Context, runID uuid.UUID) (model.ReconciliationRun, error)
+	GetReconciliationRuns(ctx context.Context, provider *model.PaymentProvider, page pagination.Page) ([]model.ReconciliationRun, pagination.PageInfo, error)
+	CreateReconciliationItems(ctx context.Context, items []model.ReconciliationItem) error
+	GetReconciliationItems(ctx context.Context, runID uuid.UUID, kind *model.ReconciliationKind, page pagination.Page) ([]model.ReconciliationItem, pagination.PageInfo, error)
+}
+
+// Reconciliation object
+type Reconciliation struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewReconciliation creates a new reference to the Reconciliation storage entity
+func NewReconciliation(s *Storage) *ReconciliationDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "reconciliation").Logger()
+	reconciliation := &Reconciliation{
+		logger:  l,
+		storage: s,
+	}
+
+	reconciliationDatabase := ReconciliationDatabase(reconciliation)
+	return &reconciliationDatabase
+}
+
+// CreateReconciliationRun adds a new run into the reconciliation_runs table
+func (r *Reconciliation) CreateReconciliationRun(ctx context.Context, run model.ReconciliationRun) (model.ReconciliationRun, error) {
+	db := r.storage.DB.WithContext(ctx).Create(&run)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("CreateReconciliationRun error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.ReconciliationRun{}, ErrRecordCreatingFailed
+	}
+
+	return run, nil
+}
+
+// UpdateReconciliationRun saves the outcome and totals of a run
+func (r *Reconciliation) UpdateReconciliationRun(ctx context.Context, run model.ReconciliationRun) error {
+	db := r.storage.DB.WithContext(ctx).Model(&model.ReconciliationRun{}).Where("id = ?", run.ID).
+		Select("status", "error", "period_start", "period_end", "records", "matched", "missing_on_our_side",
+			"missing_on_provider", "amount_mismatches", "status_mismatches", "finished_at").
+		Updates(&run)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("UpdateReconciliationRun error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// GetReconciliationRunByID returns a run by its ID
+func (r *Reconciliation) GetReconciliationRunByID(ctx context.Context, runID uuid.UUID) (model.ReconciliationRun, error) {
+	var run model.ReconciliationRun
+
+	db := r.storage.DB.WithContext(ctx).Where("id = ?", runID).First(&run)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("GetReconciliationRunByID error: %v (%v)", ErrRecordNotFound, db.Error)
+		return run, ErrRecordNotFound
+	}
+
+	return run, nil
+}
+
+// GetReconciliationRuns returns the runs, optionally of one provider, newest first
+func (r *Reconciliation) GetReconciliationRuns(ctx context.Context, provider *model.PaymentProvider, page pagination.Page) ([]model.ReconciliationRun, pagination.PageInfo, error) {
+	var runs []model.ReconciliationRun
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := r.storage.DB.WithContext(ctx).Model(&model.ReconciliationRun{})
+	if provider != nil {
+		query = query.Where("provider = ?", *provider)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&runs)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("GetReconciliationRuns error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return runs, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// CreateReconciliationItems adds the discrepancies found by a run into the reconciliation_items table
+func (r *Reconciliation) CreateReconciliationItems(ctx context.Context, items []model.ReconciliationItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	db := r.storage.DB.WithContext(ctx).CreateInBatches(&items, 500)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("CreateReconciliationItems error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return ErrRecordCreatingFailed
+	}
+
+	return nil
+}
+
+// GetReconciliationItems returns the discrepancies of a run, optionally of one kind, by kind and reference
+func (r *Reconciliation) GetReconciliationItems(ctx context.Context, runID uuid.UUID, kind *model.ReconciliationKind, page pagination.Page) ([]model.ReconciliationItem, pagination.PageInfo, error) {
+	var items []model.ReconciliationItem
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := r.storage.DB.WithContext(ctx).Model(&model.ReconciliationItem{}).Where("run_id = ?", runID)
+	if kind != nil {
+		query = query.Where("kind = ?", *kind)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("kind ASC, provider_reference ASC, transaction_id ASC").Find(&items)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("GetReconciliationItems error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return items, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
@@ -118,6 +118,45 @@ func (r *Redis) SetValue(ctx context.Context, key string, value interface{}, ttl
 	return nil
 }
 
+// SetValueIfAbsent sets and writes value into redis only when the key does not exist yet, and reports whether it did
+func (r *Redis) SetValueIfAbsent(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if r.connectionError != nil {
+		// attempt to reconnect
+		err := r.Connect()
+		if err != nil {
+			return false, ErrConnectionToSourceFailed
+		}
+	}
+
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+// IncrementBy adds value to the integer stored at key and returns the new total. A key that does not exist yet starts
+// from zero and expires after ttl
+func (r *Redis) IncrementBy(ctx context.Context, key string, value int64, ttl time.Duration) (int64, error) {
+	if r.connectionError != nil {
+		// attempt to reconnect
+		err := r.Connect()
+		if err != nil {
+			return 0, ErrConnectionToSourceFailed
+		}
+	}
+
+	total, err := r.client.IncrBy(ctx, key, value).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// the key was created by this increment
+	if total == value {
+		if err := r.client.Expire(ctx, key, ttl).Err(); err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
 // DeleteValue will delete redis key
 func (r *Redis) DeleteValue(ctx context.Context, key string) error {
 	res := r.client.Del(ctx, key)
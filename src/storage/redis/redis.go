@@ -14,6 +14,8 @@ type KvStore interface {
 	GetValue(ctx context.Context, key string, result interface{}) error
 	GetStringValue(ctx context.Context, key string) (string, error)
 	SetValue(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	SetValueIfAbsent(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	IncrementBy(ctx context.Context, key string, value int64, ttl time.Duration) (int64, error)
 	DeleteValue(ctx context.Context, key string) error
 	Connect() error
 }
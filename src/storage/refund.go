@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/model"
+	"codematic/pkg/helper"
+)
+
+// RefundDatabase enlists all possible operations on refunds
+type RefundDatabase interface {
+	CreateRefund(ctx context.Context, refund model.Refund) (model.Refund, error)
+	GetRefundByReference(ctx context.Context, tenantID uuid.UUID, reference string) (model.Refund, error)
+	GetRefundsByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]model.Refund, error)
+	UpdateRefundStatus(ctx context.Context, refundID uuid.UUID, status model.RefundStatus) error
+	GetRefundedTotal(ctx context.Context, transactionID uuid.UUID, currency string, statuses ...model.RefundStatus) (model.Money, error)
+	GetStuckRefunds(ctx context.Context, before time.Time, limit int) ([]model.Refund, error)
+	ClaimStuckRefund(ctx context.Context, refundID uuid.UUID, before time.Time) (bool, error)
+}
+
+// Refund object
+type Refund struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewRefund creates a new reference to the Refund storage entity
+func NewRefund(s *Storage) *RefundDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "refund").Logger()
+	refund := &Refund{
+		logger:  l,
+		storage: s,
+	}
+
+	refundDatabase := RefundDatabase(refund)
+	return &refundDatabase
+}
+
+// CreateRefund adds a new refund into the refunds table
+func (r *Refund) CreateRefund(ctx context.Context, refund model.Refund) (model.Refund, error) {
+	db := r.storage.DB.WithContext(ctx).Create(&refund)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("CreateRefund error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.Refund{}, ErrRecordCreatingFailed
+	}
+
+	return refund, nil
+}
+
+// GetRefundByReference returns the tenant's refund with the reference
+func (r *Refund) GetRefundByReference(ctx context.Context, tenantID uuid.UUID, reference string) (model.Refund, error) {
+	var refund model.Refund
+
+	db := r.storage.DB.WithContext(ctx).Where("tenant_id = ? AND reference = ?", tenantID, reference).First(&refund)
+	if db.Error != nil {
+		return refund, ErrRecordNotFound
+	}
+
+	return refund, nil
+}
+
+// GetRefundsByTransactionID returns the refunds of a transaction, oldest first
+func (r *Refund) GetRefundsByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]model.Refund, error) {
+	var refunds []model.Refund
+
+	db := r.storage.DB.WithContext(ctx).Where("transaction_id = ?", transactionID).Order("created_at ASC").Find(&refunds)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("GetRefundsByTransactionID error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return refunds, nil
+}
+
+// UpdateRefundStatus moves a pending refund to its final status
+func (r *Refund) UpdateRefundStatus(ctx context.Context, refundID uuid.UUID, status model.RefundStatus) error {
+	db := r.storage.DB.WithContext(ctx).Model(&model.Refund{}).
+		Where("id = ? AND status = ?", refundID, model.RefundStatusPending).
+		Updates(map[string]interface{}{"status": status, "updated_at": time.Now()})
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("UpdateRefundStatus error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// GetRefundedTotal sums the refunds of a transaction in the given statuses
+func (r *Refund) GetRefundedTotal(ctx context.Context, transactionID uuid.UUID, currency string, statuses ...model.RefundStatus) (model.Money, error) {
+	var minor int64
+	err := r.storage.DB.WithContext(ctx).Model(&model.Refund{}).
+		Where("transaction_id = ? AND amount_currency = ? AND status IN ?", transactionID, currency, statuses).
+		Select("COALESCE(SUM(amount_minor), 0)").
+		Scan(&minor).Error
+	if err != nil {
+		r.logger.Err(err).Msgf("GetRefundedTotal error: %v", err)
+		return model.Money{}, ErrGeneric
+	}
+
+	return model.NewMoney(minor, currency), nil
+}
+
+// GetStuckRefunds returns up to limit pending refunds last touched before the time, the oldest first
+func (r *Refund) GetStuckRefunds(ctx context.Context, before time.Time, limit int) ([]model.Refund, error) {
+	var refunds []model.Refund
+
+	db := r.storage.DB.WithContext(ctx).
+		Where("status = ? AND COALESCE(updated_at, created_at) < ?", model.RefundStatusPending, before).
+		Order("created_at ASC").Limit(limit).Find(&refunds)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("GetStuckRefunds error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return refunds, nil
+}
+
+// ClaimStuckRefund touches a pending refund last touched before the time. It reports false when the refund is not
+// stuck anymore, e.g. it was settled or another worker claimed it first
+func (r *Refund) ClaimStuckRefund(ctx context.Context, refundID uuid.UUID, before time.Time) (bool, error) {
+	db := r.storage.DB.WithContext(ctx).Model(&model.Refund{}).
+		Where("id = ? AND status = ? AND COALESCE(updated_at, created_at) < ?", refundID, model.RefundStatusPending, before).
+		Update("updated_at", time.Now())
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("ClaimStuckRefund error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return false, ErrRecordUpdateFailed
+	}
+
+	return db.RowsAffected == 1, nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repositories groups every storage layer bound to the same database handle, either the main connection
+// or a database transaction handed out by WithTx
+type Repositories struct {
+	User               UserDatabase
+	AuditLog           AuditLogDatabase
+	Balance            BalanceDatabase
+	Wallet             WalletDatabase
+	Transaction        TransactionDatabase
+	Tenant             TenantDatabase
+	Ledger             LedgerDatabase
+	Hold               HoldDatabase
+	FX                 FXDatabase
+	Refund             RefundDatabase
+	Dispute            DisputeDatabase
+	Reconciliation     ReconciliationDatabase
+	BalanceSnapshot    BalanceSnapshotDatabase
+	LedgerVerification LedgerVerificationDatabase
+	Fee                FeeDatabase
+	Revenue            RevenueDatabase
+	Limit              LimitDatabase
+	Payout             PayoutDatabase
+	Invoice            InvoiceDatabase
+	Notification       NotificationDatabase
+	Escrow             EscrowDatabase
+	Split              SplitDatabase
+	Idempotency        IdempotencyDatabase
+	WebhookEvent       WebhookEventDatabase
+
+	storage *Storage
+}
+
+// NewRepositories creates every storage layer on top of the Storage
+func NewRepositories(s *Storage) Repositories {
+	return Repositories{
+		User:               *NewUser(s),
+		AuditLog:           *NewAuditLog(s),
+		Balance:            *NewBalance(s),
+		Wallet:             *NewWallet(s),
+		Transaction:        *NewTransaction(s),
+		Tenant:             *NewTenant(s),
+		Ledger:             *NewLedger(s),
+		Hold:               *NewHold(s),
+		FX:                 *NewFX(s),
+		Refund:             *NewRefund(s),
+		Dispute:            *NewDispute(s),
+		Reconciliation:     *NewReconciliation(s),
+		BalanceSnapshot:    *NewBalanceSnapshot(s),
+		LedgerVerification: *NewLedgerVerification(s),
+		Fee:                *NewFee(s),
+		Revenue:            *NewRevenue(s),
+		Limit:              *NewLimit(s),
+		Payout:             *NewPayout(s),
+		Invoice:            *NewInvoice(s),
+		Notification:       *NewNotification(s),
+		Escrow:             *NewEscrow(s),
+		Split:              *NewSplit(s),
+		Idempotency:        *NewIdempotency(s),
+		WebhookEvent:       *NewWebhookEvent(s),
+		storage:            s,
+	}
+}
+
+// WithTx runs fn as a unit of work. The repositories handed to fn are bound to one database transaction,
+// which is committed when fn returns nil and rolled back when it returns an error or panics
+func (s *Storage) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepositories(&Storage{
+			Logger: s.Logger,
+			Env:    s.Env,
+			DB:     tx,
+		}))
+	})
+}
+
+// WithTx runs fn as a unit of work on the repositories' database handle. When the repositories are already
+// bound to a database transaction, fn runs in a nested transaction (a savepoint) of it
+func (r Repositories) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	return r.storage.WithTx(ctx, fn)
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// RevenueDatabase enlists all possible operations on the tenants' revenue wallets
+type RevenueDatabase interface {
+	GetRevenueEntries(ctx context.Context, accountID uuid.UUID, page pagination.Page) ([]model.RevenueEntry, pagination.PageInfo, error)
+	GetRevenueDays(ctx context.Context, accountID uuid.UUID, from, to time.Time, timezone string) ([]model.RevenueDay, error)
+	CreateRevenueWithdrawal(ctx context.Context, withdrawal model.RevenueWithdrawal) (model.RevenueWithdrawal, error)
+	UpdateRevenueWithdrawal(ctx context.Context, withdrawal model.RevenueWithdrawal) error
+	GetRevenueWithdrawals(ctx context.Context, tenantID uuid.UUID, page pagination.Page) ([]model.RevenueWithdrawal, pagination.PageInfo, error)
+}
+
+// Revenue object
+type Revenue struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// revenueDayRow is a day of GetRevenueDays as it is read from the database
+type revenueDayRow struct {
+	Day            string
+	EarnedMinor    int64
+	WithdrawnMinor int64
+	Earnings       int
+}
+
+// NewRevenue creates a new reference to the Revenue storage entity
+func NewRevenue(s *Storage) *RevenueDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "revenue").Logger()
+	revenue := &Revenue{
+		logger:  l,
+		storage: s,
+	}
+
+	revenueDatabase := RevenueDatabase(revenue)
+	return &revenueDatabase
+}
+
+// GetRevenueEntries returns the postings made to a revenue wallet's ledger account with the journal entry they belong
+// to, newest first
+func (r *Revenue) GetRevenueEntries(ctx context.Context, accountID uuid.UUID, page pagination.Page) ([]model.RevenueEntry, pagination.PageInfo, error) {
+	var entries []model.RevenueEntry
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := r.storage.DB.WithContext(ctx).Model(&model.Posting{}).
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Where("postings.account_id = ?", accountID)
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Select("postings.id AS posting_id, postings.journal_entry_id, journal_entries.transaction_id, " +
+		"journal_entries.description, postings.amount_minor, postings.amount_currency, postings.created_at").
+		Offset(offset).Limit(*page.Size).Order("postings.created_at DESC").Scan(&entries)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("GetRevenueEntries error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return entries, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// GetRevenueDays sums the postings made to a revenue wallet's ledger account from (inclusive) to (exclusive) per day
+// of the timezone, oldest first. Days without postings are left out. A withdrawal and its reversal are both withdrawal
+// entries, so a failed withdrawal nets out of what was withdrawn
+func (r *Revenue) GetRevenueDays(ctx context.Context, accountID uuid.UUID, from, to time.Time, timezone string) ([]model.RevenueDay, error) {
+	var account model.LedgerAccount
+	if err := r.storage.DB.WithContext(ctx).Where("id = ?", accountID).First(&account).Error; err != nil {
+		r.logger.Err(err).Msgf("GetRevenueDays error: %v (%v)", ErrRecordNotFound, err)
+		return nil, ErrRecordNotFound
+	}
+
+	var rows []revenueDayRow
+	db := r.storage.DB.WithContext(ctx).Model(&model.Posting{}).
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Where("postings.account_id = ? AND postings.created_at >= ? AND postings.created_at < ?", accountID, from, to).
+		Select("TO_CHAR(postings.created_at AT TIME ZONE ?, 'YYYY-MM-DD') AS day, "+
+			"COALESCE(SUM(postings.amount_minor) FILTER (WHERE journal_entries.description <> ?), 0) AS earned_minor, "+
+			"COALESCE(-SUM(postings.amount_minor) FILTER (WHERE journal_entries.description = ?), 0) AS withdrawn_minor, "+
+			"COUNT(*) FILTER (WHERE journal_entries.description <> ?) AS earnings",
+			timezone, model.RevenueWithdrawalEntryDescription, model.RevenueWithdrawalEntryDescription, model.RevenueWithdrawalEntryDescription).
+		Group("day").Order("day ASC").Scan(&rows)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("GetRevenueDays error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	days := make([]model.RevenueDay, 0, len(rows))
+	for _, row := range rows {
+		days = append(days, model.RevenueDay{
+			Day:       row.Day,
+			Earned:    model.NewMoney(row.EarnedMinor, account.Currency),
+			Withdrawn: model.NewMoney(row.WithdrawnMinor, account.Currency),
+			Net:       model.NewMoney(row.EarnedMinor-row.WithdrawnMinor, account.Currency),
+			Earnings:  row.Earnings,
+		})
+	}
+
+	return days, nil
+}
+
+// CreateRevenueWithdrawal adds a withdrawal into the revenue_withdrawals table
+func (r *Revenue) CreateRevenueWithdrawal(ctx context.Context, withdrawal model.RevenueWithdrawal) (model.RevenueWithdrawal, error) {
+	db := r.storage.DB.WithContext(ctx).Create(&withdrawal)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("CreateRevenueWithdrawal error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.RevenueWithdrawal{}, ErrRecordCreatingFailed
+	}
+
+	return withdrawal, nil
+}
+
+// UpdateRevenueWithdrawal saves the status, reversal entry and failure reason of a pending withdrawal
+func (r *Revenue) UpdateRevenueWithdrawal(ctx context.Context, withdrawal model.RevenueWithdrawal) error {
+	db := r.storage.DB.WithContext(ctx).Model(&model.RevenueWithdrawal{}).
+		Where("id = ? AND status = ?", withdrawal.ID, model.RevenueWithdrawalStatusPending).
+		Updates(map[string]interface{}{
+			"status":            withdrawal.Status,
+			"reversal_entry_id": withdrawal.ReversalEntryID,
+			"failure_reason":    withdrawal.FailureReason,
+			"updated_at":        time.Now(),
+		})
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("UpdateRevenueWithdrawal error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// GetRevenueWithdrawals returns the tenant's revenue withdrawals, newest first
+func (r *Revenue) GetRevenueWithdrawals(ctx context.Context, tenantID uuid.UUID, page pagination.Page) ([]model.RevenueWithdrawal, pagination.PageInfo, error) {
+	var withdrawals []model.RevenueWithdrawal
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := r.storage.DB.WithContext(ctx).Model(&model.RevenueWithdrawal{}).Where("tenant_id = ?", tenantID)
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&withdrawals)
+	if db.Error != nil {
+		r.logger.Err(db.Error).Msgf("GetRevenueWithdrawals error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return withdrawals, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
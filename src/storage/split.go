@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// SplitFilter narrows the splits GetSplits returns, nil fields are not filtered on
+type SplitFilter struct {
+	TenantID *uuid.UUID
+	OwnerID  *uuid.UUID
+}
+
+// SplitDatabase enlists all possible operations on splits
+type SplitDatabase interface {
+	CreateSplit(ctx context.Context, split model.Split) (model.Split, error)
+	GetSplitByID(ctx context.Context, splitID uuid.UUID) (model.Split, error)
+	GetSplits(ctx context.Context, filter SplitFilter, page pagination.Page) ([]model.Split, pagination.PageInfo, error)
+}
+
+// Split object
+type Split struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewSplit creates a new reference to the Split storage entity
+func NewSplit(s *Storage) *SplitDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "split").Logger()
+	split := &Split{
+		logger:  l,
+		storage: s,
+	}
+
+	splitDatabase := SplitDatabase(split)
+	return &splitDatabase
+}
+
+// CreateSplit adds a new split with its shares into the splits tables
+func (s *Split) CreateSplit(ctx context.Context, split model.Split) (model.Split, error) {
+	db := s.storage.DB.WithContext(ctx).Create(&split)
+	if db.Error != nil {
+		s.logger.Err(db.Error).Msgf("CreateSplit error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return model.Split{}, ErrRecordCreatingFailed
+	}
+
+	return split, nil
+}
+
+// GetSplitByID returns a split with its shares by its ID
+func (s *Split) GetSplitByID(ctx context.Context, splitID uuid.UUID) (model.Split, error) {
+	var split model.Split
+
+	db := s.storage.DB.WithContext(ctx).Preload("Shares").Where("id = ?", splitID).First(&split)
+	if db.Error != nil {
+		s.logger.Err(db.Error).Msgf("GetSplitByID error: %v (%v)", ErrRecordNotFound, db.Error)
+		return split, ErrRecordNotFound
+	}
+
+	return split, nil
+}
+
+// GetSplits returns the splits matching the filter with their shares, newest first
+func (s *Split) GetSplits(ctx context.Context, filter SplitFilter, page pagination.Page) ([]model.Split, pagination.PageInfo, error) {
+	var splits []model.Split
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := s.storage.DB.WithContext(ctx).Model(&model.Split{})
+	if filter.TenantID != nil {
+		query = query.Where("tenant_id = ?", *filter.TenantID)
+	}
+	if filter.OwnerID != nil {
+		query = query.Where("owner_id = ?", *filter.OwnerID)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Preload("Shares").Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&splits)
+	if db.Error != nil {
+		s.logger.Err(db.Error).Msgf("GetSplits error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return splits, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
@@ -89,9 +89,35 @@ func (s *Storage) Close() {
 }
 
 func (s *Storage) AutoMigrate() error {
-	return s.DB.AutoMigrate(
+	err := s.DB.AutoMigrate(
 		model.AuditLog{}, model.Balance{},
 		model.Tenant{}, model.Transaction{},
 		model.User{}, model.Wallet{},
+		model.LedgerAccount{}, model.JournalEntry{}, model.Posting{},
+		model.Hold{}, model.FXRate{}, model.FXConversion{},
+		model.Refund{}, model.Dispute{}, model.DisputeEvidence{},
+		model.ReconciliationRun{}, model.ReconciliationItem{}, model.BalanceSnapshot{},
+		model.LedgerVerification{}, model.LedgerBreak{}, model.RevenueWithdrawal{},
+		model.TransactionLimit{}, model.PayoutBatch{}, model.PayoutItem{},
+		model.Invoice{}, model.InvoiceLineItem{}, model.InvoicePayment{}, model.Notification{},
+		model.Escrow{}, model.Split{}, model.SplitShare{}, model.IdempotencyKey{},
+		model.WebhookEvent{},
 	)
+	if err != nil {
+		return err
+	}
+
+	if err := s.migrateMoneyColumns(); err != nil {
+		return err
+	}
+
+	if err := s.redactWebhookHeaders(); err != nil {
+		return err
+	}
+
+	if err := s.migrateFeeRules(); err != nil {
+		return err
+	}
+
+	return s.migrateWalletsToLedger()
 }
@@ -1,12 +1,17 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
+
+	"codematic/model"
 )
 
 func TestInit(t *testing.T) {
@@ -15,15 +20,18 @@ func TestInit(t *testing.T) {
 
 type Suite struct {
 	suite.Suite
-	DB           *gorm.DB
-	mock         sqlmock.Sqlmock
-	userDatabase UserDatabase
+	DB             *gorm.DB
+	mock           sqlmock.Sqlmock
+	userDatabase   UserDatabase
+	ledgerDatabase LedgerDatabase
 }
 
 func (s *Suite) SetupSuite() {
 	var store *Storage
 	s.mock, store = GetStorage(s.Suite.T())
+	s.DB = store.DB
 	s.userDatabase = *NewUser(store)
+	s.ledgerDatabase = *NewLedger(store)
 }
 
 func (s *Suite) AfterTest(_, _ string) {
@@ -35,3 +43,48 @@ func (s *Suite) Test_Storage_Dummy() {
 	testString := "Ninja skills are in progress. 80% loading..."
 	require.Equal(s.Suite.T(), 44, len(testString))
 }
+
+func (s *Suite) Test_PostJournalEntry_Unbalanced() {
+	entry := model.JournalEntry{
+		ID: uuid.New(),
+		Postings: []model.Posting{
+			{ID: uuid.New(), AccountID: uuid.New(), Amount: model.NewMoney(500000, "NGN")},
+			{ID: uuid.New(), AccountID: uuid.New(), Amount: model.NewMoney(-499950, "NGN")},
+		},
+	}
+
+	_, err := s.ledgerDatabase.PostJournalEntry(context.Background(), entry)
+	require.ErrorIs(s.Suite.T(), err, ErrUnbalancedJournalEntry)
+
+	// postings in different currencies can never balance
+	entry.Postings[1].Amount = model.NewMoney(-500000, "USD")
+	_, err = s.ledgerDatabase.PostJournalEntry(context.Background(), entry)
+	require.ErrorIs(s.Suite.T(), err, ErrUnbalancedJournalEntry)
+
+	// a single posting can never balance
+	entry.Postings = entry.Postings[:1]
+	_, err = s.ledgerDatabase.PostJournalEntry(context.Background(), entry)
+	require.ErrorIs(s.Suite.T(), err, ErrUnbalancedJournalEntry)
+}
+
+func (s *Suite) Test_WithTx_RollsBackOnError() {
+	store := NewFromDB(s.DB)
+	failure := errors.New("wallet creation failed")
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectRollback()
+
+	err := store.WithTx(context.Background(), func(repos Repositories) error {
+		require.NotNil(s.Suite.T(), repos.Wallet)
+		return failure
+	})
+	require.ErrorIs(s.Suite.T(), err, failure)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectCommit()
+
+	err = store.WithTx(context.Background(), func(repos Repositories) error {
+		return nil
+	})
+	require.NoError(s.Suite.T(), err)
+}
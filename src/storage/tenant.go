@@ -16,7 +16,9 @@ type TenantDatabase interface {
 	CreateTenant(ctx context.Context, tenant model.Tenant) (model.Tenant, error)
 	GetTenantByID(ctx context.Context, id uuid.UUID) (model.Tenant, error)
 	UpdateTenantByID(ctx context.Context, tenant model.Tenant) error
+	SetAllowCrossTenantTransfers(ctx context.Context, tenantID uuid.UUID, allow bool) error
 	GetTenantByEmail(ctx context.Context, email string) (model.Tenant, error)
+	GetTenants(ctx context.Context) ([]model.Tenant, error)
 }
 
 // Tenant object
@@ -74,6 +76,23 @@ func (t *Tenant) UpdateTenantByID(ctx context.Context, tenant model.Tenant) erro
 	return nil
 }
 
+// SetAllowCrossTenantTransfers sets whether the tenant's users may transfer to and from users of other tenants.
+// It is a column update of its own as UpdateTenantByID skips false values
+func (t *Tenant) SetAllowCrossTenantTransfers(ctx context.Context, tenantID uuid.UUID, allow bool) error {
+	db := t.storage.DB.WithContext(ctx).Model(&model.Tenant{}).Where("id = ?", tenantID).
+		Update("allow_cross_tenant_transfers", allow)
+	if db.Error != nil {
+		t.logger.Err(db.Error).Msgf("SetAllowCrossTenantTransfers error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	if db.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
 // GetTenantByEmail returns a tenant matching the email
 func (t *Tenant) GetTenantByEmail(ctx context.Context, email string) (model.Tenant, error) {
 	var tenant model.Tenant
@@ -86,3 +105,16 @@ func (t *Tenant) GetTenantByEmail(ctx context.Context, email string) (model.Tena
 
 	return tenant, nil
 }
+
+// GetTenants returns every tenant, oldest first
+func (t *Tenant) GetTenants(ctx context.Context) ([]model.Tenant, error) {
+	var tenants []model.Tenant
+
+	db := t.storage.DB.WithContext(ctx).Order("created_at ASC").Find(&tenants)
+	if db.Error != nil {
+		t.logger.Err(db.Error).Msgf("GetTenants error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return tenants, nil
+}
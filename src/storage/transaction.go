@@ -3,9 +3,11 @@ package storage
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"gorm.io/gorm/clause"
 
 	"codematic/model"
 	"codematic/model/pagination"
@@ -17,7 +19,12 @@ type TransactionDatabase interface {
 	CreateTransaction(ctx context.Context, transaction model.Transaction) (model.Transaction, error)
 	GetTransactionsByUserID(ctx context.Context, userID uuid.UUID, transactionFlow *model.TransactionFlow, page pagination.Page) ([]model.Transaction, pagination.PageInfo, error)
 	GetTransactionByID(ctx context.Context, transactionID uuid.UUID) (model.Transaction, error)
+	GetTransactionByIDForUpdate(ctx context.Context, transactionID uuid.UUID) (model.Transaction, error)
 	UpdateTransactionByID(ctx context.Context, transaction model.Transaction) error
+	GetTransactionsByIDs(ctx context.Context, transactionIDs []uuid.UUID) ([]model.Transaction, error)
+	GetProviderTransactions(ctx context.Context, provider model.PaymentProvider, status model.TransactionStatus, from, to time.Time) ([]model.Transaction, error)
+	GetSettledTransactionsByUserID(ctx context.Context, userID uuid.UUID, currency string, before time.Time) ([]model.Transaction, error)
+	GetTransactionUsage(ctx context.Context, userID uuid.UUID, flow model.TransactionFlow, txType model.TransactionType, currency string, from time.Time) (model.LimitUsage, error)
 }
 
 // Transaction config object
@@ -106,6 +113,20 @@ func (tx *Transaction) GetTransactionByID(ctx context.Context, transactionID uui
 	return transaction, nil
 }
 
+// GetTransactionByIDForUpdate retrieves a transaction and locks its row (SELECT ... FOR UPDATE) until the
+// surrounding database transaction ends. It must be called on a Storage bound to a database transaction
+func (tx *Transaction) GetTransactionByIDForUpdate(ctx context.Context, transactionID uuid.UUID) (model.Transaction, error) {
+	var transaction model.Transaction
+
+	db := tx.storage.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", transactionID).First(&transaction)
+	if db.Error != nil {
+		tx.logger.Err(db.Error).Msgf("TransactionService:: Error locking transaction by ID %v: %v", transactionID, db.Error)
+		return model.Transaction{}, ErrRecordNotFound
+	}
+
+	return transaction, nil
+}
+
 // UpdateTransactionByID updates a transaction by transaction ID
 func (tx *Transaction) UpdateTransactionByID(ctx context.Context, transaction model.Transaction) error {
 	db := tx.storage.DB.WithContext(ctx).Model(model.Transaction{}).Where("id = ?", transaction.ID).
@@ -117,3 +138,74 @@ func (tx *Transaction) UpdateTransactionByID(ctx context.Context, transaction mo
 
 	return nil
 }
+
+// GetTransactionsByIDs retrieves the transactions with the IDs, IDs without a transaction are left out
+func (tx *Transaction) GetTransactionsByIDs(ctx context.Context, transactionIDs []uuid.UUID) ([]model.Transaction, error) {
+	var transactions []model.Transaction
+
+	if len(transactionIDs) == 0 {
+		return transactions, nil
+	}
+
+	db := tx.storage.DB.WithContext(ctx).Where("id IN ?", transactionIDs).Find(&transactions)
+	if db.Error != nil {
+		tx.logger.Err(db.Error).Msgf("TransactionService:: Error fetching transactions by IDs: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return transactions, nil
+}
+
+// GetProviderTransactions retrieves the transactions that went through the provider in the status, created between
+// from and to inclusive
+func (tx *Transaction) GetProviderTransactions(ctx context.Context, provider model.PaymentProvider, status model.TransactionStatus, from, to time.Time) ([]model.Transaction, error) {
+	var transactions []model.Transaction
+
+	db := tx.storage.DB.WithContext(ctx).
+		Where("provider = ? AND status = ? AND created_at BETWEEN ? AND ?", provider, status, from, to).
+		Order("created_at ASC").Find(&transactions)
+	if db.Error != nil {
+		tx.logger.Err(db.Error).Msgf("TransactionService:: Error fetching %s transactions: %v", provider, db.Error)
+		return nil, ErrGeneric
+	}
+
+	return transactions, nil
+}
+
+// GetSettledTransactionsByUserID retrieves the user's transactions in the currency created before the time that moved
+// the wallet: the successful ones and the refunded credits
+func (tx *Transaction) GetSettledTransactionsByUserID(ctx context.Context, userID uuid.UUID, currency string, before time.Time) ([]model.Transaction, error) {
+	var transactions []model.Transaction
+
+	db := tx.storage.DB.WithContext(ctx).
+		Where("user_id = ? AND amount_currency = ? AND status IN ? AND created_at < ?", userID, strings.ToUpper(currency),
+			[]model.TransactionStatus{model.TransactionStatusSuccessful, model.TransactionStatusRefunded}, before).
+		Order("created_at ASC").Find(&transactions)
+	if db.Error != nil {
+		tx.logger.Err(db.Error).Msgf("TransactionService:: Error fetching settled transactions: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return transactions, nil
+}
+
+// GetTransactionUsage counts and sums the user's transactions of the flow and type in the currency created since from,
+// leaving out the failed and canceled ones
+func (tx *Transaction) GetTransactionUsage(ctx context.Context, userID uuid.UUID, flow model.TransactionFlow, txType model.TransactionType, currency string, from time.Time) (model.LimitUsage, error) {
+	var row struct {
+		Count int64
+		Minor int64
+	}
+
+	db := tx.storage.DB.WithContext(ctx).Model(&model.Transaction{}).
+		Where("user_id = ? AND transaction_flow = ? AND transaction_type = ? AND amount_currency = ? AND created_at >= ? AND status NOT IN ?",
+			userID, flow, txType, strings.ToUpper(currency), from,
+			[]model.TransactionStatus{model.TransactionStatusFailed, model.TransactionStatusCanceled}).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount_minor), 0) AS minor").Scan(&row)
+	if db.Error != nil {
+		tx.logger.Err(db.Error).Msgf("TransactionService:: Error summing transaction usage: %v", db.Error)
+		return model.LimitUsage{}, ErrGeneric
+	}
+
+	return model.LimitUsage{Count: row.Count, Amount: model.NewMoney(row.Minor, currency)}, nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
 
 	"codematic/model"
 	"codematic/pkg/helper"
@@ -14,8 +16,13 @@ import (
 // WalletDatabase shows every methods available under the wallet to interact with the database
 type WalletDatabase interface {
 	CreateWallet(ctx context.Context, wallet model.Wallet) (model.Wallet, error)
-	GetWalletByUserID(ctx context.Context, userID uuid.UUID) (model.Wallet, error)
+	GetWalletByUserID(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error)
+	GetWalletsByUserID(ctx context.Context, userID uuid.UUID) ([]model.Wallet, error)
+	GetWalletByUserIDForUpdate(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error)
+	GetWalletByIDForUpdate(ctx context.Context, walletID uuid.UUID) (model.Wallet, error)
 	UpdateWalletByID(ctx context.Context, wallet model.Wallet) error
+	UpdateWalletStatus(ctx context.Context, walletID uuid.UUID, status model.WalletStatus, reason string) error
+	GetWalletsByTenantID(ctx context.Context, tenantID uuid.UUID) ([]model.Wallet, error)
 }
 
 // Wallet object
@@ -46,17 +53,102 @@ func (w *Wallet) CreateWallet(ctx context.Context, wallet model.Wallet) (model.W
 	return wallet, nil
 }
 
-// GetWalletByUserID gets a wallet by it the user's id from the wallet table
-func (w *Wallet) GetWalletByUserID(ctx context.Context, userID uuid.UUID) (model.Wallet, error) {
+// GetWalletByUserID gets the user's wallet in the currency from the wallet table.
+// The balances on the wallet are derived from the postings made to the wallet's ledger account
+func (w *Wallet) GetWalletByUserID(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error) {
 	var wallet model.Wallet
 
-	db := w.storage.DB.WithContext(ctx).Where("user_id = ?", userID).First(&wallet)
+	db := w.storage.DB.WithContext(ctx).Where("user_id = ? AND currency = ?", userID, strings.ToUpper(currency)).First(&wallet)
 	if db.Error != nil || strings.EqualFold(wallet.ID.String(), helper.ZeroUUID) {
 		w.storage.Logger.Err(db.Error).Msgf("GetWalletByUserID ::: error: %v (%v)", ErrRecordNotFound, db.Error)
 		fmt.Println(db.Error)
 		return wallet, db.Error
 	}
 
+	return w.withLedgerBalance(ctx, wallet)
+}
+
+// GetWalletsByUserID gets every wallet of the user, one per currency
+func (w *Wallet) GetWalletsByUserID(ctx context.Context, userID uuid.UUID) ([]model.Wallet, error) {
+	var wallets []model.Wallet
+
+	db := w.storage.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&wallets)
+	if db.Error != nil {
+		w.storage.Logger.Err(db.Error).Msgf("GetWalletsByUserID ::: error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	for i := range wallets {
+		wallet, err := w.withLedgerBalance(ctx, wallets[i])
+		if err != nil {
+			return nil, err
+		}
+
+		wallets[i] = wallet
+	}
+
+	return wallets, nil
+}
+
+// GetWalletByUserIDForUpdate gets the user's wallet in the currency and locks the wallet row (SELECT ... FOR UPDATE)
+// until the surrounding database transaction ends. It must be called on a Storage bound to a database transaction
+func (w *Wallet) GetWalletByUserIDForUpdate(ctx context.Context, userID uuid.UUID, currency string) (model.Wallet, error) {
+	var wallet model.Wallet
+
+	db := w.storage.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND currency = ?", userID, strings.ToUpper(currency)).First(&wallet)
+	if db.Error != nil || strings.EqualFold(wallet.ID.String(), helper.ZeroUUID) {
+		w.storage.Logger.Err(db.Error).Msgf("GetWalletByUserIDForUpdate ::: error: %v (%v)", ErrRecordNotFound, db.Error)
+		return wallet, ErrRecordNotFound
+	}
+
+	return w.withLedgerBalance(ctx, wallet)
+}
+
+// GetWalletByIDForUpdate gets a wallet by its ID and locks the wallet row (SELECT ... FOR UPDATE) until the
+// surrounding database transaction ends. It must be called on a Storage bound to a database transaction
+func (w *Wallet) GetWalletByIDForUpdate(ctx context.Context, walletID uuid.UUID) (model.Wallet, error) {
+	var wallet model.Wallet
+
+	db := w.storage.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", walletID).First(&wallet)
+	if db.Error != nil || strings.EqualFold(wallet.ID.String(), helper.ZeroUUID) {
+		w.storage.Logger.Err(db.Error).Msgf("GetWalletByIDForUpdate ::: error: %v (%v)", ErrRecordNotFound, db.Error)
+		return wallet, ErrRecordNotFound
+	}
+
+	return w.withLedgerBalance(ctx, wallet)
+}
+
+// withLedgerBalance sets the wallet balances from the postings made to the wallet's ledger account,
+// and the available balance from the wallet's active holds
+func (w *Wallet) withLedgerBalance(ctx context.Context, wallet model.Wallet) (model.Wallet, error) {
+	if wallet.LedgerAccountID != nil {
+		balance, err := ledgerAccountBalance(w.storage.DB.WithContext(ctx), *wallet.LedgerAccountID)
+		if err != nil {
+			w.storage.Logger.Err(err).Msgf("withLedgerBalance ::: ledger balance error: %v", err)
+			return wallet, ErrGeneric
+		}
+
+		wallet.BalanceBefore = balance
+		wallet.BalanceAfter = balance
+
+		posting, err := lastPosting(w.storage.DB.WithContext(ctx), *wallet.LedgerAccountID)
+		if err == nil {
+			wallet.BalanceBefore, _ = balance.Sub(posting.Amount)
+		}
+	}
+
+	held, err := activeHoldsTotal(w.storage.DB.WithContext(ctx), wallet.ID, wallet.Currency)
+	if err != nil {
+		w.storage.Logger.Err(err).Msgf("withLedgerBalance ::: holds total error: %v", err)
+		return wallet, ErrGeneric
+	}
+
+	wallet.BookBalance = wallet.BalanceAfter
+	if wallet.AvailableBalance, err = wallet.BookBalance.Sub(held); err != nil {
+		return wallet, ErrGeneric
+	}
+
 	return wallet, nil
 }
 
@@ -73,3 +165,37 @@ func (w *Wallet) UpdateWalletByID(ctx context.Context, wallet model.Wallet) erro
 
 	return nil
 }
+
+// UpdateWalletStatus sets the status of a wallet and why it was changed
+func (w *Wallet) UpdateWalletStatus(ctx context.Context, walletID uuid.UUID, status model.WalletStatus, reason string) error {
+	now := time.Now()
+	db := w.storage.DB.WithContext(ctx).Model(&model.Wallet{}).Where("id = ?", walletID).
+		Updates(map[string]interface{}{
+			"status":            status,
+			"status_reason":     reason,
+			"status_changed_at": now,
+			"updated_at":        now,
+		})
+	if db.Error != nil {
+		w.storage.Logger.Err(db.Error).Msgf("UpdateWalletStatus ::: error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
+
+// GetWalletsByTenantID gets every wallet of the tenant's users, without their ledger balances
+func (w *Wallet) GetWalletsByTenantID(ctx context.Context, tenantID uuid.UUID) ([]model.Wallet, error) {
+	var wallets []model.Wallet
+
+	db := w.storage.DB.WithContext(ctx).
+		Joins("JOIN users ON users.id = wallets.user_id AND users.deleted_at IS NULL").
+		Where("users.tenant_id = ?", tenantID).
+		Order("wallets.created_at ASC").Find(&wallets)
+	if db.Error != nil {
+		w.storage.Logger.Err(db.Error).Msgf("GetWalletsByTenantID ::: error: %v", db.Error)
+		return nil, ErrGeneric
+	}
+
+	return wallets, nil
+}
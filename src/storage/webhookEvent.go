@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm/clause"
+
+	"codematic/model"
+	"codematic/model/pagination"
+	"codematic/pkg/helper"
+)
+
+// WebhookEventFilter narrows the events GetWebhookEvents returns, nil fields are not filtered on
+type WebhookEventFilter struct {
+	Provider *model.PaymentProvider
+	Status   *model.WebhookEventStatus
+}
+
+// WebhookEventDatabase enlists all possible operations on the webhook inbox
+type WebhookEventDatabase interface {
+	CreateWebhookEvent(ctx context.Context, event model.WebhookEvent) (bool, error)
+	GetWebhookEventByID(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error)
+	GetWebhookEventByIDForUpdate(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error)
+	GetWebhookEventByProviderEventID(ctx context.Context, provider model.PaymentProvider, providerEventID string) (model.WebhookEvent, error)
+	GetWebhookEvents(ctx context.Context, filter WebhookEventFilter, page pagination.Page) ([]model.WebhookEvent, pagination.PageInfo, error)
+	UpdateWebhookEvent(ctx context.Context, event model.WebhookEvent) error
+}
+
+// WebhookEvent object
+type WebhookEvent struct {
+	logger  zerolog.Logger
+	storage *Storage
+}
+
+// NewWebhookEvent creates a new reference to the WebhookEvent storage entity
+func NewWebhookEvent(s *Storage) *WebhookEventDatabase {
+	l := s.Logger.With().Str(helper.LogStrKeyLevel, "webhook_event").Logger()
+	webhookEvent := &WebhookEvent{
+		logger:  l,
+		storage: s,
+	}
+
+	webhookEventDatabase := WebhookEventDatabase(webhookEvent)
+	return &webhookEventDatabase
+}
+
+// CreateWebhookEvent adds a new event into the webhook events table and reports whether it was added, it is not when
+// the provider already delivered the event
+func (w *WebhookEvent) CreateWebhookEvent(ctx context.Context, event model.WebhookEvent) (bool, error) {
+	db := w.storage.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if db.Error != nil {
+		w.logger.Err(db.Error).Msgf("CreateWebhookEvent error: %v, (%v)", ErrRecordCreatingFailed, db.Error)
+		return false, ErrRecordCreatingFailed
+	}
+
+	return db.RowsAffected == 1, nil
+}
+
+// GetWebhookEventByID returns an event of the inbox by its ID
+func (w *WebhookEvent) GetWebhookEventByID(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error) {
+	var event model.WebhookEvent
+
+	db := w.storage.DB.WithContext(ctx).Where("id = ?", eventID).First(&event)
+	if db.Error != nil {
+		w.logger.Err(db.Error).Msgf("GetWebhookEventByID error: %v (%v)", ErrRecordNotFound, db.Error)
+		return event, ErrRecordNotFound
+	}
+
+	return event, nil
+}
+
+// GetWebhookEventByIDForUpdate returns an event of the inbox and locks its row (SELECT ... FOR UPDATE) until the
+// surrounding database transaction ends. It must be called on a Storage bound to a database transaction
+func (w *WebhookEvent) GetWebhookEventByIDForUpdate(ctx context.Context, eventID uuid.UUID) (model.WebhookEvent, error) {
+	var event model.WebhookEvent
+
+	db := w.storage.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", eventID).First(&event)
+	if db.Error != nil {
+		w.logger.Err(db.Error).Msgf("GetWebhookEventByIDForUpdate error: %v (%v)", ErrRecordNotFound, db.Error)
+		return event, ErrRecordNotFound
+	}
+
+	return event, nil
+}
+
+// GetWebhookEventByProviderEventID returns the event a provider delivered under its own event ID
+func (w *WebhookEvent) GetWebhookEventByProviderEventID(ctx context.Context, provider model.PaymentProvider, providerEventID string) (model.WebhookEvent, error) {
+	var event model.WebhookEvent
+
+	db := w.storage.DB.WithContext(ctx).Where("provider = ? AND event_id = ?", provider, providerEventID).First(&event)
+	if db.Error != nil {
+		w.logger.Err(db.Error).Msgf("GetWebhookEventByProviderEventID error: %v (%v)", ErrRecordNotFound, db.Error)
+		return event, ErrRecordNotFound
+	}
+
+	return event, nil
+}
+
+// GetWebhookEvents returns the events of the inbox matching the filter, newest first
+func (w *WebhookEvent) GetWebhookEvents(ctx context.Context, filter WebhookEventFilter, page pagination.Page) ([]model.WebhookEvent, pagination.PageInfo, error) {
+	var events []model.WebhookEvent
+
+	offset := 0
+	if page.Number == nil {
+		tmpPageNumber := pagination.PageDefaultNumber
+		page.Number = &tmpPageNumber
+	}
+	if page.Size == nil {
+		tmpPageSize := pagination.PageDefaultSize
+		page.Size = &tmpPageSize
+	}
+
+	if *page.Number > 1 {
+		offset = *page.Size * (*page.Number - 1)
+	}
+
+	query := w.storage.DB.WithContext(ctx).Model(&model.WebhookEvent{})
+	if filter.Provider != nil {
+		query = query.Where("provider = ?", *filter.Provider)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	db := query.Offset(offset).Limit(*page.Size).Order("created_at DESC").Find(&events)
+	if db.Error != nil {
+		w.logger.Err(db.Error).Msgf("GetWebhookEvents error: %v", db.Error)
+		return nil, pagination.PageInfo{}, ErrGeneric
+	}
+
+	return events, pagination.PageInfo{
+		Page:            *page.Number,
+		Size:            *page.Size,
+		HasNextPage:     int64(offset+*page.Size) < count,
+		HasPreviousPage: *page.Number > 1,
+		TotalCount:      count,
+	}, nil
+}
+
+// UpdateWebhookEvent saves the processing state of an event of the inbox
+func (w *WebhookEvent) UpdateWebhookEvent(ctx context.Context, event model.WebhookEvent) error {
+	now := time.Now()
+	event.UpdatedAt = &now
+
+	db := w.storage.DB.WithContext(ctx).Model(&model.WebhookEvent{}).Where("id = ?", event.ID).
+		Select("status", "attempts", "last_error", "processed_at", "updated_at").
+		Updates(&event)
+	if db.Error != nil {
+		w.logger.Err(db.Error).Msgf("UpdateWebhookEvent error: %v, (%v)", ErrRecordUpdateFailed, db.Error)
+		return ErrRecordUpdateFailed
+	}
+
+	return nil
+}
@@ -26,14 +26,21 @@ func (f *flutterwaveProvider) VirtualAccount(fullName, bankName string) (model.V
 }
 
 // Transfer make external transfers to with the users bank or someone else's bank account
-func (f *flutterwaveProvider) Transfer(bankNumber string, accountNumber string, amount float64) error {
-	fmt.Printf("Flutterwave Withdraw: %s -> %s : %.2f\n", bankNumber, accountNumber, amount)
+func (f *flutterwaveProvider) Transfer(bankNumber string, accountNumber string, amount model.Money) error {
+	fmt.Printf("Flutterwave Withdraw: %s -> %s : %s\n", bankNumber, accountNumber, amount)
 	return nil
 }
 
 // Deposit simulate making an API call to flutterwave assumming we already have
 // the users card details, so we just need an amount to be passed in
-func (f *flutterwaveProvider) Deposit(amount float64) error {
-	fmt.Printf("Flutterwave Deposit: %.2f\n", amount)
+func (f *flutterwaveProvider) Deposit(amount model.Money) error {
+	fmt.Printf("Flutterwave Deposit: %s\n", amount)
+	return nil
+}
+
+// Refund simulate making an API call to flutterwave to give back all or part of the charge with the reference. The
+// idempotency key identifies the refund, a call repeated with the same key is the same refund and is not paid twice
+func (f *flutterwaveProvider) Refund(reference, idempotencyKey string, amount model.Money) error {
+	fmt.Printf("Flutterwave Refund: %s (%s) : %s\n", reference, idempotencyKey, amount)
 	return nil
 }
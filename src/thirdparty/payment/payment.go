@@ -2,7 +2,6 @@
 package payment
 
 import (
-	"context"
 	"errors"
 	"fmt"
 
@@ -21,8 +20,9 @@ const (
 // PaymentProvider defines the contract each payment provider must implement
 type PaymentProvider interface {
 	VirtualAccount(fullName, bankName string) (model.VirtualAccount, error)
-	Transfer(bankNumber string, accountNumber string, amount float64) error
-	Deposit(amount float64) error
+	Transfer(bankNumber string, accountNumber string, amount model.Money) error
+	Deposit(amount model.Money) error
+	Refund(reference, idempotencyKey string, amount model.Money) error
 }
 
 // PaymentService provides access to all registered payment providers
@@ -93,17 +93,9 @@ func (s *PaymentService) InitiateTransaction(provider model.PaymentProvider, act
 		return model.VirtualAccount{}, p.Transfer(payload.BankNumber, payload.AccountNumber, payload.Amount)
 	case model.PaymentActionDeposit:
 		return model.VirtualAccount{}, p.Deposit(payload.Amount)
+	case model.PaymentActionRefund:
+		return model.VirtualAccount{}, p.Refund(payload.Reference, payload.IdempotencyKey, payload.Amount)
 	default:
 		return model.VirtualAccount{}, errors.New("unsupported action")
 	}
 }
-
-func (p *PaymentService) IsIdempotencyKeyUsed(ctx context.Context, key string) (bool, error) {
-	// TODO check DB idempotency key
-	return false, nil
-}
-
-func (p *PaymentService) MarkIdempotencyKeyUsed(ctx context.Context, key string) error {
-	// TODO store idempotency key
-	return nil
-}
@@ -21,14 +21,21 @@ func (p *paystackProvider) VirtualAccount(fullName, bankName string) (model.Virt
 }
 
 // Transfer make external transfers to with the users bank or someone else's bank account
-func (p *paystackProvider) Transfer(bankNumber string, accountNumber string, amount float64) error {
-	fmt.Printf("Paystack Withdraw: %s -> %s : %.2f\n", bankNumber, accountNumber, amount)
+func (p *paystackProvider) Transfer(bankNumber string, accountNumber string, amount model.Money) error {
+	fmt.Printf("Paystack Withdraw: %s -> %s : %s\n", bankNumber, accountNumber, amount)
 	return nil
 }
 
 // Deposit simulate making an API call to payatack assumming we already have
 // the users card details, so we just need an amount to be passed in
-func (p *paystackProvider) Deposit(amount float64) error {
-	fmt.Printf("Paystack Deposit: %.2f\n", amount)
+func (p *paystackProvider) Deposit(amount model.Money) error {
+	fmt.Printf("Paystack Deposit: %s\n", amount)
+	return nil
+}
+
+// Refund simulate making an API call to paystack to give back all or part of the charge with the reference. The
+// idempotency key identifies the refund, a call repeated with the same key is the same refund and is not paid twice
+func (p *paystackProvider) Refund(reference, idempotencyKey string, amount model.Money) error {
+	fmt.Printf("Paystack Refund: %s (%s) : %s\n", reference, idempotencyKey, amount)
 	return nil
 }
@@ -0,0 +1,206 @@
+package payment
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"codematic/model"
+)
+
+// ErrUnsupportedSettlementFile is returned for a settlement file that is neither .csv nor .json
+var ErrUnsupportedSettlementFile = errors.New("settlement file must be .csv or .json")
+
+type (
+	// settlementFormat describes how a provider lays out the transactions of its settlement files
+	settlementFormat struct {
+		reference string
+		amount    string
+		currency  string
+		status    string
+		settledAt string
+		// minorUnits is true when the provider reports amounts in minor units, e.g. kobo
+		minorUnits bool
+	}
+
+	// settlementFile is the JSON shape of a settlement file, the transactions are under data
+	settlementFile struct {
+		Data []map[string]any `json:"data"`
+	}
+)
+
+// settlementFormats lists the settlement file layout of every supported provider
+var settlementFormats = map[model.PaymentProvider]settlementFormat{
+	model.PaymentProviderPaystack: {
+		reference: "reference", amount: "amount", currency: "currency", status: "status", settledAt: "paid_at",
+		minorUnits: true,
+	},
+	model.PaymentProviderFlutterwave: {
+		reference: "tx_ref", amount: "amount", currency: "currency", status: "status", settledAt: "created_at",
+	},
+}
+
+// ParseSettlementFile reads the transactions of a provider's settlement file, a CSV with a header row or a JSON
+// document with the transactions under data, as told by the file name's extension
+func ParseSettlementFile(provider model.PaymentProvider, fileName string, r io.Reader) ([]model.SettlementRecord, error) {
+	format, ok := settlementFormats[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+
+	var (
+		rows []map[string]string
+		err  error
+	)
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		rows, err = readSettlementCSV(r)
+	case ".json":
+		rows, err = readSettlementJSON(r)
+	default:
+		return nil, ErrUnsupportedSettlementFile
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]model.SettlementRecord, 0, len(rows))
+	for i, row := range rows {
+		record, err := format.record(row)
+		if err != nil {
+			return nil, fmt.Errorf("settlement record %d: %w", i+1, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// record converts one row of a settlement file into a SettlementRecord
+func (f settlementFormat) record(row map[string]string) (model.SettlementRecord, error) {
+	reference := strings.TrimSpace(row[f.reference])
+	if reference == "" {
+		return model.SettlementRecord{}, fmt.Errorf("%s is missing", f.reference)
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(row[f.currency]))
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+
+	var (
+		amount model.Money
+		err    error
+	)
+
+	if f.minorUnits {
+		var minor int64
+		minor, err = strconv.ParseInt(strings.TrimSpace(row[f.amount]), 10, 64)
+		amount = model.NewMoney(minor, currency)
+	} else {
+		amount, err = model.ParseMoney(strings.TrimSpace(row[f.amount]), currency)
+	}
+	if err != nil {
+		return model.SettlementRecord{}, fmt.Errorf("invalid %s %q: %w", f.amount, row[f.amount], err)
+	}
+
+	status, err := settlementStatus(row[f.status])
+	if err != nil {
+		return model.SettlementRecord{}, err
+	}
+
+	record := model.SettlementRecord{
+		Reference: reference,
+		Amount:    amount,
+		Status:    status,
+	}
+
+	if settledAt := strings.TrimSpace(row[f.settledAt]); settledAt != "" {
+		if record.SettledAt, err = time.Parse(time.RFC3339, settledAt); err != nil {
+			return model.SettlementRecord{}, fmt.Errorf("invalid %s %q: %w", f.settledAt, settledAt, err)
+		}
+	}
+
+	return record, nil
+}
+
+// settlementStatus maps a provider's transaction status to ours
+func settlementStatus(status string) (model.TransactionStatus, error) {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "success", "successful":
+		return model.TransactionStatusSuccessful, nil
+	case "failed":
+		return model.TransactionStatusFailed, nil
+	case "pending", "processing":
+		return model.TransactionStatusPending, nil
+	case "reversed", "refunded":
+		return model.TransactionStatusRefunded, nil
+	case "abandoned", "cancelled":
+		return model.TransactionStatusCanceled, nil
+	default:
+		return "", fmt.Errorf("unknown status %q", status)
+	}
+}
+
+func readSettlementCSV(r io.Reader) ([]map[string]string, error) {
+	lines, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	header := lines[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	rows := make([]map[string]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(line) {
+				row[column] = line[i]
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func readSettlementJSON(r io.Reader) ([]map[string]string, error) {
+	var file settlementFile
+
+	decoder := json.NewDecoder(r)
+	// keep amounts exact, a float64 would round large kobo amounts
+	decoder.UseNumber()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]string, 0, len(file.Data))
+	for _, data := range file.Data {
+		row := make(map[string]string, len(data))
+		for key, value := range data {
+			if value != nil {
+				row[strings.ToLower(key)] = fmt.Sprint(value)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
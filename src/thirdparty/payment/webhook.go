@@ -0,0 +1,251 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"codematic/model"
+)
+
+// ErrUnsupportedWebhookEvent is returned for a webhook whose event type the adapters do not handle, the provider
+// sends many more event types than the ones that move a wallet
+var ErrUnsupportedWebhookEvent = errors.New("webhook event type is not supported")
+
+type (
+	// paystackWebhook is the body of a Paystack webhook, amounts are in minor units
+	paystackWebhook struct {
+		Event string `json:"event"`
+		Data  struct {
+			ID        json.Number     `json:"id"`
+			Status    string          `json:"status"`
+			Reference string          `json:"reference"`
+			Amount    json.Number     `json:"amount"`
+			Currency  string          `json:"currency"`
+			Fees      json.Number     `json:"fees"`
+			Metadata  json.RawMessage `json:"metadata"`
+		} `json:"data"`
+	}
+
+	// flutterwaveWebhook is the body of a Flutterwave webhook, amounts are in major units. A charge carries our
+	// reference in tx_ref and its fee in app_fee, a transfer in reference and fee
+	flutterwaveWebhook struct {
+		Event string `json:"event"`
+		Data  struct {
+			ID        json.Number     `json:"id"`
+			Status    string          `json:"status"`
+			TxRef     string          `json:"tx_ref"`
+			Reference string          `json:"reference"`
+			Amount    json.Number     `json:"amount"`
+			Currency  string          `json:"currency"`
+			AppFee    json.Number     `json:"app_fee"`
+			Fee       json.Number     `json:"fee"`
+			Meta      json.RawMessage `json:"meta"`
+		} `json:"data"`
+	}
+)
+
+// paystackEvents maps the Paystack event types we handle to their kind and outcome
+var paystackEvents = map[string]struct {
+	kind   model.PaymentEventKind
+	status model.TransactionStatus
+}{
+	"charge.success":    {model.PaymentEventCharge, model.TransactionStatusSuccessful},
+	"transfer.success":  {model.PaymentEventTransfer, model.TransactionStatusSuccessful},
+	"transfer.failed":   {model.PaymentEventTransfer, model.TransactionStatusFailed},
+	"transfer.reversed": {model.PaymentEventTransfer, model.TransactionStatusRefunded},
+}
+
+// flutterwaveEvents maps the Flutterwave event types we handle to their kind, the outcome is in the data's status
+var flutterwaveEvents = map[string]model.PaymentEventKind{
+	"charge.completed":   model.PaymentEventCharge,
+	"transfer.completed": model.PaymentEventTransfer,
+}
+
+// ParseWebhook normalises the body of a provider's webhook into a PaymentEvent. A body that is valid but of an event
+// type we do not handle returns ErrUnsupportedWebhookEvent with the event's ID and type set
+func ParseWebhook(provider model.PaymentProvider, body []byte) (model.PaymentEvent, error) {
+	switch provider {
+	case model.PaymentProviderPaystack:
+		return parsePaystackWebhook(body)
+	case model.PaymentProviderFlutterwave:
+		return parseFlutterwaveWebhook(body)
+	default:
+		return model.PaymentEvent{}, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// WebhookEventID is the ID the inbox keys a provider's webhook by. The providers keep the ID of the transaction or
+// transfer across its events, e.g. transfer.success then transfer.reversed, so the event type and status are part
+// of it. A body the adapter cannot read is keyed by model.WebhookEventID
+func WebhookEventID(provider model.PaymentProvider, body []byte) string {
+	event, _ := ParseWebhook(provider, body)
+	if event.ID != "" {
+		return event.ID
+	}
+
+	return model.WebhookEventID(body)
+}
+
+// parsePaystackWebhook reads a Paystack webhook, its outcome is told by the event type
+func parsePaystackWebhook(body []byte) (model.PaymentEvent, error) {
+	var webhook paystackWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return model.PaymentEvent{}, fmt.Errorf("invalid paystack webhook: %w", err)
+	}
+
+	event := model.PaymentEvent{
+		Provider:  model.PaymentProviderPaystack,
+		ID:        webhookEventID(webhook.Event, webhook.Data.Status, webhook.Data.ID),
+		Type:      webhook.Event,
+		Reference: strings.TrimSpace(webhook.Data.Reference),
+	}
+
+	outcome, ok := paystackEvents[webhook.Event]
+	if !ok {
+		return event, ErrUnsupportedWebhookEvent
+	}
+	event.Kind, event.Status = outcome.kind, outcome.status
+
+	currency, err := webhookCurrency(webhook.Data.Currency)
+	if err != nil {
+		return event, err
+	}
+
+	if event.Amount, err = minorAmount(webhook.Data.Amount, currency); err != nil {
+		return event, fmt.Errorf("invalid amount %q: %w", webhook.Data.Amount, err)
+	}
+
+	if event.Fees, err = minorAmount(webhook.Data.Fees, currency); err != nil {
+		return event, fmt.Errorf("invalid fees %q: %w", webhook.Data.Fees, err)
+	}
+
+	event.Metadata = webhookMetadata(webhook.Data.Metadata)
+	return event, checkWebhookEvent(event)
+}
+
+// parseFlutterwaveWebhook reads a Flutterwave webhook, its outcome is told by the data's status
+func parseFlutterwaveWebhook(body []byte) (model.PaymentEvent, error) {
+	var webhook flutterwaveWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return model.PaymentEvent{}, fmt.Errorf("invalid flutterwave webhook: %w", err)
+	}
+
+	event := model.PaymentEvent{
+		Provider: model.PaymentProviderFlutterwave,
+		ID:       webhookEventID(webhook.Event, webhook.Data.Status, webhook.Data.ID),
+		Type:     webhook.Event,
+	}
+
+	kind, ok := flutterwaveEvents[webhook.Event]
+	if !ok {
+		return event, ErrUnsupportedWebhookEvent
+	}
+	event.Kind = kind
+
+	fee := webhook.Data.Fee
+	event.Reference = strings.TrimSpace(webhook.Data.Reference)
+	if kind == model.PaymentEventCharge {
+		fee = webhook.Data.AppFee
+		event.Reference = strings.TrimSpace(webhook.Data.TxRef)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(webhook.Data.Status)) {
+	case "successful":
+		event.Status = model.TransactionStatusSuccessful
+	case "failed":
+		event.Status = model.TransactionStatusFailed
+	case "pending":
+		event.Status = model.TransactionStatusPending
+	default:
+		return event, fmt.Errorf("%w: %s with status %q", ErrUnsupportedWebhookEvent, webhook.Event, webhook.Data.Status)
+	}
+
+	currency, err := webhookCurrency(webhook.Data.Currency)
+	if err != nil {
+		return event, err
+	}
+
+	if event.Amount, err = majorAmount(webhook.Data.Amount, currency); err != nil {
+		return event, fmt.Errorf("invalid amount %q: %w", webhook.Data.Amount, err)
+	}
+
+	if event.Fees, err = majorAmount(fee, currency); err != nil {
+		return event, fmt.Errorf("invalid fee %q: %w", fee, err)
+	}
+
+	event.Metadata = webhookMetadata(webhook.Data.Meta)
+	return event, checkWebhookEvent(event)
+}
+
+// webhookEventID builds the ID of a provider's event from its type, status and the ID of its transaction
+func webhookEventID(event, status string, id json.Number) string {
+	if event == "" || id == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:%s:%s", event, strings.ToLower(strings.TrimSpace(status)), id)
+}
+
+// checkWebhookEvent refuses an event missing what it takes to apply it
+func checkWebhookEvent(event model.PaymentEvent) error {
+	if event.Reference == "" {
+		return errors.New("webhook reference is missing")
+	}
+
+	if !event.Amount.IsPositive() {
+		return model.ErrInvalidAmount
+	}
+
+	return nil
+}
+
+// webhookCurrency returns the supported currency code of a webhook
+func webhookCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !model.IsSupportedCurrency(currency) {
+		return "", fmt.Errorf("%w: %q", model.ErrUnsupportedCurrency, currency)
+	}
+
+	return currency, nil
+}
+
+// minorAmount reads an amount in minor units, a missing amount is zero
+func minorAmount(amount json.Number, currency string) (model.Money, error) {
+	if amount == "" {
+		return model.ZeroMoney(currency), nil
+	}
+
+	minor, err := strconv.ParseInt(amount.String(), 10, 64)
+	if err != nil {
+		return model.Money{}, model.ErrInvalidAmount
+	}
+
+	return model.NewMoney(minor, currency), nil
+}
+
+// majorAmount reads an amount in major units exactly, a missing amount is zero
+func majorAmount(amount json.Number, currency string) (model.Money, error) {
+	if amount == "" {
+		return model.ZeroMoney(currency), nil
+	}
+
+	return model.ParseMoney(amount.String(), currency)
+}
+
+// webhookMetadata returns the metadata of a webhook when it is an object, providers send 0, "" or null without it
+func webhookMetadata(raw json.RawMessage) map[string]any {
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return nil
+	}
+
+	var metadata map[string]any
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil
+	}
+
+	return metadata
+}
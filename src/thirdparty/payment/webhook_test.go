@@ -0,0 +1,183 @@
+package payment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codematic/model"
+)
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		provider model.PaymentProvider
+		expected model.PaymentEvent
+	}{
+		{
+			fixture:  "paystack_charge_success.json",
+			provider: model.PaymentProviderPaystack,
+			expected: model.PaymentEvent{
+				Provider:  model.PaymentProviderPaystack,
+				ID:        "charge.success:success:302961",
+				Type:      "charge.success",
+				Kind:      model.PaymentEventCharge,
+				Status:    model.TransactionStatusSuccessful,
+				Reference: "crt_7d9a6a52-3f1e-4c8b-9e0a-2b6f1c4d8e31",
+				Amount:    model.NewMoney(1000050, "NGN"),
+				Fees:      model.NewMoney(15001, "NGN"),
+				Metadata:  map[string]any{"invoice_reference": "INV-2024-0001"},
+			},
+		},
+		{
+			fixture:  "paystack_transfer_success.json",
+			provider: model.PaymentProviderPaystack,
+			expected: model.PaymentEvent{
+				Provider:  model.PaymentProviderPaystack,
+				ID:        "transfer.success:success:37272792",
+				Type:      "transfer.success",
+				Kind:      model.PaymentEventTransfer,
+				Status:    model.TransactionStatusSuccessful,
+				Reference: "dbt_0c4f2b1e-6a7d-4e8f-9b3c-5d2a1e7f4b60",
+				Amount:    model.NewMoney(3000000, "NGN"),
+				Fees:      model.ZeroMoney("NGN"),
+			},
+		},
+		{
+			fixture:  "paystack_transfer_failed.json",
+			provider: model.PaymentProviderPaystack,
+			expected: model.PaymentEvent{
+				Provider:  model.PaymentProviderPaystack,
+				ID:        "transfer.failed:failed:37272792",
+				Type:      "transfer.failed",
+				Kind:      model.PaymentEventTransfer,
+				Status:    model.TransactionStatusFailed,
+				Reference: "dbt_0c4f2b1e-6a7d-4e8f-9b3c-5d2a1e7f4b60",
+				Amount:    model.NewMoney(3000000, "NGN"),
+				Fees:      model.ZeroMoney("NGN"),
+			},
+		},
+		{
+			fixture:  "paystack_transfer_reversed.json",
+			provider: model.PaymentProviderPaystack,
+			expected: model.PaymentEvent{
+				Provider:  model.PaymentProviderPaystack,
+				ID:        "transfer.reversed:reversed:37272792",
+				Type:      "transfer.reversed",
+				Kind:      model.PaymentEventTransfer,
+				Status:    model.TransactionStatusRefunded,
+				Reference: "dbt_0c4f2b1e-6a7d-4e8f-9b3c-5d2a1e7f4b60",
+				Amount:    model.NewMoney(3000000, "NGN"),
+				Fees:      model.ZeroMoney("NGN"),
+			},
+		},
+		{
+			fixture:  "flutterwave_charge_completed.json",
+			provider: model.PaymentProviderFlutterwave,
+			expected: model.PaymentEvent{
+				Provider:  model.PaymentProviderFlutterwave,
+				ID:        "charge.completed:successful:285959875",
+				Type:      "charge.completed",
+				Kind:      model.PaymentEventCharge,
+				Status:    model.TransactionStatusSuccessful,
+				Reference: "crt_9b1e7c3a-5d2f-4a6b-8c0e-1f3d5a7b9c24",
+				Amount:    model.NewMoney(1000050, "NGN"),
+				Fees:      model.NewMoney(14001, "NGN"),
+			},
+		},
+		{
+			fixture:  "flutterwave_charge_failed.json",
+			provider: model.PaymentProviderFlutterwave,
+			expected: model.PaymentEvent{
+				Provider:  model.PaymentProviderFlutterwave,
+				ID:        "charge.completed:failed:285959875",
+				Type:      "charge.completed",
+				Kind:      model.PaymentEventCharge,
+				Status:    model.TransactionStatusFailed,
+				Reference: "crt_9b1e7c3a-5d2f-4a6b-8c0e-1f3d5a7b9c24",
+				Amount:    model.NewMoney(1000050, "NGN"),
+				Fees:      model.NewMoney(14001, "NGN"),
+			},
+		},
+		{
+			fixture:  "flutterwave_transfer_completed.json",
+			provider: model.PaymentProviderFlutterwave,
+			expected: model.PaymentEvent{
+				Provider:  model.PaymentProviderFlutterwave,
+				ID:        "transfer.completed:successful:33286",
+				Type:      "transfer.completed",
+				Kind:      model.PaymentEventTransfer,
+				Status:    model.TransactionStatusSuccessful,
+				Reference: "dbt_4e2a9c7b-1d3f-4b5e-8a6c-0f9d2b4e6a81",
+				Amount:    model.NewMoney(3002000, "NGN"),
+				Fees:      model.NewMoney(1075, "NGN"),
+			},
+		},
+		{
+			fixture:  "flutterwave_transfer_failed.json",
+			provider: model.PaymentProviderFlutterwave,
+			expected: model.PaymentEvent{
+				Provider:  model.PaymentProviderFlutterwave,
+				ID:        "transfer.completed:failed:33286",
+				Type:      "transfer.completed",
+				Kind:      model.PaymentEventTransfer,
+				Status:    model.TransactionStatusFailed,
+				Reference: "dbt_4e2a9c7b-1d3f-4b5e-8a6c-0f9d2b4e6a81",
+				Amount:    model.NewMoney(3002000, "NGN"),
+				Fees:      model.NewMoney(1075, "NGN"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			body := readWebhookFixture(t, test.fixture)
+
+			event, err := ParseWebhook(test.provider, body)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, event)
+			require.Equal(t, test.expected.ID, WebhookEventID(test.provider, body))
+		})
+	}
+}
+
+func TestParseWebhook_Errors(t *testing.T) {
+	body := readWebhookFixture(t, "paystack_subscription_create.json")
+
+	// an event that moves no wallet is told apart from a broken one, and still has an ID for the inbox
+	event, err := ParseWebhook(model.PaymentProviderPaystack, body)
+	require.ErrorIs(t, err, ErrUnsupportedWebhookEvent)
+	require.Equal(t, "subscription.create", event.Type)
+	require.Equal(t, model.WebhookEventID(body), WebhookEventID(model.PaymentProviderPaystack, body), "the event has no data.id")
+
+	// the same transfer's events are distinct events of the inbox
+	success := WebhookEventID(model.PaymentProviderPaystack, readWebhookFixture(t, "paystack_transfer_success.json"))
+	reversed := WebhookEventID(model.PaymentProviderPaystack, readWebhookFixture(t, "paystack_transfer_reversed.json"))
+	require.NotEqual(t, success, reversed)
+
+	// a body of one provider is not read as another's
+	_, err = ParseWebhook(model.PaymentProviderFlutterwave, readWebhookFixture(t, "paystack_charge_success.json"))
+	require.ErrorIs(t, err, ErrUnsupportedWebhookEvent)
+
+	_, err = ParseWebhook(model.PaymentProviderPaystack, []byte(`{"event":"charge.success","data":{"id":1,"reference":"crt_1","amount":100,"currency":"XYZ"}}`))
+	require.ErrorIs(t, err, model.ErrUnsupportedCurrency)
+
+	_, err = ParseWebhook(model.PaymentProviderPaystack, []byte(`{"event":"charge.success","data":{"id":1,"amount":100,"currency":"NGN"}}`))
+	require.Error(t, err)
+
+	_, err = ParseWebhook(model.PaymentProviderPaystack, []byte(`not json`))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrUnsupportedWebhookEvent)
+}
+
+// readWebhookFixture reads a webhook body as the provider sends it from testdata
+func readWebhookFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", "webhooks", name))
+	require.NoError(t, err)
+
+	return body
+}